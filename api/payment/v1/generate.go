@@ -0,0 +1,4 @@
+// Package paymentv1 содержит сгенерированный код gRPC API платежной системы.
+package paymentv1
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative payment.proto
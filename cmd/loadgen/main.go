@@ -0,0 +1,343 @@
+// Package main реализует loadgen - генератор нагрузки на API платежной системы.
+// Готовит фикстуру с кошельками для команды seed сервиса, с заданной частотой выполняет смесь
+// переводов, чтения балансов и списка транзакций между кошельками фикстуры и выводит процентили
+// длительности запросов, ошибки по кодам ответа и проверку сохранения суммы их балансов.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"infotecsTest/internal/client"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/models"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// Коды завершения
+const (
+	exitOK      = 0 // Нагрузка выполнена, сумма балансов сохранена
+	exitFailure = 1 // Ошибка подготовки или нарушение сохранения суммы балансов
+	exitUsage   = 2 // Некорректные аргументы или конфигурация
+)
+
+// Параметры проверки сохранения суммы балансов
+const (
+	balanceTolerance = 1e-6                   // Допустимое расхождение сумм с плавающей точкой
+	readRetries      = 50                     // Повторы чтения баланса при ограничении частоты
+	readRetryDelay   = 100 * time.Millisecond // Пауза между повторами
+)
+
+// fixture - файл кошельков в формате фикстуры команды seed сервиса.
+type fixture struct {
+	Wallets []models.Wallet `json:"wallets"` // Кошельки с начальными балансами
+}
+
+// options - параметры запуска.
+type options struct {
+	fixture      string
+	writeFixture bool
+	baseURL      string
+	apiKey       string
+	adminURL     string
+	adminKey     string
+	wallets      int
+	balance      float64
+	maxAmount    int
+	rps          int
+	concurrency  int
+	duration     time.Duration
+	mix          mix
+	json         bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run разбирает аргументы, выполняет нагрузку и возвращает код завершения.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		_, _ = fmt.Fprintf(stderr, "error: %s\n", err)
+		return exitUsage
+	}
+
+	if opts.writeFixture {
+		if err = writeFixture(opts); err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: write fixture: %s\n", err)
+			return exitFailure
+		}
+		_, _ = fmt.Fprintf(stderr, "wrote %d wallets with balance %v to %s, load them with the seed command\n",
+			opts.wallets, opts.balance, opts.fixture)
+		return exitOK
+	}
+
+	wallets, expected, err := readFixture(opts.fixture)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: read fixture: %s\n", err)
+		return exitFailure
+	}
+	key := opts.apiKey
+	if opts.adminURL != "" {
+		admin := client.New(client.Config{BaseURL: opts.baseURL, AdminURL: opts.adminURL, AdminKey: opts.adminKey})
+		if key, err = assignWallets(ctx, admin, wallets); err != nil {
+			_, _ = fmt.Fprintf(stderr, "error: assign wallets: %s\n", err)
+			return exitFailure
+		}
+	}
+	_, _ = fmt.Fprintf(stderr, "loaded %d wallets, running %s at %d rps\n", len(wallets), opts.duration, opts.rps)
+
+	c := client.New(client.Config{BaseURL: opts.baseURL, APIKey: key})
+	rec := newRecorder()
+	start := time.Now()
+	generate(ctx, opts, func(ctx context.Context, op string) error {
+		return execute(ctx, c, op, wallets, opts.maxAmount)
+	}, rec)
+	rep := rec.report(time.Since(start))
+
+	// Проверка выполняется и после прерывания: принятые переводы уже завершены
+	rep.Conservation, err = checkConservation(context.WithoutCancel(ctx), c, wallets, expected)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: conservation check: %s\n", err)
+		return exitFailure
+	}
+
+	if err = rep.print(stdout, opts.json); err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %s\n", err)
+		return exitFailure
+	}
+	if !rep.Conservation.OK {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// parseOptions разбирает и проверяет аргументы командной строки.
+func parseOptions(args []string, stderr io.Writer) (options, error) {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var opts options
+	fs.StringVar(&opts.fixture, "fixture", "", "wallets fixture, loaded into the service with the seed command")
+	fs.BoolVar(&opts.writeFixture, "write-fixture", false, "write a new fixture with -wallets wallets of -balance and exit")
+	fs.StringVar(&opts.baseURL, "url", "http://localhost:8080", "API base URL")
+	fs.StringVar(&opts.apiKey, "api-key", "", "API key of the user owning the fixture wallets when auth is enabled")
+	fs.StringVar(&opts.adminURL, "admin-url", "", "admin server URL: assign the fixture wallets to a new user and use its key")
+	fs.StringVar(&opts.adminKey, "admin-key", os.Getenv("AUTH_ADMIN_KEY"), "admin server key (default $AUTH_ADMIN_KEY)")
+	fs.IntVar(&opts.wallets, "wallets", 100, "number of wallets in a new fixture")
+	fs.Float64Var(&opts.balance, "balance", 1000, "initial balance of each wallet in a new fixture")
+	fs.IntVar(&opts.maxAmount, "max-amount", 10, "maximum transfer amount, amounts are whole numbers from 1")
+	fs.IntVar(&opts.rps, "rps", 100, "target requests per second")
+	fs.IntVar(&opts.concurrency, "concurrency", 16, "maximum requests in flight")
+	fs.DurationVar(&opts.duration, "duration", 30*time.Second, "load duration")
+	mixFlag := fs.String("mix", "send=70,balance=25,transactions=5", "operation weights: send, balance, transactions")
+	fs.BoolVar(&opts.json, "json", false, "print report as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+
+	var errs []error
+	if opts.fixture == "" {
+		errs = append(errs, errors.New("-fixture is required"))
+	}
+	if opts.wallets < 2 {
+		errs = append(errs, errors.New("-wallets must be at least 2"))
+	}
+	if opts.balance < 0 {
+		errs = append(errs, errors.New("-balance must not be negative"))
+	}
+	if opts.maxAmount < 1 {
+		errs = append(errs, errors.New("-max-amount must be at least 1"))
+	}
+	if opts.rps < 1 || opts.concurrency < 1 || opts.duration <= 0 {
+		errs = append(errs, errors.New("-rps, -concurrency and -duration must be positive"))
+	}
+	m, err := parseMix(*mixFlag)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	opts.mix = m
+	return opts, errors.Join(errs...)
+}
+
+// writeFixture записывает фикстуру с opts.wallets кошельками со случайными адресами
+// и балансом opts.balance. Существующий файл не перезаписывается: его кошельки могли быть
+// уже загружены в сервис.
+func writeFixture(opts options) error {
+	fx := fixture{Wallets: make([]models.Wallet, opts.wallets)}
+	for i := range fx.Wallets {
+		fx.Wallets[i] = models.Wallet{Address: address.New(), Balance: opts.balance}
+	}
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(opts.fixture, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readFixture возвращает адреса кошельков фикстуры и сумму их начальных балансов.
+func readFixture(path string) ([]string, float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var fx fixture
+	if err = json.Unmarshal(data, &fx); err != nil {
+		return nil, 0, err
+	}
+	if len(fx.Wallets) < 2 {
+		return nil, 0, fmt.Errorf("fixture has %d wallets, at least 2 are required", len(fx.Wallets))
+	}
+
+	addresses := make([]string, len(fx.Wallets))
+	var total float64
+	for i, w := range fx.Wallets {
+		addresses[i] = w.Address
+		total += w.Balance
+	}
+	return addresses, total, nil
+}
+
+// assignWallets передает кошельки фикстуры новому пользователю loadgen на служебном сервере
+// и выпускает его ключ API: при включенной аутентификации переводы разрешены только владельцу.
+// Кошельки должны быть уже загружены в сервис командой seed.
+func assignWallets(ctx context.Context, admin *client.Client, wallets []string) (string, error) {
+	user, err := admin.CreateUser(ctx, models.UserRequest{Name: "loadgen"})
+	if err != nil {
+		return "", err
+	}
+	for _, address := range wallets {
+		if _, err = admin.AssignWallet(ctx, user.ID, address); err != nil {
+			return "", fmt.Errorf("wallet %s: %w", address, err)
+		}
+	}
+
+	cred, err := admin.IssueKey(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return cred.Key, nil
+}
+
+// generate выполняет операции с частотой opts.rps не более чем opts.concurrency одновременно
+// в течение opts.duration или до отмены ctx. Запросы отправляются по расписанию независимо
+// от ответов; запрос, для которого нет свободного исполнителя, не отправляется и учитывается
+// как пропущенный. Возвращает после завершения всех отправленных запросов.
+func generate(ctx context.Context, opts options, do func(ctx context.Context, op string) error, rec *recorder) {
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range jobs {
+				start := time.Now()
+				err := do(ctx, op)
+				rec.record(op, time.Since(start), err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(opts.rps))
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.duration)
+	defer deadline.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-ticker.C:
+			select {
+			case jobs <- opts.mix.pick():
+			default:
+				rec.drop()
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// execute выполняет одну операцию над случайными кошельками.
+func execute(ctx context.Context, c *client.Client, op string, wallets []string, maxAmount int) error {
+	switch op {
+	case opSend:
+		from := rand.IntN(len(wallets))
+		to := (from + 1 + rand.IntN(len(wallets)-1)) % len(wallets)
+		_, err := c.Send(ctx, models.Transaction{
+			From:   wallets[from],
+			To:     wallets[to],
+			Amount: float64(1 + rand.IntN(maxAmount)),
+		})
+		return err
+	case opBalance:
+		_, err := c.Balance(ctx, wallets[rand.IntN(len(wallets))])
+		return err
+	case opTransactions:
+		_, err := c.Transactions(ctx, 10)
+		return err
+	default:
+		return fmt.Errorf("unknown operation %q", op)
+	}
+}
+
+// checkConservation сравнивает сумму балансов кошельков с суммой начальных балансов expected.
+// Переводы выполняются только между кошельками фикстуры, поэтому суммы должны совпадать.
+func checkConservation(ctx context.Context, c *client.Client, wallets []string, expected float64) (conservation, error) {
+	res := conservation{Wallets: len(wallets), Expected: expected}
+	for _, address := range wallets {
+		w, err := balanceWithRetry(ctx, c, address)
+		if err != nil {
+			return conservation{}, fmt.Errorf("wallet %s: %w", address, err)
+		}
+		res.Actual += w.Balance
+	}
+	res.OK = math.Abs(res.Actual-res.Expected) <= balanceTolerance
+	return res, nil
+}
+
+// balanceWithRetry читает баланс, повторяя запрос при ограничении частоты.
+func balanceWithRetry(ctx context.Context, c *client.Client, address string) (models.Wallet, error) {
+	for attempt := 0; ; attempt++ {
+		w, err := c.Balance(ctx, address)
+		var apiErr *client.APIError
+		if err == nil || attempt == readRetries || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			return w, err
+		}
+		select {
+		case <-ctx.Done():
+			return models.Wallet{}, ctx.Err()
+		case <-time.After(readRetryDelay):
+		}
+	}
+}
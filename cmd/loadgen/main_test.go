@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/client"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/http-server/handlers/transaction"
+	"infotecsTest/internal/http-server/handlers/user"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	cases := []struct {
+		name        string
+		mix         string
+		expected    map[string]int
+		expectedErr bool
+	}{
+		{name: "Все операции", mix: "send=70, balance=25, transactions=5", expected: map[string]int{opSend: 70, opBalance: 25, opTransactions: 5}},
+		{name: "Только переводы", mix: "send=1", expected: map[string]int{opSend: 1}},
+		{name: "Неизвестная операция", mix: "send=1,refund=1", expectedErr: true},
+		{name: "Повтор операции", mix: "send=1,send=2", expectedErr: true},
+		{name: "Отрицательный вес", mix: "send=-1", expectedErr: true},
+		{name: "Без веса", mix: "send", expectedErr: true},
+		{name: "Нулевые веса", mix: "send=0,balance=0", expectedErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := parseMix(tc.mix)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m.weights)
+			for range 100 {
+				require.Contains(t, tc.expected, m.pick())
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	require.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	require.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	require.Equal(t, 100*time.Millisecond, percentile(latencies, 100))
+	require.Equal(t, time.Millisecond, percentile(latencies, 0))
+	require.Zero(t, percentile(nil, 50))
+}
+
+func TestErrorCode(t *testing.T) {
+	require.Equal(t, "insufficient_funds", errorCode(fmt.Errorf("client.Send: %w", &client.APIError{StatusCode: 400, Code: "insufficient_funds"})))
+	require.Equal(t, "http_502", errorCode(&client.APIError{StatusCode: 502}))
+	require.Equal(t, "timeout", errorCode(context.DeadlineExceeded))
+	require.Equal(t, "transport", errorCode(io.ErrUnexpectedEOF))
+}
+
+func TestRun(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{JournalMode: "wal", BusyTimeout: 5 * time.Second, TxLock: "immediate"})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	// Кошельки загружаются из фикстуры, как командой seed
+	fixturePath := filepath.Join(t.TempDir(), "fixture.json")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-fixture", fixturePath, "-write-fixture", "-wallets", "5", "-balance", "3"}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	data, err := os.ReadFile(fixturePath)
+	require.NoError(t, err)
+	var fx fixture
+	require.NoError(t, json.Unmarshal(data, &fx))
+	require.Len(t, fx.Wallets, 5)
+	require.NoError(t, s.Seed(context.Background(), fx.Wallets, nil))
+
+	// Существующая фикстура не перезаписывается
+	code = run(context.Background(), []string{"-fixture", fixturePath, "-write-fixture"}, &stdout, &stderr)
+	require.Equal(t, exitFailure, code)
+
+	// Кошельки передаются пользователю и ключ выпускается на служебном сервере, API требует ключ пользователя
+	cfg := config.Auth{Enabled: true, Header: "X-API-Key", AdminKey: "0123456789abcdef"}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	adminRouter := chi.NewRouter()
+	adminRouter.Use(auth.Admin(log, cfg))
+	adminRouter.Post("/users", user.Create(log, s))
+	adminRouter.Put("/users/{id}/wallets/{address}", user.AssignWallet(log, s, s, s))
+	adminRouter.Post("/users/{id}/keys", user.IssueKey(log, s))
+	adminSrv := httptest.NewServer(adminRouter)
+	defer adminSrv.Close()
+
+	router := chi.NewRouter()
+	router.Use(auth.New(log, cfg, s))
+	router.Get("/api/transactions", transaction.GetLast(log, s))
+	authorizer := auth.NewAuthorizer(cfg, s)
+	router.Get("/api/wallet/{address}/balance", wallet.GetBalance(log, s, s, authorizer))
+	router.Post("/api/send", transaction.Send(log, s, s, authorizer))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// Малые балансы и крупные суммы: часть переводов отклоняется из-за нехватки средств
+	stderr.Reset()
+	code = run(context.Background(), []string{
+		"-fixture", fixturePath,
+		"-url", srv.URL,
+		"-admin-url", adminSrv.URL,
+		"-admin-key", cfg.AdminKey,
+		"-max-amount", "5",
+		"-rps", "400",
+		"-concurrency", "4",
+		"-duration", "300ms",
+		"-mix", "send=80,balance=15,transactions=5",
+		"-json",
+	}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	require.Positive(t, rep.Requests)
+	require.Equal(t, conservation{Wallets: 5, Expected: 15, Actual: 15, OK: true}, rep.Conservation)
+
+	codes := make(map[string]bool)
+	for _, e := range rep.Errors {
+		codes[e.Code] = true
+	}
+	require.Equal(t, map[string]bool{"insufficient_funds": true}, codes)
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-wallets", "1", "-mix", "refund=1"}, &stdout, &stderr)
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr.String(), "-fixture")
+	require.Contains(t, stderr.String(), "-wallets")
+	require.Contains(t, stderr.String(), "refund")
+}
+
+func TestGenerateDropsWhenBusy(t *testing.T) {
+	opts := options{rps: 1000, concurrency: 1, duration: 100 * time.Millisecond, mix: mix{weights: map[string]int{opBalance: 1}, total: 1}}
+	rec := newRecorder()
+	generate(context.Background(), opts, func(ctx context.Context, op string) error {
+		time.Sleep(20 * time.Millisecond)
+		return &client.APIError{StatusCode: http.StatusTooManyRequests, Code: "too_many_requests"}
+	}, rec)
+
+	rep := rec.report(opts.duration)
+	require.Positive(t, rep.Dropped)
+	require.Len(t, rep.Operations, 1)
+	require.Equal(t, rep.Operations[0].Count, rep.Operations[0].Errors)
+	require.Equal(t, []errorCount{{Operation: opBalance, Code: "too_many_requests", Count: rep.Operations[0].Count}}, rep.Errors)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Операции нагрузки
+const (
+	opSend         = "send"         // POST /api/send между случайными кошельками
+	opBalance      = "balance"      // GET /api/wallet/{address}/balance
+	opTransactions = "transactions" // GET /api/transactions
+)
+
+// operations - операции в порядке вывода отчета.
+var operations = []string{opSend, opBalance, opTransactions}
+
+// mix - доли операций в нагрузке.
+type mix struct {
+	weights map[string]int
+	total   int
+}
+
+// parseMix разбирает доли операций в формате "send=70,balance=25,transactions=5".
+// Доли задаются относительными весами, операции без веса не выполняются.
+func parseMix(s string) (mix, error) {
+	m := mix{weights: make(map[string]int)}
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return mix{}, fmt.Errorf("mix %q: expected operation=weight", part)
+		}
+		if !slices.Contains(operations, name) {
+			return mix{}, fmt.Errorf("mix %q: unknown operation, expected one of %s", part, strings.Join(operations, ", "))
+		}
+		if _, dup := m.weights[name]; dup {
+			return mix{}, fmt.Errorf("mix %q: operation is listed twice", part)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return mix{}, fmt.Errorf("mix %q: weight must be a non-negative integer", part)
+		}
+		m.weights[name] = weight
+		m.total += weight
+	}
+	if m.total == 0 {
+		return mix{}, fmt.Errorf("mix %q: at least one weight must be positive", s)
+	}
+	return m, nil
+}
+
+// pick выбирает операцию с вероятностью, пропорциональной ее весу.
+func (m mix) pick() string {
+	n := rand.IntN(m.total)
+	for _, op := range operations {
+		if n < m.weights[op] {
+			return op
+		}
+		n -= m.weights[op]
+	}
+	return operations[len(operations)-1]
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/client"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder собирает длительности и ошибки запросов из параллельных исполнителей.
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]map[string]int // Операция -> код ошибки -> число
+	dropped   int
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]map[string]int),
+	}
+}
+
+// record учитывает выполненный запрос.
+func (r *recorder) record(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[op] = append(r.latencies[op], d)
+	if err != nil {
+		if r.errors[op] == nil {
+			r.errors[op] = make(map[string]int)
+		}
+		r.errors[op][errorCode(err)]++
+	}
+}
+
+// drop учитывает запрос, не отправленный вовремя: все исполнители были заняты.
+func (r *recorder) drop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropped++
+}
+
+// errorCode возвращает код ошибки для отчета: код ответа сервера,
+// http_<статус> для ответа без кода, timeout или transport для ошибок соединения.
+func errorCode(err error) string {
+	var apiErr *client.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code != "":
+		return apiErr.Code
+	case errors.As(err, &apiErr):
+		return "http_" + strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "transport"
+	}
+}
+
+// report - итог нагрузки.
+type report struct {
+	Duration     float64          `json:"duration_seconds"` // Фактическая длительность
+	Requests     int              `json:"requests"`         // Выполнено запросов
+	RPS          float64          `json:"rps"`              // Фактическая частота запросов
+	Dropped      int              `json:"dropped"`          // Не отправлено из-за занятости исполнителей
+	Operations   []operationStats `json:"operations"`
+	Errors       []errorCount     `json:"errors"`
+	Conservation conservation     `json:"conservation"`
+}
+
+// operationStats - длительности запросов одной операции в миллисекундах.
+type operationStats struct {
+	Operation string  `json:"operation"`
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	P50       float64 `json:"p50_ms"`
+	P90       float64 `json:"p90_ms"`
+	P99       float64 `json:"p99_ms"`
+	Max       float64 `json:"max_ms"`
+}
+
+// errorCount - число ошибок операции с одним кодом.
+type errorCount struct {
+	Operation string `json:"operation"`
+	Code      string `json:"code"`
+	Count     int    `json:"count"`
+}
+
+// conservation - результат проверки сохранения суммы балансов созданных кошельков.
+type conservation struct {
+	Wallets  int     `json:"wallets"`
+	Expected float64 `json:"expected"`
+	Actual   float64 `json:"actual"`
+	OK       bool    `json:"ok"`
+}
+
+// report формирует итог по собранным данным.
+func (r *recorder) report(elapsed time.Duration) report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := report{Duration: elapsed.Seconds(), Dropped: r.dropped, Operations: []operationStats{}, Errors: []errorCount{}}
+	for _, op := range operations {
+		latencies := r.latencies[op]
+		if len(latencies) == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+
+		stats := operationStats{
+			Operation: op,
+			Count:     len(latencies),
+			P50:       milliseconds(percentile(latencies, 50)),
+			P90:       milliseconds(percentile(latencies, 90)),
+			P99:       milliseconds(percentile(latencies, 99)),
+			Max:       milliseconds(latencies[len(latencies)-1]),
+		}
+		codes := make([]string, 0, len(r.errors[op]))
+		for code, n := range r.errors[op] {
+			stats.Errors += n
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			rep.Errors = append(rep.Errors, errorCount{Operation: op, Code: code, Count: r.errors[op][code]})
+		}
+
+		rep.Operations = append(rep.Operations, stats)
+		rep.Requests += stats.Count
+	}
+	if elapsed > 0 {
+		rep.RPS = float64(rep.Requests) / elapsed.Seconds()
+	}
+	return rep
+}
+
+// percentile возвращает p-й процентиль отсортированных длительностей (метод ближайшего ранга).
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// print выводит отчет таблицами или в JSON.
+func (rep report) print(w io.Writer, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+
+	_, _ = fmt.Fprintf(w, "duration %.1fs, requests %d (%.1f rps), dropped %d\n\n",
+		rep.Duration, rep.Requests, rep.RPS, rep.Dropped)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join([]string{"OPERATION", "COUNT", "ERRORS", "P50", "P90", "P99", "MAX"}, "\t"))
+	for _, s := range rep.Operations {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%.2fms\t%.2fms\t%.2fms\t%.2fms\n",
+			s.Operation, s.Count, s.Errors, s.P50, s.P90, s.P99, s.Max)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(rep.Errors) > 0 {
+		_, _ = fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "OPERATION\tERROR CODE\tCOUNT")
+		for _, e := range rep.Errors {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\n", e.Operation, e.Code, e.Count)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	status := "OK"
+	if !rep.Conservation.OK {
+		status = "FAILED"
+	}
+	_, err := fmt.Fprintf(w, "\nconservation: %s (wallets %d, expected %s, actual %s)\n", status,
+		rep.Conservation.Wallets,
+		strconv.FormatFloat(rep.Conservation.Expected, 'f', -1, 64),
+		strconv.FormatFloat(rep.Conservation.Actual, 'f', -1, 64),
+	)
+	return err
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/ledger"
+	"infotecsTest/internal/lib/filelock"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// fixture - содержимое файла для наполнения хранилища.
+type fixture struct {
+	Wallets      []models.Wallet      `json:"wallets"`      // Кошельки с начальными балансами
+	Transactions []models.Transaction `json:"transactions"` // История транзакций (время в RFC 3339)
+}
+
+// runMigrate применяет миграции схемы без запуска сервера.
+func runMigrate(configPath string, args []string, _ io.Writer) int {
+	const op = "main.runMigrate"
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, ok := loadConfig(configPath)
+	if !ok {
+		return exitUsage
+	}
+	log := setupLogger(cfg.Env).With(slog.String("op", op))
+
+	storage, err := sqlite.Open(cfg.StoragePath, cfg.SQLite)
+	if err != nil {
+		log.Error("failed to open storage", sl.Err(err))
+		return exitFailure
+	}
+	defer func() { _ = storage.Close() }()
+
+	applied, err := storage.Migrate(context.Background())
+	for _, m := range applied {
+		log.Info("migration applied", slog.Int("version", m.Version), slog.String("name", m.Name))
+	}
+	if err != nil {
+		log.Error("failed to apply migrations", sl.Err(err))
+		return exitFailure
+	}
+	if len(applied) == 0 {
+		log.Info("schema is up to date")
+	}
+
+	return exitOK
+}
+
+// runSeed наполняет хранилище кошельками и транзакциями из файла фикстуры.
+func runSeed(configPath string, args []string, _ io.Writer) int {
+	const op = "main.runSeed"
+
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	file := fs.String("file", "", "fixture file (JSON with wallets and transactions)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *file == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "seed: -file is required")
+		return exitUsage
+	}
+
+	cfg, ok := loadConfig(configPath)
+	if !ok {
+		return exitUsage
+	}
+	log := setupLogger(cfg.Env).With(slog.String("op", op), slog.String("file", *file))
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Error("failed to read fixture", sl.Err(err))
+		return exitFailure
+	}
+	var fx fixture
+	if err = json.Unmarshal(data, &fx); err != nil {
+		log.Error("failed to parse fixture", sl.Err(err))
+		return exitFailure
+	}
+
+	storage, err := sqlite.Open(cfg.StoragePath, cfg.SQLite)
+	if err != nil {
+		log.Error("failed to open storage", sl.Err(err))
+		return exitFailure
+	}
+	defer func() { _ = storage.Close() }()
+
+	ctx := context.Background()
+	if err = storage.CheckSchema(ctx); err != nil {
+		log.Error("schema is not migrated, run migrate first", sl.Err(err))
+		return exitFailure
+	}
+	if err = storage.Seed(ctx, fx.Wallets, fx.Transactions); err != nil {
+		log.Error("failed to seed storage", sl.Err(err))
+		return exitFailure
+	}
+
+	log.Info("storage seeded",
+		slog.Int("wallets", len(fx.Wallets)),
+		slog.Int("transactions", len(fx.Transactions)),
+	)
+	return exitOK
+}
+
+// runCheck проверяет конфигурацию и целостность хранилища.
+// Выводит действующую конфигурацию со скрытыми секретами и найденные нарушения.
+func runCheck(configPath string, args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	checkStorage := fs.Bool("storage", true, "run integrity checks against the storage")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if configPath == "" {
+		_, _ = fmt.Fprintln(stdout, "config: FAIL\n  config path is not set: use -config or CONFIG_PATH")
+		return exitUsage
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "config: FAIL\n  %s\n", err)
+		return exitUsage
+	}
+
+	effective, err := yaml.Marshal(cfg.Masked())
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "config: FAIL\n  %s\n", err)
+		return exitFailure
+	}
+	_, _ = fmt.Fprintf(stdout, "config: OK (%s)\n---\n%s---\n", configPath, effective)
+
+	if !*checkStorage {
+		return exitOK
+	}
+
+	if _, err = os.Stat(cfg.StoragePath); err != nil {
+		_, _ = fmt.Fprintf(stdout, "storage: FAIL\n  %s\n", err)
+		return exitFailure
+	}
+	storage, err := sqlite.Open(cfg.StoragePath, cfg.SQLite)
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "storage: FAIL\n  %s\n", err)
+		return exitFailure
+	}
+	defer func() { _ = storage.Close() }()
+
+	issues, err := storage.CheckIntegrity(context.Background())
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "storage: FAIL\n  %s\n", err)
+		return exitFailure
+	}
+	if len(issues) > 0 {
+		_, _ = fmt.Fprintf(stdout, "storage: FAIL (%d issues)\n", len(issues))
+		for _, issue := range issues {
+			_, _ = fmt.Fprintf(stdout, "  [%s] %s\n", issue.Check, issue.Message)
+		}
+		return exitFailure
+	}
+
+	_, _ = fmt.Fprintf(stdout, "storage: OK (%s)\n", cfg.StoragePath)
+	return exitOK
+}
+
+// runRestore заменяет файл хранилища снимком из каталога резервных копий.
+// Снимок выбирается явно (-file), по моменту времени (-at, последний снимок не позже него)
+// или берется самый новый. Перед заменой снимок сверяется с контрольной суммой,
+// к его копии применяются миграции и проверки целостности. Сервер должен быть остановлен:
+// команда не выполняется, пока он удерживает блокировку хранилища.
+// Журнал движка переводов перед заменой переносится в текущий файл, чтобы сохраненная
+// копия этого файла содержала все подтвержденные переводы; с -discard-wal журнал,
+// который не удалось перенести, удаляется.
+func runRestore(configPath string, args []string, stdout io.Writer) int {
+	const op = "main.runRestore"
+
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := fs.String("file", "", "snapshot file to restore")
+	at := fs.String("at", "", "restore the latest snapshot taken at or before this time (RFC 3339)")
+	discardWAL := fs.Bool("discard-wal", false, "discard the ledger wal if it cannot be recovered into the current storage")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *file != "" && *at != "" {
+		_, _ = fmt.Fprintln(os.Stderr, "restore: -file and -at are mutually exclusive")
+		return exitUsage
+	}
+	var pointInTime time.Time
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "restore: invalid -at: %s\n", err)
+			return exitUsage
+		}
+		pointInTime = t
+	}
+
+	cfg, ok := loadConfig(configPath)
+	if !ok {
+		return exitUsage
+	}
+	log := setupLogger(cfg.Env).With(slog.String("op", op))
+
+	lock, err := filelock.Acquire(cfg.StoragePath + lockSuffix)
+	if errors.Is(err, filelock.ErrLocked) {
+		log.Error("storage is in use, stop the server before restore", sl.Err(err))
+		return exitFailure
+	}
+	if err != nil {
+		log.Error("failed to lock storage", sl.Err(err))
+		return exitFailure
+	}
+	defer func() { _ = lock.Release() }()
+
+	// Переводы из журнала движка сохраняются в текущем файле до его замены
+	if err = recoverWAL(log, cfg); err != nil {
+		if !*discardWAL {
+			log.Error("failed to recover ledger wal into current storage, use -discard-wal to drop it", sl.Err(err))
+			return exitFailure
+		}
+		log.Warn("failed to recover ledger wal into current storage, discarding it", sl.Err(err))
+	}
+
+	var snap backup.Snapshot
+	if *file != "" {
+		snap, err = backup.Open(*file)
+	} else {
+		snap, err = backup.Select(cfg.Backup.Dir, pointInTime)
+	}
+	if err != nil {
+		log.Error("failed to find snapshot", slog.String("dir", cfg.Backup.Dir), sl.Err(err))
+		return exitFailure
+	}
+	log = log.With(slog.String("snapshot", snap.Path))
+
+	previous, err := backup.Restore(context.Background(), snap, cfg.StoragePath, validateSnapshot(cfg.SQLite))
+	if err != nil {
+		log.Error("failed to restore storage", sl.Err(err))
+		return exitFailure
+	}
+
+	// Оставшийся журнал не перенесен в замененный файл и к снимку не относится
+	discarded, err := ledger.Discard(cfg.Ledger.WALDir)
+	if err != nil {
+		log.Error("failed to discard ledger wal", sl.Err(err))
+		return exitFailure
+	}
+
+	log.Info("storage restored",
+		slog.String("storage", cfg.StoragePath),
+		slog.Time("snapshot_time", snap.CreatedAt),
+		slog.String("previous", previous),
+		slog.Int("discarded_wal_segments", discarded),
+	)
+	_, _ = fmt.Fprintf(stdout, "restored %s from %s\n", cfg.StoragePath, snap.Name)
+	if previous != "" {
+		_, _ = fmt.Fprintf(stdout, "previous storage kept as %s\n", previous)
+	}
+	return exitOK
+}
+
+// recoverWAL переносит журнал движка переводов в текущий файл хранилища так же,
+// как это делает сервер при запуске. При отсутствии файла переносить некуда,
+// и журнал остается для удаления.
+func recoverWAL(log *slog.Logger, cfg *config.Config) error {
+	if _, err := os.Stat(cfg.StoragePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	storage, err := sqlite.Open(cfg.StoragePath, cfg.SQLite)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = storage.Close() }()
+
+	// Только миграции: заменяемый файл не наполняется кошельками и не изменяется сверх журнала
+	if _, err = storage.Migrate(context.Background()); err != nil {
+		return err
+	}
+	_, err = ledger.Recover(context.Background(), log, storage, cfg.Ledger.WALDir)
+	return err
+}
+
+// validateSnapshot возвращает проверку копии снимка: схема доводится до текущей версии,
+// затем выполняются проверки целостности.
+func validateSnapshot(cfg config.SQLite) backup.ValidateFunc {
+	return func(ctx context.Context, path string) error {
+		storage, err := sqlite.Open(path, cfg)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = storage.Close() }()
+
+		if _, err = storage.Migrate(ctx); err != nil {
+			return err
+		}
+		issues, err := storage.CheckIntegrity(ctx)
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			errs := make([]error, 0, len(issues))
+			for _, issue := range issues {
+				errs = append(errs, fmt.Errorf("[%s] %s", issue.Check, issue.Message))
+			}
+			return errors.Join(errs...)
+		}
+		return nil
+	}
+}
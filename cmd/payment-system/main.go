@@ -1,21 +1,16 @@
 // Package main является точкой входа приложения.
-// Содержит конфигурацию, инициализацию компонентов и запуск сервера.
+// Содержит конфигурацию, инициализацию компонентов и запуск сервера,
+// а также служебные команды для работы с хранилищем без запуска сервера.
 package main
 
 import (
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"errors"
+	"flag"
+	"fmt"
 	"infotecsTest/internal/config"
-	"infotecsTest/internal/http-server/handlers/transaction"
-	"infotecsTest/internal/http-server/handlers/wallet"
-	mwLogger "infotecsTest/internal/http-server/middleware/logger"
-	"infotecsTest/internal/lib/logger/sl"
-	"infotecsTest/internal/storage/sqlite"
+	"io"
 	"log/slog"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 )
 
 // Константы окружений для настройки логгера
@@ -25,68 +20,127 @@ const (
 	envProd  = "prod"  // Продакшн окружение
 )
 
-// main инициализирует и запускает приложение:
+// Коды завершения
+const (
+	exitOK      = 0 // Команда выполнена
+	exitFailure = 1 // Ошибка выполнения или найдены нарушения
+	exitUsage   = 2 // Некорректные аргументы или конфигурация
+)
+
+// lockSuffix - суффикс файла блокировки хранилища рядом с файлом базы.
+// Блокировку удерживает работающий сервер, команда restore не выполняется при ее наличии.
+const lockSuffix = ".lock"
+
+// command описывает подкоманду приложения.
+type command struct {
+	name    string
+	summary string
+	run     func(configPath string, args []string, stdout io.Writer) int
+}
+
+// commands - подкоманды в порядке вывода справки.
+var commands = []command{
+	{name: "serve", summary: "run HTTP, admin and gRPC servers (default)", run: runServe},
+	{name: "migrate", summary: "apply pending schema migrations and exit", run: runMigrate},
+	{name: "seed", summary: "load wallets and transactions from a fixture file", run: runSeed},
+	{name: "check", summary: "validate config, print it with secrets masked and check storage integrity", run: runCheck},
+	{name: "restore", summary: "replace storage with a verified backup snapshot (server must be stopped)", run: runRestore},
+}
+
+// main разбирает аргументы и выполняет подкоманду.
+// Без подкоманды запускает сервер:
 // 1. Загружает конфигурацию
 // 2. Настраивает логгер
-// 3. Инициализирует хранилище
+// 3. Инициализирует хранилище (при ошибке завершает работу)
 // 4. Настраивает роутер и обработчики
-// 5. Запускает HTTP-сервер
-// 6. Обрабатывает сигналы завершения
+// 5. Запускает HTTP-сервер и фоновые задачи
+// 6. Обрабатывает сигналы завершения и выполняет graceful shutdown
 func main() {
-	// Загрузка конфигурации приложения
-	cfg := config.MustLoad()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	// Инициализация логгера в зависимости от окружения
-	logger := setupLogger(cfg.Env)
+// run выбирает подкоманду и возвращает код завершения.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("payment-system", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(fs) }
+	configPath := fs.String("config", "", "config file (default $CONFIG_PATH)")
 
-	// Подключение к хранилищу SQLite
-	storage, err := sqlite.New(cfg.StoragePath)
-	if err != nil {
-		logger.Error("failed to initialize storage", sl.Err(err))
-	}
-	defer func() {
-		// Гарантированное закрытие соединения с БД при завершении
-		if err = storage.Close(); err != nil {
-			logger.Error("unable to close database: ", sl.Err(err))
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
-	}()
-
-	// Настройка роутера
-	router := chi.NewRouter()
-	router.Use(middleware.RequestID) // Добавляет ID к каждому запросу
-	router.Use(mwLogger.New(logger)) // Логирование запросов
-	router.Use(middleware.Recoverer) // Восстановление после паник
-
-	// Регистрация обработчиков маршрутов
-	router.Get("/api/transactions", transaction.GetLast(logger, storage))
-	router.Get("/api/wallet/{address}/balance", wallet.GetBalance(logger, storage))
-	router.Post("/api/send", transaction.Send(logger, storage))
-
-	// Канал для обработки сигналов завершения
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// Конфигурация HTTP-сервера
-	server := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		return exitUsage
 	}
 
-	// Запуск сервера в отдельной горутине
-	go func() {
-		if err = server.ListenAndServe(); err != nil {
-			logger.Error("failed to serve server")
+	if *configPath == "" {
+		*configPath = os.Getenv("CONFIG_PATH")
+	}
+
+	name := "serve"
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		var cmdArgs []string
+		if fs.NArg() > 0 {
+			cmdArgs = fs.Args()[1:]
 		}
-	}()
+		return c.run(*configPath, cmdArgs, stdout)
+	}
+
+	_, _ = fmt.Fprintf(stderr, "unknown command %q\n", name)
+	usage(fs)
+	return exitUsage
+}
+
+// usage выводит справку по подкомандам.
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	_, _ = fmt.Fprintln(w, "Usage: payment-system [-config path] [command] [flags]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	_, _ = fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+// loadConfig загружает конфигурацию для подкоманды.
+// Ошибки выводятся в stderr процесса, так как логгер еще не настроен.
+func loadConfig(configPath string) (*config.Config, bool) {
+	if configPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "config path is not set: use -config or CONFIG_PATH")
+		return nil, false
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	return cfg, true
+}
 
-	logger.Info("server started")
+// runServe запускает сервер.
+func runServe(configPath string, args []string, _ io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	// Загрузка конфигурации приложения
+	cfg, ok := loadConfig(configPath)
+	if !ok {
+		return exitUsage
+	}
+
+	// Инициализация логгера в зависимости от окружения
+	logger := setupLogger(cfg.Env)
 
-	// Ожидание сигнала завершения
-	<-done
-	logger.Info("stopping server")
+	return serve(cfg, logger)
 }
 
 // setupLogger инициализирует логгер в зависимости от окружения
@@ -105,7 +159,10 @@ func setupLogger(env string) *slog.Logger {
 			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		)
 	case envProd:
-		// JSON-логгер для продакшна
+		fallthrough
+	default:
+		// JSON-логгер для продакшна, он же для непроверенного окружения:
+		// логгер нужен и для записи ошибки проверки конфигурации
 		log = slog.New(
 			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
 		)
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
+	paymentv1 "infotecsTest/api/payment/v1"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/grpc-server/interceptor"
+	grpcPayment "infotecsTest/internal/grpc-server/payment"
+	backupHandlers "infotecsTest/internal/http-server/handlers/backup"
+	handleHandlers "infotecsTest/internal/http-server/handlers/handle"
+	"infotecsTest/internal/http-server/handlers/health"
+	payoutHandlers "infotecsTest/internal/http-server/handlers/payout"
+	"infotecsTest/internal/http-server/handlers/transaction"
+	userHandlers "infotecsTest/internal/http-server/handlers/user"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	mwAuth "infotecsTest/internal/http-server/middleware/auth"
+	mwLogger "infotecsTest/internal/http-server/middleware/logger"
+	mwMetrics "infotecsTest/internal/http-server/middleware/metrics"
+	mwProblem "infotecsTest/internal/http-server/middleware/problem"
+	"infotecsTest/internal/http-server/middleware/ratelimit"
+	"infotecsTest/internal/http-server/openapi"
+	"infotecsTest/internal/ledger"
+	"infotecsTest/internal/lib/filelock"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/lib/metrics"
+	"infotecsTest/internal/lib/worker"
+	"infotecsTest/internal/payout"
+	storagepkg "infotecsTest/internal/storage"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve запускает HTTP-, служебный и gRPC-серверы и фоновые задачи,
+// ожидает сигнала завершения и выполняет graceful shutdown.
+// Возвращает код завершения процесса.
+func serve(cfg *config.Config, logger *slog.Logger) int {
+	// Хранилище используется одним сервером, restore ожидает его остановки
+	lock, err := filelock.Acquire(cfg.StoragePath + lockSuffix)
+	if errors.Is(err, filelock.ErrLocked) {
+		logger.Error("storage is used by another process", sl.Err(err))
+		return exitFailure
+	}
+	if err != nil {
+		logger.Error("failed to lock storage", sl.Err(err))
+		return exitFailure
+	}
+	defer func() { _ = lock.Release() }()
+
+	// Подключение к хранилищу SQLite
+	storage, err := sqlite.New(cfg.StoragePath, cfg.SQLite)
+	if err != nil {
+		logger.Error("failed to initialize storage", sl.Err(err))
+		return exitFailure
+	}
+
+	// Баланс горячих кошельков делится на подбалансы, изменения списка применяются при запуске
+	if err = storage.SetHotWallets(context.Background(), cfg.HotWallets); err != nil {
+		logger.Error("failed to configure hot wallets", sl.Err(err))
+		_ = storage.Close()
+		return exitFailure
+	}
+
+	// Реестр метрик и сбор метрик хранилища
+	registry := metrics.NewRegistry()
+	storageObserver := storagepkg.NewMetricsObserver(registry)
+	storage.SetObserver(storageObserver)
+
+	// Группа фоновых задач, останавливаемых при завершении.
+	// Задачи запускаются после настройки серверов: при ошибке настройки останавливать нечего
+	workers := worker.NewGroup(logger)
+
+	// Переводы, балансы и история: напрямую в SQLite или через движок в памяти,
+	// который переносит журнал в SQLite перед чтением истории
+	var ledgerStore interface {
+		wallet.BalanceReceiver
+		wallet.StatementReader
+		transaction.TransactionMaker
+		transaction.TransactionsReceiver
+		grpcPayment.TransactionsStreamer
+		payout.TransactionMaker
+		backup.Source
+		TotalBalance(ctx context.Context) (float64, error)
+	} = storage
+	closers := []io.Closer{storage}
+	var engine *ledger.Engine
+	if cfg.Ledger.Enabled {
+		if engine, err = ledger.New(logger, storage, cfg.Ledger); err != nil {
+			logger.Error("failed to initialize ledger", sl.Err(err))
+			_ = storage.Close()
+			return exitFailure
+		}
+		engine.SetObserver(storageObserver)
+		ledgerStore = engine
+		closers = []io.Closer{engine, storage}
+	} else if _, err := ledger.Recover(context.Background(), logger, storage, cfg.Ledger.WALDir); err != nil {
+		// Журнал движка, выключенного после сбоя, переносится до приема переводов
+		logger.Error("failed to recover ledger wal", sl.Err(err))
+		_ = storage.Close()
+		return exitFailure
+	}
+
+	// Сумма балансов читается при каждом опросе метрик: запрос к хранилищу ограничен
+	// таймаутом обработки запросов, чтобы занятая база не задерживала опрос
+	registry.NewGaugeFunc(
+		"payment_wallet_supply",
+		"Current total balance of all wallets.",
+		func() (float64, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.Timeout)
+			defer cancel()
+			return ledgerStore.TotalBalance(ctx)
+		},
+	)
+
+	// Задания на массовую выплату выполняются в фоне
+	payouts := payout.New(logger, storage, ledgerStore, cfg.Payouts)
+
+	// Резервные копии хранилища: по расписанию, если задан интервал, и по запросу
+	backups := backup.New(logger, ledgerStore, cfg.Backup)
+	registry.NewGaugeFunc(
+		"payment_backup_last_success_timestamp_seconds",
+		"Unix time of the last successful backup since start, 0 if none.",
+		func() (float64, error) {
+			last := backups.LastSuccess()
+			if last.IsZero() {
+				return 0, nil
+			}
+			return float64(last.Unix()), nil
+		},
+	)
+
+	// Проверки готовности сервиса
+	probes := health.NewRegistry()
+	probes.Register("database", storage.Ping)
+	probes.Register("migrations", storage.CheckSchema)
+	probes.Register("workers", workers.Check)
+	if engine != nil {
+		probes.Register("ledger", engine.Check)
+	}
+
+	// Настройка роутера
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)                // Добавляет ID к каждому запросу
+	router.Use(mwLogger.New(logger, cfg.AccessLog)) // Логирование запросов
+	router.Use(mwMetrics.New(registry))             // Метрики запросов
+	router.Use(middleware.Recoverer)                // Восстановление после паник
+	router.Use(mwProblem.New(cfg.Errors))           // Формат ответов с ошибками
+
+	// Проверка запросов и ответов по спецификации OpenAPI
+	spec := openapi.Spec()
+	router.Use(openapi.Validator(logger, spec, openapi.Options{
+		ValidateRequests:  cfg.OpenAPI.ValidateRequests,
+		ValidateResponses: cfg.OpenAPI.ValidateResponses,
+	}))
+
+	// Ограничение частоты запросов по маршрутам
+	limiter := ratelimit.New(logger, cfg.RateLimit)
+	limiter.SetAddressResolver(storage)
+	registry.NewCounterFunc(
+		"payment_ratelimit_requests_total",
+		"Number of requests checked by the rate limiter by decision.",
+		func() ([]metrics.Sample, error) {
+			stats := limiter.Stats()
+			samples := make([]metrics.Sample, 0, 2*len(stats))
+			for _, st := range stats {
+				samples = append(samples,
+					metrics.Sample{Labels: []string{st.Route, st.Dimension, "allowed"}, Value: float64(st.Allowed)},
+					metrics.Sample{Labels: []string{st.Route, st.Dimension, "limited"}, Value: float64(st.Limited)},
+				)
+			}
+			return samples, nil
+		},
+		"route", "dimension", "decision",
+	)
+
+	// Пробы живости и готовности
+	router.Get("/healthz", health.Live())
+	router.Get("/readyz", health.Ready(logger, probes))
+
+	// Аутентификация пользователей и проверка их прав на кошельки
+	authorizer := mwAuth.NewAuthorizer(cfg.Auth, storage)
+
+	// Регистрация обработчиков маршрутов: все маршруты API, кроме спецификации, требуют ключа пользователя
+	router.Group(func(router chi.Router) {
+		router.Use(mwAuth.New(logger, cfg.Auth, storage))
+
+		router.With(limiter.Route("/api/transactions")).
+			Get("/api/transactions", transaction.GetLast(logger, ledgerStore))
+		router.With(limiter.Route("/api/wallet/{address}/balance")).
+			Get("/api/wallet/{address}/balance", wallet.GetBalance(logger, ledgerStore, storage, authorizer))
+		router.With(limiter.Route("/api/wallet/{address}")).
+			Get("/api/wallet/{address}", wallet.Details(logger, ledgerStore, storage, storage, authorizer))
+		router.With(limiter.Route("/api/wallet/{address}/metadata")).
+			Put("/api/wallet/{address}/metadata", wallet.SetMetadata(logger, storage, storage, authorizer))
+		router.With(limiter.Route("/api/wallets")).
+			Get("/api/wallets", wallet.Search(logger, storage, ledgerStore))
+		router.With(limiter.Route("/api/wallet/{address}/statement")).
+			Get("/api/wallet/{address}/statement", wallet.Statement(logger, ledgerStore, storage, authorizer))
+		router.With(limiter.Route("/api/send")).
+			Post("/api/send", transaction.Send(logger, ledgerStore, storage, authorizer))
+		handleRouter := router.With(limiter.Route("/api/wallet/{address}/handle"))
+		handleRouter.Put("/api/wallet/{address}/handle", handleHandlers.Claim(logger, storage, storage, authorizer))
+		handleRouter.Delete("/api/wallet/{address}/handle", handleHandlers.Release(logger, storage, storage, authorizer))
+		router.With(limiter.Route("/api/handles/{handle}")).
+			Get("/api/handles/{handle}", handleHandlers.Resolve(logger, storage))
+		router.With(limiter.Route("/api/payouts")).
+			Post("/api/payouts", payoutHandlers.Create(logger, payouts, storage, authorizer))
+		router.With(limiter.Route("/api/payouts/{id}")).
+			Get("/api/payouts/{id}", payoutHandlers.Get(logger, payouts, authorizer))
+		router.With(limiter.Route("/api/payouts/{id}/results")).
+			Get("/api/payouts/{id}/results", payoutHandlers.Results(logger, payouts, authorizer))
+		router.With(limiter.Route("/api/me")).
+			Get("/api/me", userHandlers.Me(logger))
+		router.With(limiter.Route("/api/me/wallets")).
+			Get("/api/me/wallets", userHandlers.MyWallets(logger, storage, ledgerStore))
+	})
+	router.Get("/api/openapi.json", openapi.Handler(spec))
+
+	// Маршруты должны совпадать со спецификацией
+	if err := openapi.CheckRoutes(router, spec); err != nil {
+		logger.Error("routes do not match openapi specification", sl.Err(err))
+		_ = closeStorages(logger, closers...)
+		return exitFailure
+	}
+
+	// Служебный роутер: метрики, отладочная информация, резервные копии и пользователи.
+	// Все маршруты требуют ключ администратора, если он задан
+	adminRouter := chi.NewRouter()
+	adminRouter.Use(middleware.Recoverer)
+	adminRouter.Use(mwAuth.Admin(logger, cfg.Auth))
+	adminRouter.Get("/metrics", registry.Handler())
+	adminRouter.Get("/debug/ratelimit", limiter.StatsHandler())
+	adminRouter.Get("/backups", backupHandlers.List(logger, backups))
+	adminRouter.Post("/backups", backupHandlers.Create(logger, backups))
+	adminRouter.Post("/users", userHandlers.Create(logger, storage))
+	adminRouter.Get("/users/{id}", userHandlers.Get(logger, storage))
+	adminRouter.Get("/users/{id}/wallets", userHandlers.Wallets(logger, storage, ledgerStore))
+	adminRouter.Put("/users/{id}/wallets/{address}", userHandlers.AssignWallet(logger, storage, storage, ledgerStore))
+	adminRouter.Delete("/users/{id}/wallets/{address}", userHandlers.UnassignWallet(logger, storage, storage, ledgerStore))
+	adminRouter.Post("/users/{id}/keys", userHandlers.IssueKey(logger, storage))
+	adminRouter.Delete("/users/{id}/keys", userHandlers.RevokeKeys(logger, storage))
+
+	// gRPC-сервер с теми же хранилищем и метриками, что и HTTP API
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.GRPCServer.Address != "" {
+		grpcListener, err = net.Listen("tcp", cfg.GRPCServer.Address)
+		if err != nil {
+			logger.Error("failed to listen grpc address", sl.Err(err))
+			_ = closeStorages(logger, closers...)
+			return exitFailure
+		}
+
+		recoverer := interceptor.NewRecoverer(logger)
+		observer := interceptor.NewObserver(logger, registry)
+		authenticator := interceptor.NewAuthenticator(logger, cfg.Auth, storage)
+		grpcServer = grpc.NewServer(
+			grpc.ChainUnaryInterceptor(observer.Unary, recoverer.Unary, authenticator.Unary),
+			grpc.ChainStreamInterceptor(observer.Stream, recoverer.Stream, authenticator.Stream),
+		)
+		paymentv1.RegisterPaymentServiceServer(grpcServer, grpcPayment.New(logger, ledgerStore, ledgerStore, ledgerStore, storage, authorizer))
+	}
+
+	// Запуск фоновых задач
+	if engine != nil {
+		workers.Go("ledger", engine.Run)
+	}
+	workers.Go("payouts", payouts.Run)
+	if cfg.Backup.Interval > 0 {
+		workers.Go("backup", backups.Run)
+	}
+
+	// Канал для обработки сигналов завершения
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	// Конфигурация HTTP-сервера
+	server := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	servers := []*http.Server{server}
+
+	// Конфигурация служебного сервера на отдельном адресе
+	if cfg.AdminServer.Address != "" {
+		servers = append(servers, &http.Server{
+			Addr:         cfg.AdminServer.Address,
+			Handler:      adminRouter,
+			ReadTimeout:  cfg.HTTPServer.Timeout,
+			WriteTimeout: cfg.HTTPServer.Timeout,
+			IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		})
+	}
+
+	// Запуск серверов в отдельных горутинах
+	serveErr := make(chan error, len(servers)+1)
+	for _, srv := range servers {
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+		logger.Info("server started", slog.String("address", srv.Addr))
+	}
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serveErr <- err
+			}
+		}()
+		logger.Info("grpc server started", slog.String("address", grpcListener.Addr().String()))
+	}
+
+	// Ожидание сигнала завершения или ошибки сервера
+	exitCode := exitOK
+	select {
+	case sig := <-done:
+		logger.Info("received signal", slog.String("signal", sig.String()))
+	case err := <-serveErr:
+		logger.Error("failed to serve server", sl.Err(err))
+		exitCode = exitFailure
+	}
+
+	// После ошибки сервера экземпляр уже не обслуживает запросы: ждать балансировщик незачем
+	stopCfg := cfg.HTTPServer
+	if exitCode != exitOK {
+		stopCfg.ShutdownDelay = 0
+	}
+	if err := shutdown(logger, stopCfg, probes, servers, grpcServer, workers, closers...); err != nil {
+		exitCode = exitFailure
+	}
+
+	logger.Info("application stopped")
+	return exitCode
+}
+
+// shutdown последовательно останавливает компоненты приложения:
+// переводит пробу готовности в ошибку и продолжает обслуживать запросы в течение
+// cfg.ShutdownDelay, чтобы балансировщик успел исключить экземпляр, затем дожидается
+// завершения активных запросов и фоновых задач, и только после этого закрывает хранилища в переданном порядке.
+// Каждому серверу и фоновым задачам отводится собственный таймаут cfg.ShutdownTimeout.
+// Если какой-либо этап не завершился вовремя, обработчики или задачи могут еще работать
+// с хранилищами, поэтому хранилища не закрываются: записанные данные сохранены в базе
+// и журнале и восстанавливаются при следующем запуске.
+func shutdown(
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	probes *health.Registry,
+	servers []*http.Server,
+	grpcServer *grpc.Server,
+	workers *worker.Group,
+	storages ...io.Closer,
+) error {
+	var failed error
+	timeout := cfg.ShutdownTimeout
+
+	probes.SetShuttingDown()
+	log.Info("readiness probe switched to failing")
+
+	if cfg.ShutdownDelay > 0 {
+		log.Info("waiting for load balancers to drain traffic", slog.String("delay", cfg.ShutdownDelay.String()))
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	for _, srv := range servers {
+		log := log.With(slog.String("address", srv.Addr))
+
+		log.Info("stopping server", slog.String("timeout", timeout.String()))
+		if err := withTimeout(timeout, srv.Shutdown); err != nil {
+			log.Error("failed to stop server gracefully, closing connections", sl.Err(err))
+			_ = srv.Close()
+			failed = err
+		} else {
+			log.Info("server stopped, all requests finished")
+		}
+	}
+
+	if grpcServer != nil {
+		log.Info("stopping grpc server", slog.String("timeout", timeout.String()))
+		if err := withTimeout(timeout, func(ctx context.Context) error { return stopGRPC(ctx, grpcServer) }); err != nil {
+			log.Error("failed to stop grpc server gracefully", sl.Err(err))
+			failed = err
+		} else {
+			log.Info("grpc server stopped, all calls finished")
+		}
+	}
+
+	log.Info("stopping background workers", slog.String("timeout", timeout.String()))
+	if err := withTimeout(timeout, workers.Shutdown); err != nil {
+		log.Error("failed to stop background workers", sl.Err(err))
+		failed = err
+	} else {
+		log.Info("background workers stopped")
+	}
+
+	if failed != nil {
+		log.Warn("storages left open: requests or workers may still be using them")
+		return failed
+	}
+
+	return closeStorages(log, storages...)
+}
+
+// closeStorages закрывает хранилища в переданном порядке.
+// Возвращает последнюю ошибку закрытия.
+func closeStorages(log *slog.Logger, storages ...io.Closer) error {
+	var failed error
+	for _, storage := range storages {
+		log.Info("closing storage")
+		if err := storage.Close(); err != nil {
+			log.Error("unable to close database", sl.Err(err))
+			failed = err
+		} else {
+			log.Info("storage closed")
+		}
+	}
+	return failed
+}
+
+// withTimeout выполняет этап остановки с собственным таймаутом.
+func withTimeout(timeout time.Duration, stop func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return stop(ctx)
+}
+
+// stopGRPC дожидается завершения активных вызовов gRPC-сервера.
+// По истечении ctx оставшиеся вызовы прерываются.
+func stopGRPC(ctx context.Context, srv *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,773 @@
+// Package main реализует paymentctl - консольный клиент платежной системы для операторов.
+// Покрывает эндпоинты HTTP API, выводит результаты таблицей или в JSON
+// и завершается с кодом, соответствующим статусу ответа сервера.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/client"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/models"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Коды завершения
+const (
+	exitOK          = 0 // Команда выполнена
+	exitFailure     = 1 // Ошибка соединения или разбора ответа
+	exitUsage       = 2 // Некорректные аргументы или конфигурация
+	exitClientError = 3 // Сервер отклонил запрос (4xx)
+	exitRateLimited = 4 // Превышен лимит запросов (429)
+	exitServerError = 5 // Ошибка или неготовность сервера (5xx)
+)
+
+// errUsage помечает ошибки аргументов командной строки.
+var errUsage = errors.New("usage error")
+
+// reportedError - ошибка, вместе с которой команда уже вывела результат.
+// В формате JSON повторно не выводится, чтобы вывод оставался одним документом.
+type reportedError struct {
+	err error
+}
+
+func (e *reportedError) Error() string { return e.err.Error() }
+func (e *reportedError) Unwrap() error { return e.err }
+
+// command описывает подкоманду.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, env *environment, args []string) error
+}
+
+// environment - общее окружение подкоманд.
+type environment struct {
+	client      *client.Client
+	print       printer
+	profileName string
+	profiles    Profiles
+}
+
+// commands - подкоманды в порядке вывода справки.
+var commands = []command{
+	{name: "balance", args: "<address>", summary: "show wallet balance", run: runBalance},
+	{name: "wallet", args: "<address>", summary: "show wallet balance, owner, labels and metadata", run: runWallet},
+	{name: "metadata", args: "<address> [-owner <ref>] [-label <label>]... [-meta <key=value>]...", summary: "replace wallet owner, labels and metadata", run: runMetadata},
+	{name: "wallets", args: "[-label <label>] [-owner <ref>] [-limit <n>]", summary: "find wallets by label or owner", run: runWallets},
+	{name: "statement", args: "<address> [-from <date>] [-to <date>] [-format csv|jsonl]", summary: "export wallet statement for a period", run: runStatement},
+	{name: "send", args: "-from <address> -to <address> -amount <n>", summary: "transfer funds between wallets", run: runSend},
+	{name: "handle", args: "claim <address> <handle> | release <address> | resolve <handle>", summary: "manage wallet handles (@alice)", run: runHandle},
+	{name: "payout", args: "submit -from <address> <file.csv|-> | status <job> | results <job>", summary: "run bulk payout job from CSV file", run: runPayout},
+	{name: "transactions", args: "[-count <n>]", summary: "list recent transactions", run: runTransactions},
+	{name: "me", args: "[wallets]", summary: "show current user profile or wallets with total balance", run: runMe},
+	{name: "health", summary: "run liveness probe", run: runHealth},
+	{name: "ready", summary: "run readiness probe", run: runReady},
+	{name: "spec", summary: "print OpenAPI specification", run: runSpec},
+	{name: "metrics", summary: "print Prometheus metrics (admin server)", run: runMetrics},
+	{name: "ratelimit", summary: "show rate limiter counters (admin server)", run: runRateLimit},
+	{name: "backup", args: "create | list", summary: "create or list storage snapshots (admin server)", run: runBackup},
+	{name: "user", args: "create -name <name> [-email <email>] | show <id> | wallets <id> | key <id> | revoke-keys <id> | assign <id> <address> | unassign <id> <address>", summary: "manage users, API keys and wallet ownership (admin server)", run: runUser},
+	{name: "profiles", summary: "list configured profiles", run: runProfiles},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run разбирает аргументы, выполняет подкоманду и возвращает код завершения.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("paymentctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(fs) }
+
+	configFile := fs.String("config", "", "profiles file (default $"+envConfig+" or <user config dir>/paymentctl/config.yaml)")
+	profileName := fs.String("profile", "", "profile name (default $"+envProfile+" or current profile)")
+	output := fs.String("o", outputTable, "output format: table|json")
+	baseURL := fs.String("url", "", "API base URL, overrides profile")
+	adminURL := fs.String("admin-url", "", "admin server URL, overrides profile")
+	lang := fs.String("lang", "", "language of error messages (ru|en), overrides profile")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if *output != outputTable && *output != outputJSON {
+		_, _ = fmt.Fprintf(stderr, "error: unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		usage(fs)
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+			break
+		}
+	}
+	if cmd == nil {
+		_, _ = fmt.Fprintf(stderr, "error: unknown command %q\n", name)
+		usage(fs)
+		return exitUsage
+	}
+
+	p := printer{format: *output, out: stdout, errOut: stderr}
+
+	profiles, err := loadProfiles(configPath(*configFile))
+	if err != nil {
+		p.error(err)
+		return exitUsage
+	}
+	resolved, profile, err := profiles.resolve(*profileName)
+	if err != nil {
+		p.error(err)
+		return exitUsage
+	}
+	if *baseURL != "" {
+		profile.BaseURL = *baseURL
+	}
+	if *adminURL != "" {
+		profile.AdminURL = *adminURL
+	}
+	if *lang != "" {
+		profile.Language = *lang
+	}
+
+	env := &environment{
+		client:      client.New(profile.clientConfig()),
+		print:       p,
+		profileName: resolved,
+		profiles:    profiles,
+	}
+
+	if err = cmd.run(ctx, env, fs.Args()[1:]); err != nil {
+		var reported *reportedError
+		if !errors.As(err, &reported) || p.format != outputJSON {
+			p.error(err)
+		}
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode сопоставляет ошибку команды с кодом завершения.
+func exitCode(err error) int {
+	if errors.Is(err, errUsage) || errors.Is(err, client.ErrNoAdminURL) {
+		return exitUsage
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return exitFailure
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return exitRateLimited
+	case apiErr.StatusCode >= http.StatusInternalServerError:
+		return exitServerError
+	case apiErr.StatusCode >= http.StatusBadRequest:
+		return exitClientError
+	}
+	return exitFailure
+}
+
+// usage выводит справку по командам и глобальным флагам.
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	_, _ = fmt.Fprintln(w, "Usage: paymentctl [flags] <command> [args]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(w, "  %-13s %s\n", c.name, c.summary)
+		if c.args != "" {
+			_, _ = fmt.Fprintf(w, "  %-13s   %s %s\n", "", c.name, c.args)
+		}
+	}
+	_, _ = fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	_, _ = fmt.Fprintf(w, "\nExit codes: %d ok, %d failure, %d usage, %d rejected (4xx), %d rate limited, %d server error (5xx)\n",
+		exitOK, exitFailure, exitUsage, exitClientError, exitRateLimited, exitServerError)
+}
+
+// usageError формирует ошибку аргументов подкоманды.
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// newFlagSet создает набор флагов подкоманды.
+func newFlagSet(env *environment, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.print.errOut)
+	return fs
+}
+
+// parseFlags разбирает флаги подкоманды.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	return nil
+}
+
+// listFlag - флаг, который можно указать несколько раз.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func runBalance(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 1 {
+		return usageError("balance expects exactly one wallet address")
+	}
+
+	w, err := env.client.Balance(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return env.print.table(w,
+		[]string{"ADDRESS", "BALANCE", "VERSION"},
+		[][]string{{w.Address, formatAmount(w.Balance), strconv.FormatInt(w.Version, 10)}},
+	)
+}
+
+// walletHeader - заголовок таблицы кошельков.
+var walletHeader = []string{"ADDRESS", "BALANCE", "VERSION", "OWNER", "LABELS", "METADATA"}
+
+// walletRows формирует строки таблицы кошельков.
+// Метаданные выводятся парами key=value, упорядоченными по ключу.
+func walletRows(wallets ...models.Wallet) [][]string {
+	rows := make([][]string, 0, len(wallets))
+	for _, w := range wallets {
+		keys := make([]string, 0, len(w.Metadata))
+		for k := range w.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + w.Metadata[k]
+		}
+		rows = append(rows, []string{
+			w.Address, formatAmount(w.Balance), strconv.FormatInt(w.Version, 10),
+			w.Owner, strings.Join(w.Labels, ","), strings.Join(pairs, ","),
+		})
+	}
+	return rows
+}
+
+func runWallet(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 1 {
+		return usageError("wallet expects exactly one wallet address")
+	}
+
+	w, err := env.client.Wallet(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return env.print.table(w, walletHeader, walletRows(w))
+}
+
+func runMetadata(ctx context.Context, env *environment, args []string) error {
+	if len(args) == 0 {
+		return usageError("metadata expects a wallet address")
+	}
+	addr := args[0]
+
+	fs := newFlagSet(env, "metadata")
+	owner := fs.String("owner", "", "external owner reference (empty clears it)")
+	var labels, meta listFlag
+	fs.Var(&labels, "label", "wallet label, may be repeated")
+	fs.Var(&meta, "meta", "metadata entry key=value, may be repeated")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	md := models.WalletMetadata{Owner: *owner, Labels: labels}
+	for _, kv := range meta {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return usageError("invalid metadata entry %q, expected key=value", kv)
+		}
+		if md.Metadata == nil {
+			md.Metadata = make(map[string]string, len(meta))
+		}
+		md.Metadata[k] = v
+	}
+
+	saved, err := env.client.SetWalletMetadata(ctx, addr, md)
+	if err != nil {
+		return err
+	}
+	row := walletRows(models.Wallet{Owner: saved.Owner, Labels: saved.Labels, Metadata: saved.Metadata})[0]
+	return env.print.table(saved, []string{"OWNER", "LABELS", "METADATA"}, [][]string{row[3:]})
+}
+
+func runWallets(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "wallets")
+	label := fs.String("label", "", "wallet label")
+	owner := fs.String("owner", "", "external owner reference")
+	limit := fs.Int("limit", 0, "max wallets to return (default 100)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *label == "" && *owner == "" {
+		return usageError("wallets requires -label or -owner")
+	}
+
+	wallets, err := env.client.FindWallets(ctx, models.WalletFilter{Label: *label, Owner: *owner, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	return env.print.table(wallets, walletHeader, walletRows(wallets...))
+}
+
+func runStatement(ctx context.Context, env *environment, args []string) error {
+	if len(args) == 0 {
+		return usageError("statement expects a wallet address")
+	}
+	address := args[0]
+
+	fs := newFlagSet(env, "statement")
+	from := fs.String("from", "", "period start, RFC 3339 or YYYY-MM-DD (default start of month)")
+	to := fs.String("to", "", "period end, RFC 3339 or YYYY-MM-DD inclusive (default now)")
+	format := fs.String("format", "", "statement format: csv|jsonl (default csv, jsonl with -o json)")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if *format == "" && env.print.format == outputJSON {
+		*format = "jsonl"
+	}
+
+	return env.client.Statement(ctx, address, client.StatementQuery{From: *from, To: *to, Format: *format}, env.print.out)
+}
+
+func runSend(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "send")
+	from := fs.String("from", "", "sender wallet address")
+	to := fs.String("to", "", "recipient wallet address")
+	amount := fs.String("amount", "", "amount to transfer")
+	ifVersion := fs.Int64("if-version", 0, "transfer only if the sender wallet version matches (see balance)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || *amount == "" {
+		return usageError("send requires -from, -to and -amount")
+	}
+	value, err := strconv.ParseFloat(*amount, 64)
+	if err != nil {
+		return usageError("invalid amount %q", *amount)
+	}
+
+	tx := models.Transaction{From: *from, To: *to, Amount: value}
+	var msg string
+	if *ifVersion > 0 {
+		msg, err = env.client.SendIfVersion(ctx, tx, *ifVersion)
+	} else {
+		msg, err = env.client.Send(ctx, tx)
+	}
+	if err != nil {
+		return err
+	}
+	return env.print.text(msg)
+}
+
+func runHandle(ctx context.Context, env *environment, args []string) error {
+	if len(args) == 0 {
+		return usageError("handle expects claim, release or resolve")
+	}
+
+	var (
+		h   models.Handle
+		err error
+	)
+	switch args[0] {
+	case "claim":
+		if len(args) != 3 {
+			return usageError("handle claim expects a wallet address and a handle")
+		}
+		h, err = env.client.ClaimHandle(ctx, args[1], args[2])
+	case "release":
+		if len(args) != 2 {
+			return usageError("handle release expects exactly one wallet address")
+		}
+		h, err = env.client.ReleaseHandle(ctx, args[1])
+	case "resolve":
+		if len(args) != 2 {
+			return usageError("handle resolve expects exactly one handle")
+		}
+		h, err = env.client.ResolveHandle(ctx, args[1])
+	default:
+		return usageError("unknown handle command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return env.print.table(h, []string{"HANDLE", "ADDRESS"}, [][]string{{address.HandlePrefix + h.Handle, h.Address}})
+}
+
+func runPayout(ctx context.Context, env *environment, args []string) error {
+	if len(args) == 0 {
+		return usageError("payout expects submit, status or results")
+	}
+
+	switch args[0] {
+	case "submit":
+		fs := newFlagSet(env, "payout submit")
+		from := fs.String("from", "", "source wallet address")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		if *from == "" || fs.NArg() != 1 {
+			return usageError("payout submit requires -from and a CSV file (- for stdin)")
+		}
+
+		var file io.Reader = os.Stdin
+		if name := fs.Arg(0); name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			file = f
+		}
+
+		job, err := env.client.CreatePayout(ctx, *from, file)
+		if err != nil {
+			return err
+		}
+		return printPayoutJob(env, job)
+	case "status":
+		if len(args) != 2 {
+			return usageError("payout status expects exactly one job id")
+		}
+		job, err := env.client.PayoutJob(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return printPayoutJob(env, job)
+	case "results":
+		if len(args) != 2 {
+			return usageError("payout results expects exactly one job id")
+		}
+		return env.client.PayoutResults(ctx, args[1], env.print.out)
+	default:
+		return usageError("unknown payout command %q", args[0])
+	}
+}
+
+// printPayoutJob выводит сводку по заданию на выплату.
+func printPayoutJob(env *environment, job models.PayoutJob) error {
+	return env.print.table(job,
+		[]string{"ID", "FROM", "STATUS", "TOTAL", "PENDING", "SUCCEEDED", "FAILED", "UNKNOWN"},
+		[][]string{{
+			job.ID, job.From, job.Status,
+			strconv.Itoa(job.Total), strconv.Itoa(job.Pending), strconv.Itoa(job.Succeeded),
+			strconv.Itoa(job.Failed), strconv.Itoa(job.Unknown),
+		}},
+	)
+}
+
+func runTransactions(ctx context.Context, env *environment, args []string) error {
+	fs := newFlagSet(env, "transactions")
+	count := fs.Int("count", 10, "number of transactions")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	txs, err := env.client.Transactions(ctx, *count)
+	if err != nil {
+		return err
+	}
+	if txs == nil {
+		txs = []models.Transaction{}
+	}
+
+	rows := make([][]string, 0, len(txs))
+	for _, tx := range txs {
+		rows = append(rows, []string{tx.Time, tx.From, tx.To, formatAmount(tx.Amount)})
+	}
+	return env.print.table(txs, []string{"TIME", "FROM", "TO", "AMOUNT"}, rows)
+}
+
+func runHealth(ctx context.Context, env *environment, _ []string) error {
+	report, err := env.client.Live(ctx)
+	if err != nil {
+		return err
+	}
+	return env.print.table(report, []string{"STATUS"}, [][]string{{report.Status}})
+}
+
+func runReady(ctx context.Context, env *environment, _ []string) error {
+	report, err := env.client.Ready(ctx)
+	if report.Status != "" {
+		names := make([]string, 0, len(report.Checks))
+		for name := range report.Checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rows := make([][]string, 0, len(names))
+		for _, name := range names {
+			c := report.Checks[name]
+			rows = append(rows, []string{name, c.Status, c.Duration, c.Error})
+		}
+		if perr := env.print.table(report, []string{"CHECK", "STATUS", "DURATION", "ERROR"}, rows); perr != nil {
+			return perr
+		}
+		if err != nil {
+			return &reportedError{err: err}
+		}
+	}
+	return err
+}
+
+func runSpec(ctx context.Context, env *environment, _ []string) error {
+	spec, err := env.client.Spec(ctx)
+	if err != nil {
+		return err
+	}
+	return env.print.json(spec)
+}
+
+func runMetrics(ctx context.Context, env *environment, _ []string) error {
+	text, err := env.client.Metrics(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(env.print.out, text)
+	return err
+}
+
+func runRateLimit(ctx context.Context, env *environment, _ []string) error {
+	stats, err := env.client.RateLimits(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(stats))
+	for _, st := range stats {
+		rows = append(rows, []string{
+			st.Route, st.Dimension,
+			strconv.FormatUint(st.Allowed, 10), strconv.FormatUint(st.Limited, 10), strconv.Itoa(st.Keys),
+		})
+	}
+	return env.print.table(stats, []string{"ROUTE", "DIMENSION", "ALLOWED", "LIMITED", "KEYS"}, rows)
+}
+
+func runBackup(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 1 {
+		return usageError("backup expects create or list")
+	}
+
+	var snaps []backup.Snapshot
+	switch args[0] {
+	case "create":
+		snap, err := env.client.CreateBackup(ctx)
+		if err != nil {
+			return err
+		}
+		snaps = []backup.Snapshot{snap}
+		if env.print.format == outputJSON {
+			return env.print.json(snap)
+		}
+	case "list":
+		var err error
+		if snaps, err = env.client.Backups(ctx); err != nil {
+			return err
+		}
+		if snaps == nil {
+			snaps = []backup.Snapshot{}
+		}
+	default:
+		return usageError("unknown backup command %q", args[0])
+	}
+
+	rows := make([][]string, 0, len(snaps))
+	for _, snap := range snaps {
+		rows = append(rows, []string{
+			snap.Name, snap.CreatedAt.Format(time.RFC3339), strconv.FormatInt(snap.Size, 10), snap.SHA256,
+		})
+	}
+	return env.print.table(snaps, []string{"NAME", "CREATED", "SIZE", "SHA256"}, rows)
+}
+
+func runMe(ctx context.Context, env *environment, args []string) error {
+	switch {
+	case len(args) == 0:
+		user, err := env.client.Me(ctx)
+		if err != nil {
+			return err
+		}
+		return printUser(env, user)
+	case len(args) == 1 && args[0] == "wallets":
+		wallets, err := env.client.MyWallets(ctx)
+		if err != nil {
+			return err
+		}
+		return printUserWallets(env, wallets)
+	}
+	return usageError("me expects no arguments or wallets")
+}
+
+func runUser(ctx context.Context, env *environment, args []string) error {
+	if len(args) == 0 {
+		return usageError("user expects create, show, wallets, key, revoke-keys, assign or unassign")
+	}
+
+	// Все команды, кроме create, принимают идентификатор пользователя,
+	// assign и unassign - также адрес кошелька
+	expected := map[string]int{"show": 1, "wallets": 1, "key": 1, "revoke-keys": 1, "assign": 2, "unassign": 2}
+	if n, ok := expected[args[0]]; ok && len(args) != n+1 {
+		if n == 1 {
+			return usageError("user %s expects exactly one user id", args[0])
+		}
+		return usageError("user %s expects a user id and a wallet address", args[0])
+	}
+
+	switch args[0] {
+	case "create":
+		fs := newFlagSet(env, "user create")
+		name := fs.String("name", "", "display name")
+		email := fs.String("email", "", "email address")
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return err
+		}
+		if *name == "" || fs.NArg() != 0 {
+			return usageError("user create requires -name")
+		}
+		user, err := env.client.CreateUser(ctx, models.UserRequest{Name: *name, Email: *email})
+		if err != nil {
+			return err
+		}
+		return printUser(env, user)
+	case "show":
+		user, err := env.client.User(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return printUser(env, user)
+	case "wallets":
+		wallets, err := env.client.UserWallets(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return printUserWallets(env, wallets)
+	case "key":
+		cred, err := env.client.IssueKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return env.print.table(cred, []string{"USER", "KEY", "CREATED"},
+			[][]string{{cred.UserID, cred.Key, cred.CreatedAt.Format(time.RFC3339)}})
+	case "revoke-keys":
+		revoked, err := env.client.RevokeKeys(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return env.print.table(revoked, []string{"USER", "REVOKED"},
+			[][]string{{revoked.UserID, strconv.Itoa(revoked.Revoked)}})
+	case "assign":
+		wallets, err := env.client.AssignWallet(ctx, args[1], args[2])
+		if err != nil {
+			return err
+		}
+		return printUserWallets(env, wallets)
+	case "unassign":
+		wallets, err := env.client.UnassignWallet(ctx, args[1], args[2])
+		if err != nil {
+			return err
+		}
+		return printUserWallets(env, wallets)
+	}
+	return usageError("unknown user command %q", args[0])
+}
+
+// printUser выводит профиль пользователя.
+func printUser(env *environment, user models.User) error {
+	return env.print.table(user, []string{"ID", "NAME", "EMAIL", "CREATED"},
+		[][]string{{user.ID, user.Name, user.Email, user.CreatedAt.Format(time.RFC3339)}})
+}
+
+// printUserWallets выводит кошельки пользователя; последняя строка таблицы - суммарный баланс.
+func printUserWallets(env *environment, wallets models.UserWallets) error {
+	rows := make([][]string, 0, len(wallets.Wallets)+1)
+	for _, w := range wallets.Wallets {
+		rows = append(rows, []string{w.Address, formatAmount(w.Balance), strconv.FormatInt(w.Version, 10)})
+	}
+	rows = append(rows, []string{"TOTAL", formatAmount(wallets.TotalBalance), ""})
+	return env.print.table(wallets, []string{"ADDRESS", "BALANCE", "VERSION"}, rows)
+}
+
+// profileView - представление профиля без секретов.
+type profileView struct {
+	Name     string `json:"name"`
+	Current  bool   `json:"current"`
+	BaseURL  string `json:"base_url"`
+	AdminURL string `json:"admin_url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+}
+
+func runProfiles(_ context.Context, env *environment, _ []string) error {
+	names := make([]string, 0, len(env.profiles.Profiles))
+	for name := range env.profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	views := make([]profileView, 0, len(names))
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		p := env.profiles.Profiles[name]
+		v := profileView{
+			Name:     name,
+			Current:  name == env.profileName,
+			BaseURL:  p.BaseURL,
+			AdminURL: p.AdminURL,
+			APIKey:   mask(p.APIKey),
+		}
+		views = append(views, v)
+
+		marker := ""
+		if v.Current {
+			marker = "*"
+		}
+		rows = append(rows, []string{marker, v.Name, v.BaseURL, v.AdminURL, v.APIKey})
+	}
+	return env.print.table(views, []string{"", "NAME", "BASE URL", "ADMIN URL", "API KEY"}, rows)
+}
+
+// mask скрывает секрет, оставляя последние символы для опознания.
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 4) + secret[len(secret)-4:]
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	cases := []struct {
+		name         string
+		args         []string
+		status       int
+		body         string
+		expectedCode int
+		expectedOut  string
+	}{
+		{
+			name:         "Успешный запрос",
+			args:         []string{"balance", "a"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"address":"a","balance":10,"version":3}}`,
+			expectedCode: exitOK,
+			expectedOut:  "ADDRESS  BALANCE  VERSION\na        10       3\n",
+		},
+		{
+			name:         "Вывод в JSON",
+			args:         []string{"-o", "json", "balance", "a"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"address":"a","balance":10,"version":3}}`,
+			expectedCode: exitOK,
+			expectedOut:  "{\n  \"address\": \"a\",\n  \"balance\": 10,\n  \"version\": 3\n}\n",
+		},
+		{
+			name:         "Запрос отклонен",
+			args:         []string{"send", "-from", "a", "-to", "b", "-amount", "100"},
+			status:       http.StatusBadRequest,
+			body:         `{"status":"Error","code":400,"error":"Недостаточно средств","error_code":"insufficient_funds"}`,
+			expectedCode: exitClientError,
+		},
+		{
+			name:         "Кошелек отправителя изменился",
+			args:         []string{"send", "-from", "a", "-to", "b", "-amount", "1", "-if-version", "3"},
+			status:       http.StatusPreconditionFailed,
+			body:         `{"status":"Error","code":412,"error":"Кошелек изменился","error_code":"version_mismatch"}`,
+			expectedCode: exitClientError,
+		},
+		{
+			name:         "Превышен лимит запросов",
+			args:         []string{"transactions"},
+			status:       http.StatusTooManyRequests,
+			body:         `{"status":"Error","code":429,"error":"Слишком много запросов","error_code":"too_many_requests"}`,
+			expectedCode: exitRateLimited,
+		},
+		{
+			name:         "Ошибка сервера",
+			args:         []string{"transactions"},
+			status:       http.StatusInternalServerError,
+			body:         `{"status":"Error","code":500,"error":"Внутренняя ошибка","error_code":"internal_error"}`,
+			expectedCode: exitServerError,
+		},
+		{
+			name:         "Некорректная сумма",
+			args:         []string{"send", "-from", "a", "-to", "b", "-amount", "ten"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Статус задания на выплату",
+			args:         []string{"payout", "status", "job1"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"id":"job1","from":"a","status":"completed","total":2,"pending":0,"succeeded":1,"failed":1,"unknown":0,"created_at":"2024-05-01T00:00:00Z"}}`,
+			expectedCode: exitOK,
+			expectedOut: "ID    FROM  STATUS     TOTAL  PENDING  SUCCEEDED  FAILED  UNKNOWN\n" +
+				"job1  a     completed  2      0        1          1       0\n",
+		},
+		{
+			name:         "Задание на выплату без файла",
+			args:         []string{"payout", "submit", "-from", "a"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Сведения о кошельке",
+			args:         []string{"wallet", "a"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"address":"a","balance":10,"version":3,"owner":"cust-1","labels":["vip","eu"],"metadata":{"tier":"gold","crm":"42"}}}`,
+			expectedCode: exitOK,
+			expectedOut: "ADDRESS  BALANCE  VERSION  OWNER   LABELS  METADATA\n" +
+				"a        10       3        cust-1  vip,eu  crm=42,tier=gold\n",
+		},
+		{
+			name:         "Некорректная запись метаданных",
+			args:         []string{"metadata", "a", "-meta", "tier"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Поиск кошельков без условий",
+			args:         []string{"wallets", "-limit", "5"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Присвоение имени кошельку",
+			args:         []string{"handle", "claim", "a", "@Alice"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"handle":"alice","address":"a"}}`,
+			expectedCode: exitOK,
+			expectedOut:  "HANDLE  ADDRESS\n@alice  a\n",
+		},
+		{
+			name:         "Имя кошелька занято",
+			args:         []string{"handle", "claim", "b", "alice"},
+			status:       http.StatusConflict,
+			body:         `{"status":"Error","code":409,"error":"Имя кошелька уже занято","error_code":"handle_taken"}`,
+			expectedCode: exitClientError,
+		},
+		{
+			name:         "Имя кошелька без адреса",
+			args:         []string{"handle", "claim", "alice"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Список снимков хранилища",
+			args:         []string{"backup", "list"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":[{"name":"storage-20240501T000000.000Z.db","size":4096,"sha256":"abc","created_at":"2024-05-01T00:00:00Z"}]}`,
+			expectedCode: exitOK,
+			expectedOut: "NAME                             CREATED               SIZE  SHA256\n" +
+				"storage-20240501T000000.000Z.db  2024-05-01T00:00:00Z  4096  abc\n",
+		},
+		{
+			name:         "Неизвестная команда резервного копирования",
+			args:         []string{"backup", "restore"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Кошельки текущего пользователя",
+			args:         []string{"me", "wallets"},
+			status:       http.StatusOK,
+			body:         `{"status":"OK","code":200,"data":{"user_id":"u1","wallets":[{"address":"a","balance":10,"version":3},{"address":"b","balance":2.5,"version":1}],"total_balance":12.5}}`,
+			expectedCode: exitOK,
+			expectedOut: "ADDRESS  BALANCE  VERSION\n" +
+				"a        10       3\n" +
+				"b        2.5      1\n" +
+				"TOTAL    12.5     \n",
+		},
+		{
+			name:         "Кошелек другого пользователя",
+			args:         []string{"balance", "b"},
+			status:       http.StatusForbidden,
+			body:         `{"status":"Error","code":403,"error":"Нет доступа к кошельку","error_code":"forbidden"}`,
+			expectedCode: exitClientError,
+		},
+		{
+			name:         "Ключ пользователя",
+			args:         []string{"user", "key", "u1"},
+			status:       http.StatusCreated,
+			body:         `{"status":"OK","code":201,"data":{"user_id":"u1","key":"psk_0123","created_at":"2024-05-01T00:00:00Z"}}`,
+			expectedCode: exitOK,
+			expectedOut:  "USER  KEY       CREATED\nu1    psk_0123  2024-05-01T00:00:00Z\n",
+		},
+		{
+			name:         "Пользователь без имени",
+			args:         []string{"user", "create", "-email", "alice@example.com"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Передача кошелька без адреса",
+			args:         []string{"user", "assign", "u1"},
+			expectedCode: exitUsage,
+		},
+		{
+			name:         "Неизвестная команда",
+			args:         []string{"bogus"},
+			expectedCode: exitUsage,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			t.Setenv(envConfig, t.TempDir()+"/missing.yaml")
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-url", srv.URL, "-admin-url", srv.URL}, tc.args...)
+
+			code := run(context.Background(), args, &stdout, &stderr)
+			require.Equal(t, tc.expectedCode, code, stderr.String())
+			if tc.expectedOut != "" {
+				require.Equal(t, tc.expectedOut, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/client"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Форматы вывода
+const (
+	outputTable = "table" // Таблица для человека
+	outputJSON  = "json"  // JSON для скриптов
+)
+
+// printer выводит результаты команд в выбранном формате.
+type printer struct {
+	format string
+	out    io.Writer
+	errOut io.Writer
+}
+
+// table выводит строки с заголовком, выравнивая столбцы.
+// В формате JSON вместо таблицы выводится значение v.
+func (p printer) table(v any, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		return p.json(v)
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// json выводит значение в формате JSON с отступами.
+func (p printer) json(v any) error {
+	enc := json.NewEncoder(p.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// text выводит сообщение; в формате JSON - объект {"message": ...}.
+func (p printer) text(msg string) error {
+	if p.format == outputJSON {
+		return p.json(map[string]string{"message": msg})
+	}
+	_, err := fmt.Fprintln(p.out, msg)
+	return err
+}
+
+// errorOutput - представление ошибки в формате JSON.
+type errorOutput struct {
+	StatusCode int            `json:"status_code,omitempty"`
+	ErrorCode  string         `json:"error_code,omitempty"`
+	Error      string         `json:"error"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// error выводит ошибку команды.
+// В формате JSON ошибка выводится в стандартный вывод, чтобы ее могли разобрать скрипты.
+func (p printer) error(err error) {
+	out := errorOutput{Error: err.Error()}
+
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		out = errorOutput{
+			StatusCode: apiErr.StatusCode,
+			ErrorCode:  apiErr.Code,
+			Error:      apiErr.Message,
+			Details:    apiErr.Details,
+		}
+	}
+
+	if p.format == outputJSON {
+		_ = p.json(out)
+		return
+	}
+
+	msg := out.Error
+	if out.ErrorCode != "" {
+		msg = out.ErrorCode + ": " + msg
+	}
+	_, _ = fmt.Fprintln(p.errOut, "error:", msg)
+
+	keys := make([]string, 0, len(out.Details))
+	for k := range out.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := out.Details[k]
+		if f, ok := v.(float64); ok {
+			v = formatAmount(f)
+		}
+		_, _ = fmt.Fprintf(p.errOut, "  %s: %v\n", k, v)
+	}
+}
+
+// formatAmount форматирует сумму без лишних нулей.
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
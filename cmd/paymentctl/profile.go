@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"infotecsTest/internal/client"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Переменные окружения клиента
+const (
+	envConfig  = "PAYMENTCTL_CONFIG"  // Путь к файлу профилей
+	envProfile = "PAYMENTCTL_PROFILE" // Имя профиля
+	envAPIKey  = "PAYMENTCTL_API_KEY" // Ключ клиента, переопределяет ключ профиля
+
+	envAdminKey = "PAYMENTCTL_ADMIN_KEY" // Ключ администратора, переопределяет ключ профиля
+)
+
+// defaultProfile - профиль, используемый при отсутствии файла профилей.
+var defaultProfile = Profile{
+	BaseURL:  "http://localhost:8080",
+	AdminURL: "http://localhost:9090",
+}
+
+// Profile содержит параметры подключения к одному окружению.
+type Profile struct {
+	BaseURL      string        `yaml:"base_url"`       // Адрес основного сервера
+	AdminURL     string        `yaml:"admin_url"`      // Адрес служебного сервера
+	APIKey       string        `yaml:"api_key"`        // Ключ клиента
+	APIKeyHeader string        `yaml:"api_key_header"` // Заголовок ключа клиента
+	AdminKey     string        `yaml:"admin_key"`      // Ключ администратора служебного сервера
+	Language     string        `yaml:"language"`       // Язык сообщений об ошибках
+	Timeout      time.Duration `yaml:"timeout"`        // Таймаут запроса
+}
+
+// Profiles - содержимое файла профилей.
+type Profiles struct {
+	Current  string             `yaml:"current"`  // Профиль по умолчанию
+	Profiles map[string]Profile `yaml:"profiles"` // Профили по имени
+}
+
+// clientConfig преобразует профиль в настройки клиента API.
+func (p Profile) clientConfig() client.Config {
+	return client.Config{
+		BaseURL:      p.BaseURL,
+		AdminURL:     p.AdminURL,
+		APIKey:       p.APIKey,
+		APIKeyHeader: p.APIKeyHeader,
+		AdminKey:     p.AdminKey,
+		Language:     p.Language,
+		Timeout:      p.Timeout,
+	}
+}
+
+// configPath возвращает путь к файлу профилей:
+// из флага, переменной окружения или каталога конфигурации пользователя.
+func configPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "paymentctl", "config.yaml")
+}
+
+// loadProfiles читает файл профилей.
+// Отсутствующий файл не является ошибкой: используется профиль по умолчанию.
+func loadProfiles(path string) (Profiles, error) {
+	const op = "paymentctl.loadProfiles"
+
+	var profiles Profiles
+	if path == "" {
+		return profiles, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err := cleanenv.ReadConfig(path, &profiles); err != nil {
+		return profiles, fmt.Errorf("%s: %w", op, err)
+	}
+	return profiles, nil
+}
+
+// resolve выбирает профиль по имени из флага, переменной окружения или поля current.
+// Пустые поля профиля заполняются значениями по умолчанию.
+func (ps Profiles) resolve(name string) (string, Profile, error) {
+	if name == "" {
+		name = os.Getenv(envProfile)
+	}
+	if name == "" {
+		name = ps.Current
+	}
+
+	profile := defaultProfile
+	if name != "" {
+		p, ok := ps.Profiles[name]
+		if !ok {
+			return "", Profile{}, fmt.Errorf("profile %q not found", name)
+		}
+		profile = p
+		if profile.BaseURL == "" {
+			profile.BaseURL = defaultProfile.BaseURL
+		}
+	}
+
+	if key := os.Getenv(envAPIKey); key != "" {
+		profile.APIKey = key
+	}
+	if key := os.Getenv(envAdminKey); key != "" {
+		profile.AdminKey = key
+	}
+	return name, profile, nil
+}
@@ -0,0 +1,342 @@
+// Package backup создает резервные копии хранилища по расписанию и по запросу,
+// хранит заданное число последних снимков с контрольными суммами
+// и восстанавливает хранилище из проверенного снимка.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/logger/sl"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Имена файлов снимков: storage-<время UTC>.db и контрольная сумма рядом в <имя>.sha256
+const (
+	snapshotPrefix   = "storage-"
+	snapshotExt      = ".db"
+	checksumExt      = ".sha256"
+	snapshotTimeFmt  = "20060102T150405.000Z"
+	snapshotFileMode = 0o600
+)
+
+var (
+	// ErrNoChecksum возвращается для снимка без файла контрольной суммы.
+	ErrNoChecksum = errors.New("snapshot checksum file is missing")
+
+	// ErrChecksumMismatch возвращается, если содержимое снимка не совпадает с контрольной суммой.
+	ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+	// ErrNoSnapshot возвращается, если подходящий снимок не найден.
+	ErrNoSnapshot = errors.New("no snapshot found")
+)
+
+// Snapshot описывает снимок хранилища.
+type Snapshot struct {
+	Name      string    `json:"name"`       // Имя файла снимка
+	Path      string    `json:"-"`          // Путь к файлу снимка
+	Size      int64     `json:"size"`       // Размер в байтах
+	SHA256    string    `json:"sha256"`     // Контрольная сумма из файла .sha256
+	CreatedAt time.Time `json:"created_at"` // Время создания (UTC)
+}
+
+// Source создает согласованную копию хранилища в новом файле.
+type Source interface {
+	Backup(ctx context.Context, dest string) error
+}
+
+// Manager создает снимки хранилища и удаляет устаревшие.
+// Снимки, запрошенные одновременно, создаются по очереди.
+type Manager struct {
+	log    *slog.Logger
+	source Source
+	cfg    config.Backup
+
+	mu          sync.Mutex   // Очередь создания снимков
+	lastSuccess atomic.Int64 // Время последнего снимка (Unix, нс)
+}
+
+// New создает менеджер резервного копирования.
+// Копирование по расписанию запускается отдельно через Run.
+func New(log *slog.Logger, source Source, cfg config.Backup) *Manager {
+	return &Manager{
+		log:    log.With(slog.String("component", "backup")),
+		source: source,
+		cfg:    cfg,
+	}
+}
+
+// Create создает снимок хранилища с контрольной суммой и удаляет снимки сверх лимита хранения.
+// Снимок записывается во временный файл и становится видимым только после записи контрольной суммы.
+func (m *Manager) Create(ctx context.Context) (Snapshot, error) {
+	const op = "backup.Create"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log := m.log.With("op", op)
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o750); err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created := time.Now().UTC()
+	name := snapshotPrefix + created.Format(snapshotTimeFmt) + snapshotExt
+	path := filepath.Join(m.cfg.Dir, name)
+	tmp := filepath.Join(m.cfg.Dir, "."+name+".tmp")
+	defer func() { _ = os.Remove(tmp) }()
+
+	start := time.Now()
+	if err := m.source.Backup(ctx, tmp); err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sum, size, err := checksum(tmp)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = os.Chmod(tmp, snapshotFileMode); err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = writeChecksum(path, sum); err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(path + checksumExt)
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	snap := Snapshot{Name: name, Path: path, Size: size, SHA256: sum, CreatedAt: created}
+	m.lastSuccess.Store(created.UnixNano())
+	log.Info("backup created",
+		slog.String("snapshot", name),
+		slog.Int64("size", size),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	if err = m.rotate(); err != nil {
+		// Снимок уже создан: ошибка удаления старых снимков не отменяет его
+		log.Error("failed to remove expired backups", sl.Err(err))
+	}
+	return snap, nil
+}
+
+// List возвращает снимки каталога резервных копий от новых к старым.
+func (m *Manager) List() ([]Snapshot, error) {
+	return List(m.cfg.Dir)
+}
+
+// LastSuccess возвращает время последнего успешного снимка с момента запуска.
+// Для процесса без снимков возвращает нулевое время.
+func (m *Manager) LastSuccess() time.Time {
+	ns := m.lastSuccess.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns).UTC()
+}
+
+// Run создает снимки с интервалом из конфигурации до отмены ctx.
+func (m *Manager) Run(ctx context.Context) {
+	const op = "backup.Run"
+
+	log := m.log.With("op", op)
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Create(ctx); err != nil && ctx.Err() == nil {
+				log.Error("scheduled backup failed", sl.Err(err))
+			}
+		}
+	}
+}
+
+// rotate удаляет снимки сверх лимита хранения, начиная с самых старых.
+func (m *Manager) rotate() error {
+	snaps, err := List(m.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	if len(snaps) <= m.cfg.Retention {
+		return nil
+	}
+
+	var errs []error
+	for _, snap := range snaps[m.cfg.Retention:] {
+		if err = os.Remove(snap.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err = os.Remove(snap.Path + checksumExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		m.log.Info("expired backup removed", slog.String("snapshot", snap.Name))
+	}
+	return errors.Join(errs...)
+}
+
+// List возвращает снимки каталога dir от новых к старым.
+// Для отсутствующего каталога возвращает пустой список.
+func List(dir string) ([]Snapshot, error) {
+	const op = "backup.List"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var snaps []Snapshot
+	for _, e := range entries {
+		if e.IsDir() || !isSnapshotName(e.Name()) {
+			continue
+		}
+		snap, err := Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		snaps = append(snaps, snap)
+	}
+
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].CreatedAt.After(snaps[j].CreatedAt) })
+	return snaps, nil
+}
+
+// Open возвращает описание файла снимка.
+// Время создания берется из имени файла, а для файлов с другим именем - из времени изменения.
+func Open(path string) (Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snap := Snapshot{
+		Name:      info.Name(),
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	}
+	if t, ok := snapshotTime(snap.Name); ok {
+		snap.CreatedAt = t
+	}
+
+	sum, err := readChecksum(path)
+	if err != nil && !errors.Is(err, ErrNoChecksum) {
+		return Snapshot{}, err
+	}
+	snap.SHA256 = sum
+	return snap, nil
+}
+
+// Select возвращает последний снимок каталога dir, созданный не позже at.
+// Нулевое at выбирает самый новый снимок.
+func Select(dir string, at time.Time) (Snapshot, error) {
+	const op = "backup.Select"
+
+	snaps, err := List(dir)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, snap := range snaps {
+		if at.IsZero() || !snap.CreatedAt.After(at) {
+			return snap, nil
+		}
+	}
+	return Snapshot{}, fmt.Errorf("%s: %w", op, ErrNoSnapshot)
+}
+
+// Verify сверяет содержимое снимка с контрольной суммой из файла .sha256.
+func Verify(snap Snapshot) error {
+	const op = "backup.Verify"
+
+	expected, err := readChecksum(snap.Path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	actual, _, err := checksum(snap.Path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("%s: %s: %w", op, snap.Name, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// isSnapshotName проверяет, что файл является снимком, созданным Manager.
+func isSnapshotName(name string) bool {
+	_, ok := snapshotTime(name)
+	return ok
+}
+
+// snapshotTime извлекает время создания из имени снимка.
+func snapshotTime(name string) (time.Time, bool) {
+	raw, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	raw, ok = strings.CutSuffix(raw, snapshotExt)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(snapshotTimeFmt, raw)
+	return t, err == nil
+}
+
+// checksum вычисляет SHA-256 и размер файла.
+func checksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// writeChecksum записывает контрольную сумму снимка в формате sha256sum.
+func writeChecksum(path, sum string) error {
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return os.WriteFile(path+checksumExt, []byte(line), snapshotFileMode)
+}
+
+// readChecksum читает контрольную сумму снимка из файла .sha256.
+func readChecksum(path string) (string, error) {
+	data, err := os.ReadFile(path + checksumExt)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNoChecksum
+		}
+		return "", err
+	}
+	sum, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	if len(sum) != hex.EncodedLen(sha256.Size) {
+		return "", fmt.Errorf("malformed checksum file %s", path+checksumExt)
+	}
+	return sum, nil
+}
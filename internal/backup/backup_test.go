@@ -0,0 +1,237 @@
+package backup_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newStorage создает хранилище с кошельками a (100) и b (0).
+func newStorage(t *testing.T) (*sqlite.Storage, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+	s, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	require.NoError(t, s.Seed(context.Background(),
+		[]models.Wallet{{Address: "a", Balance: 100}, {Address: "b"}}, nil))
+	return s, path
+}
+
+// validate проверяет копию снимка так же, как команда restore.
+func validate(ctx context.Context, path string) error {
+	s, err := sqlite.Open(path, config.SQLite{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	issues, err := s.CheckIntegrity(ctx)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d integrity issues", len(issues))
+	}
+	return nil
+}
+
+func TestCreate(t *testing.T) {
+	s, _ := newStorage(t)
+	dir := filepath.Join(t.TempDir(), "backups")
+	m := backup.New(testLogger, s, config.Backup{Dir: dir, Retention: 2})
+
+	require.True(t, m.LastSuccess().IsZero())
+
+	var created []backup.Snapshot
+	for range 3 {
+		snap, err := m.Create(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, backup.Verify(snap))
+		created = append(created, snap)
+		time.Sleep(2 * time.Millisecond) // Имена снимков различаются с точностью до миллисекунды
+	}
+	require.Equal(t, created[2].CreatedAt, m.LastSuccess())
+
+	// Хранятся только два последних снимка, от новых к старым
+	snaps, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+	require.Equal(t, created[2].Name, snaps[0].Name)
+	require.Equal(t, created[1].Name, snaps[1].Name)
+	require.Equal(t, created[2].SHA256, snaps[0].SHA256)
+
+	_, err = os.Stat(created[0].Path + ".sha256")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Во временном каталоге не остается промежуточных файлов
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+}
+
+func TestSelect(t *testing.T) {
+	dir := t.TempDir()
+	times := []time.Time{
+		time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	for _, ts := range times {
+		writeSnapshot(t, dir, ts, []byte(ts.String()))
+	}
+
+	cases := []struct {
+		name        string
+		at          time.Time
+		expected    time.Time
+		expectedErr error
+	}{
+		{name: "Самый новый снимок", expected: times[2]},
+		{name: "Снимок до момента времени", at: times[1].Add(30 * time.Minute), expected: times[1]},
+		{name: "Снимок в момент времени", at: times[1], expected: times[1]},
+		{name: "Снимков до момента нет", at: times[0].Add(-time.Minute), expectedErr: backup.ErrNoSnapshot},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			snap, err := backup.Select(dir, tc.at)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, snap.CreatedAt)
+		})
+	}
+
+	_, err := backup.Select(filepath.Join(dir, "missing"), time.Time{})
+	require.ErrorIs(t, err, backup.ErrNoSnapshot)
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	snap := writeSnapshot(t, dir, time.Now().UTC(), []byte("snapshot"))
+	require.NoError(t, backup.Verify(snap))
+
+	require.NoError(t, os.WriteFile(snap.Path, []byte("tampered"), 0o600))
+	require.ErrorIs(t, backup.Verify(snap), backup.ErrChecksumMismatch)
+
+	require.NoError(t, os.Remove(snap.Path+".sha256"))
+	require.ErrorIs(t, backup.Verify(snap), backup.ErrNoChecksum)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+
+	s, path := newStorage(t)
+	m := backup.New(testLogger, s, config.Backup{Dir: filepath.Join(t.TempDir(), "backups"), Retention: 3})
+
+	require.NoError(t, s.AddTransaction("a", "b", 10))
+	snap, err := m.Create(ctx)
+	require.NoError(t, err)
+
+	// Изменения после снимка теряются при восстановлении
+	require.NoError(t, s.AddTransaction("a", "b", 5))
+	require.NoError(t, s.Close())
+
+	previous, err := backup.Restore(ctx, snap, path, validate)
+	require.NoError(t, err)
+	require.NotEmpty(t, previous)
+
+	restored, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = restored.Close() }()
+
+	w, err := restored.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 90.0, w.Balance)
+
+	// Прежний файл хранилища сохранен рядом
+	kept, err := sqlite.New(previous, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = kept.Close() }()
+	w, err = kept.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 85.0, w.Balance)
+
+	_, err = os.Stat(path + ".restore")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRestoreRejectsInvalidSnapshot(t *testing.T) {
+	cases := []struct {
+		name    string
+		prepare func(t *testing.T, dir string) backup.Snapshot
+	}{
+		{
+			name: "Контрольная сумма не совпадает",
+			prepare: func(t *testing.T, dir string) backup.Snapshot {
+				snap := writeSnapshot(t, dir, time.Now().UTC(), []byte("snapshot"))
+				require.NoError(t, os.WriteFile(snap.Path, []byte("tampered"), 0o600))
+				return snap
+			},
+		},
+		{
+			name: "Файл не является базой данных",
+			prepare: func(t *testing.T, dir string) backup.Snapshot {
+				return writeSnapshot(t, dir, time.Now().UTC(), []byte("not a database file, just some text"))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "storage.db")
+			require.NoError(t, os.WriteFile(target, []byte("current"), 0o600))
+
+			snap := tc.prepare(t, t.TempDir())
+			previous, err := backup.Restore(context.Background(), snap, target, validate)
+			require.Error(t, err)
+			require.Empty(t, previous)
+
+			// Текущий файл хранилища не изменился
+			data, err := os.ReadFile(target)
+			require.NoError(t, err)
+			require.Equal(t, "current", string(data))
+
+			entries, err := os.ReadDir(filepath.Dir(target))
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+}
+
+// writeSnapshot записывает файл снимка с контрольной суммой в формате Manager.
+func writeSnapshot(t *testing.T, dir string, created time.Time, data []byte) backup.Snapshot {
+	t.Helper()
+
+	name := "storage-" + created.Format("20060102T150405.000Z") + ".db"
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	sum := sha256.Sum256(data)
+	line := hex.EncodeToString(sum[:]) + "  " + name + "\n"
+	require.NoError(t, os.WriteFile(path+".sha256", []byte(line), 0o600))
+
+	snap, err := backup.Open(path)
+	require.NoError(t, err)
+	return snap
+}
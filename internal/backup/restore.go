@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ValidateFunc проверяет копию снимка перед заменой файла хранилища.
+// Может изменять переданный файл, например применять миграции схемы.
+type ValidateFunc func(ctx context.Context, path string) error
+
+// sidecarSuffixes - служебные файлы SQLite, относящиеся к файлу базы.
+var sidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// Restore заменяет файл хранилища target снимком snap.
+// Снимок сверяется с контрольной суммой, копируется рядом с target и проверяется validate;
+// только после успешной проверки текущий файл (вместе с журналами SQLite) сохраняется
+// под именем <target>.before-restore-<время> и копия переименовывается в target.
+// Сервис, использующий target, должен быть остановлен.
+// Возвращает путь сохраненного прежнего файла или пустую строку, если его не было.
+func Restore(ctx context.Context, snap Snapshot, target string, validate ValidateFunc) (string, error) {
+	const op = "backup.Restore"
+
+	if err := Verify(snap); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp := target + ".restore"
+	if err := copyFile(snap.Path, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer removeWithSidecars(tmp)
+
+	if err := validate(ctx, tmp); err != nil {
+		return "", fmt.Errorf("%s: snapshot %s is invalid: %w", op, snap.Name, err)
+	}
+
+	previous := ""
+	if _, err := os.Stat(target); err == nil {
+		previous = fmt.Sprintf("%s.before-restore-%s", target, time.Now().UTC().Format(snapshotTimeFmt))
+		for _, suffix := range append([]string{""}, sidecarSuffixes...) {
+			err := os.Rename(target+suffix, previous+suffix)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("%s: keep current storage: %w", op, err)
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return previous, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := syncDir(filepath.Dir(target)); err != nil {
+		return previous, fmt.Errorf("%s: %w", op, err)
+	}
+	return previous, nil
+}
+
+// copyFile копирует файл и сбрасывает его на диск.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, snapshotFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir сбрасывает на диск изменения каталога (переименования файлов).
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
+
+// removeWithSidecars удаляет файл базы вместе со служебными файлами SQLite.
+func removeWithSidecars(path string) {
+	for _, suffix := range append([]string{""}, sidecarSuffixes...) {
+		_ = os.Remove(path + suffix)
+	}
+}
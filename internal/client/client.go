@@ -0,0 +1,729 @@
+// Package client реализует HTTP-клиент API платежной системы.
+// Разбирает ответы в стандартной обертке Response и в формате RFC 7807.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/http-server/handlers/health"
+	"infotecsTest/internal/http-server/middleware/ratelimit"
+	"infotecsTest/internal/lib/api/etag"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout - таймаут запроса по умолчанию.
+const DefaultTimeout = 10 * time.Second
+
+// Config содержит параметры подключения к API.
+type Config struct {
+	BaseURL      string        // Адрес основного сервера, например http://localhost:8080
+	AdminURL     string        // Адрес служебного сервера, например http://localhost:9090
+	APIKey       string        // Ключ клиента, передается в заголовке APIKeyHeader
+	APIKeyHeader string        // Заголовок ключа клиента (по умолчанию X-API-Key)
+	AdminKey     string        // Ключ администратора, передается служебному серверу в заголовке Authorization
+	Language     string        // Язык сообщений об ошибках (Accept-Language)
+	Timeout      time.Duration // Таймаут запроса
+}
+
+// Client выполняет запросы к API платежной системы.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// New создает клиента API.
+func New(cfg Config) *Client {
+	if cfg.APIKeyHeader == "" {
+		cfg.APIKeyHeader = "X-API-Key"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	cfg.AdminURL = strings.TrimSuffix(cfg.AdminURL, "/")
+
+	return &Client{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// ErrNoAdminURL возвращается при вызове служебных методов без адреса служебного сервера.
+var ErrNoAdminURL = errors.New("admin url is not configured")
+
+// APIError - ошибка, возвращенная сервером.
+type APIError struct {
+	StatusCode int            // HTTP-статус ответа
+	Code       string         // Машиночитаемый код ошибки
+	Message    string         // Сообщение об ошибке
+	Details    map[string]any // Структурированные сведения об ошибке
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// envelope - ответ в стандартной обертке с необработанными данными.
+type envelope struct {
+	Status    string          `json:"status"`
+	Code      int             `json:"code"`
+	Data      json.RawMessage `json:"data"`
+	Error     string          `json:"error"`
+	ErrorCode string          `json:"error_code"`
+	Details   map[string]any  `json:"details"`
+}
+
+// Balance возвращает баланс кошелька.
+func (c *Client) Balance(ctx context.Context, address string) (models.Wallet, error) {
+	const op = "client.Balance"
+
+	var w models.Wallet
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/wallet/"+url.PathEscape(address)+"/balance", nil, &w); err != nil {
+		return models.Wallet{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return w, nil
+}
+
+// Wallet возвращает сведения о кошельке: баланс, владельца, метки и метаданные.
+func (c *Client) Wallet(ctx context.Context, address string) (models.Wallet, error) {
+	const op = "client.Wallet"
+
+	var w models.Wallet
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/wallet/"+url.PathEscape(address), nil, &w); err != nil {
+		return models.Wallet{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return w, nil
+}
+
+// SetWalletMetadata заменяет владельца, метки и метаданные кошелька и возвращает сохраненные сведения.
+func (c *Client) SetWalletMetadata(ctx context.Context, address string, md models.WalletMetadata) (models.WalletMetadata, error) {
+	const op = "client.SetWalletMetadata"
+
+	body, err := json.Marshal(md)
+	if err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var saved models.WalletMetadata
+	if _, err = c.do(ctx, http.MethodPut, c.cfg.BaseURL+"/api/wallet/"+url.PathEscape(address)+"/metadata", body, &saved); err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return saved, nil
+}
+
+// FindWallets возвращает кошельки с указанными меткой и владельцем.
+// Нулевой filter.Limit оставляет ограничение сервера по умолчанию.
+func (c *Client) FindWallets(ctx context.Context, filter models.WalletFilter) ([]models.Wallet, error) {
+	const op = "client.FindWallets"
+
+	params := url.Values{}
+	if filter.Label != "" {
+		params.Set("label", filter.Label)
+	}
+	if filter.Owner != "" {
+		params.Set("owner", filter.Owner)
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var wallets []models.Wallet
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/wallets?"+params.Encode(), nil, &wallets); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// Send выполняет перевод и возвращает сообщение сервера.
+func (c *Client) Send(ctx context.Context, tx models.Transaction) (string, error) {
+	const op = "client.Send"
+
+	msg, err := c.send(ctx, tx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return msg, nil
+}
+
+// SendIfVersion выполняет перевод, только если версия кошелька отправителя равна version.
+// Если кошелек изменился, сервер отвечает 412 с кодом version_mismatch.
+func (c *Client) SendIfVersion(ctx context.Context, tx models.Transaction, version int64) (string, error) {
+	const op = "client.SendIfVersion"
+
+	msg, err := c.send(ctx, tx, http.Header{"If-Match": {etag.Format(version)}})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return msg, nil
+}
+
+// send отправляет перевод с дополнительными заголовками.
+func (c *Client) send(ctx context.Context, tx models.Transaction, header http.Header) (string, error) {
+	body, err := json.Marshal(tx)
+	if err != nil {
+		return "", err
+	}
+
+	var msg string
+	if _, err = c.doWithHeader(ctx, http.MethodPost, c.cfg.BaseURL+"/api/send", header, body, &msg); err != nil {
+		return "", err
+	}
+	return msg, nil
+}
+
+// ClaimHandle присваивает кошельку имя (@alice), заменяя прежнее.
+// Если имя занято другим кошельком, сервер отвечает 409 с кодом handle_taken.
+func (c *Client) ClaimHandle(ctx context.Context, address, handle string) (models.Handle, error) {
+	const op = "client.ClaimHandle"
+
+	body, err := json.Marshal(models.HandleRequest{Handle: handle})
+	if err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var h models.Handle
+	if _, err = c.do(ctx, http.MethodPut, c.cfg.BaseURL+"/api/wallet/"+url.PathEscape(address)+"/handle", body, &h); err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return h, nil
+}
+
+// ReleaseHandle освобождает имя кошелька и возвращает его.
+func (c *Client) ReleaseHandle(ctx context.Context, address string) (models.Handle, error) {
+	const op = "client.ReleaseHandle"
+
+	var h models.Handle
+	if _, err := c.do(ctx, http.MethodDelete, c.cfg.BaseURL+"/api/wallet/"+url.PathEscape(address)+"/handle", nil, &h); err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return h, nil
+}
+
+// ResolveHandle возвращает кошелек с указанным именем.
+func (c *Client) ResolveHandle(ctx context.Context, handle string) (models.Handle, error) {
+	const op = "client.ResolveHandle"
+
+	var h models.Handle
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/handles/"+url.PathEscape(handle), nil, &h); err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return h, nil
+}
+
+// Transactions возвращает последние count транзакций.
+func (c *Client) Transactions(ctx context.Context, count int) ([]models.Transaction, error) {
+	const op = "client.Transactions"
+
+	var txs []models.Transaction
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/transactions?count="+strconv.Itoa(count), nil, &txs); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return txs, nil
+}
+
+// Me возвращает профиль пользователя, которому принадлежит ключ клиента.
+func (c *Client) Me(ctx context.Context) (models.User, error) {
+	const op = "client.Me"
+
+	var user models.User
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/me", nil, &user); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// MyWallets возвращает кошельки пользователя, которому принадлежит ключ клиента,
+// с текущими балансами и их суммой.
+func (c *Client) MyWallets(ctx context.Context) (models.UserWallets, error) {
+	const op = "client.MyWallets"
+
+	var wallets models.UserWallets
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/me/wallets", nil, &wallets); err != nil {
+		return models.UserWallets{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// Live выполняет пробу живости.
+func (c *Client) Live(ctx context.Context) (health.Report, error) {
+	const op = "client.Live"
+
+	var report health.Report
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/healthz", nil, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	return report, nil
+}
+
+// Ready выполняет пробу готовности.
+// Отчет о проверках возвращается и при неготовности сервиса.
+func (c *Client) Ready(ctx context.Context) (health.Report, error) {
+	const op = "client.Ready"
+
+	var report health.Report
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/readyz", nil, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	return report, nil
+}
+
+// Spec возвращает спецификацию OpenAPI.
+func (c *Client) Spec(ctx context.Context) (json.RawMessage, error) {
+	const op = "client.Spec"
+
+	body, err := c.raw(ctx, c.cfg.BaseURL+"/api/openapi.json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return body, nil
+}
+
+// StatementQuery задает период и формат выписки.
+// Пустые значения заменяются значениями по умолчанию на сервере.
+type StatementQuery struct {
+	From   string // Начало периода (RFC 3339 или ГГГГ-ММ-ДД)
+	To     string // Конец периода (RFC 3339 или ГГГГ-ММ-ДД)
+	Format string // Формат: csv или jsonl
+}
+
+// Statement записывает выписку по кошельку в w по мере получения.
+// Таймаут клиента не применяется: длительность выгрузки ограничивается ctx.
+func (c *Client) Statement(ctx context.Context, address string, q StatementQuery, w io.Writer) error {
+	const op = "client.Statement"
+
+	params := url.Values{}
+	if q.From != "" {
+		params.Set("from", q.From)
+	}
+	if q.To != "" {
+		params.Set("to", q.To)
+	}
+	if q.Format != "" {
+		params.Set("format", q.Format)
+	}
+	rawURL := c.cfg.BaseURL + "/api/wallet/" + url.PathEscape(address) + "/statement"
+	if len(params) > 0 {
+		rawURL += "?" + params.Encode()
+	}
+
+	if err := c.stream(ctx, rawURL, "text/csv, application/x-ndjson", w); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CreatePayout загружает CSV-файл выплат с кошелька from и возвращает принятое задание.
+// Строки выполняются сервером в фоне; статус задания запрашивается через PayoutJob.
+func (c *Client) CreatePayout(ctx context.Context, from string, file io.Reader) (models.PayoutJob, error) {
+	const op = "client.CreatePayout"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.cfg.BaseURL+"/api/payouts?from="+url.QueryEscape(from), file)
+	if err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Accept", "application/json, "+response.ContentTypeProblem)
+	c.authorize(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var job models.PayoutJob
+	if err = decode(resp, data, &job); err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return job, nil
+}
+
+// PayoutJob возвращает статус задания на выплату.
+func (c *Client) PayoutJob(ctx context.Context, id string) (models.PayoutJob, error) {
+	const op = "client.PayoutJob"
+
+	var job models.PayoutJob
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.BaseURL+"/api/payouts/"+url.PathEscape(id), nil, &job); err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return job, nil
+}
+
+// PayoutResults записывает в w результаты задания на выплату в формате CSV.
+func (c *Client) PayoutResults(ctx context.Context, id string, w io.Writer) error {
+	const op = "client.PayoutResults"
+
+	if err := c.stream(ctx, c.cfg.BaseURL+"/api/payouts/"+url.PathEscape(id)+"/results", "text/csv", w); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Metrics возвращает метрики служебного сервера в текстовом формате Prometheus.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	const op = "client.Metrics"
+
+	if c.cfg.AdminURL == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	body, err := c.raw(ctx, c.cfg.AdminURL+"/metrics")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return string(body), nil
+}
+
+// RateLimits возвращает счетчики ограничителя частоты запросов.
+func (c *Client) RateLimits(ctx context.Context) ([]ratelimit.Stat, error) {
+	const op = "client.RateLimits"
+
+	if c.cfg.AdminURL == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var stats []ratelimit.Stat
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.AdminURL+"/debug/ratelimit", nil, &stats); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return stats, nil
+}
+
+// CreateBackup создает снимок хранилища на служебном сервере.
+func (c *Client) CreateBackup(ctx context.Context) (backup.Snapshot, error) {
+	const op = "client.CreateBackup"
+
+	if c.cfg.AdminURL == "" {
+		return backup.Snapshot{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var snap backup.Snapshot
+	if _, err := c.do(ctx, http.MethodPost, c.cfg.AdminURL+"/backups", nil, &snap); err != nil {
+		return backup.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return snap, nil
+}
+
+// Backups возвращает снимки хранилища от новых к старым.
+func (c *Client) Backups(ctx context.Context) ([]backup.Snapshot, error) {
+	const op = "client.Backups"
+
+	if c.cfg.AdminURL == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var snaps []backup.Snapshot
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.AdminURL+"/backups", nil, &snaps); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return snaps, nil
+}
+
+// CreateUser регистрирует пользователя на служебном сервере.
+func (c *Client) CreateUser(ctx context.Context, req models.UserRequest) (models.User, error) {
+	const op = "client.CreateUser"
+
+	if c.cfg.AdminURL == "" {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var user models.User
+	if _, err = c.do(ctx, http.MethodPost, c.cfg.AdminURL+"/users", body, &user); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// User возвращает профиль пользователя.
+func (c *Client) User(ctx context.Context, id string) (models.User, error) {
+	const op = "client.User"
+
+	if c.cfg.AdminURL == "" {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var user models.User
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.AdminURL+"/users/"+url.PathEscape(id), nil, &user); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// UserWallets возвращает кошельки пользователя с текущими балансами и их суммой.
+func (c *Client) UserWallets(ctx context.Context, id string) (models.UserWallets, error) {
+	const op = "client.UserWallets"
+
+	if c.cfg.AdminURL == "" {
+		return models.UserWallets{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var wallets models.UserWallets
+	if _, err := c.do(ctx, http.MethodGet, c.cfg.AdminURL+"/users/"+url.PathEscape(id)+"/wallets", nil, &wallets); err != nil {
+		return models.UserWallets{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// AssignWallet передает кошелек во владение пользователю и возвращает его кошельки.
+// Если кошелек принадлежит другому пользователю, сервер отвечает 409 с кодом wallet_owned.
+func (c *Client) AssignWallet(ctx context.Context, id, address string) (models.UserWallets, error) {
+	const op = "client.AssignWallet"
+
+	wallets, err := c.userWallet(ctx, http.MethodPut, id, address)
+	if err != nil {
+		return models.UserWallets{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// UnassignWallet освобождает кошелек пользователя и возвращает его оставшиеся кошельки.
+func (c *Client) UnassignWallet(ctx context.Context, id, address string) (models.UserWallets, error) {
+	const op = "client.UnassignWallet"
+
+	wallets, err := c.userWallet(ctx, http.MethodDelete, id, address)
+	if err != nil {
+		return models.UserWallets{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// userWallet изменяет владение кошельком на служебном сервере.
+func (c *Client) userWallet(ctx context.Context, method, id, address string) (models.UserWallets, error) {
+	if c.cfg.AdminURL == "" {
+		return models.UserWallets{}, ErrNoAdminURL
+	}
+	var wallets models.UserWallets
+	rawURL := c.cfg.AdminURL + "/users/" + url.PathEscape(id) + "/wallets/" + url.PathEscape(address)
+	if _, err := c.do(ctx, method, rawURL, nil, &wallets); err != nil {
+		return models.UserWallets{}, err
+	}
+	return wallets, nil
+}
+
+// IssueKey выпускает пользователю ключ API. Ключ возвращается только один раз.
+func (c *Client) IssueKey(ctx context.Context, id string) (models.Credential, error) {
+	const op = "client.IssueKey"
+
+	if c.cfg.AdminURL == "" {
+		return models.Credential{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var cred models.Credential
+	if _, err := c.do(ctx, http.MethodPost, c.cfg.AdminURL+"/users/"+url.PathEscape(id)+"/keys", nil, &cred); err != nil {
+		return models.Credential{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return cred, nil
+}
+
+// RevokeKeys отзывает все ключи API пользователя.
+func (c *Client) RevokeKeys(ctx context.Context, id string) (models.RevokedKeys, error) {
+	const op = "client.RevokeKeys"
+
+	if c.cfg.AdminURL == "" {
+		return models.RevokedKeys{}, fmt.Errorf("%s: %w", op, ErrNoAdminURL)
+	}
+	var revoked models.RevokedKeys
+	if _, err := c.do(ctx, http.MethodDelete, c.cfg.AdminURL+"/users/"+url.PathEscape(id)+"/keys", nil, &revoked); err != nil {
+		return models.RevokedKeys{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
+
+// do выполняет запрос и разбирает ответ в обертке Response.
+func (c *Client) do(ctx context.Context, method, rawURL string, body []byte, out any) (int, error) {
+	return c.doWithHeader(ctx, method, rawURL, nil, body, out)
+}
+
+// doWithHeader выполняет запрос с дополнительными заголовками и разбирает ответ в обертке Response.
+func (c *Client) doWithHeader(ctx context.Context, method, rawURL string, header http.Header, body []byte, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, "+response.ContentTypeProblem)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	c.authorize(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	return resp.StatusCode, decode(resp, data, out)
+}
+
+// decode разбирает тело ответа в обертке Response или в формате RFC 7807.
+// Данные ответа декодируются в out, в том числе для ответов с ошибкой.
+func decode(resp *http.Response, data []byte, out any) error {
+	if isProblem(resp.Header.Get("Content-Type")) {
+		return decodeProblem(resp.StatusCode, data, out)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	if len(env.Data) > 0 && out != nil {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return fmt.Errorf("decode response data: %w", err)
+		}
+	}
+
+	if env.Status == response.StatusError || resp.StatusCode >= http.StatusBadRequest {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       env.ErrorCode,
+			Message:    env.Error,
+			Details:    env.Details,
+		}
+	}
+	return nil
+}
+
+// stream выполняет GET-запрос и копирует тело успешного ответа в w по мере получения.
+// Таймаут клиента не применяется: длительность выгрузки ограничивается ctx.
+func (c *Client) stream(ctx context.Context, rawURL, accept string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept+", application/json, "+response.ContentTypeProblem)
+	c.authorize(req)
+
+	stream := &http.Client{Transport: c.http.Transport}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return decode(resp, data, nil)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// raw выполняет GET-запрос и возвращает тело успешного ответа без разбора.
+func (c *Client) raw(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return data, nil
+}
+
+// authorize добавляет к запросу ключ клиента и язык сообщений.
+// Ключ администратора передается только в запросах к служебному серверу.
+func (c *Client) authorize(req *http.Request) {
+	if c.cfg.APIKey != "" {
+		req.Header.Set(c.cfg.APIKeyHeader, c.cfg.APIKey)
+	}
+	if c.cfg.AdminKey != "" && c.cfg.AdminURL != "" && strings.HasPrefix(req.URL.String(), c.cfg.AdminURL+"/") {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.AdminKey)
+	}
+	if c.cfg.Language != "" {
+		req.Header.Set("Accept-Language", c.cfg.Language)
+	}
+}
+
+// isProblem проверяет, что ответ передан в формате RFC 7807.
+func isProblem(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == response.ContentTypeProblem
+}
+
+// decodeProblem преобразует документ RFC 7807 в APIError.
+func decodeProblem(status int, data []byte, out any) error {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return &APIError{StatusCode: status, Message: strings.TrimSpace(string(data))}
+	}
+
+	var payload struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &payload); err == nil && len(payload.Data) > 0 && out != nil {
+		if err = json.Unmarshal(payload.Data, out); err != nil {
+			return fmt.Errorf("decode response data: %w", err)
+		}
+	}
+
+	apiErr := &APIError{StatusCode: status, Details: make(map[string]any)}
+	for k, v := range doc {
+		switch k {
+		case "detail":
+			apiErr.Message, _ = v.(string)
+		case "error_code":
+			apiErr.Code, _ = v.(string)
+		case "type", "title", "status", "instance", "data":
+		default:
+			apiErr.Details[k] = v
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message, _ = doc["title"].(string)
+	}
+	if len(apiErr.Details) == 0 {
+		apiErr.Details = nil
+	}
+	return apiErr
+}
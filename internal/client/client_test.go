@@ -0,0 +1,299 @@
+package client_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/client"
+	"infotecsTest/internal/http-server/handlers/health"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		call        func(c *client.Client) (any, error)
+		expected    any
+		expectedErr *client.APIError
+	}{
+		{
+			name:   "Баланс кошелька",
+			status: http.StatusOK,
+			body:   `{"status":"OK","code":200,"data":{"address":"a","balance":10,"version":3}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Balance(context.Background(), "a")
+			},
+			expected: models.Wallet{Address: "a", Balance: 10, Version: 3},
+		},
+		{
+			name:   "Поиск кошельков по метке",
+			status: http.StatusOK,
+			body:   `{"status":"OK","code":200,"data":[{"address":"a","balance":10,"version":3,"owner":"cust-1","labels":["vip"]}]}`,
+			call: func(c *client.Client) (any, error) {
+				return c.FindWallets(context.Background(), models.WalletFilter{Label: "vip"})
+			},
+			expected: []models.Wallet{{Address: "a", Balance: 10, Version: 3, Owner: "cust-1", Labels: []string{"vip"}}},
+		},
+		{
+			name:   "Поиск кошелька по имени",
+			status: http.StatusOK,
+			body:   `{"status":"OK","code":200,"data":{"handle":"alice","address":"a"}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.ResolveHandle(context.Background(), "@alice")
+			},
+			expected: models.Handle{Handle: "alice", Address: "a"},
+		},
+		{
+			name:   "Имя кошелька занято",
+			status: http.StatusConflict,
+			body:   `{"status":"Error","code":409,"error":"Имя кошелька уже занято","error_code":"handle_taken"}`,
+			call: func(c *client.Client) (any, error) {
+				return c.ClaimHandle(context.Background(), "b", "alice")
+			},
+			expected: models.Handle{},
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusConflict,
+				Code:       "handle_taken",
+				Message:    "Имя кошелька уже занято",
+			},
+		},
+		{
+			name:   "Кошелек отправителя изменился",
+			status: http.StatusPreconditionFailed,
+			body:   `{"status":"Error","code":412,"error":"Кошелек изменился","error_code":"version_mismatch","details":{"expected_version":3,"current_version":4}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.SendIfVersion(context.Background(), models.Transaction{From: "a", To: "b", Amount: 1}, 3)
+			},
+			expected: "",
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusPreconditionFailed,
+				Code:       "version_mismatch",
+				Message:    "Кошелек изменился",
+				Details:    map[string]any{"expected_version": float64(3), "current_version": float64(4)},
+			},
+		},
+		{
+			name:   "Ошибка в стандартной обертке",
+			status: http.StatusBadRequest,
+			body:   `{"status":"Error","code":400,"error":"Недостаточно средств","error_code":"insufficient_funds","details":{"available":5}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Send(context.Background(), models.Transaction{From: "a", To: "b", Amount: 10})
+			},
+			expected: "",
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "insufficient_funds",
+				Message:    "Недостаточно средств",
+				Details:    map[string]any{"available": float64(5)},
+			},
+		},
+		{
+			name:        "Ошибка в формате RFC 7807",
+			contentType: response.ContentTypeProblem,
+			status:      http.StatusTooManyRequests,
+			body:        `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"Слишком много запросов","error_code":"too_many_requests","retry_after":2}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Transactions(context.Background(), 5)
+			},
+			expected: []models.Transaction(nil),
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusTooManyRequests,
+				Code:       "too_many_requests",
+				Message:    "Слишком много запросов",
+				Details:    map[string]any{"retry_after": float64(2)},
+			},
+		},
+		{
+			name:   "Отчет о неготовности",
+			status: http.StatusServiceUnavailable,
+			body:   `{"status":"Error","code":503,"data":{"status":"fail"},"error":"Сервис не готов","error_code":"not_ready"}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Ready(context.Background())
+			},
+			expected: health.Report{Status: health.StatusFail},
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusServiceUnavailable,
+				Code:       "not_ready",
+				Message:    "Сервис не готов",
+			},
+		},
+		{
+			name:        "Отчет о неготовности в формате RFC 7807",
+			contentType: response.ContentTypeProblem,
+			status:      http.StatusServiceUnavailable,
+			body:        `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Сервис не готов","error_code":"not_ready","data":{"status":"fail","checks":{"database":{"status":"fail","duration":"1ms","error":"closed"}}}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Ready(context.Background())
+			},
+			expected: health.Report{Status: health.StatusFail, Checks: map[string]health.CheckResult{
+				"database": {Status: health.StatusFail, Duration: "1ms", Error: "closed"},
+			}},
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusServiceUnavailable,
+				Code:       "not_ready",
+				Message:    "Сервис не готов",
+			},
+		},
+		{
+			name:   "Задание на выплату принято",
+			status: http.StatusAccepted,
+			body:   `{"status":"OK","code":202,"data":{"id":"job1","from":"a","status":"pending","total":1,"pending":1,"succeeded":0,"failed":0,"unknown":0,"created_at":"2024-05-01T00:00:00Z"}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.CreatePayout(context.Background(), "a", strings.NewReader("b,10\n"))
+			},
+			expected: models.PayoutJob{ID: "job1", From: "a", Status: models.PayoutJobPending, Total: 1, Pending: 1,
+				CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "Снимок хранилища создан",
+			status: http.StatusOK,
+			body:   `{"status":"OK","code":200,"data":{"name":"storage-20240501T000000.000Z.db","size":4096,"sha256":"abc","created_at":"2024-05-01T00:00:00Z"}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.CreateBackup(context.Background())
+			},
+			expected: backup.Snapshot{Name: "storage-20240501T000000.000Z.db", Size: 4096, SHA256: "abc",
+				CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "Кошельки пользователя",
+			status: http.StatusOK,
+			body:   `{"status":"OK","code":200,"data":{"user_id":"u1","wallets":[{"address":"a","balance":10,"version":3},{"address":"b","balance":2.5,"version":1}],"total_balance":12.5}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.MyWallets(context.Background())
+			},
+			expected: models.UserWallets{
+				UserID:       "u1",
+				Wallets:      []models.Wallet{{Address: "a", Balance: 10, Version: 3}, {Address: "b", Balance: 2.5, Version: 1}},
+				TotalBalance: 12.5,
+			},
+		},
+		{
+			name:   "Кошелек другого пользователя",
+			status: http.StatusForbidden,
+			body:   `{"status":"Error","code":403,"error":"Нет доступа к кошельку","error_code":"forbidden"}`,
+			call: func(c *client.Client) (any, error) {
+				return c.Balance(context.Background(), "b")
+			},
+			expected: models.Wallet{},
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusForbidden,
+				Code:       "forbidden",
+				Message:    "Нет доступа к кошельку",
+			},
+		},
+		{
+			name:   "Ключ пользователя выпущен",
+			status: http.StatusCreated,
+			body:   `{"status":"OK","code":201,"data":{"user_id":"u1","key":"psk_0123","created_at":"2024-05-01T00:00:00Z"}}`,
+			call: func(c *client.Client) (any, error) {
+				return c.IssueKey(context.Background(), "u1")
+			},
+			expected: models.Credential{UserID: "u1", Key: "psk_0123", CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "Кошелек принадлежит другому пользователю",
+			status: http.StatusConflict,
+			body:   `{"status":"Error","code":409,"error":"Кошелек принадлежит другому пользователю","error_code":"wallet_owned"}`,
+			call: func(c *client.Client) (any, error) {
+				return c.AssignWallet(context.Background(), "u1", "b")
+			},
+			expected: models.UserWallets{},
+			expectedErr: &client.APIError{
+				StatusCode: http.StatusConflict,
+				Code:       "wallet_owned",
+				Message:    "Кошелек принадлежит другому пользователю",
+			},
+		},
+		{
+			name:   "Ответ не в формате JSON",
+			status: http.StatusBadGateway,
+			body:   "bad gateway",
+			call: func(c *client.Client) (any, error) {
+				return c.Balance(context.Background(), "a")
+			},
+			expected:    models.Wallet{},
+			expectedErr: &client.APIError{StatusCode: http.StatusBadGateway, Message: "bad gateway"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "secret", r.Header.Get("X-API-Key"))
+				require.Equal(t, "en", r.Header.Get("Accept-Language"))
+
+				contentType := tc.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				w.Header().Set("Content-Type", contentType)
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			c := client.New(client.Config{BaseURL: srv.URL, AdminURL: srv.URL, APIKey: "secret", Language: "en"})
+
+			got, err := tc.call(c)
+			require.Equal(t, tc.expected, got)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var apiErr *client.APIError
+			require.ErrorAs(t, err, &apiErr)
+			require.Equal(t, tc.expectedErr, apiErr)
+		})
+	}
+}
+
+func TestClientWithoutAdminURL(t *testing.T) {
+	c := client.New(client.Config{BaseURL: "http://localhost"})
+
+	_, err := c.Metrics(context.Background())
+	require.ErrorIs(t, err, client.ErrNoAdminURL)
+
+	_, err = c.CreateBackup(context.Background())
+	require.ErrorIs(t, err, client.ErrNoAdminURL)
+
+	_, err = c.AssignWallet(context.Background(), "u1", "a")
+	require.ErrorIs(t, err, client.ErrNoAdminURL)
+}
+
+func TestClientAdminKey(t *testing.T) {
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer admin-secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"status":"OK","code":200,"data":{"id":"u1","name":"alice"}}`)
+	}))
+	defer admin.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ключ администратора не передается основному серверу
+		require.Empty(t, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"status":"OK","code":200,"data":{"id":"u1","name":"alice"}}`)
+	}))
+	defer api.Close()
+
+	c := client.New(client.Config{BaseURL: api.URL, AdminURL: admin.URL, AdminKey: "admin-secret"})
+
+	user, err := c.User(context.Background(), "u1")
+	require.NoError(t, err)
+	require.Equal(t, "alice", user.Name)
+
+	user, err = c.Me(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "u1", user.ID)
+}
@@ -3,6 +3,7 @@
 package config
 
 import (
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"os"
@@ -12,9 +13,21 @@ import (
 // Config представляет основную конфигурацию приложения.
 // Содержит настройки среды выполнения, хранилища и HTTP-сервера.
 type Config struct {
-	Env         string               `yaml:"env" env-default:"development"`    // Окружение приложения (dev/prod)
+	Env         string               `yaml:"env" env-default:"prod"`           // Окружение приложения (local/dev/prod)
 	StoragePath string               `yaml:"storage_path" env-required:"true"` // Путь к файлу хранилища данных
+	SQLite      SQLite               `yaml:"sqlite"`                           // Параметры подключения к SQLite
 	HTTPServer  `yaml:"http_server"` // Настройки HTTP-сервера
+	AdminServer AdminServer          `yaml:"admin_server"` // Настройки служебного HTTP-сервера
+	GRPCServer  GRPCServer           `yaml:"grpc_server"`  // Настройки gRPC-сервера
+	Auth        Auth                 `yaml:"auth"`         // Аутентификация пользователей
+	RateLimit   RateLimit            `yaml:"rate_limit"`   // Ограничение частоты запросов
+	AccessLog   AccessLog            `yaml:"access_log"`   // Настройки журнала запросов
+	Errors      Errors               `yaml:"errors"`       // Формат ответов с ошибками
+	OpenAPI     OpenAPI              `yaml:"openapi"`      // Проверка запросов по спецификации
+	Payouts     Payouts              `yaml:"payouts"`      // Массовые выплаты
+	Backup      Backup               `yaml:"backup"`       // Резервное копирование хранилища
+	Ledger      Ledger               `yaml:"ledger"`       // Движок переводов в памяти
+	HotWallets  []HotWallet          `yaml:"hot_wallets"`  // Кошельки с балансом, разделенным на подбалансы
 }
 
 // HTTPServer содержит конфигурационные параметры HTTP-сервера.
@@ -22,13 +35,137 @@ type HTTPServer struct {
 	Address         string        `yaml:"address" env-default:"0.0.0.0:8080"` // Адрес сервера (host:port)
 	Timeout         time.Duration `yaml:"timeout" env-default:"4s"`           // Таймаут обработки запросов
 	IdleTimeout     time.Duration `yaml:"idle_timeout" env-default:"60s"`     // Таймаут бездействующих соединений
-	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"` // Таймаут graceful shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"` // Таймаут каждого этапа graceful shutdown
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env-default:"0s"`    // Пауза между отказом пробы готовности и остановкой серверов
+}
+
+// AdminServer содержит параметры служебного HTTP-сервера (метрики, отладка).
+// Пустой адрес отключает служебный сервер.
+type AdminServer struct {
+	Address string `yaml:"address" env-default:"127.0.0.1:9090"` // Адрес служебного сервера
+}
+
+// GRPCServer содержит параметры gRPC-сервера.
+// Пустой адрес отключает gRPC API.
+type GRPCServer struct {
+	Address string `yaml:"address" env-default:""` // Адрес gRPC-сервера (host:port)
+}
+
+// Auth содержит настройки аутентификации пользователей по ключу API.
+// Если аутентификация включена, запросы к API без ключа отклоняются,
+// а операции с кошельком разрешены только его владельцу.
+// Непустой AdminKey требуется в заголовке Authorization (Bearer) всех запросов к служебному серверу.
+type Auth struct {
+	Enabled  bool   `yaml:"enabled" env-default:"false"`                  // Требовать ключ пользователя
+	Header   string `yaml:"header" env-default:"X-API-Key"`               // Заголовок с ключом пользователя
+	AdminKey string `yaml:"admin_key" env:"AUTH_ADMIN_KEY" secret:"true"` // Ключ администратора служебного сервера
+}
+
+// OpenAPI содержит настройки проверки запросов и ответов по спецификации API.
+type OpenAPI struct {
+	ValidateRequests  bool `yaml:"validate_requests" env-default:"true"`   // Отклонять запросы, не соответствующие спецификации
+	ValidateResponses bool `yaml:"validate_responses" env-default:"false"` // Журналировать ответы, не соответствующие спецификации
+}
+
+// Payouts содержит параметры заданий на массовую выплату.
+type Payouts struct {
+	MaxRows      int           `yaml:"max_rows" env-default:"10000"`        // Максимальное число строк в файле
+	MaxFileSize  int64         `yaml:"max_file_size" env-default:"4194304"` // Максимальный размер файла в байтах
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`      // Интервал проверки ожидающих строк
+}
+
+// SQLite содержит параметры подключения к базе и повторов при блокировке.
+// Режимы журнала, синхронизации и блокировки передаются SQLite без изменений;
+// пустое значение оставляет настройку драйвера по умолчанию.
+type SQLite struct {
+	Driver          string        `yaml:"driver" env-default:""`              // Драйвер: mattn (CGO)/modernc (чистый Go), пустой - mattn при сборке с CGO
+	JournalMode     string        `yaml:"journal_mode" env-default:"wal"`     // Режим журнала: wal/delete/truncate/persist/memory/off
+	Synchronous     string        `yaml:"synchronous" env-default:"normal"`   // Синхронизация с диском: off/normal/full/extra
+	BusyTimeout     time.Duration `yaml:"busy_timeout" env-default:"5s"`      // Ожидание снятия блокировки другим соединением
+	TxLock          string        `yaml:"tx_lock" env-default:"immediate"`    // Блокировка в начале транзакции: deferred/immediate/exclusive
+	MaxOpenConns    int           `yaml:"max_open_conns" env-default:"8"`     // Максимум открытых соединений (0 - без ограничения)
+	MaxIdleConns    int           `yaml:"max_idle_conns" env-default:"8"`     // Максимум простаивающих соединений
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"0s"` // Время жизни соединения (0 - без ограничения)
+	BusyRetries     int           `yaml:"busy_retries" env-default:"3"`       // Повторы перевода при SQLITE_BUSY
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"10ms"`   // Пауза перед первым повтором, удваивается с каждым
+
+	GroupCommitSize  int           `yaml:"group_commit_size" env-default:"1"`    // Максимум переводов в одной транзакции (1 - без группировки)
+	GroupCommitDelay time.Duration `yaml:"group_commit_delay" env-default:"2ms"` // Ожидание следующих переводов группы после первого
+}
+
+// Backup содержит параметры резервного копирования хранилища.
+type Backup struct {
+	Dir       string        `yaml:"dir" env-default:"./storage/backups"` // Каталог снимков
+	Interval  time.Duration `yaml:"interval" env-default:"0s"`           // Интервал копирования по расписанию (0 - отключено)
+	Retention int           `yaml:"retention" env-default:"7"`           // Число хранимых снимков
+}
+
+// Ledger содержит параметры движка переводов в памяти.
+// Балансы хранятся в памяти, переводы записываются в журнал упреждающей записи
+// и периодически переносятся в SQLite.
+type Ledger struct {
+	Enabled            bool          `yaml:"enabled" env-default:"false"`          // Использовать движок вместо переводов напрямую в SQLite
+	WALDir             string        `yaml:"wal_dir" env-default:"./storage/wal"`  // Каталог журнала упреждающей записи
+	Stripes            int           `yaml:"stripes" env-default:"256"`            // Число блокировок, между которыми распределяются кошельки
+	MaxBatch           int           `yaml:"max_batch" env-default:"512"`          // Максимум переводов в одной записи журнала на диск
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval" env-default:"1s"` // Интервал переноса журнала в SQLite
+}
+
+// HotWallet описывает кошелек, получающий большую часть переводов.
+// Зачисления распределяются по подбалансам по очереди, чтобы не изменять одну строку базы;
+// баланс кошелька - сумма основного баланса и подбалансов.
+type HotWallet struct {
+	Address string `yaml:"address"` // Адрес кошелька
+	Shards  int    `yaml:"shards"`  // Число подбалансов
+}
+
+// Форматы ответов с ошибками
+const (
+	ErrorFormatEnvelope = "envelope" // Стандартная структура Response
+	ErrorFormatProblem  = "problem"  // application/problem+json (RFC 7807)
+)
+
+// Errors содержит настройки формата ответов с ошибками.
+type Errors struct {
+	Format      string `yaml:"format" env-default:"envelope"`           // Формат по умолчанию: envelope/problem
+	TypeBaseURI string `yaml:"type_base_uri" env-default:"about:blank"` // Префикс URI типа проблемы
+}
+
+// AccessLog содержит настройки журнала HTTP-запросов.
+type AccessLog struct {
+	Levels        map[string]string  `yaml:"levels"`                                                      // Уровень записи по классу статуса (2xx, 4xx...)
+	ExcludePaths  []string           `yaml:"exclude_paths" env-default:"/healthz,/readyz"`                // Пути, которые не журналируются
+	Sampling      map[string]float64 `yaml:"sampling"`                                                    // Доля журналируемых успешных запросов по маршруту
+	LogHeaders    bool               `yaml:"log_headers" env-default:"false"`                             // Журналировать заголовки запроса
+	RedactHeaders []string           `yaml:"redact_headers" env-default:"Authorization,Cookie,X-API-Key"` // Скрываемые заголовки
+	RedactQuery   []string           `yaml:"redact_query"`                                                // Скрываемые параметры запроса
+}
+
+// RateLimit содержит настройки ограничения частоты запросов.
+// Лимиты задаются отдельно для каждого маршрута (ключ - шаблон маршрута chi).
+type RateLimit struct {
+	Enabled bool                  `yaml:"enabled" env-default:"false"` // Включено ли ограничение
+	Routes  map[string]RouteLimit `yaml:"routes"`                      // Лимиты по маршрутам
+}
+
+// RouteLimit описывает лимиты одного маршрута.
+// Нулевой Limit означает отсутствие ограничения по данному ключу.
+type RouteLimit struct {
+	Client Limit `yaml:"client"` // Лимит по клиенту (пользователь или IP)
+	Wallet Limit `yaml:"wallet"` // Лимит по кошельку отправителя
+}
+
+// Limit задает параметры token bucket.
+type Limit struct {
+	Rate  float64 `yaml:"rate"`  // Скорость пополнения (токенов в секунду)
+	Burst int     `yaml:"burst"` // Емкость корзины
 }
 
 // MustLoad загружает конфигурацию из файла и переменных окружения.
 // Завершает выполнение приложения с фатальной ошибкой в случае:
 // - Не указан путь к конфигурации (CONFIG_PATH)
 // - Ошибки чтения/парсинга конфигурационного файла
+// - Некорректных значений параметров
 //
 // Возвращает:
 //   - *Config: указатель на загруженную конфигурацию
@@ -38,16 +175,31 @@ func MustLoad() *Config {
 		log.Fatal("Переменная окружения CONFIG_PATH не установлена")
 	}
 
+	cfg, err := Load(configPath)
+	if err != nil {
+		log.Fatalf("Не удалось загрузить конфиг: %s", err)
+	}
+
+	return cfg
+}
+
+// Load читает конфигурацию из файла и переменных окружения и проверяет ее.
+func Load(configPath string) (*Config, error) {
+	const op = "config.Load"
+
 	if _, err := os.Stat(configPath); err != nil {
-		log.Fatalf("Не удалось открыть конфиг файл: %s", err)
+		return nil, fmt.Errorf("%s: Не удалось открыть конфиг файл: %w", op, err)
 	}
 
 	var cfg Config
 
-	err := cleanenv.ReadConfig(configPath, &cfg)
-	if err != nil {
-		log.Fatalf("Не удалось прочитать конфиг файл: %s", err)
+	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: Не удалось прочитать конфиг файл: %w", op, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &cfg
+	return &cfg, nil
 }
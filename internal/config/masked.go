@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+)
+
+// secretMask заменяет непустые значения секретов при выводе конфигурации.
+const secretMask = "********"
+
+// Masked возвращает копию конфигурации, в которой значения секретов заменены маской.
+// Секретными считаются строковые поля с тегом secret:"true".
+func (c Config) Masked() Config {
+	masked := c
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+	return masked
+}
+
+// maskSecrets рекурсивно заменяет значения секретных полей структуры.
+// Map копируются, чтобы не изменять исходную конфигурацию.
+func maskSecrets(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String {
+				if field.String() != "" {
+					field.SetString(secretMask)
+				}
+				continue
+			}
+			maskSecrets(field)
+		}
+	case reflect.Map:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			maskSecrets(elem)
+			cp.SetMapIndex(iter.Key(), elem)
+		}
+		v.Set(cp)
+	case reflect.Slice:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(cp, v)
+		for i := 0; i < cp.Len(); i++ {
+			maskSecrets(cp.Index(i))
+		}
+		v.Set(cp)
+	}
+}
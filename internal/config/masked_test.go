@@ -0,0 +1,95 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	type credentials struct {
+		User     string `yaml:"user"`
+		Password string `yaml:"password" secret:"true"`
+	}
+	type settings struct {
+		Token    string                 `yaml:"token" secret:"true"`
+		Empty    string                 `yaml:"empty" secret:"true"`
+		Main     credentials            `yaml:"main"`
+		Profiles map[string]credentials `yaml:"profiles"`
+		List     []credentials          `yaml:"list"`
+	}
+
+	original := settings{
+		Token:    "t0ken",
+		Main:     credentials{User: "admin", Password: "p1"},
+		Profiles: map[string]credentials{"ops": {User: "ops", Password: "p2"}},
+		List:     []credentials{{User: "ci", Password: "p3"}},
+	}
+
+	masked := original
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+
+	require.Equal(t, settings{
+		Token:    secretMask,
+		Main:     credentials{User: "admin", Password: secretMask},
+		Profiles: map[string]credentials{"ops": {User: "ops", Password: secretMask}},
+		List:     []credentials{{User: "ci", Password: secretMask}},
+	}, masked)
+
+	// Исходная конфигурация не изменяется
+	require.Equal(t, "p2", original.Profiles["ops"].Password)
+	require.Equal(t, "p3", original.List[0].Password)
+}
+
+func TestConfigMasked(t *testing.T) {
+	const adminKey = "0123456789abcdef"
+
+	cases := []struct {
+		name string
+		file string // Содержимое файла конфигурации
+		env  string // Значение AUTH_ADMIN_KEY
+	}{
+		{
+			name: "Ключ администратора в файле",
+			file: "storage_path: storage.db\nauth:\n  admin_key: " + adminKey + "\n",
+		},
+		{
+			name: "Ключ администратора в окружении",
+			file: "storage_path: storage.db\n",
+			env:  adminKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.file), 0o600))
+			t.Setenv("AUTH_ADMIN_KEY", tc.env)
+			if tc.env == "" {
+				require.NoError(t, os.Unsetenv("AUTH_ADMIN_KEY"))
+			}
+
+			cfg, err := Load(path)
+			require.NoError(t, err)
+			require.Equal(t, adminKey, cfg.Auth.AdminKey)
+
+			masked := cfg.Masked()
+			require.Equal(t, secretMask, masked.Auth.AdminKey)
+			require.Equal(t, cfg.StoragePath, masked.StoragePath)
+			require.Equal(t, cfg.Auth.Header, masked.Auth.Header)
+
+			// Вывод команды check не содержит ключа, исходная конфигурация не изменяется
+			out, err := yaml.Marshal(masked)
+			require.NoError(t, err)
+			require.NotContains(t, string(out), adminKey)
+			require.Contains(t, string(out), "admin_key: '"+secretMask+"'")
+			require.Equal(t, adminKey, cfg.Auth.AdminKey)
+		})
+	}
+
+	// Пустой ключ не заменяется маской: по выводу видно, что ключ не задан
+	require.Empty(t, Config{}.Masked().Auth.AdminKey)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+)
+
+// envs - допустимые окружения приложения.
+var envs = []string{"local", "dev", "prod"}
+
+// Допустимые значения параметров SQLite
+var (
+	sqliteDrivers      = []string{"mattn", "modernc"}
+	sqliteJournalModes = []string{"wal", "delete", "truncate", "persist", "memory", "off"}
+	sqliteSynchronous  = []string{"off", "normal", "full", "extra"}
+	sqliteTxLocks      = []string{"deferred", "immediate", "exclusive"}
+)
+
+// minAdminKeyLength - минимальная длина ключа администратора.
+const minAdminKeyLength = 16
+
+// Validate проверяет значения параметров конфигурации.
+// Возвращает все найденные ошибки, объединенные через errors.Join.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !slices.Contains(envs, c.Env) {
+		add("env: must be one of %s", strings.Join(envs, ", "))
+	}
+
+	if strings.TrimSpace(c.StoragePath) == "" {
+		add("storage_path: must not be empty")
+	}
+
+	if d := c.SQLite.Driver; d != "" && !slices.Contains(sqliteDrivers, d) {
+		add("sqlite.driver: must be one of %s", strings.Join(sqliteDrivers, ", "))
+	}
+	for _, p := range []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"sqlite.journal_mode", c.SQLite.JournalMode, sqliteJournalModes},
+		{"sqlite.synchronous", c.SQLite.Synchronous, sqliteSynchronous},
+		{"sqlite.tx_lock", c.SQLite.TxLock, sqliteTxLocks},
+	} {
+		if !slices.Contains(p.allowed, strings.ToLower(p.value)) {
+			add("%s: must be one of %s", p.name, strings.Join(p.allowed, ", "))
+		}
+	}
+	if c.SQLite.BusyTimeout < 0 {
+		add("sqlite.busy_timeout: must not be negative")
+	}
+	if c.SQLite.MaxOpenConns < 0 || c.SQLite.MaxIdleConns < 0 {
+		add("sqlite.max_open_conns, sqlite.max_idle_conns: must not be negative")
+	}
+	if c.SQLite.ConnMaxLifetime < 0 {
+		add("sqlite.conn_max_lifetime: must not be negative")
+	}
+	if c.SQLite.BusyRetries < 0 || c.SQLite.RetryBackoff < 0 {
+		add("sqlite.busy_retries, sqlite.retry_backoff: must not be negative")
+	}
+	if c.SQLite.GroupCommitSize < 1 {
+		add("sqlite.group_commit_size: must be positive")
+	}
+	if c.SQLite.GroupCommitDelay < 0 {
+		add("sqlite.group_commit_delay: must not be negative")
+	}
+
+	addresses := map[string]string{"http_server.address": c.HTTPServer.Address}
+	if c.AdminServer.Address != "" {
+		addresses["admin_server.address"] = c.AdminServer.Address
+	}
+	if c.GRPCServer.Address != "" {
+		addresses["grpc_server.address"] = c.GRPCServer.Address
+	}
+	seen := make(map[string]string, len(addresses))
+	for _, name := range []string{"http_server.address", "admin_server.address", "grpc_server.address"} {
+		addr, ok := addresses[name]
+		if !ok {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			add("%s: %w", name, err)
+			continue
+		}
+		if other, dup := seen[addr]; dup {
+			add("%s: address %s is already used by %s", name, addr, other)
+			continue
+		}
+		seen[addr] = name
+	}
+
+	if c.HTTPServer.Timeout <= 0 {
+		add("http_server.timeout: must be positive")
+	}
+	if c.HTTPServer.IdleTimeout <= 0 {
+		add("http_server.idle_timeout: must be positive")
+	}
+	if c.HTTPServer.ShutdownTimeout <= 0 {
+		add("http_server.shutdown_timeout: must be positive")
+	}
+	if c.HTTPServer.ShutdownDelay < 0 {
+		add("http_server.shutdown_delay: must not be negative")
+	}
+
+	if c.Auth.Enabled {
+		if strings.TrimSpace(c.Auth.Header) == "" {
+			add("auth.header: must not be empty")
+		}
+		if c.AdminServer.Address == "" {
+			add("auth.enabled: users are managed on the admin server, admin_server.address must not be empty")
+		} else if !isLoopback(c.AdminServer.Address) {
+			add("auth.enabled: admin server issues user keys, admin_server.address must be a loopback address")
+		}
+		if len(c.Auth.AdminKey) < minAdminKeyLength {
+			add("auth.admin_key: must be at least %d characters when auth is enabled", minAdminKeyLength)
+		}
+	} else if c.Auth.AdminKey != "" && len(c.Auth.AdminKey) < minAdminKeyLength {
+		add("auth.admin_key: must be at least %d characters", minAdminKeyLength)
+	}
+
+	for route, limit := range c.RateLimit.Routes {
+		for dimension, l := range map[string]Limit{"client": limit.Client, "wallet": limit.Wallet} {
+			if l.Rate < 0 || l.Burst < 0 {
+				add("rate_limit.routes[%s].%s: rate and burst must not be negative", route, dimension)
+			}
+			if l.Rate > 0 && l.Burst < 1 {
+				add("rate_limit.routes[%s].%s: burst must be at least 1", route, dimension)
+			}
+		}
+	}
+
+	for class, value := range c.AccessLog.Levels {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			add("access_log.levels[%s]: %w", class, err)
+		}
+	}
+	for route, rate := range c.AccessLog.Sampling {
+		if rate < 0 || rate > 1 {
+			add("access_log.sampling[%s]: must be between 0 and 1", route)
+		}
+	}
+
+	if c.Payouts.MaxRows <= 0 {
+		add("payouts.max_rows: must be positive")
+	}
+	if c.Payouts.MaxFileSize <= 0 {
+		add("payouts.max_file_size: must be positive")
+	}
+	if c.Payouts.PollInterval <= 0 {
+		add("payouts.poll_interval: must be positive")
+	}
+
+	if strings.TrimSpace(c.Backup.Dir) == "" {
+		add("backup.dir: must not be empty")
+	}
+	if c.Backup.Interval < 0 {
+		add("backup.interval: must not be negative")
+	}
+	if c.Backup.Retention < 1 {
+		add("backup.retention: must be at least 1")
+	}
+
+	hot := make(map[string]bool, len(c.HotWallets))
+	for i, w := range c.HotWallets {
+		switch {
+		case strings.TrimSpace(w.Address) == "":
+			add("hot_wallets[%d].address: must not be empty", i)
+		case hot[w.Address]:
+			add("hot_wallets[%d].address: wallet %s is listed twice", i, w.Address)
+		}
+		hot[w.Address] = true
+		if w.Shards < 2 {
+			add("hot_wallets[%d].shards: must be at least 2", i)
+		}
+	}
+
+	if c.Ledger.Enabled {
+		if strings.TrimSpace(c.Ledger.WALDir) == "" {
+			add("ledger.wal_dir: must not be empty")
+		}
+		if c.Ledger.Stripes <= 0 {
+			add("ledger.stripes: must be positive")
+		}
+		if c.Ledger.MaxBatch <= 0 {
+			add("ledger.max_batch: must be positive")
+		}
+		if c.Ledger.CheckpointInterval <= 0 {
+			add("ledger.checkpoint_interval: must be positive")
+		}
+	}
+
+	if c.Errors.Format != ErrorFormatEnvelope && c.Errors.Format != ErrorFormatProblem {
+		add("errors.format: must be %q or %q", ErrorFormatEnvelope, ErrorFormatProblem)
+	}
+
+	return errors.Join(errs...)
+}
+
+// isLoopback проверяет, что адрес host:port принимает соединения только с локальной машины.
+// Пустой хост означает все интерфейсы.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,194 @@
+package config_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"testing"
+	"time"
+)
+
+// validConfig возвращает корректную конфигурацию для изменения в тестах.
+func validConfig() config.Config {
+	return config.Config{
+		Env:         "local",
+		StoragePath: "./storage/storage.db",
+		SQLite: config.SQLite{
+			JournalMode:  "wal",
+			Synchronous:  "normal",
+			BusyTimeout:  5 * time.Second,
+			TxLock:       "immediate",
+			MaxOpenConns: 8,
+			MaxIdleConns: 8,
+			BusyRetries:  3,
+			RetryBackoff: 10 * time.Millisecond,
+
+			GroupCommitSize:  1,
+			GroupCommitDelay: 2 * time.Millisecond,
+		},
+		HTTPServer: config.HTTPServer{
+			Address:         "0.0.0.0:8080",
+			Timeout:         4 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		AdminServer: config.AdminServer{Address: "127.0.0.1:9090"},
+		Errors:      config.Errors{Format: config.ErrorFormatEnvelope},
+		Payouts:     config.Payouts{MaxRows: 10000, MaxFileSize: 4 << 20, PollInterval: 5 * time.Second},
+		Backup:      config.Backup{Dir: "./storage/backups", Retention: 7},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name        string
+		modify      func(cfg *config.Config)
+		expectedErr []string
+	}{
+		{
+			name:   "Корректная конфигурация",
+			modify: func(*config.Config) {},
+		},
+		{
+			name: "Неизвестное окружение",
+			modify: func(cfg *config.Config) {
+				cfg.Env = "development"
+			},
+			expectedErr: []string{"env: must be one of local, dev, prod"},
+		},
+		{
+			name: "Некорректный адрес",
+			modify: func(cfg *config.Config) {
+				cfg.HTTPServer.Address = "8080"
+			},
+			expectedErr: []string{"http_server.address"},
+		},
+		{
+			name: "Совпадающие адреса серверов",
+			modify: func(cfg *config.Config) {
+				cfg.GRPCServer.Address = cfg.HTTPServer.Address
+			},
+			expectedErr: []string{"grpc_server.address: address 0.0.0.0:8080 is already used by http_server.address"},
+		},
+		{
+			name: "Некорректные параметры выплат",
+			modify: func(cfg *config.Config) {
+				cfg.Payouts.MaxRows = 0
+				cfg.Payouts.PollInterval = -time.Second
+			},
+			expectedErr: []string{"payouts.max_rows", "payouts.poll_interval"},
+		},
+		{
+			name: "Некорректные параметры SQLite",
+			modify: func(cfg *config.Config) {
+				cfg.SQLite.Driver = "cgo"
+				cfg.SQLite.JournalMode = "wall"
+				cfg.SQLite.TxLock = ""
+				cfg.SQLite.BusyRetries = -1
+				cfg.SQLite.GroupCommitSize = 0
+				cfg.SQLite.GroupCommitDelay = -time.Millisecond
+			},
+			expectedErr: []string{
+				"sqlite.driver", "sqlite.journal_mode", "sqlite.tx_lock", "sqlite.busy_retries",
+				"sqlite.group_commit_size", "sqlite.group_commit_delay",
+			},
+		},
+		{
+			name: "Некорректные параметры резервного копирования",
+			modify: func(cfg *config.Config) {
+				cfg.Backup.Interval = -time.Hour
+				cfg.Backup.Retention = 0
+			},
+			expectedErr: []string{"backup.interval", "backup.retention"},
+		},
+		{
+			name: "Некорректные параметры движка переводов",
+			modify: func(cfg *config.Config) {
+				cfg.Ledger = config.Ledger{Enabled: true, Stripes: 0, MaxBatch: 1, CheckpointInterval: 0}
+			},
+			expectedErr: []string{"ledger.wal_dir", "ledger.stripes", "ledger.checkpoint_interval"},
+		},
+		{
+			name: "Аутентификация без служебного сервера",
+			modify: func(cfg *config.Config) {
+				cfg.Auth = config.Auth{Enabled: true}
+				cfg.AdminServer.Address = ""
+			},
+			expectedErr: []string{"auth.header", "auth.enabled", "auth.admin_key"},
+		},
+		{
+			name: "Аутентификация с открытым служебным сервером",
+			modify: func(cfg *config.Config) {
+				cfg.Auth = config.Auth{Enabled: true, Header: "X-API-Key", AdminKey: "0123456789abcdef"}
+				cfg.AdminServer.Address = "0.0.0.0:9090"
+			},
+			expectedErr: []string{"admin_server.address must be a loopback address"},
+		},
+		{
+			name: "Аутентификация со служебным сервером на localhost",
+			modify: func(cfg *config.Config) {
+				cfg.Auth = config.Auth{Enabled: true, Header: "X-API-Key", AdminKey: "0123456789abcdef"}
+				cfg.AdminServer.Address = "localhost:9090"
+			},
+		},
+		{
+			name: "Короткий ключ администратора",
+			modify: func(cfg *config.Config) {
+				cfg.Auth = config.Auth{Header: "X-API-Key", AdminKey: "secret"}
+			},
+			expectedErr: []string{"auth.admin_key"},
+		},
+		{
+			name: "Некорректные горячие кошельки",
+			modify: func(cfg *config.Config) {
+				cfg.HotWallets = []config.HotWallet{
+					{Address: "fee", Shards: 8},
+					{Address: "fee", Shards: 4},
+					{Address: "", Shards: 1},
+				}
+			},
+			expectedErr: []string{
+				"hot_wallets[1].address: wallet fee is listed twice",
+				"hot_wallets[2].address",
+				"hot_wallets[2].shards",
+			},
+		},
+		{
+			name: "Несколько ошибок",
+			modify: func(cfg *config.Config) {
+				cfg.Errors.Format = "xml"
+				cfg.HTTPServer.ShutdownTimeout = 0
+				cfg.HTTPServer.ShutdownDelay = -time.Second
+				cfg.AccessLog.Sampling = map[string]float64{"/api/send": 2}
+				cfg.AccessLog.Levels = map[string]string{"2xx": "loud"}
+				cfg.RateLimit.Routes = map[string]config.RouteLimit{
+					"/api/send": {Client: config.Limit{Rate: 1}},
+				}
+			},
+			expectedErr: []string{
+				"errors.format",
+				"http_server.shutdown_timeout",
+				"http_server.shutdown_delay",
+				"access_log.sampling[/api/send]",
+				"access_log.levels[2xx]",
+				"rate_limit.routes[/api/send].client: burst must be at least 1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if len(tc.expectedErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tc.expectedErr {
+				require.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
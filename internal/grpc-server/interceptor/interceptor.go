@@ -0,0 +1,186 @@
+// Package interceptor содержит перехватчики gRPC-сервера:
+// восстановление после паник, журналирование, метрики вызовов и аутентификацию.
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/lib/metrics"
+	"infotecsTest/internal/storage"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Recoverer преобразует панику обработчика в статус codes.Internal.
+type Recoverer struct {
+	log *slog.Logger
+}
+
+// NewRecoverer создает перехватчик восстановления после паник.
+func NewRecoverer(log *slog.Logger) *Recoverer {
+	return &Recoverer{log: log.With(slog.String("component", "grpc/recoverer"))}
+}
+
+// Unary перехватывает одиночные вызовы.
+func (rc *Recoverer) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer rc.recover(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// Stream перехватывает потоковые вызовы.
+func (rc *Recoverer) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer rc.recover(info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func (rc *Recoverer) recover(method string, err *error) {
+	if p := recover(); p != nil {
+		rc.log.Error("panic in grpc handler", slog.String("method", method), slog.Any("panic", p))
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// Observer журналирует вызовы и собирает по ним метрики.
+type Observer struct {
+	log      *slog.Logger
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+// NewObserver создает перехватчик журналирования и метрик.
+func NewObserver(log *slog.Logger, reg *metrics.Registry) *Observer {
+	return &Observer{
+		log: log.With(slog.String("component", "grpc/observer")),
+		requests: reg.NewCounterVec(
+			"grpc_server_handled_total",
+			"Number of gRPC calls by method and status code.",
+			"method", "code",
+		),
+		duration: reg.NewHistogramVec(
+			"grpc_server_handling_seconds",
+			"Duration of gRPC calls by method.",
+			nil, "method",
+		),
+	}
+}
+
+// Unary перехватывает одиночные вызовы.
+func (o *Observer) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	t1 := time.Now()
+	resp, err := handler(ctx, req)
+	o.observe(ctx, info.FullMethod, t1, err)
+	return resp, err
+}
+
+// Stream перехватывает потоковые вызовы.
+func (o *Observer) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	t1 := time.Now()
+	err := handler(srv, ss)
+	o.observe(ss.Context(), info.FullMethod, t1, err)
+	return err
+}
+
+// observe записывает результат вызова в журнал и метрики.
+func (o *Observer) observe(ctx context.Context, method string, t1 time.Time, err error) {
+	d := time.Since(t1)
+	code := status.Code(err)
+
+	o.requests.With(method, code.String()).Inc()
+	o.duration.With(method).Observe(d.Seconds())
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	o.log.LogAttrs(ctx, level, "call completed",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.String("duration", d.String()),
+	)
+}
+
+// Authenticator аутентифицирует вызовы по ключу API из метаданных.
+// Ключ передается под именем заголовка из конфигурации в нижнем регистре (x-api-key).
+type Authenticator struct {
+	log     *slog.Logger
+	enabled bool
+	key     string
+	users   auth.UserFinder
+}
+
+// NewAuthenticator создает перехватчик аутентификации.
+// Если аутентификация выключена, вызовы передаются без изменений.
+func NewAuthenticator(log *slog.Logger, cfg config.Auth, users auth.UserFinder) *Authenticator {
+	return &Authenticator{
+		log:     log.With(slog.String("component", "grpc/authenticator")),
+		enabled: cfg.Enabled,
+		key:     strings.ToLower(cfg.Header),
+		users:   users,
+	}
+}
+
+// Unary перехватывает одиночные вызовы.
+func (a *Authenticator) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Stream перехватывает потоковые вызовы.
+func (a *Authenticator) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate возвращает контекст с пользователем, которому принадлежит ключ из метаданных вызова.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !a.enabled {
+		return ctx, nil
+	}
+
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(a.key); len(values) > 0 {
+			key = values[0]
+		}
+	}
+
+	user, err := auth.Authenticate(ctx, a.users, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			a.log.Warn("call not authenticated", slog.String("method", method))
+			return nil, status.Error(codes.Unauthenticated, "missing or unknown API key")
+		}
+		a.log.Error("unable to authenticate call", slog.String("method", method), sl.Err(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return auth.WithUser(ctx, user), nil
+}
+
+// authStream подменяет контекст потока контекстом с пользователем.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
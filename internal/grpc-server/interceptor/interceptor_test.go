@@ -0,0 +1,177 @@
+package interceptor_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	paymentv1 "infotecsTest/api/payment/v1"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/grpc-server/interceptor"
+	"infotecsTest/internal/grpc-server/payment"
+	paymentMocks "infotecsTest/internal/grpc-server/payment/mocks"
+	txMocks "infotecsTest/internal/http-server/handlers/transaction/mocks"
+	walletMocks "infotecsTest/internal/http-server/handlers/wallet/mocks"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/apikey"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+// usersFunc ищет пользователя по хешу ключа функцией.
+type usersFunc func(string) (models.User, error)
+
+func (f usersFunc) UserByKey(_ context.Context, keyHash string) (models.User, error) {
+	return f(keyHash)
+}
+
+// userID возвращает сопоставитель контекста с аутентифицированным пользователем id;
+// пустой id соответствует контексту без пользователя.
+func userID(id string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		user, ok := auth.UserFromContext(ctx)
+		if id == "" {
+			return !ok
+		}
+		return ok && user.ID == id
+	})
+}
+
+// newClient запускает сервис с перехватчиком аутентификации на соединении в памяти.
+func newClient(t *testing.T, cfg config.Auth, balance *walletMocks.BalanceReceiver, streamer *paymentMocks.TransactionsStreamer, authorizer *walletMocks.Authorizer) paymentv1.PaymentServiceClient {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Ключ key-alice принадлежит alice, key-broken вызывает ошибку хранилища
+	users := usersFunc(func(keyHash string) (models.User, error) {
+		switch keyHash {
+		case apikey.Hash("key-alice"):
+			return models.User{ID: "u1", Name: "Alice"}, nil
+		case apikey.Hash("key-broken"):
+			return models.User{}, errors.New("db error")
+		}
+		return models.User{}, storage.ErrUserNotFound
+	})
+
+	resolver := walletMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+
+	authenticator := interceptor.NewAuthenticator(log, cfg, users)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.Unary),
+		grpc.ChainStreamInterceptor(authenticator.Stream),
+	)
+	paymentv1.RegisterPaymentServiceServer(srv,
+		payment.New(log, balance, txMocks.NewTransactionMaker(t), streamer, resolver, authorizer))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return paymentv1.NewPaymentServiceClient(conn)
+}
+
+func TestAuthenticator(t *testing.T) {
+	enabled := config.Auth{Enabled: true, Header: "X-API-Key"}
+
+	cases := []struct {
+		name         string
+		cfg          config.Auth
+		key          string
+		expectedCode codes.Code
+		expectedUser string // Пользователь в контексте обработчика
+	}{
+		{
+			name:         "Аутентификация выключена",
+			cfg:          config.Auth{Header: "X-API-Key"},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Ключ пользователя",
+			cfg:          enabled,
+			key:          "key-alice",
+			expectedCode: codes.OK,
+			expectedUser: "u1",
+		},
+		{
+			name:         "Вызов без ключа",
+			cfg:          enabled,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Неизвестный ключ",
+			cfg:          enabled,
+			key:          "key-bob",
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Ошибка хранилища",
+			cfg:          enabled,
+			key:          "key-broken",
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			balance := walletMocks.NewBalanceReceiver(t)
+			streamer := paymentMocks.NewTransactionsStreamer(t)
+			authorizer := walletMocks.NewAuthorizer(t)
+			if tc.expectedCode == codes.OK {
+				// Обработчики получают пользователя из контекста одиночного и потокового вызова
+				authorizer.On("AuthorizeWallet", userID(tc.expectedUser), "a").Return(nil).Once()
+				balance.On("GetWalletBalance", "a").Return(models.Wallet{Address: "a", Balance: 10}, nil).Once()
+				streamer.On("RecentTransactions", userID(tc.expectedUser), tc.expectedUser, 2, mock.Anything).
+					Return(func(_ context.Context, _ string, _ int, fn func(models.Transaction) error) error {
+						return fn(models.Transaction{From: "a", To: "b", Amount: 1})
+					}).Once()
+			}
+
+			client := newClient(t, tc.cfg, balance, streamer, authorizer)
+
+			// Несовпадение ожиданий мока в обработчике не должно приводить к зависанию вызова
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tc.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tc.key)
+			}
+
+			resp, err := client.GetBalance(ctx, &paymentv1.GetBalanceRequest{Address: "a"})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			if err == nil {
+				require.Equal(t, 10.0, resp.GetWallet().GetBalance())
+			}
+
+			stream, err := client.ListTransactions(ctx, &paymentv1.ListTransactionsRequest{Count: 2})
+			require.NoError(t, err)
+			tx, err := stream.Recv()
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			if err == nil {
+				require.Equal(t, "b", tx.GetTo())
+				_, err = stream.Recv()
+				require.ErrorIs(t, err, io.EOF)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package payment реализует gRPC-сервис платежной системы.
+// Использует те же интерфейсы хранилища, что и HTTP-обработчики.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	paymentv1 "infotecsTest/api/payment/v1"
+	"infotecsTest/internal/http-server/handlers/transaction"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/i18n"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"log/slog"
+)
+
+// ErrorDomain - домен ошибок в google.rpc.ErrorInfo.
+const ErrorDomain = "payment-system"
+
+// TransactionsStreamer передает последние транзакции по одной:
+// все или, при непустом userID, с участием кошельков пользователя.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=TransactionsStreamer --dir=. --output=./mocks --filename=mock_TransactionsStreamer
+type TransactionsStreamer interface {
+	RecentTransactions(ctx context.Context, userID string, N int, fn func(models.Transaction) error) error
+}
+
+// Server реализует paymentv1.PaymentServiceServer.
+type Server struct {
+	paymentv1.UnimplementedPaymentServiceServer
+
+	log        *slog.Logger
+	balance    wallet.BalanceReceiver
+	maker      transaction.TransactionMaker
+	streamer   TransactionsStreamer
+	resolver   wallet.AddressResolver
+	authorizer wallet.Authorizer
+}
+
+// New создает gRPC-сервис поверх хранилища.
+func New(
+	log *slog.Logger,
+	balance wallet.BalanceReceiver,
+	maker transaction.TransactionMaker,
+	streamer TransactionsStreamer,
+	resolver wallet.AddressResolver,
+	authorizer wallet.Authorizer,
+) *Server {
+	return &Server{
+		log:        log,
+		balance:    balance,
+		maker:      maker,
+		streamer:   streamer,
+		resolver:   resolver,
+		authorizer: authorizer,
+	}
+}
+
+// GetBalance возвращает баланс кошелька аутентифицированного пользователя.
+func (s *Server) GetBalance(ctx context.Context, req *paymentv1.GetBalanceRequest) (*paymentv1.GetBalanceResponse, error) {
+	const op = "grpc.payment.GetBalance"
+
+	log := s.log.With("op", op)
+
+	address, err := s.resolver.ResolveAddress(req.GetAddress())
+	if err != nil {
+		return nil, toStatus(ctx, log, "unable to resolve address", err)
+	}
+	if err = s.authorizer.AuthorizeWallet(ctx, address); err != nil {
+		return nil, toStatus(ctx, log, "wallet access denied", err)
+	}
+
+	w, err := s.balance.GetWalletBalance(address)
+	if err != nil {
+		return nil, toStatus(ctx, log, "unable to get balance", err)
+	}
+
+	return &paymentv1.GetBalanceResponse{
+		Wallet: &paymentv1.Wallet{Address: w.Address, Balance: w.Balance},
+	}, nil
+}
+
+// SendTransfer выполняет перевод с кошелька аутентифицированного пользователя.
+func (s *Server) SendTransfer(ctx context.Context, req *paymentv1.SendTransferRequest) (*paymentv1.SendTransferResponse, error) {
+	const op = "grpc.payment.SendTransfer"
+
+	log := s.log.With("op", op)
+
+	from, err := s.resolver.ResolveAddress(req.GetFrom())
+	if err != nil {
+		return nil, toStatus(ctx, log, "unable to resolve address", err)
+	}
+	to, err := s.resolver.ResolveAddress(req.GetTo())
+	if err != nil {
+		return nil, toStatus(ctx, log, "unable to resolve address", err)
+	}
+	if err = s.authorizer.AuthorizeWallet(ctx, from); err != nil {
+		return nil, toStatus(ctx, log, "wallet access denied", err)
+	}
+
+	if err = s.maker.AddTransaction(from, to, req.GetAmount()); err != nil {
+		return nil, toStatus(ctx, log, "failed to make transaction", err)
+	}
+
+	return &paymentv1.SendTransferResponse{}, nil
+}
+
+// ListTransactions передает последние транзакции потоком по мере чтения из хранилища.
+// Аутентифицированному пользователю передаются только транзакции его кошельков.
+func (s *Server) ListTransactions(req *paymentv1.ListTransactionsRequest, stream grpc.ServerStreamingServer[paymentv1.Transaction]) error {
+	const op = "grpc.payment.ListTransactions"
+
+	log := s.log.With("op", op)
+	ctx := stream.Context()
+
+	if req.GetCount() <= 0 {
+		log.Warn("invalid count", slog.Int("count", int(req.GetCount())))
+		return withInfo(status.New(codes.InvalidArgument, i18n.Message(language(ctx), response.CodeInvalidCount)), response.CodeInvalidCount, nil)
+	}
+
+	var userID string
+	if user, ok := auth.UserFromContext(ctx); ok {
+		userID = user.ID
+	}
+
+	var sendErr error
+	err := s.streamer.RecentTransactions(ctx, userID, int(req.GetCount()), func(tx models.Transaction) error {
+		sendErr = stream.Send(&paymentv1.Transaction{
+			From:   tx.From,
+			To:     tx.To,
+			Amount: tx.Amount,
+			Time:   tx.Time,
+		})
+		return sendErr
+	})
+	if sendErr != nil {
+		log.Error("failed to send transaction", sl.Err(sendErr))
+		return sendErr
+	}
+	if err != nil {
+		return toStatus(ctx, log, "unable to get transactions", err)
+	}
+
+	return nil
+}
+
+// toStatus преобразует ошибку хранилища в статус gRPC.
+// Клиент получает сообщение каталога для кода ошибки, код передается в google.rpc.ErrorInfo.
+// Текст исходной ошибки может содержать детали хранилища и пишется только в лог.
+func toStatus(ctx context.Context, log *slog.Logger, msg string, err error) error {
+	log.Error(msg, sl.Err(err))
+
+	lang := language(ctx)
+	var code codes.Code
+	switch {
+	case errors.Is(err, storage.ErrWalletNotFound), errors.Is(err, storage.ErrHandleNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		code = codes.FailedPrecondition
+	case errors.Is(err, storage.ErrBusy):
+		code = codes.Unavailable
+	case errors.Is(err, storage.ErrIncorrectAmount),
+		errors.Is(err, storage.ErrAddressesEqual),
+		errors.Is(err, storage.ErrInvalidRequest),
+		errors.Is(err, storage.ErrMalformedAddress),
+		errors.Is(err, storage.ErrInvalidHandle):
+		code = codes.InvalidArgument
+	default:
+		return status.Error(codes.Internal, i18n.Message(lang, response.CodeInternal))
+	}
+
+	errCode := storage.Code(err)
+	return withInfo(status.New(code, i18n.Message(lang, errCode)), errCode, storage.Details(err))
+}
+
+// language выбирает язык сообщений об ошибках по метаданным accept-language вызова,
+// как HTTP-обработчики - по заголовку Accept-Language.
+func language(ctx context.Context) string {
+	var acceptLanguage string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("accept-language"); len(values) > 0 {
+			acceptLanguage = values[0]
+		}
+	}
+	return i18n.Negotiate(acceptLanguage)
+}
+
+// withInfo добавляет к статусу google.rpc.ErrorInfo с машиночитаемым кодом ошибки.
+func withInfo(st *status.Status, reason string, details map[string]any) error {
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}
+	if len(details) > 0 {
+		info.Metadata = make(map[string]string, len(details))
+		for k, v := range details {
+			info.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+
+	if withDetails, derr := st.WithDetails(info); derr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
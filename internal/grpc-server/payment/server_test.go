@@ -0,0 +1,401 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	paymentv1 "infotecsTest/api/payment/v1"
+	"infotecsTest/internal/grpc-server/payment"
+	paymentMocks "infotecsTest/internal/grpc-server/payment/mocks"
+	txMocks "infotecsTest/internal/http-server/handlers/transaction/mocks"
+	walletMocks "infotecsTest/internal/http-server/handlers/wallet/mocks"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+)
+
+// mocks объединяет зависимости сервиса.
+type mocks struct {
+	balance  *walletMocks.BalanceReceiver
+	maker    *txMocks.TransactionMaker
+	streamer *paymentMocks.TransactionsStreamer
+	user     *models.User // Аутентифицированный пользователь всех вызовов, nil - без аутентификации
+}
+
+// newClient запускает сервис на соединении в памяти и возвращает клиента.
+func newClient(t *testing.T, m mocks) paymentv1.PaymentServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	var opts []grpc.ServerOption
+	if m.user != nil {
+		// Пользователь передается в контексте вызова, как после аутентификации
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				return handler(auth.WithUser(ctx, *m.user), req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				return handler(srv, &userStream{ServerStream: ss, ctx: auth.WithUser(ss.Context(), *m.user)})
+			}),
+		)
+	}
+	srv := grpc.NewServer(opts...)
+	// Адреса сохраняются без изменений, кроме адреса с опечаткой и имен кошельков
+	resolver := walletMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", "wlt1typo").Return("", storage.ErrMalformedAddress).Maybe()
+	resolver.On("ResolveAddress", "@ghost").Return("", storage.ErrHandleNotFound).Maybe()
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	// Операции запрещены только с кошельком wlt1foreign
+	authorizer := walletMocks.NewAuthorizer(t)
+	authorizer.On("AuthorizeWallet", mock.Anything, "wlt1foreign").Return(storage.ErrForbidden).Maybe()
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	paymentv1.RegisterPaymentServiceServer(srv,
+		payment.New(slog.New(slog.NewTextHandler(io.Discard, nil)), m.balance, m.maker, m.streamer, resolver, authorizer))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return paymentv1.NewPaymentServiceClient(conn)
+}
+
+// userStream подменяет контекст потока контекстом с пользователем.
+type userStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *userStream) Context() context.Context { return s.ctx }
+
+// errorInfo извлекает google.rpc.ErrorInfo из статуса ошибки.
+func errorInfo(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+func TestGetBalance(t *testing.T) {
+	cases := []struct {
+		name         string
+		address      string
+		mockSetup    func(m *walletMocks.BalanceReceiver)
+		expectedCode codes.Code
+		expectedErr  string
+		expectedResp *paymentv1.Wallet
+	}{
+		{
+			name:    "Успешный запрос",
+			address: "addr1",
+			mockSetup: func(m *walletMocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100}, nil).Once()
+			},
+			expectedCode: codes.OK,
+			expectedResp: &paymentv1.Wallet{Address: "addr1", Balance: 100},
+		},
+		{
+			name:    "Кошелек не найден",
+			address: "unknown",
+			mockSetup: func(m *walletMocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "unknown").Return(models.Wallet{}, storage.ErrWalletNotFound).Once()
+			},
+			expectedCode: codes.NotFound,
+			expectedErr:  storage.CodeWalletNotFound,
+		},
+		{
+			name:    "Внутренняя ошибка",
+			address: "addr1",
+			mockSetup: func(m *walletMocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "addr1").Return(models.Wallet{}, errors.New("db error")).Once()
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:         "Опечатка в адресе",
+			address:      "wlt1typo",
+			mockSetup:    func(m *walletMocks.BalanceReceiver) {},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  storage.CodeMalformedAddress,
+		},
+		{
+			name:         "Неизвестное имя кошелька",
+			address:      "@ghost",
+			mockSetup:    func(m *walletMocks.BalanceReceiver) {},
+			expectedCode: codes.NotFound,
+			expectedErr:  storage.CodeHandleNotFound,
+		},
+		{
+			name:         "Кошелек другого пользователя",
+			address:      "wlt1foreign",
+			mockSetup:    func(m *walletMocks.BalanceReceiver) {},
+			expectedCode: codes.PermissionDenied,
+			expectedErr:  storage.CodeForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			balance := walletMocks.NewBalanceReceiver(t)
+			tc.mockSetup(balance)
+
+			client := newClient(t, mocks{balance: balance})
+
+			resp, err := client.GetBalance(context.Background(), &paymentv1.GetBalanceRequest{Address: tc.address})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedCode == codes.OK {
+				require.Equal(t, tc.expectedResp.GetAddress(), resp.GetWallet().GetAddress())
+				require.Equal(t, tc.expectedResp.GetBalance(), resp.GetWallet().GetBalance())
+				return
+			}
+
+			info := errorInfo(t, err)
+			if tc.expectedErr == "" {
+				require.Nil(t, info)
+				return
+			}
+			require.NotNil(t, info)
+			require.Equal(t, tc.expectedErr, info.GetReason())
+			require.Equal(t, payment.ErrorDomain, info.GetDomain())
+		})
+	}
+}
+
+func TestSendTransfer(t *testing.T) {
+	cases := []struct {
+		name         string
+		req          *paymentv1.SendTransferRequest
+		mockErr      error
+		expectedCode codes.Code
+		expectedErr  string
+		expectedMeta map[string]string
+	}{
+		{
+			name:         "Успешный перевод",
+			req:          &paymentv1.SendTransferRequest{From: "a", To: "b", Amount: 10},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Недостаточно средств",
+			req:          &paymentv1.SendTransferRequest{From: "a", To: "b", Amount: 10},
+			mockErr:      &storage.InsufficientFundsError{Available: 5, Requested: 10},
+			expectedCode: codes.FailedPrecondition,
+			expectedErr:  storage.CodeInsufficientFunds,
+			expectedMeta: map[string]string{"available": "5", "requested": "10"},
+		},
+		{
+			name:         "Некорректная сумма",
+			req:          &paymentv1.SendTransferRequest{From: "a", To: "b", Amount: -1},
+			mockErr:      storage.ErrIncorrectAmount,
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  storage.CodeIncorrectAmount,
+		},
+		{
+			name:         "Совпадающие адреса",
+			req:          &paymentv1.SendTransferRequest{From: "a", To: "a", Amount: 1},
+			mockErr:      storage.ErrAddressesEqual,
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  storage.CodeAddressesEqual,
+		},
+		{
+			name:         "База занята после всех повторов",
+			req:          &paymentv1.SendTransferRequest{From: "a", To: "b", Amount: 1},
+			mockErr:      fmt.Errorf("storage.sqlite.AddTransaction: %w: database is locked", storage.ErrBusy),
+			expectedCode: codes.Unavailable,
+			expectedErr:  storage.CodeBusy,
+		},
+		{
+			name:         "Перевод с кошелька другого пользователя",
+			req:          &paymentv1.SendTransferRequest{From: "wlt1foreign", To: "b", Amount: 1},
+			expectedCode: codes.PermissionDenied,
+			expectedErr:  storage.CodeForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker := txMocks.NewTransactionMaker(t)
+			if tc.expectedCode != codes.PermissionDenied {
+				maker.On("AddTransaction", tc.req.From, tc.req.To, tc.req.Amount).Return(tc.mockErr).Once()
+			}
+
+			client := newClient(t, mocks{maker: maker})
+
+			_, err := client.SendTransfer(context.Background(), tc.req)
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedCode == codes.OK {
+				return
+			}
+
+			info := errorInfo(t, err)
+			require.NotNil(t, info)
+			require.Equal(t, tc.expectedErr, info.GetReason())
+			if tc.expectedMeta != nil {
+				require.Equal(t, tc.expectedMeta, info.GetMetadata())
+			}
+		})
+	}
+}
+
+// sendAll возвращает реализацию RecentTransactions, передающую транзакции txs, затем ошибку err.
+func sendAll(txs []models.Transaction, err error) func(context.Context, string, int, func(models.Transaction) error) error {
+	return func(_ context.Context, _ string, _ int, fn func(models.Transaction) error) error {
+		for _, tx := range txs {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// recvAll читает поток до конца и возвращает полученные транзакции и завершившую поток ошибку.
+func recvAll(t *testing.T, stream grpc.ServerStreamingClient[paymentv1.Transaction]) ([]models.Transaction, error) {
+	t.Helper()
+
+	var got []models.Transaction
+	for {
+		tx, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return got, nil
+		}
+		if err != nil {
+			return got, err
+		}
+		got = append(got, models.Transaction{From: tx.GetFrom(), To: tx.GetTo(), Amount: tx.GetAmount(), Time: tx.GetTime()})
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	txs := []models.Transaction{
+		{From: "a", To: "b", Amount: 1, Time: "2024-01-01T00:00:00Z"},
+		{From: "b", To: "c", Amount: 2, Time: "2024-01-01T00:00:01Z"},
+	}
+
+	t.Run("Поток транзакций", func(t *testing.T) {
+		streamer := paymentMocks.NewTransactionsStreamer(t)
+		streamer.On("RecentTransactions", mock.Anything, "", 2, mock.Anything).Return(sendAll(txs, nil)).Once()
+
+		client := newClient(t, mocks{streamer: streamer})
+
+		stream, err := client.ListTransactions(context.Background(), &paymentv1.ListTransactionsRequest{Count: 2})
+		require.NoError(t, err)
+		got, err := recvAll(t, stream)
+		require.NoError(t, err)
+		require.Equal(t, txs, got)
+	})
+
+	t.Run("Транзакции кошельков пользователя", func(t *testing.T) {
+		streamer := paymentMocks.NewTransactionsStreamer(t)
+		streamer.On("RecentTransactions", mock.Anything, "u1", 5, mock.Anything).Return(sendAll(txs[:1], nil)).Once()
+
+		client := newClient(t, mocks{streamer: streamer, user: &models.User{ID: "u1"}})
+
+		stream, err := client.ListTransactions(context.Background(), &paymentv1.ListTransactionsRequest{Count: 5})
+		require.NoError(t, err)
+		got, err := recvAll(t, stream)
+		require.NoError(t, err)
+		require.Equal(t, txs[:1], got)
+	})
+
+	t.Run("Ошибка хранилища во время чтения", func(t *testing.T) {
+		streamer := paymentMocks.NewTransactionsStreamer(t)
+		streamer.On("RecentTransactions", mock.Anything, "", 2, mock.Anything).
+			Return(sendAll(txs[:1], errors.New("storage.sqlite.recentTransactions: disk I/O error"))).Once()
+
+		client := newClient(t, mocks{streamer: streamer})
+
+		// Переданные транзакции получены, затем поток завершается ошибкой без текста драйвера
+		stream, err := client.ListTransactions(context.Background(), &paymentv1.ListTransactionsRequest{Count: 2})
+		require.NoError(t, err)
+		got, err := recvAll(t, stream)
+		require.Equal(t, txs[:1], got)
+		require.Equal(t, codes.Internal, status.Code(err))
+		require.NotContains(t, status.Convert(err).Message(), "disk")
+	})
+
+	t.Run("Некорректное количество", func(t *testing.T) {
+		client := newClient(t, mocks{streamer: paymentMocks.NewTransactionsStreamer(t)})
+
+		stream, err := client.ListTransactions(context.Background(), &paymentv1.ListTransactionsRequest{Count: 0})
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Equal(t, response.CodeInvalidCount, errorInfo(t, err).GetReason())
+	})
+}
+
+func TestErrorMessage(t *testing.T) {
+	cases := []struct {
+		name            string
+		acceptLanguage  string
+		mockErr         error
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "Сообщение каталога вместо текста ошибки",
+			mockErr:         fmt.Errorf("storage.sqlite.AddTransaction: %w: database is locked", storage.ErrBusy),
+			expectedCode:    codes.Unavailable,
+			expectedMessage: "Хранилище занято, повторите запрос позже",
+		},
+		{
+			name:            "Язык из метаданных",
+			acceptLanguage:  "en-US,en;q=0.9",
+			mockErr:         &storage.InsufficientFundsError{Available: 5, Requested: 10},
+			expectedCode:    codes.FailedPrecondition,
+			expectedMessage: "Insufficient funds",
+		},
+		{
+			name:            "Внутренняя ошибка",
+			acceptLanguage:  "en",
+			mockErr:         errors.New("storage.sqlite.AddTransaction: no such table: wallets"),
+			expectedCode:    codes.Internal,
+			expectedMessage: "Internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker := txMocks.NewTransactionMaker(t)
+			maker.On("AddTransaction", "a", "b", 1.0).Return(tc.mockErr).Once()
+
+			client := newClient(t, mocks{maker: maker})
+
+			ctx := context.Background()
+			if tc.acceptLanguage != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", tc.acceptLanguage)
+			}
+			_, err := client.SendTransfer(ctx, &paymentv1.SendTransferRequest{From: "a", To: "b", Amount: 1})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			require.Equal(t, tc.expectedMessage, status.Convert(err).Message())
+		})
+	}
+}
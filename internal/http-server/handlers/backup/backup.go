@@ -0,0 +1,62 @@
+// Package backup содержит обработчики служебного сервера для резервного копирования хранилища.
+package backup
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/backup"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Manager определяет интерфейс создания и просмотра снимков хранилища.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Manager --dir=. --output=./mocks --filename=mock_Manager
+type Manager interface {
+	Create(ctx context.Context) (backup.Snapshot, error)
+	List() ([]backup.Snapshot, error)
+}
+
+// Create создает HTTP-обработчик, создающий снимок хранилища по запросу.
+// Таймаут записи ответа снимается: длительность копирования зависит от размера базы.
+func Create(log *slog.Logger, manager Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.backup.Create"
+
+		log := log.With("op", op)
+
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		snap, err := manager.Create(r.Context())
+		if err != nil {
+			log.Error("failed to create backup", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+		render.JSON(w, r, response.Success(snap))
+	}
+}
+
+// List создает HTTP-обработчик списка снимков от новых к старым.
+func List(log *slog.Logger, manager Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.backup.List"
+
+		log := log.With("op", op)
+
+		snaps, err := manager.List()
+		if err != nil {
+			log.Error("failed to list backups", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+		if snaps == nil {
+			snaps = []backup.Snapshot{}
+		}
+		render.JSON(w, r, response.Success(snaps))
+	}
+}
@@ -0,0 +1,144 @@
+package backup_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/backup"
+	backupHandlers "infotecsTest/internal/http-server/handlers/backup"
+	"infotecsTest/internal/http-server/handlers/backup/mocks"
+	"infotecsTest/internal/lib/api/response"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var snapshot = backup.Snapshot{
+	Name:      "storage-20240501T000000.000Z.db",
+	Size:      4096,
+	SHA256:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Manager)
+	}{
+		{
+			name:         "Снимок создан",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Manager) {
+				m.On("Create", mock.Anything).Return(snapshot, nil).Once()
+			},
+		},
+		{
+			name:          "Ошибка копирования",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.Manager) {
+				m.On("Create", mock.Anything).Return(backup.Snapshot{}, errors.New("disk full")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := mocks.NewManager(t)
+			tc.mockSetup(manager)
+
+			router := chi.NewRouter()
+			router.Post("/backups", backupHandlers.Create(testLogger, manager))
+
+			req := httptest.NewRequest(http.MethodPost, "/backups", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data backup.Snapshot `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			if tc.expectedError != "" {
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				return
+			}
+			require.Equal(t, snapshot, resp.Data)
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedError string
+		expectedLen   int
+		mockSetup     func(m *mocks.Manager)
+	}{
+		{
+			name:         "Список снимков",
+			expectedCode: http.StatusOK,
+			expectedLen:  1,
+			mockSetup: func(m *mocks.Manager) {
+				m.On("List").Return([]backup.Snapshot{snapshot}, nil).Once()
+			},
+		},
+		{
+			name:         "Снимков нет",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Manager) {
+				m.On("List").Return(nil, nil).Once()
+			},
+		},
+		{
+			name:          "Каталог недоступен",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.Manager) {
+				m.On("List").Return(nil, errors.New("permission denied")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := mocks.NewManager(t)
+			tc.mockSetup(manager)
+
+			router := chi.NewRouter()
+			router.Get("/backups", backupHandlers.List(testLogger, manager))
+
+			req := httptest.NewRequest(http.MethodGet, "/backups", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data []backup.Snapshot `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			if tc.expectedError != "" {
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				return
+			}
+			require.NotNil(t, resp.Data)
+			require.Len(t, resp.Data, tc.expectedLen)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package handle содержит обработчики HTTP-запросов для имен кошельков (@alice).
+package handle
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Registry определяет интерфейс присвоения, освобождения и поиска имен кошельков.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Registry --dir=. --output=./mocks --filename=mock_Registry
+type Registry interface {
+	ClaimHandle(ctx context.Context, address, handle string) (models.Handle, error)
+	ReleaseHandle(ctx context.Context, address string) (models.Handle, error)
+	ResolveHandle(ctx context.Context, handle string) (models.Handle, error)
+}
+
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=AddressResolver --dir=. --output=./mocks --filename=mock_AddressResolver
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Authorizer определяет интерфейс проверки прав пользователя на кошелек.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Authorizer --dir=. --output=./mocks --filename=mock_Authorizer
+type Authorizer interface {
+	AuthorizeWallet(ctx context.Context, address string) error
+}
+
+// Claim создает HTTP-обработчик присвоения имени кошельку.
+// Принимает JSON с именем; прежнее имя кошелька освобождается.
+// Для имени, занятого другим кошельком, возвращает 409, для чужого кошелька - 403.
+func Claim(log *slog.Logger, registry Registry, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.handle.Claim"
+
+		log := log.With("op", op)
+
+		var req models.HandleRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			response.Render(w, r, response.Fail(r, response.CodeEmptyBody, http.StatusBadRequest, nil))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidJSON, http.StatusBadRequest, nil))
+			return
+		}
+
+		address, err := resolver.ResolveAddress(chi.URLParam(r, "address"))
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), address)
+		}
+		if err == nil {
+			var h models.Handle
+			if h, err = registry.ClaimHandle(r.Context(), address, req.Handle); err == nil {
+				log.Info("wallet handle claimed", slog.String("handle", h.Handle), slog.String("address", h.Address))
+				render.JSON(w, r, response.Success(h))
+				return
+			}
+		}
+		renderError(w, r, log, err)
+	}
+}
+
+// Release создает HTTP-обработчик освобождения имени кошелька.
+// Возвращает освобожденное имя; для кошелька без имени возвращает 400, для чужого кошелька - 403.
+func Release(log *slog.Logger, registry Registry, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.handle.Release"
+
+		log := log.With("op", op)
+
+		address, err := resolver.ResolveAddress(chi.URLParam(r, "address"))
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), address)
+		}
+		if err == nil {
+			var h models.Handle
+			if h, err = registry.ReleaseHandle(r.Context(), address); err == nil {
+				log.Info("wallet handle released", slog.String("handle", h.Handle), slog.String("address", h.Address))
+				render.JSON(w, r, response.Success(h))
+				return
+			}
+		}
+		renderError(w, r, log, err)
+	}
+}
+
+// Resolve создает HTTP-обработчик поиска кошелька по имени.
+// Имя принимается с @ или без, регистр не учитывается.
+func Resolve(log *slog.Logger, registry Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.handle.Resolve"
+
+		log := log.With("op", op)
+
+		h, err := registry.ResolveHandle(r.Context(), chi.URLParam(r, "handle"))
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+		render.JSON(w, r, response.Success(h))
+	}
+}
+
+// renderError отвечает ошибкой операции с именем или адресом кошелька.
+func renderError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrHandleTaken):
+		log.Warn("wallet handle is taken", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusConflict, nil))
+	case errors.Is(err, storage.ErrForbidden):
+		log.Warn("wallet access denied", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusForbidden, nil))
+	case errors.Is(err, storage.ErrInvalidHandle),
+		errors.Is(err, storage.ErrHandleNotFound),
+		errors.Is(err, storage.ErrWalletNotFound),
+		errors.Is(err, storage.ErrMalformedAddress):
+		log.Warn("invalid wallet handle request", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+	default:
+		log.Error("failed to process wallet handle", sl.Err(err))
+		response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+	}
+}
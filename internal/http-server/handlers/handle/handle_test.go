@@ -0,0 +1,294 @@
+package handle_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handle"
+	"infotecsTest/internal/http-server/handlers/handle/mocks"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestClaimHandler(t *testing.T) {
+	alice := models.Handle{Handle: "alice", Address: "addr1"}
+
+	cases := []struct {
+		name          string
+		address       string
+		requestBody   string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Имя присвоено",
+			address:      "addr1",
+			requestBody:  `{"handle": "@Alice"}`,
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "addr1", "@Alice").Return(alice, nil).Once()
+			},
+		},
+		{
+			name:         "Адрес прежнего формата",
+			address:      "1077f2e5-cfd1-4a02-86cc-96fc7337d18c",
+			requestBody:  `{"handle": "alice"}`,
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "wlt1canonical", "alice").Return(alice, nil).Once()
+			},
+		},
+		{
+			name:          "Пустое тело запроса",
+			address:       "addr1",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeEmptyBody,
+		},
+		{
+			name:          "Невалидный JSON",
+			address:       "addr1",
+			requestBody:   `{"handle":`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidJSON,
+		},
+		{
+			name:          "Опечатка в адресе",
+			address:       "wlt1typo",
+			requestBody:   `{"handle": "alice"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			address:       "wlt1foreign",
+			requestBody:   `{"handle": "alice"}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+		},
+		{
+			name:          "Некорректное имя",
+			address:       "addr1",
+			requestBody:   `{"handle": "al"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeInvalidHandle,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "addr1", "al").Return(models.Handle{}, storage.ErrInvalidHandle).Once()
+			},
+		},
+		{
+			name:          "Кошелек не найден",
+			address:       "addr1",
+			requestBody:   `{"handle": "alice"}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "addr1", "alice").Return(models.Handle{}, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:          "Имя занято",
+			address:       "addr1",
+			requestBody:   `{"handle": "alice"}`,
+			expectedCode:  http.StatusConflict,
+			expectedError: storage.CodeHandleTaken,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "addr1", "alice").Return(models.Handle{}, storage.ErrHandleTaken).Once()
+			},
+		},
+		{
+			name:          "Внутренняя ошибка",
+			address:       "addr1",
+			requestBody:   `{"handle": "alice"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ClaimHandle", mock.Anything, "addr1", "alice").Return(models.Handle{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(registry)
+			}
+
+			router := chi.NewRouter()
+			router.Put("/api/wallet/{address}/handle", handle.Claim(testLogger, registry, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/wallet/"+tc.address+"/handle", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.Handle `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, alice, resp.Data)
+			}
+		})
+	}
+}
+
+func TestReleaseHandler(t *testing.T) {
+	alice := models.Handle{Handle: "alice", Address: "addr1"}
+
+	cases := []struct {
+		name          string
+		address       string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Имя освобождено",
+			address:      "addr1",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ReleaseHandle", mock.Anything, "addr1").Return(alice, nil).Once()
+			},
+		},
+		{
+			name:          "У кошелька нет имени",
+			address:       "addr1",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeHandleNotFound,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ReleaseHandle", mock.Anything, "addr1").Return(models.Handle{}, storage.ErrHandleNotFound).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе",
+			address:       "wlt1typo",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			address:       "wlt1foreign",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+		},
+		{
+			name:          "Внутренняя ошибка",
+			address:       "addr1",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ReleaseHandle", mock.Anything, "addr1").Return(models.Handle{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(registry)
+			}
+
+			router := chi.NewRouter()
+			router.Delete("/api/wallet/{address}/handle", handle.Release(testLogger, registry, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/wallet/"+tc.address+"/handle", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.Handle `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, alice, resp.Data)
+			}
+		})
+	}
+}
+
+func TestResolveHandler(t *testing.T) {
+	alice := models.Handle{Handle: "alice", Address: "addr1"}
+
+	cases := []struct {
+		name          string
+		handle        string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Имя найдено",
+			handle:       "@Alice",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ResolveHandle", mock.Anything, "@Alice").Return(alice, nil).Once()
+			},
+		},
+		{
+			name:          "Имя не найдено",
+			handle:        "bob",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeHandleNotFound,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ResolveHandle", mock.Anything, "bob").Return(models.Handle{}, storage.ErrHandleNotFound).Once()
+			},
+		},
+		{
+			name:          "Некорректное имя",
+			handle:        "1x",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeInvalidHandle,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("ResolveHandle", mock.Anything, "1x").Return(models.Handle{}, storage.ErrInvalidHandle).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			tc.mockSetup(registry)
+
+			router := chi.NewRouter()
+			router.Get("/api/handles/{handle}", handle.Resolve(testLogger, registry))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/handles/"+tc.handle, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.Handle `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, alice, resp.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Package handlerstest содержит общие настройки моков для тестов обработчиков HTTP.
+// Моки создаются mockery в пакете mocks каждого обработчика, здесь им задаются ожидания.
+package handlerstest
+
+import (
+	"github.com/stretchr/testify/mock"
+	"infotecsTest/internal/storage"
+)
+
+// Mock - мок, созданный mockery: ожидания задаются методом On встроенного mock.Mock.
+type Mock interface {
+	On(methodName string, arguments ...any) *mock.Call
+}
+
+// Resolver настраивает мок AddressResolver: адреса сохраняются без изменений, кроме адреса
+// с опечаткой wlt1typo, адреса прежнего формата и имен кошельков @alice и @ghost.
+func Resolver[M Mock](resolver M) M {
+	resolver.On("ResolveAddress", "wlt1typo").Return("", storage.ErrMalformedAddress).Maybe()
+	resolver.On("ResolveAddress", "1077f2e5-cfd1-4a02-86cc-96fc7337d18c").Return("wlt1canonical", nil).Maybe()
+	resolver.On("ResolveAddress", "@alice").Return("wlt1alice", nil).Maybe()
+	resolver.On("ResolveAddress", "@ghost").Return("", storage.ErrHandleNotFound).Maybe()
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	return resolver
+}
+
+// Authorizer настраивает мок Authorizer: операции разрешены со всеми кошельками, кроме wlt1foreign.
+func Authorizer[M Mock](authorizer M) M {
+	authorizer.On("AuthorizeWallet", mock.Anything, "wlt1foreign").Return(storage.ErrForbidden).Maybe()
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return authorizer
+}
@@ -0,0 +1,164 @@
+// Package health содержит обработчики проб живости и готовности сервиса.
+// Проверки готовности регистрируются подсистемами через Registry.
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/response"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Статусы отдельных проверок
+const (
+	StatusPass = "pass" // Проверка пройдена
+	StatusFail = "fail" // Проверка не пройдена
+)
+
+// CheckShutdown - имя встроенной проверки остановки сервиса.
+const CheckShutdown = "shutdown"
+
+// checkTimeout ограничивает время выполнения одной проверки.
+const checkTimeout = 2 * time.Second
+
+// errShuttingDown возвращается встроенной проверкой во время остановки сервиса.
+var errShuttingDown = errors.New("service is shutting down")
+
+// Check проверяет состояние подсистемы.
+// Возвращает ошибку, если подсистема не готова обслуживать запросы.
+type Check func(ctx context.Context) error
+
+// Report описывает результат всех проверок.
+type Report struct {
+	Status string                 `json:"status"`           // Общий статус: pass/fail
+	Checks map[string]CheckResult `json:"checks,omitempty"` // Результаты по проверкам
+}
+
+// CheckResult описывает результат одной проверки.
+type CheckResult struct {
+	Status   string `json:"status"`          // Статус: pass/fail
+	Duration string `json:"duration"`        // Время выполнения
+	Error    string `json:"error,omitempty"` // Причина ошибки
+}
+
+// Registry хранит зарегистрированные проверки готовности.
+// Безопасен для конкурентного использования.
+type Registry struct {
+	mu           sync.RWMutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry создает реестр со встроенной проверкой остановки сервиса.
+func NewRegistry() *Registry {
+	r := &Registry{checks: make(map[string]Check)}
+	r.Register(CheckShutdown, func(context.Context) error {
+		if r.shuttingDown.Load() {
+			return errShuttingDown
+		}
+		return nil
+	})
+	return r
+}
+
+// Register добавляет проверку готовности.
+// Повторная регистрация с тем же именем заменяет проверку.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// SetShuttingDown переводит сервис в состояние остановки.
+// После вызова проба готовности всегда завершается ошибкой.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Run параллельно выполняет все проверки и собирает отчет.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusPass {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run выполняет одну проверку с таймаутом.
+func run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+
+	res := CheckResult{Status: StatusPass, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Live создает HTTP-обработчик пробы живости.
+// Отвечает успешно, пока процесс способен обрабатывать запросы.
+func Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, response.Success(Report{Status: StatusPass}))
+	}
+}
+
+// Ready создает HTTP-обработчик пробы готовности.
+// Возвращает 503 с детализацией, если хотя бы одна проверка не пройдена.
+func Ready(log *slog.Logger, registry *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.Ready"
+
+		log := log.With("op", op)
+
+		report := registry.Run(r.Context())
+		if report.Status != StatusPass {
+			log.Warn("service is not ready", slog.Any("checks", report.Checks))
+			resp := response.Fail(r, response.CodeNotReady, http.StatusServiceUnavailable, nil)
+			resp.Data = report
+			response.Render(w, r, resp)
+			return
+		}
+
+		render.JSON(w, r, response.Success(report))
+	}
+}
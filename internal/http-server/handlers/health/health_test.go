@@ -0,0 +1,116 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/health"
+	"infotecsTest/internal/lib/api/response"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestReadyHandler(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cases := []struct {
+		name           string
+		setup          func(r *health.Registry)
+		expectedCode   int
+		expectedStatus string
+		failedChecks   []string
+	}{
+		{
+			name: "Все проверки пройдены",
+			setup: func(r *health.Registry) {
+				r.Register("database", func(context.Context) error { return nil })
+			},
+			expectedCode:   http.StatusOK,
+			expectedStatus: response.StatusOK,
+		},
+		{
+			name: "База данных недоступна",
+			setup: func(r *health.Registry) {
+				r.Register("database", func(context.Context) error { return errors.New("database is closed") })
+			},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: response.StatusError,
+			failedChecks:   []string{"database"},
+		},
+		{
+			name: "Сервис останавливается",
+			setup: func(r *health.Registry) {
+				r.Register("database", func(context.Context) error { return nil })
+				r.SetShuttingDown()
+			},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: response.StatusError,
+			failedChecks:   []string{health.CheckShutdown},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := health.NewRegistry()
+			tc.setup(registry)
+
+			req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			health.Ready(testLogger, registry).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				Status string        `json:"status"`
+				Data   health.Report `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedStatus, resp.Status)
+			require.Contains(t, resp.Data.Checks, "database")
+
+			for name, check := range resp.Data.Checks {
+				if slices.Contains(tc.failedChecks, name) {
+					require.Equal(t, health.StatusFail, check.Status)
+					require.NotEmpty(t, check.Error)
+					continue
+				}
+				require.Equal(t, health.StatusPass, check.Status)
+			}
+		})
+	}
+}
+
+func TestReadyHandlerProblem(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	registry := health.NewRegistry()
+	registry.Register("database", func(context.Context) error { return errors.New("database is closed") })
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req.Header.Set("Accept", response.ContentTypeProblem)
+	rr := httptest.NewRecorder()
+	health.Ready(testLogger, registry).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, response.ContentTypeProblem, rr.Header().Get("Content-Type"))
+
+	// Отчет о проверках передается и в документе RFC 7807
+	var doc struct {
+		Status    int           `json:"status"`
+		ErrorCode string        `json:"error_code"`
+		Data      health.Report `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	require.Equal(t, http.StatusServiceUnavailable, doc.Status)
+	require.Equal(t, response.CodeNotReady, doc.ErrorCode)
+	require.Equal(t, health.StatusFail, doc.Data.Status)
+	require.Equal(t, health.StatusFail, doc.Data.Checks["database"].Status)
+	require.Equal(t, "database is closed", doc.Data.Checks["database"].Error)
+}
@@ -0,0 +1,206 @@
+// Package payout содержит обработчики HTTP-запросов для заданий на массовую выплату.
+package payout
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/payout"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// JobCreator определяет интерфейс создания задания на выплату.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=JobCreator --dir=. --output=./mocks --filename=mock_JobCreator
+type JobCreator interface {
+	Create(ctx context.Context, from string, file io.Reader) (models.PayoutJob, error)
+}
+
+// JobReader определяет интерфейс чтения заданий на выплату и их результатов.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=JobReader --dir=. --output=./mocks --filename=mock_JobReader
+type JobReader interface {
+	Job(ctx context.Context, id string) (models.PayoutJob, error)
+	Results(ctx context.Context, id string, fn func(models.PayoutRow) error) error
+}
+
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=AddressResolver --dir=. --output=./mocks --filename=mock_AddressResolver
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Authorizer определяет интерфейс проверки прав пользователя на кошелек.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Authorizer --dir=. --output=./mocks --filename=mock_Authorizer
+type Authorizer interface {
+	AuthorizeWallet(ctx context.Context, address string) error
+}
+
+// resultColumns - заголовок файла результатов.
+var resultColumns = []string{"line", "to", "amount", "reference", "status", "error_code"}
+
+// Create создает HTTP-обработчик загрузки задания на выплату.
+// Тело запроса - CSV-файл со строками to,amount,reference, кошелек-источник задается параметром from.
+// Возвращает 202 Accepted с заданием; строки выполняются в фоне.
+// Выплата с кошелька другого пользователя отклоняется с кодом 403.
+func Create(log *slog.Logger, creator JobCreator, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payout.Create"
+
+		log := log.With("op", op)
+
+		from, err := resolver.ResolveAddress(r.URL.Query().Get("from"))
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), from)
+		}
+
+		var job models.PayoutJob
+		if err == nil {
+			job, err = creator.Create(r.Context(), from, r.Body)
+		}
+		if err != nil {
+			var perr *payout.ParseError
+			switch {
+			case errors.Is(err, storage.ErrForbidden):
+				log.Warn("wallet access denied", slog.String("from", from))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusForbidden, nil))
+			case errors.As(err, &perr):
+				log.Warn("invalid payout file", sl.Err(err))
+				response.Render(w, r, response.Fail(r, response.CodeInvalidCSV, http.StatusBadRequest, perr.Details()))
+			case errors.Is(err, storage.ErrWalletNotFound),
+				errors.Is(err, storage.ErrMalformedAddress),
+				errors.Is(err, storage.ErrInvalidHandle),
+				errors.Is(err, storage.ErrHandleNotFound):
+				log.Warn("invalid source wallet", sl.Err(err))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+			default:
+				log.Error("failed to create payout job", sl.Err(err))
+				response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			}
+			return
+		}
+
+		log.Info("payout job created", slog.String("job", job.ID), slog.Int("rows", job.Total))
+
+		resp := response.Success(job)
+		resp.Code = http.StatusAccepted
+		w.Header().Set("Location", "/api/payouts/"+job.ID)
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, resp)
+	}
+}
+
+// Get создает HTTP-обработчик получения статуса задания на выплату.
+// Задание доступно только владельцу кошелька-источника.
+func Get(log *slog.Logger, reader JobReader, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payout.Get"
+
+		log := log.With("op", op)
+
+		job, err := reader.Job(r.Context(), chi.URLParam(r, "id"))
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), job.From)
+		}
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+		render.JSON(w, r, response.Success(job))
+	}
+}
+
+// Results создает HTTP-обработчик выгрузки результатов задания в формате CSV.
+// Для каждой строки файла передаются статус и код ошибки; незавершенное задание
+// выгружается с текущими статусами строк. Результаты доступны только владельцу кошелька-источника.
+func Results(log *slog.Logger, reader JobReader, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payout.Results"
+
+		log := log.With("op", op)
+
+		id := chi.URLParam(r, "id")
+
+		job, err := reader.Job(r.Context(), id)
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), job.From)
+		}
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		cw := csv.NewWriter(w)
+
+		// Заголовки отправляются с первой строкой, чтобы ошибку поиска задания
+		// можно было вернуть обычным ответом
+		started := false
+		start := func() error {
+			started = true
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="payout-%s-results.csv"`, id))
+			w.WriteHeader(http.StatusOK)
+			return cw.Write(resultColumns)
+		}
+
+		err = reader.Results(r.Context(), id, func(row models.PayoutRow) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			return cw.Write([]string{
+				strconv.Itoa(row.Line),
+				row.To,
+				strconv.FormatFloat(row.Amount, 'f', -1, 64),
+				row.Reference,
+				row.Status,
+				row.ErrorCode,
+			})
+		})
+		if err != nil && !started {
+			renderError(w, r, log, err)
+			return
+		}
+		if err == nil && !started {
+			err = start()
+		}
+		if err != nil {
+			// Статус уже отправлен: результаты выгружены не полностью
+			log.Error("payout results stream interrupted", sl.Err(err))
+		}
+		cw.Flush()
+	}
+}
+
+// renderError отвечает ошибкой поиска задания или проверки прав на него.
+func renderError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrPayoutNotFound):
+		log.Warn("payout job not found", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+		return
+	case errors.Is(err, storage.ErrForbidden):
+		log.Warn("payout job access denied", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusForbidden, nil))
+		return
+	}
+	log.Error("failed to read payout job", sl.Err(err))
+	response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+}
@@ -0,0 +1,307 @@
+package payout_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
+	payoutHandlers "infotecsTest/internal/http-server/handlers/payout"
+	"infotecsTest/internal/http-server/handlers/payout/mocks"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/payout"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestCreateHandler(t *testing.T) {
+	job := models.PayoutJob{
+		ID:        "job1",
+		From:      "addr1",
+		Status:    models.PayoutJobPending,
+		Total:     2,
+		Pending:   2,
+		CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name          string
+		from          string
+		expectedCode  int
+		expectedError string
+		expectedLine  float64
+		mockSetup     func(m *mocks.JobCreator)
+	}{
+		{
+			name:         "Задание принято",
+			expectedCode: http.StatusAccepted,
+			mockSetup: func(m *mocks.JobCreator) {
+				m.On("Create", mock.Anything, "addr1", mock.Anything).Return(job, nil).Once()
+			},
+		},
+		{
+			name:          "Некорректный файл",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidCSV,
+			expectedLine:  3,
+			mockSetup: func(m *mocks.JobCreator) {
+				m.On("Create", mock.Anything, "addr1", mock.Anything).
+					Return(models.PayoutJob{}, &payout.ParseError{Line: 3, Reason: `invalid amount "ten"`}).Once()
+			},
+		},
+		{
+			name:          "Кошелек-источник не найден",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(m *mocks.JobCreator) {
+				m.On("Create", mock.Anything, "addr1", mock.Anything).
+					Return(models.PayoutJob{}, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе источника",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+			mockSetup: func(m *mocks.JobCreator) {
+				m.On("Create", mock.Anything, "addr1", mock.Anything).
+					Return(models.PayoutJob{}, fmt.Errorf("payout.Create: %w", storage.ErrMalformedAddress)).Once()
+			},
+		},
+		{
+			name:          "Опечатка в параметре from",
+			from:          "wlt1typo",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			from:          "wlt1foreign",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+		},
+		{
+			name:          "Внутренняя ошибка",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.JobCreator) {
+				m.On("Create", mock.Anything, "addr1", mock.Anything).
+					Return(models.PayoutJob{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			creator := mocks.NewJobCreator(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(creator)
+			}
+			if tc.from == "" {
+				tc.from = "addr1"
+			}
+
+			router := chi.NewRouter()
+			router.Post("/api/payouts", payoutHandlers.Create(testLogger, creator, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/payouts?from="+tc.from,
+				strings.NewReader("to,amount,reference\naddr2,10,salary\n"))
+			req.Header.Set("Content-Type", "text/csv")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.PayoutJob `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			if tc.expectedError != "" {
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				if tc.expectedLine != 0 {
+					require.Equal(t, tc.expectedLine, resp.Details["line"])
+				}
+				return
+			}
+
+			require.Equal(t, "/api/payouts/job1", rr.Header().Get("Location"))
+			require.Equal(t, http.StatusAccepted, resp.Code)
+			require.Equal(t, job, resp.Data)
+		})
+	}
+}
+
+func TestGetHandler(t *testing.T) {
+	job := models.PayoutJob{ID: "job1", From: "addr1", Status: models.PayoutJobCompleted, Total: 1, Succeeded: 1}
+
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.JobReader)
+	}{
+		{
+			name:         "Статус задания",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(job, nil).Once()
+			},
+		},
+		{
+			name:          "Задание не найдено",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodePayoutNotFound,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(models.PayoutJob{}, storage.ErrPayoutNotFound).Once()
+			},
+		},
+		{
+			name:          "Задание другого пользователя",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(models.PayoutJob{ID: "job1", From: "wlt1foreign"}, nil).Once()
+			},
+		},
+		{
+			name:          "Внутренняя ошибка",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(models.PayoutJob{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := mocks.NewJobReader(t)
+			tc.mockSetup(reader)
+
+			router := chi.NewRouter()
+			router.Get("/api/payouts/{id}", payoutHandlers.Get(testLogger, reader, handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/payouts/job1", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.PayoutJob `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, job, resp.Data)
+			}
+		})
+	}
+}
+
+func TestResultsHandler(t *testing.T) {
+	job := models.PayoutJob{ID: "job1", From: "addr1", Status: models.PayoutJobCompleted, Total: 2, Succeeded: 1, Failed: 1}
+	rows := []models.PayoutRow{
+		{Line: 2, To: "addr2", Amount: 10, Reference: "salary", Status: models.PayoutRowSucceeded},
+		{Line: 3, To: "ghost", Amount: 2.5, Reference: "bonus, May", Status: models.PayoutRowFailed, ErrorCode: storage.CodeWalletNotFound},
+	}
+
+	// streamRows передает строки в обработчик так же, как хранилище.
+	streamRows := func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.PayoutRow) error)
+		for _, row := range rows {
+			require.NoError(t, fn(row))
+		}
+	}
+
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedBody  string
+		expectedError string
+		mockSetup     func(m *mocks.JobReader)
+	}{
+		{
+			name:         "Результаты задания",
+			expectedCode: http.StatusOK,
+			expectedBody: "line,to,amount,reference,status,error_code\n" +
+				"2,addr2,10,salary,succeeded,\n" +
+				"3,ghost,2.5,\"bonus, May\",failed,wallet_not_found\n",
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(job, nil).Once()
+				m.On("Results", mock.Anything, "job1", mock.Anything).Run(streamRows).Return(nil).Once()
+			},
+		},
+		{
+			name:          "Задание не найдено",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodePayoutNotFound,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(models.PayoutJob{}, storage.ErrPayoutNotFound).Once()
+			},
+		},
+		{
+			name:          "Задание другого пользователя",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(models.PayoutJob{ID: "job1", From: "wlt1foreign"}, nil).Once()
+			},
+		},
+		{
+			name:         "Обрыв потока",
+			expectedCode: http.StatusOK,
+			expectedBody: "line,to,amount,reference,status,error_code\n" +
+				"2,addr2,10,salary,succeeded,\n",
+			mockSetup: func(m *mocks.JobReader) {
+				m.On("Job", mock.Anything, "job1").Return(job, nil).Once()
+				m.On("Results", mock.Anything, "job1", mock.Anything).
+					Run(func(args mock.Arguments) {
+						fn := args.Get(2).(func(models.PayoutRow) error)
+						require.NoError(t, fn(rows[0]))
+					}).
+					Return(errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := mocks.NewJobReader(t)
+			tc.mockSetup(reader)
+
+			router := chi.NewRouter()
+			router.Get("/api/payouts/{id}/results", payoutHandlers.Results(testLogger, reader, handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/payouts/job1/results", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			if tc.expectedError != "" {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				return
+			}
+
+			require.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+			require.Contains(t, rr.Header().Get("Content-Disposition"), "payout-job1-results.csv")
+			require.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
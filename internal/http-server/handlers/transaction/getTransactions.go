@@ -2,8 +2,10 @@
 package transaction
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/render"
+	"infotecsTest/internal/http-server/middleware/auth"
 	"infotecsTest/internal/lib/api/response"
 	"infotecsTest/internal/lib/logger/sl"
 	"infotecsTest/internal/models"
@@ -13,15 +15,18 @@ import (
 	"strconv"
 )
 
-// TransactionsReceiver определяет интерфейс для получения транзакций из хранилища.
+// TransactionsReceiver определяет интерфейс для получения транзакций из хранилища:
+// всех или только с участием кошельков пользователя.
 // Генерирует моки через go:generate
 type TransactionsReceiver interface {
 	GetNTransactions(N int) ([]models.Transaction, error)
+	UserTransactions(ctx context.Context, userID string, N int) ([]models.Transaction, error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=TransactionsReceiver --dir=. --output=./mocks --filename=mock_TransactionsReceiver
 
 // GetLast создает HTTP-обработчик для получения последних N транзакций.
+// Аутентифицированному пользователю возвращаются только транзакции его кошельков.
 func GetLast(log *slog.Logger, receiver TransactionsReceiver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.transaction.GetLast"
@@ -32,22 +37,31 @@ func GetLast(log *slog.Logger, receiver TransactionsReceiver) http.HandlerFunc {
 		var N, err = strconv.Atoi(query)
 		if err != nil {
 			log.Error("unable to convert count to number", sl.Err(err))
-			render.JSON(w, r, response.Error(r, "Некорректное значение count", http.StatusBadRequest))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidCount, http.StatusBadRequest, nil))
 			return
 		}
 
-		txs, err := receiver.GetNTransactions(N)
+		txs, err := lastTransactions(r.Context(), receiver, N)
 		if err != nil {
 			if errors.Is(err, storage.ErrInvalidRequest) {
 				log.Error("invalid request", sl.Err(err))
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
 				return
 			}
 			log.Error("unable to get transactions", sl.Err(err))
-			render.JSON(w, r, response.Error(r, "Внутренняя ошибка", http.StatusInternalServerError))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
 			return
 		}
 
 		render.JSON(w, r, response.Success(txs))
 	}
 }
+
+// lastTransactions возвращает N последних транзакций, видимых пользователю из контекста:
+// с участием его кошельков, а без аутентификации - все.
+func lastTransactions(ctx context.Context, receiver TransactionsReceiver, N int) ([]models.Transaction, error) {
+	if user, ok := auth.UserFromContext(ctx); ok {
+		return receiver.UserTransactions(ctx, user.ID, N)
+	}
+	return receiver.GetNTransactions(N)
+}
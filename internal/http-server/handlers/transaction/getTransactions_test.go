@@ -3,9 +3,11 @@ package transaction_test
 import (
 	"encoding/json"
 	"errors"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"infotecsTest/internal/http-server/handlers/transaction"
 	"infotecsTest/internal/http-server/handlers/transaction/mocks"
+	"infotecsTest/internal/http-server/middleware/auth"
 	"infotecsTest/internal/lib/api/response"
 	"infotecsTest/internal/models"
 	"infotecsTest/internal/storage"
@@ -22,6 +24,7 @@ func TestGetLastHandler(t *testing.T) {
 	cases := []struct {
 		name         string
 		countParam   string
+		user         *models.User
 		expectedCode int
 		expectedResp response.Response
 		mockSetup    func(receiver *mocks.TransactionsReceiver)
@@ -38,13 +41,28 @@ func TestGetLastHandler(t *testing.T) {
 				m.On("GetNTransactions", 5).Return([]models.Transaction{}, nil).Once()
 			},
 		},
+		{
+			name:         "Транзакции кошельков пользователя",
+			countParam:   "5",
+			user:         &models.User{ID: "u1"},
+			expectedCode: http.StatusOK,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Data:   []models.Transaction{{From: "a", To: "b", Amount: 1}},
+			},
+			mockSetup: func(m *mocks.TransactionsReceiver) {
+				m.On("UserTransactions", mock.Anything, "u1", 5).
+					Return([]models.Transaction{{From: "a", To: "b", Amount: 1}}, nil).Once()
+			},
+		},
 		{
 			name:         "Нулевое значение",
 			countParam:   "0",
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  storage.ErrInvalidRequest.Error(),
+				Status:    response.StatusError,
+				Error:     storage.ErrInvalidRequest.Error(),
+				ErrorCode: storage.CodeInvalidRequest,
 			},
 			mockSetup: func(m *mocks.TransactionsReceiver) {
 				m.On("GetNTransactions", 0).Return(nil, storage.ErrInvalidRequest).Once()
@@ -55,8 +73,9 @@ func TestGetLastHandler(t *testing.T) {
 			countParam:   "-52",
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  storage.ErrInvalidRequest.Error(),
+				Status:    response.StatusError,
+				Error:     storage.ErrInvalidRequest.Error(),
+				ErrorCode: storage.CodeInvalidRequest,
 			},
 			mockSetup: func(m *mocks.TransactionsReceiver) {
 				m.On("GetNTransactions", -52).Return(nil, storage.ErrInvalidRequest).Once()
@@ -67,8 +86,9 @@ func TestGetLastHandler(t *testing.T) {
 			countParam:   "infotecs))",
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  "Некорректное значение count",
+				Status:    response.StatusError,
+				Error:     "Некорректное значение count",
+				ErrorCode: response.CodeInvalidCount,
 			},
 		},
 		{
@@ -76,8 +96,9 @@ func TestGetLastHandler(t *testing.T) {
 			countParam:   "5",
 			expectedCode: http.StatusInternalServerError,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  "Внутренняя ошибка",
+				Status:    response.StatusError,
+				Error:     "Внутренняя ошибка",
+				ErrorCode: response.CodeInternal,
 			},
 			mockSetup: func(m *mocks.TransactionsReceiver) {
 				m.On("GetNTransactions", 5).Return(nil, errors.New("unexpected error")).Once()
@@ -101,6 +122,9 @@ func TestGetLastHandler(t *testing.T) {
 				nil,
 			)
 			require.NoError(t, err)
+			if tc.user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), *tc.user))
+			}
 
 			rr := httptest.NewRecorder()
 
@@ -114,6 +138,7 @@ func TestGetLastHandler(t *testing.T) {
 
 			require.Equal(t, tc.expectedResp.Status, resp.Status)
 			require.Equal(t, tc.expectedResp.Error, resp.Error)
+			require.Equal(t, tc.expectedResp.ErrorCode, resp.ErrorCode)
 
 			if tc.expectedCode == http.StatusOK {
 				jsonData, err := json.Marshal(resp.Data)
@@ -122,6 +147,7 @@ func TestGetLastHandler(t *testing.T) {
 				var transactions []models.Transaction
 				err = json.Unmarshal(jsonData, &transactions)
 				require.NoError(t, err)
+				require.Equal(t, tc.expectedResp.Data, transactions)
 			}
 
 			if tc.mockSetup != nil {
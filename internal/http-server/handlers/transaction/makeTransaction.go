@@ -2,8 +2,10 @@
 package transaction
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/etag"
 	"infotecsTest/internal/lib/api/response"
 	"infotecsTest/internal/lib/logger/sl"
 	"infotecsTest/internal/models"
@@ -11,19 +13,46 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 )
 
+// busyRetryAfter - пауза в секундах перед повтором перевода, не выполненного из-за занятой базы.
+const busyRetryAfter = 1
+
 // TransactionMaker определяет интерфейс для выполнения транзакций.
 // Генерирует моки через go:generate.
 type TransactionMaker interface {
 	AddTransaction(from, to string, amount float64) error
+	AddTransactionIfVersion(from, to string, amount float64, version int64) error
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=TransactionMaker --dir=. --output=./mocks --filename=mock_TransactionMaker
 
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=AddressResolver --dir=. --output=./mocks --filename=mock_AddressResolver
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Authorizer определяет интерфейс проверки прав пользователя на кошелек.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Authorizer --dir=. --output=./mocks --filename=mock_Authorizer
+type Authorizer interface {
+	AuthorizeWallet(ctx context.Context, address string) error
+}
+
 // Send создает HTTP-обработчик для выполнения денежных переводов.
-// Принимает JSON с данными транзакции.
-func Send(log *slog.Logger, maker TransactionMaker) http.HandlerFunc {
+// Принимает JSON с данными транзакции. Отправитель и получатель задаются адресом
+// или именем кошелька (@alice) и приводятся к каноническому адресу; для адреса
+// с неверной контрольной суммой и неизвестного имени возвращается 400.
+// Списывать средства можно только с кошелька аутентифицированного пользователя, иначе возвращается 403.
+// Заголовок If-Match с версией кошелька отправителя делает перевод условным:
+// если кошелек изменился, возвращается 412. Если база осталась занятой после всех повторов,
+// возвращается 503 с заголовком Retry-After.
+func Send(log *slog.Logger, maker TransactionMaker, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.transaction.Send"
 
@@ -34,28 +63,59 @@ func Send(log *slog.Logger, maker TransactionMaker) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
-			render.JSON(w, r, response.Error(r, "Требуется JSON-объект", http.StatusBadRequest))
+			response.Render(w, r, response.Fail(r, response.CodeEmptyBody, http.StatusBadRequest, nil))
 			return
 		}
 		if err != nil {
 			log.Error("failed to decode request body", sl.Err(err))
-			render.JSON(w, r, response.Error(r, "Некорректный JSON-объект", http.StatusBadRequest))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidJSON, http.StatusBadRequest, nil))
 			return
 		}
 
-		if err = maker.AddTransaction(req.From, req.To, req.Amount); err != nil {
+		var version int64
+		if header := r.Header.Get("If-Match"); header != "" {
+			version, err = etag.ParseIfMatch(header)
+			if err != nil {
+				log.Error("invalid If-Match header", slog.String("if_match", header))
+				response.Render(w, r, response.Fail(r, response.CodeInvalidETag, http.StatusBadRequest, nil))
+				return
+			}
+		}
+
+		if req.From, err = resolver.ResolveAddress(req.From); err == nil {
+			req.To, err = resolver.ResolveAddress(req.To)
+		}
+		if err == nil {
+			err = authorizer.AuthorizeWallet(r.Context(), req.From)
+		}
+		switch {
+		case err != nil:
+		case version != 0:
+			err = maker.AddTransactionIfVersion(req.From, req.To, req.Amount, version)
+		default:
+			err = maker.AddTransaction(req.From, req.To, req.Amount)
+		}
+		if err != nil {
 			log.Error("failed to make transaction", sl.Err(err))
 			switch {
-			case errors.Is(err, storage.ErrWalletNotFound):
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
-			case errors.Is(err, storage.ErrIncorrectAmount):
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
-			case errors.Is(err, storage.ErrInsufficientFunds):
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
-			case errors.Is(err, storage.ErrAddressesEqual):
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
+			case errors.Is(err, storage.ErrVersionMismatch):
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusPreconditionFailed, storage.Details(err)))
+			case errors.Is(err, storage.ErrForbidden):
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusForbidden, nil))
+			case errors.Is(err, storage.ErrBusy):
+				// Перевод не выполнен: база оставалась заблокированной после всех повторов
+				w.Header().Set("Retry-After", strconv.Itoa(busyRetryAfter))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusServiceUnavailable, nil))
+			case errors.Is(err, storage.ErrWalletNotFound),
+				errors.Is(err, storage.ErrIncorrectAmount),
+				errors.Is(err, storage.ErrInsufficientFunds),
+				errors.Is(err, storage.ErrAddressesEqual),
+				errors.Is(err, storage.ErrMalformedAddress),
+				errors.Is(err, storage.ErrInvalidHandle),
+				errors.Is(err, storage.ErrHandleNotFound):
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, storage.Details(err)))
 			default:
-				render.JSON(w, r, response.Error(r, "Внутренняя ошибка", http.StatusInternalServerError))
+				response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
 			}
 			return
 		}
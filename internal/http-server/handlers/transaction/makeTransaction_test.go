@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
 	"infotecsTest/internal/http-server/handlers/transaction"
 	"infotecsTest/internal/http-server/handlers/transaction/mocks"
 	"infotecsTest/internal/lib/api/response"
@@ -20,11 +22,14 @@ import (
 func TestSendHandler(t *testing.T) {
 	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	cases := []struct {
-		name         string
-		requestBody  string
-		expectedCode int
-		expectedResp response.Response
-		mockSetup    func(*mocks.TransactionMaker)
+		name           string
+		requestBody    string
+		acceptLanguage string
+		ifMatch        string
+		expectedCode   int
+		expectedResp   response.Response
+		retryAfter     string // Ожидаемый заголовок Retry-After
+		mockSetup      func(*mocks.TransactionMaker)
 	}{
 		{
 			name: "Успешный перевод",
@@ -50,9 +55,10 @@ func TestSendHandler(t *testing.T) {
 			requestBody:  ``,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Требуется JSON-объект",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Требуется JSON-объект",
+				ErrorCode: response.CodeEmptyBody,
 			},
 		},
 		{
@@ -64,9 +70,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Некорректный JSON-объект",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Некорректный JSON-объект",
+				ErrorCode: response.CodeInvalidJSON,
 			},
 		},
 		{
@@ -78,9 +85,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Кошелек не найден",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Кошелек не найден",
+				ErrorCode: storage.CodeWalletNotFound,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "", "addr2", 100.0).
@@ -97,9 +105,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Кошелек не найден",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Кошелек не найден",
+				ErrorCode: storage.CodeWalletNotFound,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "addr1", "", 100.0).
@@ -116,9 +125,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Сумма перевода должна быть больше нуля",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Сумма перевода должна быть больше нуля",
+				ErrorCode: storage.CodeIncorrectAmount,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "addr1", "addr2", -100.0).
@@ -135,9 +145,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Адреса одинаковые",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Адреса одинаковые",
+				ErrorCode: storage.CodeAddressesEqual,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "addr1", "addr1", 100.0).
@@ -154,9 +165,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusBadRequest,
-				Error:  "Недостаточно средств",
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Недостаточно средств",
+				ErrorCode: storage.CodeInsufficientFunds,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "addr1", "addr2", 100.0).
@@ -164,6 +176,106 @@ func TestSendHandler(t *testing.T) {
 					Once()
 			},
 		},
+		{
+			name: "Недостаточно средств на английском",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			acceptLanguage: "en-US,en;q=0.9",
+			expectedCode:   http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Insufficient funds",
+				ErrorCode: storage.CodeInsufficientFunds,
+				Details:   map[string]any{"available": 50.0, "requested": 100.0},
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransaction", "addr1", "addr2", 100.0).
+					Return(&storage.InsufficientFundsError{Available: 50, Requested: 100}).
+					Once()
+			},
+		},
+		{
+			name: "Условный перевод",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			ifMatch:      `"7"`,
+			expectedCode: http.StatusOK,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Code:   http.StatusOK,
+				Data:   "Платеж прошел успешно",
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransactionIfVersion", "addr1", "addr2", 100.0, int64(7)).
+					Return(nil).
+					Once()
+			},
+		},
+		{
+			name: "Условный перевод с любой версией",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			ifMatch:      "*",
+			expectedCode: http.StatusOK,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Code:   http.StatusOK,
+				Data:   "Платеж прошел успешно",
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransaction", "addr1", "addr2", 100.0).
+					Return(nil).
+					Once()
+			},
+		},
+		{
+			name: "Кошелек изменился",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			ifMatch:      `"7"`,
+			expectedCode: http.StatusPreconditionFailed,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusPreconditionFailed,
+				Error:     "Кошелек изменился, запросите баланс повторно",
+				ErrorCode: storage.CodeVersionMismatch,
+				Details:   map[string]any{"expected_version": 7.0, "current_version": 9.0},
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransactionIfVersion", "addr1", "addr2", 100.0, int64(7)).
+					Return(&storage.VersionMismatchError{Expected: 7, Current: 9}).
+					Once()
+			},
+		},
+		{
+			name: "Некорректный If-Match",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			ifMatch:      "7",
+			expectedCode: http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Некорректный заголовок If-Match: ожидается версия кошелька в кавычках",
+				ErrorCode: response.CodeInvalidETag,
+			},
+		},
 		{
 			name: "Внутренняя ошибка",
 			requestBody: `{
@@ -173,9 +285,10 @@ func TestSendHandler(t *testing.T) {
 			}`,
 			expectedCode: http.StatusInternalServerError,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Code:   http.StatusInternalServerError,
-				Error:  "Внутренняя ошибка",
+				Status:    response.StatusError,
+				Code:      http.StatusInternalServerError,
+				Error:     "Внутренняя ошибка",
+				ErrorCode: response.CodeInternal,
 			},
 			mockSetup: func(m *mocks.TransactionMaker) {
 				m.On("AddTransaction", "addr1", "addr2", 100.0).
@@ -183,6 +296,110 @@ func TestSendHandler(t *testing.T) {
 					Once()
 			},
 		},
+		{
+			name: "База занята после всех повторов",
+			requestBody: `{
+				"from": "addr1",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusServiceUnavailable,
+				Error:     "Хранилище занято, повторите запрос позже",
+				ErrorCode: storage.CodeBusy,
+			},
+			retryAfter: "1",
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransaction", "addr1", "addr2", 100.0).
+					Return(fmt.Errorf("storage.sqlite.AddTransaction: %w: database is locked", storage.ErrBusy)).
+					Once()
+			},
+		},
+		{
+			name: "Опечатка в адресе получателя",
+			requestBody: `{
+				"from": "addr1",
+				"to": "wlt1typo",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Некорректный адрес кошелька: проверьте адрес на опечатки",
+				ErrorCode: storage.CodeMalformedAddress,
+			},
+		},
+		{
+			name: "Адрес прежнего формата",
+			requestBody: `{
+				"from": "1077f2e5-cfd1-4a02-86cc-96fc7337d18c",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusOK,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Code:   http.StatusOK,
+				Data:   "Платеж прошел успешно",
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransaction", "wlt1canonical", "addr2", 100.0).
+					Return(nil).
+					Once()
+			},
+		},
+		{
+			name: "Перевод по имени кошелька",
+			requestBody: `{
+				"from": "addr1",
+				"to": "@alice",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusOK,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Code:   http.StatusOK,
+				Data:   "Платеж прошел успешно",
+			},
+			mockSetup: func(m *mocks.TransactionMaker) {
+				m.On("AddTransaction", "addr1", "wlt1alice", 100.0).
+					Return(nil).
+					Once()
+			},
+		},
+		{
+			name: "Неизвестное имя получателя",
+			requestBody: `{
+				"from": "addr1",
+				"to": "@ghost",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusBadRequest,
+				Error:     "Имя кошелька не найдено",
+				ErrorCode: storage.CodeHandleNotFound,
+			},
+		},
+		{
+			name: "Перевод с кошелька другого пользователя",
+			requestBody: `{
+				"from": "wlt1foreign",
+				"to": "addr2",
+				"amount": 100.0
+			}`,
+			expectedCode: http.StatusForbidden,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Code:      http.StatusForbidden,
+				Error:     "Нет доступа к кошельку: кошелек принадлежит другому пользователю",
+				ErrorCode: storage.CodeForbidden,
+			},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -192,7 +409,7 @@ func TestSendHandler(t *testing.T) {
 				tc.mockSetup(mockTransactionMaker)
 			}
 
-			handler := transaction.Send(testLogger, mockTransactionMaker)
+			handler := transaction.Send(testLogger, mockTransactionMaker, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t)))
 
 			req, err := http.NewRequest(
 				http.MethodPost,
@@ -200,11 +417,18 @@ func TestSendHandler(t *testing.T) {
 				bytes.NewReader([]byte(tc.requestBody)),
 			)
 			require.NoError(t, err)
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
 			require.Equal(t, tc.expectedCode, rr.Code)
+			require.Equal(t, tc.retryAfter, rr.Header().Get("Retry-After"))
 
 			var resp response.Response
 			err = json.Unmarshal(rr.Body.Bytes(), &resp)
@@ -213,6 +437,8 @@ func TestSendHandler(t *testing.T) {
 			require.Equal(t, tc.expectedResp.Status, resp.Status)
 			require.Equal(t, tc.expectedResp.Code, resp.Code)
 			require.Equal(t, tc.expectedResp.Error, resp.Error)
+			require.Equal(t, tc.expectedResp.ErrorCode, resp.ErrorCode)
+			require.Equal(t, tc.expectedResp.Details, resp.Details)
 			require.Equal(t, tc.expectedResp.Data, resp.Data)
 
 			if tc.mockSetup != nil {
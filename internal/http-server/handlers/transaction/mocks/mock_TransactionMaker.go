@@ -27,6 +27,24 @@ func (_m *TransactionMaker) AddTransaction(from string, to string, amount float6
 	return r0
 }
 
+// AddTransactionIfVersion provides a mock function with given fields: from, to, amount, version
+func (_m *TransactionMaker) AddTransactionIfVersion(from string, to string, amount float64, version int64) error {
+	ret := _m.Called(from, to, amount, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTransactionIfVersion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, float64, int64) error); ok {
+		r0 = rf(from, to, amount, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewTransactionMaker creates a new instance of TransactionMaker. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewTransactionMaker(t interface {
@@ -3,9 +3,11 @@
 package mocks
 
 import (
-	models "infotecsTest/internal/models"
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
+
+	models "infotecsTest/internal/models"
 )
 
 // TransactionsReceiver is an autogenerated mock type for the TransactionsReceiver type
@@ -43,6 +45,36 @@ func (_m *TransactionsReceiver) GetNTransactions(N int) ([]models.Transaction, e
 	return r0, r1
 }
 
+// UserTransactions provides a mock function with given fields: ctx, userID, N
+func (_m *TransactionsReceiver) UserTransactions(ctx context.Context, userID string, N int) ([]models.Transaction, error) {
+	ret := _m.Called(ctx, userID, N)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UserTransactions")
+	}
+
+	var r0 []models.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.Transaction, error)); ok {
+		return rf(ctx, userID, N)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.Transaction); ok {
+		r0 = rf(ctx, userID, N)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, userID, N)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewTransactionsReceiver creates a new instance of TransactionsReceiver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewTransactionsReceiver(t interface {
@@ -0,0 +1,293 @@
+// Package user содержит обработчики HTTP-запросов для пользователей и их кошельков:
+// управление пользователями на служебном сервере и профиль текущего пользователя в API.
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Registry определяет интерфейс хранилища пользователей, их ключей и кошельков.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Registry --dir=. --output=./mocks --filename=mock_Registry
+type Registry interface {
+	CreateUser(ctx context.Context, req models.UserRequest) (models.User, error)
+	User(ctx context.Context, id string) (models.User, error)
+	IssueKey(ctx context.Context, userID string) (models.Credential, error)
+	RevokeKeys(ctx context.Context, userID string) (int, error)
+	AssignWallet(ctx context.Context, userID, address string) error
+	UnassignWallet(ctx context.Context, userID, address string) error
+	UserWallets(ctx context.Context, userID string) ([]string, error)
+}
+
+// BalanceReceiver определяет интерфейс для получения баланса из хранилища.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=BalanceReceiver --dir=. --output=./mocks --filename=mock_BalanceReceiver
+type BalanceReceiver interface {
+	GetWalletBalance(address string) (models.Wallet, error)
+}
+
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=AddressResolver --dir=. --output=./mocks --filename=mock_AddressResolver
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Create создает HTTP-обработчик регистрации пользователя.
+// Принимает JSON с именем и адресом почты, возвращает 201 Created с профилем.
+func Create(log *slog.Logger, registry Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.Create"
+
+		log := log.With("op", op)
+
+		var req models.UserRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			response.Render(w, r, response.Fail(r, response.CodeEmptyBody, http.StatusBadRequest, nil))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidJSON, http.StatusBadRequest, nil))
+			return
+		}
+
+		user, err := registry.CreateUser(r.Context(), req)
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		log.Info("user created", slog.String("user", user.ID))
+
+		resp := response.Success(user)
+		resp.Code = http.StatusCreated
+		w.Header().Set("Location", "/users/"+user.ID)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, resp)
+	}
+}
+
+// Get создает HTTP-обработчик получения профиля пользователя.
+func Get(log *slog.Logger, registry Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.Get"
+
+		log := log.With("op", op)
+
+		user, err := registry.User(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+		render.JSON(w, r, response.Success(user))
+	}
+}
+
+// Wallets создает HTTP-обработчик списка кошельков пользователя с балансами и их суммой.
+func Wallets(log *slog.Logger, registry Registry, receiver BalanceReceiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.Wallets"
+
+		log := log.With("op", op)
+
+		renderWallets(w, r, log, registry, receiver, chi.URLParam(r, "id"))
+	}
+}
+
+// IssueKey создает HTTP-обработчик выпуска ключа API пользователя.
+// Возвращает 201 Created с ключом; повторно получить ключ нельзя.
+func IssueKey(log *slog.Logger, registry Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.IssueKey"
+
+		log := log.With("op", op)
+
+		cred, err := registry.IssueKey(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		log.Info("api key issued", slog.String("user", cred.UserID))
+
+		resp := response.Success(cred)
+		resp.Code = http.StatusCreated
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, resp)
+	}
+}
+
+// RevokeKeys создает HTTP-обработчик отзыва всех ключей API пользователя.
+// Возвращает число отозванных ключей.
+func RevokeKeys(log *slog.Logger, registry Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.RevokeKeys"
+
+		log := log.With("op", op)
+
+		id := chi.URLParam(r, "id")
+		n, err := registry.RevokeKeys(r.Context(), id)
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		log.Info("api keys revoked", slog.String("user", id), slog.Int("revoked", n))
+		render.JSON(w, r, response.Success(models.RevokedKeys{UserID: id, Revoked: n}))
+	}
+}
+
+// AssignWallet создает HTTP-обработчик передачи кошелька во владение пользователю.
+// Адрес или имя кошелька приводится к каноническому виду; для кошелька другого пользователя возвращает 409.
+// Возвращает кошельки пользователя после изменения.
+func AssignWallet(log *slog.Logger, registry Registry, resolver AddressResolver, receiver BalanceReceiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.AssignWallet"
+
+		log := log.With("op", op)
+
+		id := chi.URLParam(r, "id")
+		address, err := resolver.ResolveAddress(chi.URLParam(r, "address"))
+		if err == nil {
+			err = registry.AssignWallet(r.Context(), id, address)
+		}
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		log.Info("wallet assigned", slog.String("user", id), slog.String("address", address))
+		renderWallets(w, r, log, registry, receiver, id)
+	}
+}
+
+// UnassignWallet создает HTTP-обработчик освобождения кошелька пользователя.
+// Кошелек остается без владельца; возвращает кошельки пользователя после изменения.
+func UnassignWallet(log *slog.Logger, registry Registry, resolver AddressResolver, receiver BalanceReceiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.UnassignWallet"
+
+		log := log.With("op", op)
+
+		id := chi.URLParam(r, "id")
+		address, err := resolver.ResolveAddress(chi.URLParam(r, "address"))
+		if err == nil {
+			err = registry.UnassignWallet(r.Context(), id, address)
+		}
+		if err != nil {
+			renderError(w, r, log, err)
+			return
+		}
+
+		log.Info("wallet unassigned", slog.String("user", id), slog.String("address", address))
+		renderWallets(w, r, log, registry, receiver, id)
+	}
+}
+
+// Me создает HTTP-обработчик профиля аутентифицированного пользователя.
+// Без аутентификации возвращает 401.
+func Me(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.Me"
+
+		log := log.With("op", op)
+
+		user, ok := currentUser(w, r, log)
+		if !ok {
+			return
+		}
+		render.JSON(w, r, response.Success(user))
+	}
+}
+
+// MyWallets создает HTTP-обработчик кошельков аутентифицированного пользователя
+// с текущими балансами и их суммой. Без аутентификации возвращает 401.
+func MyWallets(log *slog.Logger, registry Registry, receiver BalanceReceiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.MyWallets"
+
+		log := log.With("op", op)
+
+		user, ok := currentUser(w, r, log)
+		if !ok {
+			return
+		}
+		renderWallets(w, r, log, registry, receiver, user.ID)
+	}
+}
+
+// currentUser возвращает пользователя из контекста запроса.
+// Без аутентификации отправляет 401 и возвращает false.
+func currentUser(w http.ResponseWriter, r *http.Request, log *slog.Logger) (models.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		log.Warn("request not authenticated")
+		response.Render(w, r, response.Fail(r, response.CodeUnauthorized, http.StatusUnauthorized, nil))
+	}
+	return user, ok
+}
+
+// renderWallets отвечает кошельками пользователя с балансами и их суммой.
+// Баланс и версия берутся из того же источника, что и в GetBalance.
+func renderWallets(w http.ResponseWriter, r *http.Request, log *slog.Logger, registry Registry, receiver BalanceReceiver, userID string) {
+	addresses, err := registry.UserWallets(r.Context(), userID)
+	if err != nil {
+		renderError(w, r, log, err)
+		return
+	}
+
+	res := models.UserWallets{UserID: userID, Wallets: make([]models.Wallet, 0, len(addresses))}
+	for _, address := range addresses {
+		wallet, err := receiver.GetWalletBalance(address)
+		if errors.Is(err, storage.ErrWalletNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Error("unable to get balance", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+		res.Wallets = append(res.Wallets, wallet)
+		res.TotalBalance += wallet.Balance
+	}
+
+	render.JSON(w, r, response.Success(res))
+}
+
+// renderError отвечает ошибкой операции с пользователем или его кошельком.
+func renderError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrWalletOwned):
+		log.Warn("wallet is owned by another user", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusConflict, nil))
+	case errors.Is(err, storage.ErrUserNotFound),
+		errors.Is(err, storage.ErrInvalidUser),
+		errors.Is(err, storage.ErrWalletNotFound),
+		errors.Is(err, storage.ErrMalformedAddress),
+		errors.Is(err, storage.ErrInvalidHandle),
+		errors.Is(err, storage.ErrHandleNotFound):
+		log.Warn("invalid user request", sl.Err(err))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+	default:
+		log.Error("failed to process user request", sl.Err(err))
+		response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+	}
+}
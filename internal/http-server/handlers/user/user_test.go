@@ -0,0 +1,440 @@
+package user_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
+	userHandlers "infotecsTest/internal/http-server/handlers/user"
+	"infotecsTest/internal/http-server/handlers/user/mocks"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var alice = models.User{
+	ID:        "u1",
+	Name:      "Alice",
+	Email:     "alice@example.com",
+	CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// newReceiver возвращает мок с балансами кошельков addr1 и addr2.
+func newReceiver(t *testing.T) *mocks.BalanceReceiver {
+	receiver := mocks.NewBalanceReceiver(t)
+	receiver.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 10, Version: 1}, nil).Maybe()
+	receiver.On("GetWalletBalance", "addr2").Return(models.Wallet{Address: "addr2", Balance: 2.5, Version: 3}, nil).Maybe()
+	return receiver
+}
+
+// decode разбирает ответ с данными типа T.
+func decode[T any](t *testing.T, rr *httptest.ResponseRecorder) (response.Response, T) {
+	t.Helper()
+
+	var resp struct {
+		response.Response
+		Data T `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return resp.Response, resp.Data
+}
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name          string
+		requestBody   string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Пользователь создан",
+			requestBody:  `{"name": "Alice", "email": "alice@example.com"}`,
+			expectedCode: http.StatusCreated,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("CreateUser", mock.Anything, models.UserRequest{Name: "Alice", Email: "alice@example.com"}).Return(alice, nil).Once()
+			},
+		},
+		{
+			name:          "Пустое тело запроса",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeEmptyBody,
+		},
+		{
+			name:          "Невалидный JSON",
+			requestBody:   `{"name":`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidJSON,
+		},
+		{
+			name:          "Пустое имя",
+			requestBody:   `{"name": " "}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeInvalidUser,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("CreateUser", mock.Anything, models.UserRequest{Name: " "}).Return(models.User{}, storage.ErrInvalidUser).Once()
+			},
+		},
+		{
+			name:          "Внутренняя ошибка",
+			requestBody:   `{"name": "Alice"}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("CreateUser", mock.Anything, models.UserRequest{Name: "Alice"}).Return(models.User{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(registry)
+			}
+
+			router := chi.NewRouter()
+			router.Post("/users", userHandlers.Create(testLogger, registry))
+
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			resp, user := decode[models.User](t, rr)
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, alice, user)
+				require.Equal(t, "/users/u1", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestGetHandler(t *testing.T) {
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedError string
+		mockErr       error
+	}{
+		{name: "Профиль пользователя", expectedCode: http.StatusOK},
+		{name: "Пользователь не найден", expectedCode: http.StatusBadRequest, expectedError: storage.CodeUserNotFound, mockErr: storage.ErrUserNotFound},
+		{name: "Внутренняя ошибка", expectedCode: http.StatusInternalServerError, expectedError: response.CodeInternal, mockErr: errors.New("db error")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			if tc.mockErr != nil {
+				registry.On("User", mock.Anything, "u1").Return(models.User{}, tc.mockErr).Once()
+			} else {
+				registry.On("User", mock.Anything, "u1").Return(alice, nil).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Get("/users/{id}", userHandlers.Get(testLogger, registry))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/u1", nil))
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			resp, user := decode[models.User](t, rr)
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, alice, user)
+			}
+		})
+	}
+}
+
+func TestWalletsHandler(t *testing.T) {
+	cases := []struct {
+		name          string
+		expectedCode  int
+		expectedError string
+		expected      models.UserWallets
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Кошельки с суммарным балансом",
+			expectedCode: http.StatusOK,
+			expected: models.UserWallets{
+				UserID: "u1",
+				Wallets: []models.Wallet{
+					{Address: "addr1", Balance: 10, Version: 1},
+					{Address: "addr2", Balance: 2.5, Version: 3},
+				},
+				TotalBalance: 12.5,
+			},
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UserWallets", mock.Anything, "u1").Return([]string{"addr1", "addr2"}, nil).Once()
+			},
+		},
+		{
+			name:         "У пользователя нет кошельков",
+			expectedCode: http.StatusOK,
+			expected:     models.UserWallets{UserID: "u1", Wallets: []models.Wallet{}},
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UserWallets", mock.Anything, "u1").Return(nil, nil).Once()
+			},
+		},
+		{
+			name:          "Пользователь не найден",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeUserNotFound,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UserWallets", mock.Anything, "u1").Return(nil, storage.ErrUserNotFound).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			tc.mockSetup(registry)
+
+			router := chi.NewRouter()
+			router.Get("/users/{id}/wallets", userHandlers.Wallets(testLogger, registry, newReceiver(t)))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/u1/wallets", nil))
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			resp, wallets := decode[models.UserWallets](t, rr)
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, tc.expected, wallets)
+			}
+		})
+	}
+}
+
+func TestKeysHandlers(t *testing.T) {
+	t.Run("Ключ выпущен", func(t *testing.T) {
+		cred := models.Credential{UserID: "u1", Key: "psk_0123", CreatedAt: alice.CreatedAt}
+		registry := mocks.NewRegistry(t)
+		registry.On("IssueKey", mock.Anything, "u1").Return(cred, nil).Once()
+
+		router := chi.NewRouter()
+		router.Post("/users/{id}/keys", userHandlers.IssueKey(testLogger, registry))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users/u1/keys", nil))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		_, got := decode[models.Credential](t, rr)
+		require.Equal(t, cred, got)
+	})
+
+	t.Run("Ключ неизвестному пользователю", func(t *testing.T) {
+		registry := mocks.NewRegistry(t)
+		registry.On("IssueKey", mock.Anything, "u1").Return(models.Credential{}, storage.ErrUserNotFound).Once()
+
+		router := chi.NewRouter()
+		router.Post("/users/{id}/keys", userHandlers.IssueKey(testLogger, registry))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users/u1/keys", nil))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		resp, _ := decode[models.Credential](t, rr)
+		require.Equal(t, storage.CodeUserNotFound, resp.ErrorCode)
+	})
+
+	t.Run("Ключи отозваны", func(t *testing.T) {
+		registry := mocks.NewRegistry(t)
+		registry.On("RevokeKeys", mock.Anything, "u1").Return(2, nil).Once()
+
+		router := chi.NewRouter()
+		router.Delete("/users/{id}/keys", userHandlers.RevokeKeys(testLogger, registry))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/users/u1/keys", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		_, got := decode[models.RevokedKeys](t, rr)
+		require.Equal(t, models.RevokedKeys{UserID: "u1", Revoked: 2}, got)
+	})
+}
+
+func TestAssignWalletHandlers(t *testing.T) {
+	cases := []struct {
+		name          string
+		method        string
+		address       string
+		expectedCode  int
+		expectedError string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Кошелек передан пользователю",
+			method:       http.MethodPut,
+			address:      "addr1",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("AssignWallet", mock.Anything, "u1", "addr1").Return(nil).Once()
+				m.On("UserWallets", mock.Anything, "u1").Return([]string{"addr1"}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек передан по имени",
+			method:       http.MethodPut,
+			address:      "@alice",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("AssignWallet", mock.Anything, "u1", "wlt1alice").Return(nil).Once()
+				m.On("UserWallets", mock.Anything, "u1").Return([]string{"addr1"}, nil).Once()
+			},
+		},
+		{
+			name:          "Кошелек принадлежит другому пользователю",
+			method:        http.MethodPut,
+			address:       "addr1",
+			expectedCode:  http.StatusConflict,
+			expectedError: storage.CodeWalletOwned,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("AssignWallet", mock.Anything, "u1", "addr1").Return(storage.ErrWalletOwned).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе",
+			method:        http.MethodPut,
+			address:       "wlt1typo",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+			mockSetup:     func(m *mocks.Registry) {},
+		},
+		{
+			name:         "Кошелек освобожден",
+			method:       http.MethodDelete,
+			address:      "addr2",
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UnassignWallet", mock.Anything, "u1", "addr2").Return(nil).Once()
+				m.On("UserWallets", mock.Anything, "u1").Return([]string{"addr1"}, nil).Once()
+			},
+		},
+		{
+			name:          "Освобождение чужого кошелька",
+			method:        http.MethodDelete,
+			address:       "addr2",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UnassignWallet", mock.Anything, "u1", "addr2").Return(storage.ErrWalletNotFound).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			tc.mockSetup(registry)
+
+			router := chi.NewRouter()
+			router.Put("/users/{id}/wallets/{address}", userHandlers.AssignWallet(testLogger, registry, handlerstest.Resolver(mocks.NewAddressResolver(t)), newReceiver(t)))
+			router.Delete("/users/{id}/wallets/{address}", userHandlers.UnassignWallet(testLogger, registry, handlerstest.Resolver(mocks.NewAddressResolver(t)), newReceiver(t)))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, "/users/u1/wallets/"+tc.address, nil))
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			resp, wallets := decode[models.UserWallets](t, rr)
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, models.UserWallets{
+					UserID:       "u1",
+					Wallets:      []models.Wallet{{Address: "addr1", Balance: 10, Version: 1}},
+					TotalBalance: 10,
+				}, wallets)
+			}
+		})
+	}
+}
+
+func TestMeHandlers(t *testing.T) {
+	cases := []struct {
+		name          string
+		path          string
+		user          *models.User
+		expectedCode  int
+		expectedError string
+		expectedBody  string
+		mockSetup     func(m *mocks.Registry)
+	}{
+		{
+			name:         "Профиль текущего пользователя",
+			path:         "/api/me",
+			user:         &alice,
+			expectedCode: http.StatusOK,
+			expectedBody: `"name":"Alice"`,
+		},
+		{
+			name:          "Профиль без аутентификации",
+			path:          "/api/me",
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: response.CodeUnauthorized,
+		},
+		{
+			name:         "Кошельки текущего пользователя",
+			path:         "/api/me/wallets",
+			user:         &alice,
+			expectedCode: http.StatusOK,
+			expectedBody: `"total_balance":12.5`,
+			mockSetup: func(m *mocks.Registry) {
+				m.On("UserWallets", mock.Anything, "u1").Return([]string{"addr1", "addr2"}, nil).Once()
+			},
+		},
+		{
+			name:          "Кошельки без аутентификации",
+			path:          "/api/me/wallets",
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: response.CodeUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := mocks.NewRegistry(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(registry)
+			}
+
+			router := chi.NewRouter()
+			router.Get("/api/me", userHandlers.Me(testLogger))
+			router.Get("/api/me/wallets", userHandlers.MyWallets(testLogger, registry, newReceiver(t)))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), *tc.user))
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedError != "" {
+				resp, _ := decode[any](t, rr)
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				return
+			}
+			require.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+}
@@ -2,9 +2,11 @@
 package wallet
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/etag"
 	"infotecsTest/internal/lib/api/response"
 	"infotecsTest/internal/lib/logger/sl"
 	"infotecsTest/internal/models"
@@ -19,29 +21,99 @@ type BalanceReceiver interface {
 	GetWalletBalance(address string) (models.Wallet, error)
 }
 
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=AddressResolver --dir=. --output=./mocks --filename=mock_AddressResolver
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Authorizer определяет интерфейс проверки прав пользователя на кошелек.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=Authorizer --dir=. --output=./mocks --filename=mock_Authorizer
+type Authorizer interface {
+	AuthorizeWallet(ctx context.Context, address string) error
+}
+
 // GetBalance создает HTTP-обработчик для получения баланса кошелька.
-// Извлекает адрес из URL-параметров, обрабатывает ошибки хранилища,
+// Извлекает адрес или имя кошелька (@alice) из URL-параметров и приводит его к каноническому виду;
+// для адреса с неверной контрольной суммой и неизвестного имени возвращает 400, для чужого кошелька - 403.
+// Обрабатывает ошибки хранилища,
 // возвращает баланс в формате JSON или соответствующие HTTP-ошибки.
-func GetBalance(log *slog.Logger, receiver BalanceReceiver) http.HandlerFunc {
+// Версия кошелька передается в заголовке ETag; при совпадении с If-None-Match
+// возвращается 304 без тела.
+func GetBalance(log *slog.Logger, receiver BalanceReceiver, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.wallet.GetBalance"
 
 		log := log.With("op", op)
 
-		address := chi.URLParam(r, "address")
+		address, ok := resolveAddress(w, r, log, resolver, chi.URLParam(r, "address"))
+		if !ok || !authorizeWallet(w, r, log, authorizer, address) {
+			return
+		}
 
 		wallet, err := receiver.GetWalletBalance(address)
 		if err != nil {
 			if errors.Is(err, storage.ErrWalletNotFound) {
 				log.Error("wallet not found", sl.Err(err))
-				render.JSON(w, r, response.Error(r, err.Error(), http.StatusBadRequest))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
 				return
 			}
 			log.Error("unable to get balance", sl.Err(err))
-			render.JSON(w, r, response.Error(r, "Внутренняя ошибка", http.StatusInternalServerError))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+
+		w.Header().Set("ETag", etag.Format(wallet.Version))
+		if etag.Match(r.Header.Get("If-None-Match"), wallet.Version) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
 
 		render.JSON(w, r, response.Success(wallet))
 	}
 }
+
+// resolveAddress приводит адрес из запроса к каноническому виду.
+// При ошибке отправляет ответ и возвращает false.
+func resolveAddress(w http.ResponseWriter, r *http.Request, log *slog.Logger, resolver AddressResolver, input string) (string, bool) {
+	address, err := resolver.ResolveAddress(input)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrMalformedAddress):
+			log.Warn("malformed wallet address", slog.String("address", input))
+			response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+		case errors.Is(err, storage.ErrInvalidHandle), errors.Is(err, storage.ErrHandleNotFound):
+			log.Warn("unknown wallet handle", slog.String("handle", input), sl.Err(err))
+			response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+		case errors.Is(err, storage.ErrWalletNotFound):
+			log.Error("wallet not found", sl.Err(err))
+			response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+		default:
+			log.Error("unable to resolve address", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+		}
+		return "", false
+	}
+	return address, true
+}
+
+// authorizeWallet проверяет права пользователя на кошелек.
+// При ошибке отправляет ответ и возвращает false.
+func authorizeWallet(w http.ResponseWriter, r *http.Request, log *slog.Logger, authorizer Authorizer, address string) bool {
+	err := authorizer.AuthorizeWallet(r.Context(), address)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrForbidden):
+		log.Warn("wallet access denied", slog.String("address", address))
+		response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusForbidden, nil))
+	default:
+		log.Error("unable to authorize wallet access", sl.Err(err))
+		response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
 	"infotecsTest/internal/http-server/handlers/wallet"
 	"infotecsTest/internal/http-server/handlers/wallet/mocks"
 	"infotecsTest/internal/lib/api/response"
@@ -23,7 +24,9 @@ func TestGetBalanceHandler(t *testing.T) {
 	cases := []struct {
 		name         string
 		address      string
+		ifNoneMatch  string
 		expectedCode int
+		expectedETag string
 		expectedResp response.Response
 		mockSetup    func(receiver *mocks.BalanceReceiver)
 	}{
@@ -31,12 +34,37 @@ func TestGetBalanceHandler(t *testing.T) {
 			name:         "Успешный запрос",
 			address:      "addr1",
 			expectedCode: http.StatusOK,
+			expectedETag: `"3"`,
 			expectedResp: response.Response{
 				Status: response.StatusOK,
-				Data:   models.Wallet{Address: "addr1", Balance: 100},
+				Data:   models.Wallet{Address: "addr1", Balance: 100, Version: 3},
 			},
 			mockSetup: func(m *mocks.BalanceReceiver) {
-				m.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100}, nil).Once()
+				m.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100, Version: 3}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек не изменился",
+			address:      "addr1",
+			ifNoneMatch:  `"3"`,
+			expectedCode: http.StatusNotModified,
+			expectedETag: `"3"`,
+			mockSetup: func(m *mocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100, Version: 3}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек изменился",
+			address:      "addr1",
+			ifNoneMatch:  `"2"`,
+			expectedCode: http.StatusOK,
+			expectedETag: `"3"`,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Data:   models.Wallet{Address: "addr1", Balance: 100, Version: 3},
+			},
+			mockSetup: func(m *mocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100, Version: 3}, nil).Once()
 			},
 		},
 		{
@@ -44,8 +72,9 @@ func TestGetBalanceHandler(t *testing.T) {
 			address:      "not_found_address",
 			expectedCode: http.StatusBadRequest,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  storage.ErrWalletNotFound.Error(),
+				Status:    response.StatusError,
+				Error:     storage.ErrWalletNotFound.Error(),
+				ErrorCode: storage.CodeWalletNotFound,
 			},
 			mockSetup: func(m *mocks.BalanceReceiver) {
 				m.On("GetWalletBalance", "not_found_address").Return(models.Wallet{}, storage.ErrWalletNotFound).Once()
@@ -56,13 +85,70 @@ func TestGetBalanceHandler(t *testing.T) {
 			address:      "addr1",
 			expectedCode: http.StatusInternalServerError,
 			expectedResp: response.Response{
-				Status: response.StatusError,
-				Error:  "Внутренняя ошибка",
+				Status:    response.StatusError,
+				Error:     "Внутренняя ошибка",
+				ErrorCode: response.CodeInternal,
 			},
 			mockSetup: func(m *mocks.BalanceReceiver) {
 				m.On("GetWalletBalance", "addr1").Return(models.Wallet{}, errors.New("unexpected error")).Once()
 			},
 		},
+		{
+			name:         "Опечатка в адресе",
+			address:      "wlt1typo",
+			expectedCode: http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Error:     "Некорректный адрес кошелька: проверьте адрес на опечатки",
+				ErrorCode: storage.CodeMalformedAddress,
+			},
+		},
+		{
+			name:         "Адрес прежнего формата",
+			address:      "1077f2e5-cfd1-4a02-86cc-96fc7337d18c",
+			expectedCode: http.StatusOK,
+			expectedETag: `"1"`,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Data:   models.Wallet{Address: "wlt1canonical", Balance: 5, Version: 1},
+			},
+			mockSetup: func(m *mocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "wlt1canonical").Return(models.Wallet{Address: "wlt1canonical", Balance: 5, Version: 1}, nil).Once()
+			},
+		},
+		{
+			name:         "Имя кошелька",
+			address:      "@alice",
+			expectedCode: http.StatusOK,
+			expectedETag: `"2"`,
+			expectedResp: response.Response{
+				Status: response.StatusOK,
+				Data:   models.Wallet{Address: "wlt1alice", Balance: 7, Version: 2},
+			},
+			mockSetup: func(m *mocks.BalanceReceiver) {
+				m.On("GetWalletBalance", "wlt1alice").Return(models.Wallet{Address: "wlt1alice", Balance: 7, Version: 2}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек другого пользователя",
+			address:      "wlt1foreign",
+			expectedCode: http.StatusForbidden,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Error:     "Нет доступа к кошельку: кошелек принадлежит другому пользователю",
+				ErrorCode: storage.CodeForbidden,
+			},
+		},
+		{
+			name:         "Неизвестное имя кошелька",
+			address:      "@ghost",
+			expectedCode: http.StatusBadRequest,
+			expectedResp: response.Response{
+				Status:    response.StatusError,
+				Error:     "Имя кошелька не найдено",
+				ErrorCode: storage.CodeHandleNotFound,
+			},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -72,7 +158,7 @@ func TestGetBalanceHandler(t *testing.T) {
 				tc.mockSetup(mockBalanceReceiver)
 			}
 
-			handler := wallet.GetBalance(testLogger, mockBalanceReceiver)
+			handler := wallet.GetBalance(testLogger, mockBalanceReceiver, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t)))
 
 			router := chi.NewRouter()
 			router.Get("/{address}", handler)
@@ -83,12 +169,20 @@ func TestGetBalanceHandler(t *testing.T) {
 				nil,
 			)
 			require.NoError(t, err)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
 
 			rr := httptest.NewRecorder()
 
 			router.ServeHTTP(rr, req)
 
 			require.Equal(t, tc.expectedCode, rr.Code)
+			require.Equal(t, tc.expectedETag, rr.Header().Get("ETag"))
+			if tc.expectedCode == http.StatusNotModified {
+				require.Empty(t, rr.Body.Bytes())
+				return
+			}
 
 			var resp response.Response
 			err = json.Unmarshal(rr.Body.Bytes(), &resp)
@@ -96,6 +190,7 @@ func TestGetBalanceHandler(t *testing.T) {
 
 			require.Equal(t, tc.expectedResp.Status, resp.Status)
 			require.Equal(t, tc.expectedResp.Error, resp.Error)
+			require.Equal(t, tc.expectedResp.ErrorCode, resp.ErrorCode)
 
 			if tc.expectedCode == http.StatusOK {
 				jsonData, err := json.Marshal(resp.Data)
@@ -0,0 +1,187 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MetadataReader определяет интерфейс чтения сведений о кошельке: владельца, меток и метаданных.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=MetadataReader --dir=. --output=./mocks --filename=mock_MetadataReader
+type MetadataReader interface {
+	WalletMetadata(ctx context.Context, address string) (models.WalletMetadata, error)
+}
+
+// MetadataWriter определяет интерфейс изменения сведений о кошельке.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=MetadataWriter --dir=. --output=./mocks --filename=mock_MetadataWriter
+type MetadataWriter interface {
+	SetWalletMetadata(ctx context.Context, address string, md models.WalletMetadata) (models.WalletMetadata, error)
+}
+
+// WalletFinder определяет интерфейс поиска кошельков по меткам и владельцу.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=WalletFinder --dir=. --output=./mocks --filename=mock_WalletFinder
+type WalletFinder interface {
+	FindWallets(ctx context.Context, filter models.WalletFilter) ([]models.Wallet, error)
+}
+
+// Ограничения числа кошельков в результатах поиска
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// Details создает HTTP-обработчик сведений о кошельке: баланса, версии, владельца, меток и метаданных.
+// Адрес или имя кошелька приводится к каноническому виду и проверяется, как в GetBalance.
+func Details(log *slog.Logger, receiver BalanceReceiver, reader MetadataReader, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.wallet.Details"
+
+		log := log.With("op", op)
+
+		address, ok := resolveAddress(w, r, log, resolver, chi.URLParam(r, "address"))
+		if !ok || !authorizeWallet(w, r, log, authorizer, address) {
+			return
+		}
+
+		wallet, err := receiver.GetWalletBalance(address)
+		if err == nil {
+			var md models.WalletMetadata
+			if md, err = reader.WalletMetadata(r.Context(), address); err == nil {
+				wallet.Owner, wallet.Labels, wallet.Metadata = md.Owner, md.Labels, md.Metadata
+				render.JSON(w, r, response.Success(wallet))
+				return
+			}
+		}
+		if errors.Is(err, storage.ErrWalletNotFound) {
+			log.Error("wallet not found", sl.Err(err))
+			response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+			return
+		}
+		log.Error("unable to get wallet details", sl.Err(err))
+		response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+	}
+}
+
+// SetMetadata создает HTTP-обработчик замены владельца, меток и метаданных кошелька.
+// Принимает JSON со всеми сведениями; отсутствующие поля очищаются.
+// Возвращает сохраненные сведения: метки в нижнем регистре без повторов.
+func SetMetadata(log *slog.Logger, writer MetadataWriter, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.wallet.SetMetadata"
+
+		log := log.With("op", op)
+
+		var req models.WalletMetadata
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			response.Render(w, r, response.Fail(r, response.CodeEmptyBody, http.StatusBadRequest, nil))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidJSON, http.StatusBadRequest, nil))
+			return
+		}
+
+		address, ok := resolveAddress(w, r, log, resolver, chi.URLParam(r, "address"))
+		if !ok || !authorizeWallet(w, r, log, authorizer, address) {
+			return
+		}
+
+		md, err := writer.SetWalletMetadata(r.Context(), address, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrWalletNotFound), errors.Is(err, storage.ErrInvalidMetadata):
+				log.Warn("invalid wallet metadata request", sl.Err(err))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, storage.Details(err)))
+			default:
+				log.Error("unable to set wallet metadata", sl.Err(err))
+				response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			}
+			return
+		}
+
+		log.Info("wallet metadata updated", slog.String("address", address))
+		render.JSON(w, r, response.Success(md))
+	}
+}
+
+// Search создает HTTP-обработчик поиска кошельков по метке (label) и владельцу (owner).
+// Требуется хотя бы одно условие; limit ограничивает число кошельков (по умолчанию 100, не более 1000).
+// Кошельки упорядочены по адресу и возвращаются со сведениями и текущим балансом;
+// аутентифицированному пользователю возвращаются только его кошельки.
+func Search(log *slog.Logger, finder WalletFinder, receiver BalanceReceiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.wallet.Search"
+
+		log := log.With("op", op)
+
+		query := r.URL.Query()
+		filter := models.WalletFilter{
+			Label: strings.TrimSpace(query.Get("label")),
+			Owner: strings.TrimSpace(query.Get("owner")),
+			Limit: defaultSearchLimit,
+		}
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			filter.UserID = user.ID
+		}
+		if raw := query.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				log.Warn("invalid limit", slog.String("limit", raw))
+				response.Render(w, r, response.Fail(r, response.CodeInvalidFilter, http.StatusBadRequest, nil))
+				return
+			}
+			filter.Limit = n
+		}
+		if filter.Label == "" && filter.Owner == "" {
+			log.Warn("empty wallet filter")
+			response.Render(w, r, response.Fail(r, response.CodeInvalidFilter, http.StatusBadRequest, nil))
+			return
+		}
+
+		found, err := finder.FindWallets(r.Context(), filter)
+		if err != nil {
+			log.Error("unable to find wallets", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+
+		// Баланс и версия берутся из того же источника, что и в GetBalance
+		wallets := make([]models.Wallet, 0, len(found))
+		for _, wallet := range found {
+			b, err := receiver.GetWalletBalance(wallet.Address)
+			if errors.Is(err, storage.ErrWalletNotFound) {
+				continue
+			}
+			if err != nil {
+				log.Error("unable to get balance", sl.Err(err))
+				response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+				return
+			}
+			wallet.Balance, wallet.Version = b.Balance, b.Version
+			wallets = append(wallets, wallet)
+		}
+
+		render.JSON(w, r, response.Success(wallets))
+	}
+}
@@ -0,0 +1,346 @@
+package wallet_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	"infotecsTest/internal/http-server/handlers/wallet/mocks"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetailsHandler(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	md := models.WalletMetadata{Owner: "cust-1", Labels: []string{"vip"}, Metadata: map[string]string{"region": "eu"}}
+
+	cases := []struct {
+		name          string
+		address       string
+		expectedCode  int
+		expectedError string
+		expected      models.Wallet
+		mockSetup     func(b *mocks.BalanceReceiver, m *mocks.MetadataReader)
+	}{
+		{
+			name:         "Сведения о кошельке",
+			address:      "addr1",
+			expectedCode: http.StatusOK,
+			expected: models.Wallet{
+				Address: "addr1", Balance: 100, Version: 3,
+				Owner: "cust-1", Labels: []string{"vip"}, Metadata: map[string]string{"region": "eu"},
+			},
+			mockSetup: func(b *mocks.BalanceReceiver, m *mocks.MetadataReader) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100, Version: 3}, nil).Once()
+				m.On("WalletMetadata", mock.Anything, "addr1").Return(md, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек по имени",
+			address:      "@alice",
+			expectedCode: http.StatusOK,
+			expected:     models.Wallet{Address: "wlt1alice", Balance: 7, Version: 1},
+			mockSetup: func(b *mocks.BalanceReceiver, m *mocks.MetadataReader) {
+				b.On("GetWalletBalance", "wlt1alice").Return(models.Wallet{Address: "wlt1alice", Balance: 7, Version: 1}, nil).Once()
+				m.On("WalletMetadata", mock.Anything, "wlt1alice").Return(models.WalletMetadata{}, nil).Once()
+			},
+		},
+		{
+			name:          "Кошелек не найден",
+			address:       "addr1",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(b *mocks.BalanceReceiver, _ *mocks.MetadataReader) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{}, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе",
+			address:       "wlt1typo",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+			mockSetup:     func(*mocks.BalanceReceiver, *mocks.MetadataReader) {},
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			address:       "wlt1foreign",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+			mockSetup:     func(*mocks.BalanceReceiver, *mocks.MetadataReader) {},
+		},
+		{
+			name:          "Внутренняя ошибка",
+			address:       "addr1",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(b *mocks.BalanceReceiver, m *mocks.MetadataReader) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1"}, nil).Once()
+				m.On("WalletMetadata", mock.Anything, "addr1").Return(models.WalletMetadata{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			balance := mocks.NewBalanceReceiver(t)
+			reader := mocks.NewMetadataReader(t)
+			tc.mockSetup(balance, reader)
+
+			router := chi.NewRouter()
+			router.Get("/api/wallet/{address}", wallet.Details(testLogger, balance, reader, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/wallet/"+tc.address, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.Wallet `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, tc.expected, resp.Data)
+			}
+		})
+	}
+}
+
+func TestSetMetadataHandler(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	saved := models.WalletMetadata{Owner: "cust-1", Labels: []string{"vip"}}
+
+	cases := []struct {
+		name          string
+		address       string
+		requestBody   string
+		expectedCode  int
+		expectedError string
+		expectedInfo  map[string]any
+		mockSetup     func(m *mocks.MetadataWriter)
+	}{
+		{
+			name:         "Сведения сохранены",
+			address:      "addr1",
+			requestBody:  `{"owner": "cust-1", "labels": ["VIP"]}`,
+			expectedCode: http.StatusOK,
+			mockSetup: func(m *mocks.MetadataWriter) {
+				m.On("SetWalletMetadata", mock.Anything, "addr1", models.WalletMetadata{Owner: "cust-1", Labels: []string{"VIP"}}).
+					Return(saved, nil).Once()
+			},
+		},
+		{
+			name:          "Пустое тело запроса",
+			address:       "addr1",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeEmptyBody,
+		},
+		{
+			name:          "Некорректные метаданные",
+			address:       "addr1",
+			requestBody:   `{"labels": [""]}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeInvalidMetadata,
+			expectedInfo:  map[string]any{"reason": "label is empty"},
+			mockSetup: func(m *mocks.MetadataWriter) {
+				m.On("SetWalletMetadata", mock.Anything, "addr1", mock.Anything).
+					Return(models.WalletMetadata{}, &storage.InvalidMetadataError{Reason: "label is empty"}).Once()
+			},
+		},
+		{
+			name:          "Кошелек не найден",
+			address:       "addr1",
+			requestBody:   `{}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(m *mocks.MetadataWriter) {
+				m.On("SetWalletMetadata", mock.Anything, "addr1", models.WalletMetadata{}).
+					Return(models.WalletMetadata{}, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе",
+			address:       "wlt1typo",
+			requestBody:   `{}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			address:       "wlt1foreign",
+			requestBody:   `{}`,
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+		},
+		{
+			name:          "Внутренняя ошибка",
+			address:       "addr1",
+			requestBody:   `{}`,
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.MetadataWriter) {
+				m.On("SetWalletMetadata", mock.Anything, "addr1", models.WalletMetadata{}).
+					Return(models.WalletMetadata{}, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := mocks.NewMetadataWriter(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(writer)
+			}
+
+			router := chi.NewRouter()
+			router.Put("/api/wallet/{address}/metadata", wallet.SetMetadata(testLogger, writer, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/wallet/"+tc.address+"/metadata", strings.NewReader(tc.requestBody))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data models.WalletMetadata `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			require.Equal(t, tc.expectedInfo, resp.Details)
+			if tc.expectedError == "" {
+				require.Equal(t, saved, resp.Data)
+			}
+		})
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	found := []models.Wallet{
+		{Address: "addr1", Owner: "cust-1", Labels: []string{"vip"}},
+		{Address: "addr2", Owner: "cust-1"},
+	}
+
+	cases := []struct {
+		name          string
+		query         string
+		user          *models.User
+		expectedCode  int
+		expectedError string
+		expected      []models.Wallet
+		mockSetup     func(f *mocks.WalletFinder, b *mocks.BalanceReceiver)
+	}{
+		{
+			name:         "Поиск по владельцу",
+			query:        "owner=cust-1",
+			expectedCode: http.StatusOK,
+			expected: []models.Wallet{
+				{Address: "addr1", Balance: 10, Version: 2, Owner: "cust-1", Labels: []string{"vip"}},
+				{Address: "addr2", Balance: 5, Version: 1, Owner: "cust-1"},
+			},
+			mockSetup: func(f *mocks.WalletFinder, b *mocks.BalanceReceiver) {
+				f.On("FindWallets", mock.Anything, models.WalletFilter{Owner: "cust-1", Limit: 100}).Return(found, nil).Once()
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 10, Version: 2}, nil).Once()
+				b.On("GetWalletBalance", "addr2").Return(models.Wallet{Address: "addr2", Balance: 5, Version: 1}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек без баланса пропускается",
+			query:        "label=vip&owner=cust-1&limit=2",
+			expectedCode: http.StatusOK,
+			expected:     []models.Wallet{{Address: "addr2", Balance: 5, Version: 1, Owner: "cust-1"}},
+			mockSetup: func(f *mocks.WalletFinder, b *mocks.BalanceReceiver) {
+				f.On("FindWallets", mock.Anything, models.WalletFilter{Label: "vip", Owner: "cust-1", Limit: 2}).Return(found, nil).Once()
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{}, storage.ErrWalletNotFound).Once()
+				b.On("GetWalletBalance", "addr2").Return(models.Wallet{Address: "addr2", Balance: 5, Version: 1}, nil).Once()
+			},
+		},
+		{
+			name:         "Поиск среди кошельков пользователя",
+			query:        "label=vip",
+			user:         &models.User{ID: "u1"},
+			expectedCode: http.StatusOK,
+			expected:     []models.Wallet{{Address: "addr1", Balance: 10, Version: 2, Owner: "cust-1", Labels: []string{"vip"}}},
+			mockSetup: func(f *mocks.WalletFinder, b *mocks.BalanceReceiver) {
+				f.On("FindWallets", mock.Anything, models.WalletFilter{Label: "vip", UserID: "u1", Limit: 100}).Return(found[:1], nil).Once()
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 10, Version: 2}, nil).Once()
+			},
+		},
+		{
+			name:         "Ничего не найдено",
+			query:        "label=none",
+			expectedCode: http.StatusOK,
+			expected:     []models.Wallet{},
+			mockSetup: func(f *mocks.WalletFinder, _ *mocks.BalanceReceiver) {
+				f.On("FindWallets", mock.Anything, models.WalletFilter{Label: "none", Limit: 100}).Return(nil, nil).Once()
+			},
+		},
+		{
+			name:          "Без условий",
+			query:         "limit=10",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidFilter,
+		},
+		{
+			name:          "Некорректный limit",
+			query:         "label=vip&limit=5000",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidFilter,
+		},
+		{
+			name:          "Внутренняя ошибка",
+			query:         "label=vip",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(f *mocks.WalletFinder, _ *mocks.BalanceReceiver) {
+				f.On("FindWallets", mock.Anything, mock.Anything).Return(nil, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			finder := mocks.NewWalletFinder(t)
+			balance := mocks.NewBalanceReceiver(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(finder, balance)
+			}
+
+			router := chi.NewRouter()
+			router.Get("/api/wallets", wallet.Search(testLogger, finder, balance))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/wallets?"+tc.query, nil)
+			if tc.user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), *tc.user))
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			var resp struct {
+				response.Response
+				Data []models.Wallet `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+			if tc.expectedError == "" {
+				require.Equal(t, tc.expected, resp.Data)
+			}
+		})
+	}
+}
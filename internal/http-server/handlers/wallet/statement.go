@@ -0,0 +1,290 @@
+package wallet
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatementReader определяет интерфейс чтения данных для выписки по кошельку.
+// Генерирует моки через go:generate.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.3 --name=StatementReader --dir=. --output=./mocks --filename=mock_StatementReader
+type StatementReader interface {
+	OpeningBalance(ctx context.Context, address string, at time.Time) (float64, error)
+	Movements(ctx context.Context, address string, from, to time.Time, fn func(models.Movement) error) error
+}
+
+// Форматы выписки
+const (
+	StatementCSV   = "csv"   // CSV с заголовком
+	StatementJSONL = "jsonl" // JSON Lines: один объект на строку
+)
+
+// Типы строк выписки
+const (
+	EntryOpening = "opening" // Входящий остаток на начало периода
+	EntryCredit  = "credit"  // Зачисление
+	EntryDebit   = "debit"   // Списание
+	EntryClosing = "closing" // Исходящий остаток на конец периода
+)
+
+// dateLayout - формат даты без времени в параметрах from и to.
+const dateLayout = "2006-01-02"
+
+// statementFlushEvery - число строк, после которого данные отправляются клиенту.
+const statementFlushEvery = 100
+
+// statementWriteWindow продлевает таймаут записи при каждой отправке данных,
+// чтобы выписка за большой период не обрывалась по WriteTimeout сервера.
+const statementWriteWindow = 30 * time.Second
+
+// StatementLine - строка выписки.
+// Для строк opening и closing поля перевода не заполняются.
+type StatementLine struct {
+	Entry         string   `json:"entry"`                    // Тип строки
+	Time          string   `json:"time"`                     // Время (RFC 3339)
+	TransactionID int64    `json:"transaction_id,omitempty"` // Идентификатор транзакции
+	Counterparty  string   `json:"counterparty,omitempty"`   // Адрес второй стороны перевода
+	Amount        *float64 `json:"amount,omitempty"`         // Сумма со знаком: списания отрицательны
+	Balance       float64  `json:"balance"`                  // Баланс после строки
+}
+
+// statementColumns - заголовок выписки в формате CSV.
+var statementColumns = []string{"entry", "time", "transaction_id", "counterparty", "amount", "balance"}
+
+// Statement создает HTTP-обработчик выписки по кошельку за период.
+// Параметры from и to задают период [from, to) в формате RFC 3339 или ГГГГ-ММ-ДД;
+// дата без времени в to включает весь день. По умолчанию выписка строится
+// с начала текущего месяца (UTC) до текущего момента.
+// Выписка передается потоком: входящий остаток, переводы с текущим балансом, исходящий остаток.
+func Statement(log *slog.Logger, reader StatementReader, resolver AddressResolver, authorizer Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.wallet.Statement"
+
+		log := log.With("op", op)
+
+		query := r.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = StatementCSV
+		}
+		if format != StatementCSV && format != StatementJSONL {
+			log.Warn("unsupported statement format", slog.String("format", format))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidFormat, http.StatusBadRequest,
+				map[string]any{"supported": []string{StatementCSV, StatementJSONL}}))
+			return
+		}
+
+		from, to, err := statementPeriod(query.Get("from"), query.Get("to"), time.Now().UTC())
+		if err != nil {
+			log.Warn("invalid statement period", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInvalidPeriod, http.StatusBadRequest, nil))
+			return
+		}
+
+		address, ok := resolveAddress(w, r, log, resolver, chi.URLParam(r, "address"))
+		if !ok || !authorizeWallet(w, r, log, authorizer, address) {
+			return
+		}
+
+		opening, err := reader.OpeningBalance(r.Context(), address, from)
+		if err != nil {
+			if errors.Is(err, storage.ErrWalletNotFound) {
+				log.Error("wallet not found", sl.Err(err))
+				response.Render(w, r, response.Fail(r, storage.Code(err), http.StatusBadRequest, nil))
+				return
+			}
+			log.Error("unable to get opening balance", sl.Err(err))
+			response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+			return
+		}
+
+		contentType, enc := newStatementEncoder(format, w)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s-%s.%s"`,
+			address, from.Format(dateLayout), to.Format(dateLayout), format))
+		w.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(w)
+		flush := func() error {
+			if err := enc.flush(); err != nil {
+				return err
+			}
+			_ = rc.SetWriteDeadline(time.Now().Add(statementWriteWindow))
+			_ = rc.Flush()
+			return nil
+		}
+
+		balance := opening
+		err = enc.write(StatementLine{Entry: EntryOpening, Time: from.Format(time.RFC3339), Balance: balance})
+		if err == nil {
+			lines := 0
+			err = reader.Movements(r.Context(), address, from, to, func(m models.Movement) error {
+				line := movementLine(address, m, balance)
+				balance = line.Balance
+
+				if err := enc.write(line); err != nil {
+					return err
+				}
+				if lines++; lines%statementFlushEvery == 0 {
+					return flush()
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			err = enc.write(StatementLine{Entry: EntryClosing, Time: to.Format(time.RFC3339), Balance: balance})
+		}
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			// Статус уже отправлен: выписка без строки closing означает обрыв
+			log.Error("statement stream interrupted", sl.Err(err))
+			_ = enc.flush()
+		}
+	}
+}
+
+// statementPeriod разбирает границы периода выписки.
+func statementPeriod(rawFrom, rawTo string, now time.Time) (time.Time, time.Time, error) {
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+
+	if rawFrom != "" {
+		t, _, err := parseBound(rawFrom)
+		if err != nil {
+			return from, to, fmt.Errorf("from: %w", err)
+		}
+		from = t
+	}
+	if rawTo != "" {
+		t, dateOnly, err := parseBound(rawTo)
+		if err != nil {
+			return from, to, fmt.Errorf("to: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+// parseBound разбирает время в формате RFC 3339 или дату ГГГГ-ММ-ДД (UTC).
+func parseBound(raw string) (time.Time, bool, error) {
+	if t, err := time.Parse(dateLayout, raw); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time %q", raw)
+	}
+	return t, false, nil
+}
+
+// movementLine формирует строку выписки для перевода и баланса до него.
+func movementLine(address string, m models.Movement, balance float64) StatementLine {
+	line := StatementLine{
+		Entry:         EntryCredit,
+		Time:          m.Time.UTC().Format(time.RFC3339),
+		TransactionID: m.ID,
+		Counterparty:  m.From,
+	}
+
+	amount := m.Amount
+	if m.From == address {
+		line.Entry = EntryDebit
+		line.Counterparty = m.To
+		amount = -amount
+	}
+	line.Amount = &amount
+	line.Balance = balance + amount
+	return line
+}
+
+// statementEncoder записывает строки выписки в буфер ответа.
+type statementEncoder interface {
+	write(line StatementLine) error
+	flush() error
+}
+
+// newStatementEncoder возвращает тип содержимого и кодировщик для формата.
+func newStatementEncoder(format string, w io.Writer) (string, statementEncoder) {
+	bw := bufio.NewWriter(w)
+	if format == StatementJSONL {
+		return "application/x-ndjson", &jsonlEncoder{w: bw, enc: json.NewEncoder(bw)}
+	}
+	return "text/csv; charset=utf-8", &csvEncoder{w: bw, csv: csv.NewWriter(bw)}
+}
+
+// csvEncoder записывает выписку в формате CSV.
+type csvEncoder struct {
+	w      *bufio.Writer
+	csv    *csv.Writer
+	header bool
+}
+
+func (e *csvEncoder) write(line StatementLine) error {
+	if !e.header {
+		e.header = true
+		if err := e.csv.Write(statementColumns); err != nil {
+			return err
+		}
+	}
+
+	var id, amount string
+	if line.TransactionID != 0 {
+		id = strconv.FormatInt(line.TransactionID, 10)
+	}
+	if line.Amount != nil {
+		amount = formatAmount(*line.Amount)
+	}
+	return e.csv.Write([]string{line.Entry, line.Time, id, line.Counterparty, amount, formatAmount(line.Balance)})
+}
+
+func (e *csvEncoder) flush() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// jsonlEncoder записывает выписку в формате JSON Lines.
+type jsonlEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) write(line StatementLine) error {
+	return e.enc.Encode(line)
+}
+
+func (e *jsonlEncoder) flush() error {
+	return e.w.Flush()
+}
+
+// formatAmount форматирует сумму без экспоненты и лишних нулей.
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,179 @@
+package wallet_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/http-server/handlers/handlerstest"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	"infotecsTest/internal/http-server/handlers/wallet/mocks"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatementHandler(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	movements := []models.Movement{
+		{ID: 7, Time: from.Add(time.Hour), From: "other", To: "addr1", Amount: 30},
+		{ID: 9, Time: from.Add(2 * time.Hour), From: "addr1", To: "shop", Amount: 12.5},
+	}
+
+	// streamMovements передает переводы в обработчик так же, как хранилище.
+	streamMovements := func(args mock.Arguments) {
+		fn := args.Get(4).(func(models.Movement) error)
+		for _, m := range movements {
+			require.NoError(t, fn(m))
+		}
+	}
+
+	cases := []struct {
+		name          string
+		address       string // По умолчанию addr1
+		query         string
+		expectedCode  int
+		expectedType  string
+		expectedBody  string
+		expectedError string
+		mockSetup     func(m *mocks.StatementReader)
+	}{
+		{
+			name:         "Выписка в CSV",
+			query:        "?from=2024-05-01&to=2024-05-31",
+			expectedCode: http.StatusOK,
+			expectedType: "text/csv; charset=utf-8",
+			expectedBody: "entry,time,transaction_id,counterparty,amount,balance\n" +
+				"opening,2024-05-01T00:00:00Z,,,,100\n" +
+				"credit,2024-05-01T01:00:00Z,7,other,30,130\n" +
+				"debit,2024-05-01T02:00:00Z,9,shop,-12.5,117.5\n" +
+				"closing,2024-06-01T00:00:00Z,,,,117.5\n",
+			mockSetup: func(m *mocks.StatementReader) {
+				m.On("OpeningBalance", mock.Anything, "addr1", from).Return(100.0, nil).Once()
+				m.On("Movements", mock.Anything, "addr1", from, to, mock.Anything).
+					Run(streamMovements).Return(nil).Once()
+			},
+		},
+		{
+			name:         "Выписка в JSON Lines",
+			query:        "?from=2024-05-01T00:00:00Z&to=2024-06-01T00:00:00Z&format=jsonl",
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-ndjson",
+			expectedBody: `{"entry":"opening","time":"2024-05-01T00:00:00Z","balance":100}` + "\n" +
+				`{"entry":"credit","time":"2024-05-01T01:00:00Z","transaction_id":7,"counterparty":"other","amount":30,"balance":130}` + "\n" +
+				`{"entry":"debit","time":"2024-05-01T02:00:00Z","transaction_id":9,"counterparty":"shop","amount":-12.5,"balance":117.5}` + "\n" +
+				`{"entry":"closing","time":"2024-06-01T00:00:00Z","balance":117.5}` + "\n",
+			mockSetup: func(m *mocks.StatementReader) {
+				m.On("OpeningBalance", mock.Anything, "addr1", from).Return(100.0, nil).Once()
+				m.On("Movements", mock.Anything, "addr1", from, to, mock.Anything).
+					Run(streamMovements).Return(nil).Once()
+			},
+		},
+		{
+			name:          "Неподдерживаемый формат",
+			query:         "?format=xlsx",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidFormat,
+		},
+		{
+			name:          "Начало периода после конца",
+			query:         "?from=2024-06-01&to=2024-05-01",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidPeriod,
+		},
+		{
+			name:          "Некорректная дата",
+			query:         "?from=01.05.2024",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: response.CodeInvalidPeriod,
+		},
+		{
+			name:          "Кошелек не найден",
+			query:         "?from=2024-05-01&to=2024-05-31",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeWalletNotFound,
+			mockSetup: func(m *mocks.StatementReader) {
+				m.On("OpeningBalance", mock.Anything, "addr1", from).Return(0.0, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:          "Внутренняя ошибка",
+			query:         "?from=2024-05-01&to=2024-05-31",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+			mockSetup: func(m *mocks.StatementReader) {
+				m.On("OpeningBalance", mock.Anything, "addr1", from).Return(0.0, errors.New("db error")).Once()
+			},
+		},
+		{
+			name:         "Обрыв потока",
+			query:        "?from=2024-05-01&to=2024-05-31",
+			expectedCode: http.StatusOK,
+			expectedType: "text/csv; charset=utf-8",
+			expectedBody: "entry,time,transaction_id,counterparty,amount,balance\n" +
+				"opening,2024-05-01T00:00:00Z,,,,100\n",
+			mockSetup: func(m *mocks.StatementReader) {
+				m.On("OpeningBalance", mock.Anything, "addr1", from).Return(100.0, nil).Once()
+				m.On("Movements", mock.Anything, "addr1", from, to, mock.Anything).
+					Return(errors.New("db error")).Once()
+			},
+		},
+		{
+			name:          "Опечатка в адресе",
+			address:       "wlt1typo",
+			query:         "?from=2024-05-01&to=2024-05-31",
+			expectedCode:  http.StatusBadRequest,
+			expectedError: storage.CodeMalformedAddress,
+		},
+		{
+			name:          "Кошелек другого пользователя",
+			address:       "wlt1foreign",
+			query:         "?from=2024-05-01&to=2024-05-31",
+			expectedCode:  http.StatusForbidden,
+			expectedError: storage.CodeForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := mocks.NewStatementReader(t)
+			if tc.mockSetup != nil {
+				tc.mockSetup(reader)
+			}
+
+			router := chi.NewRouter()
+			router.Get("/api/wallet/{address}/statement", wallet.Statement(testLogger, reader, handlerstest.Resolver(mocks.NewAddressResolver(t)), handlerstest.Authorizer(mocks.NewAuthorizer(t))))
+
+			address := tc.address
+			if address == "" {
+				address = "addr1"
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/wallet/"+address+"/statement"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			if tc.expectedError != "" {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.expectedError, resp.ErrorCode)
+				return
+			}
+
+			require.Equal(t, tc.expectedType, rr.Header().Get("Content-Type"))
+			require.Contains(t, rr.Header().Get("Content-Disposition"), "statement-addr1-2024-05-01-2024-06-01")
+			require.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package auth предоставляет middleware аутентификации пользователей по ключу API
+// и проверку прав пользователя на кошелек.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/apikey"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// UserFinder определяет интерфейс поиска пользователя по хешу ключа API.
+type UserFinder interface {
+	UserByKey(ctx context.Context, keyHash string) (models.User, error)
+}
+
+// OwnerReader определяет интерфейс чтения владельца кошелька.
+type OwnerReader interface {
+	WalletUser(ctx context.Context, address string) (string, error)
+}
+
+type userKey struct{}
+
+// WithUser сохраняет аутентифицированного пользователя в контексте.
+func WithUser(ctx context.Context, user models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext возвращает аутентифицированного пользователя из контекста.
+// Без аутентификации возвращает false.
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userKey{}).(models.User)
+	return user, ok
+}
+
+// Authenticate возвращает пользователя по ключу API.
+// Возвращает ErrUserNotFound для пустого, неизвестного или отозванного ключа.
+func Authenticate(ctx context.Context, users UserFinder, key string) (models.User, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return models.User{}, storage.ErrUserNotFound
+	}
+	return users.UserByKey(ctx, apikey.Hash(key))
+}
+
+// New создает middleware аутентификации по ключу из заголовка cfg.Header.
+// Запрос без ключа или с неизвестным ключом отклоняется с 401,
+// пользователь передается обработчикам через контекст (см. UserFromContext).
+// Если аутентификация выключена, запросы передаются без изменений.
+func New(log *slog.Logger, cfg config.Auth, users UserFinder) func(next http.Handler) http.Handler {
+	log = log.With(slog.String("component", "middleware/auth"))
+
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user, err := Authenticate(r.Context(), users, r.Header.Get(cfg.Header))
+			if err != nil {
+				if errors.Is(err, storage.ErrUserNotFound) {
+					log.Warn("request not authenticated", slog.String("path", r.URL.Path))
+					response.Render(w, r, response.Fail(r, response.CodeUnauthorized, http.StatusUnauthorized, nil))
+					return
+				}
+				log.Error("unable to authenticate request", sl.Err(err))
+				response.Render(w, r, response.Fail(r, response.CodeInternal, http.StatusInternalServerError, nil))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Admin создает middleware служебного сервера, требующее ключ администратора
+// в заголовке Authorization: Bearer <ключ>. Запрос без ключа или с другим ключом отклоняется с 401.
+// Если ключ администратора не задан, запросы передаются без изменений.
+func Admin(log *slog.Logger, cfg config.Auth) func(next http.Handler) http.Handler {
+	log = log.With(slog.String("component", "middleware/auth"))
+
+	return func(next http.Handler) http.Handler {
+		if cfg.AdminKey == "" {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(cfg.AdminKey)) != 1 {
+				log.Warn("admin request not authenticated", slog.String("path", r.URL.Path))
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				response.Render(w, r, response.Fail(r, response.CodeUnauthorized, http.StatusUnauthorized, nil))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authorizer проверяет, что аутентифицированный пользователь владеет кошельком.
+// Безопасен для конкурентного использования.
+type Authorizer struct {
+	enabled bool
+	owners  OwnerReader
+}
+
+// NewAuthorizer создает проверку прав на кошельки.
+// Если аутентификация выключена, любые операции с кошельками разрешены.
+func NewAuthorizer(cfg config.Auth, owners OwnerReader) *Authorizer {
+	return &Authorizer{enabled: cfg.Enabled, owners: owners}
+}
+
+// AuthorizeWallet проверяет права пользователя из контекста на кошелек с каноническим адресом.
+// Возвращает ErrForbidden, если пользователь не аутентифицирован или кошелек ему не принадлежит.
+// Для неизвестного кошелька также возвращается ErrForbidden, чтобы не раскрывать существование чужих кошельков.
+func (a *Authorizer) AuthorizeWallet(ctx context.Context, address string) error {
+	const op = "auth.AuthorizeWallet"
+
+	if !a.enabled {
+		return nil
+	}
+
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return storage.ErrForbidden
+	}
+
+	owner, err := a.owners.WalletUser(ctx, address)
+	switch {
+	case errors.Is(err, storage.ErrWalletNotFound):
+		return storage.ErrForbidden
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case owner != user.ID:
+		return storage.ErrForbidden
+	}
+	return nil
+}
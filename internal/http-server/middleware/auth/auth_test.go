@@ -0,0 +1,241 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/apikey"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// usersFunc ищет пользователя по хешу ключа функцией.
+type usersFunc func(string) (models.User, error)
+
+func (f usersFunc) UserByKey(_ context.Context, keyHash string) (models.User, error) {
+	return f(keyHash)
+}
+
+// ownersFunc возвращает владельца кошелька функцией.
+type ownersFunc func(string) (string, error)
+
+func (f ownersFunc) WalletUser(_ context.Context, address string) (string, error) { return f(address) }
+
+func TestMiddleware(t *testing.T) {
+	alice := models.User{ID: "u1", Name: "Alice"}
+
+	// Ключ key-alice принадлежит alice, key-broken вызывает ошибку хранилища
+	users := usersFunc(func(keyHash string) (models.User, error) {
+		switch keyHash {
+		case apikey.Hash("key-alice"):
+			return alice, nil
+		case apikey.Hash("key-broken"):
+			return models.User{}, errors.New("db error")
+		}
+		return models.User{}, storage.ErrUserNotFound
+	})
+
+	cases := []struct {
+		name          string
+		cfg           config.Auth
+		key           string
+		expectedCode  int
+		expectedError string
+		expectedUser  string
+	}{
+		{
+			name:         "Аутентификация выключена",
+			cfg:          config.Auth{Header: "X-API-Key"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Ключ пользователя",
+			cfg:          config.Auth{Enabled: true, Header: "X-API-Key"},
+			key:          "key-alice",
+			expectedCode: http.StatusOK,
+			expectedUser: "u1",
+		},
+		{
+			name:          "Запрос без ключа",
+			cfg:           config.Auth{Enabled: true, Header: "X-API-Key"},
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: response.CodeUnauthorized,
+		},
+		{
+			name:          "Неизвестный ключ",
+			cfg:           config.Auth{Enabled: true, Header: "X-API-Key"},
+			key:           "key-bob",
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: response.CodeUnauthorized,
+		},
+		{
+			name:          "Ошибка хранилища",
+			cfg:           config.Auth{Enabled: true, Header: "X-API-Key"},
+			key:           "key-broken",
+			expectedCode:  http.StatusInternalServerError,
+			expectedError: response.CodeInternal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := auth.New(testLogger, tc.cfg, users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, _ := auth.UserFromContext(r.Context())
+				_, _ = w.Write([]byte(user.ID))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			if tc.key != "" {
+				req.Header.Set("X-API-Key", tc.key)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedError == "" {
+				require.Equal(t, tc.expectedUser, rr.Body.String())
+				return
+			}
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedError, resp.ErrorCode)
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	const adminKey = "0123456789abcdef"
+
+	cases := []struct {
+		name          string
+		cfg           config.Auth
+		authorization string
+		expectedCode  int
+	}{
+		{
+			name:         "Ключ администратора не задан",
+			cfg:          config.Auth{},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "Ключ администратора",
+			cfg:           config.Auth{AdminKey: adminKey},
+			authorization: "Bearer " + adminKey,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "Запрос без ключа",
+			cfg:          config.Auth{AdminKey: adminKey},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "Неверный ключ",
+			cfg:           config.Auth{AdminKey: adminKey},
+			authorization: "Bearer fedcba9876543210",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Ключ без схемы Bearer",
+			cfg:           config.Auth{AdminKey: adminKey},
+			authorization: adminKey,
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "Ключ пользователя не заменяет ключ администратора",
+			cfg:           config.Auth{Enabled: true, Header: "X-API-Key", AdminKey: adminKey},
+			authorization: "Bearer key-alice",
+			expectedCode:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := auth.Admin(testLogger, tc.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/users", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+			if tc.expectedCode != http.StatusUnauthorized {
+				return
+			}
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, response.CodeUnauthorized, resp.ErrorCode)
+			require.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	alice := models.User{ID: "u1", Name: "Alice"}
+
+	// Кошелек a принадлежит alice, b - другому пользователю, c - без владельца
+	owners := ownersFunc(func(address string) (string, error) {
+		switch address {
+		case "a":
+			return "u1", nil
+		case "b":
+			return "u2", nil
+		case "c":
+			return "", nil
+		case "broken":
+			return "", errors.New("db error")
+		}
+		return "", storage.ErrWalletNotFound
+	})
+
+	cases := []struct {
+		name        string
+		enabled     bool
+		user        *models.User
+		address     string
+		expectedErr error
+	}{
+		{name: "Аутентификация выключена", address: "b"},
+		{name: "Кошелек пользователя", enabled: true, user: &alice, address: "a"},
+		{name: "Кошелек другого пользователя", enabled: true, user: &alice, address: "b", expectedErr: storage.ErrForbidden},
+		{name: "Кошелек без владельца", enabled: true, user: &alice, address: "c", expectedErr: storage.ErrForbidden},
+		{name: "Неизвестный кошелек", enabled: true, user: &alice, address: "ghost", expectedErr: storage.ErrForbidden},
+		{name: "Пользователь не аутентифицирован", enabled: true, address: "a", expectedErr: storage.ErrForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.user != nil {
+				ctx = auth.WithUser(ctx, *tc.user)
+			}
+
+			err := auth.NewAuthorizer(config.Auth{Enabled: tc.enabled}, owners).AuthorizeWallet(ctx, tc.address)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+
+	err := auth.NewAuthorizer(config.Auth{Enabled: true}, owners).AuthorizeWallet(auth.WithUser(context.Background(), alice), "broken")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, storage.ErrForbidden)
+}
@@ -2,30 +2,94 @@
 package logger
 
 import (
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"infotecsTest/internal/config"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// redacted заменяет значения скрываемых параметров и заголовков.
+const redacted = "[REDACTED]"
+
+// defaultLevels задает уровни записи по классу статуса ответа.
+var defaultLevels = map[string]slog.Level{
+	"1xx": slog.LevelInfo,
+	"2xx": slog.LevelInfo,
+	"3xx": slog.LevelInfo,
+	"4xx": slog.LevelWarn,
+	"5xx": slog.LevelError,
+}
+
+// options - подготовленные для быстрого доступа настройки журнала.
+type options struct {
+	levels        map[string]slog.Level
+	excludePaths  map[string]struct{}
+	sampling      map[string]float64
+	logHeaders    bool
+	redactHeaders map[string]struct{}
+	redactQuery   map[string]struct{}
+}
+
 // New создает middleware для логирования информации о HTTP-запросах.
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		log = log.With(
-			slog.String("component", "middleware/logger"),
-		)
+// Для каждого запроса пишется одна запись после формирования ответа.
+func New(log *slog.Logger, cfg config.AccessLog) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/logger"),
+	)
 
-		log.Info("middleware logger enabled")
+	opts := newOptions(log, cfg)
 
+	log.Info("middleware logger enabled")
+
+	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := opts.excludePaths[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Обертка для получения статуса ответа и размера данных
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			// Фиксируем время начала обработки запроса
 			t1 := time.Now()
 			defer func() {
-				// Логируем продолжительность выполнения запроса
-				log.With(slog.String("duration", time.Since(t1).String()))
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				route := routePattern(r)
+
+				if !opts.sampled(route, status) {
+					return
+				}
+
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("route", route),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.String("duration", time.Since(t1).String()),
+					slog.String("remote_addr", r.RemoteAddr),
+					slog.String("user_agent", r.UserAgent()),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				}
+				if r.URL.RawQuery != "" {
+					attrs = append(attrs, slog.String("query", opts.query(r.URL.Query())))
+				}
+				if opts.logHeaders {
+					attrs = append(attrs, opts.headers(r.Header))
+				}
+
+				log.LogAttrs(r.Context(), opts.level(status), "request completed", attrs...)
 			}()
 
 			// Передаем управление следующему обработчику в цепочке
@@ -35,3 +99,117 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+// newOptions разбирает конфигурацию журнала.
+// Некорректные уровни заменяются значениями по умолчанию с предупреждением.
+func newOptions(log *slog.Logger, cfg config.AccessLog) options {
+	opts := options{
+		levels:        make(map[string]slog.Level, len(defaultLevels)),
+		excludePaths:  make(map[string]struct{}, len(cfg.ExcludePaths)),
+		sampling:      cfg.Sampling,
+		logHeaders:    cfg.LogHeaders,
+		redactHeaders: make(map[string]struct{}, len(cfg.RedactHeaders)),
+		redactQuery:   make(map[string]struct{}, len(cfg.RedactQuery)),
+	}
+
+	for class, level := range defaultLevels {
+		opts.levels[class] = level
+	}
+	for class, value := range cfg.Levels {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			log.Warn("invalid access log level, using default",
+				slog.String("class", class), slog.String("level", value))
+			continue
+		}
+		opts.levels[strings.ToLower(class)] = level
+	}
+
+	for _, path := range cfg.ExcludePaths {
+		opts.excludePaths[path] = struct{}{}
+	}
+	for _, header := range cfg.RedactHeaders {
+		opts.redactHeaders[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	for _, param := range cfg.RedactQuery {
+		opts.redactQuery[param] = struct{}{}
+	}
+
+	return opts
+}
+
+// level возвращает уровень записи по классу статуса.
+func (o options) level(status int) slog.Level {
+	class := strconv.Itoa(status/100) + "xx"
+	if level, ok := o.levels[class]; ok {
+		return level
+	}
+	return slog.LevelInfo
+}
+
+// sampled решает, журналировать ли запрос.
+// Ошибочные ответы журналируются всегда, выборка применяется только к успешным.
+func (o options) sampled(route string, status int) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	rate, ok := o.sampling[route]
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+// query возвращает строку запроса со скрытыми значениями чувствительных параметров.
+func (o options) query(values url.Values) string {
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	slices.Sort(params)
+
+	var sb strings.Builder
+	for _, param := range params {
+		_, hide := o.redactQuery[param]
+		for _, value := range values[param] {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(param))
+			sb.WriteByte('=')
+			if hide {
+				sb.WriteString(redacted)
+				continue
+			}
+			sb.WriteString(url.QueryEscape(value))
+		}
+	}
+	return sb.String()
+}
+
+// headers возвращает группу заголовков со скрытыми чувствительными значениями.
+func (o options) headers(h http.Header) slog.Attr {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	attrs := make([]any, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(h.Values(name), ", ")
+		if _, ok := o.redactHeaders[name]; ok {
+			value = redacted
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// routePattern возвращает шаблон маршрута chi, если запрос был сопоставлен.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	mwLogger "infotecsTest/internal/http-server/middleware/logger"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	cfg := config.AccessLog{
+		Levels:        map[string]string{"4xx": "error"},
+		ExcludePaths:  []string{"/healthz"},
+		Sampling:      map[string]float64{"/sampled": 0},
+		LogHeaders:    true,
+		RedactHeaders: []string{"X-API-Key"},
+		RedactQuery:   []string{"token"},
+	}
+
+	cases := []struct {
+		name          string
+		path          string
+		headers       map[string]string
+		expectedEntry map[string]any
+	}{
+		{
+			name:    "Успешный запрос",
+			path:    "/api/wallet/addr1/balance?token=secret&count=5",
+			headers: map[string]string{"X-API-Key": "key1", "User-Agent": "test-agent"},
+			expectedEntry: map[string]any{
+				"level":      "INFO",
+				"method":     http.MethodGet,
+				"path":       "/api/wallet/addr1/balance",
+				"route":      "/api/wallet/{address}/balance",
+				"status":     float64(http.StatusOK),
+				"bytes":      float64(2),
+				"user_agent": "test-agent",
+				"query":      "count=5&token=[REDACTED]",
+				"headers":    map[string]any{"X-Api-Key": "[REDACTED]", "User-Agent": "test-agent"},
+			},
+		},
+		{
+			name: "Ошибка клиента",
+			path: "/api/missing",
+			expectedEntry: map[string]any{
+				"level":  "ERROR",
+				"route":  "",
+				"status": float64(http.StatusNotFound),
+			},
+		},
+		{
+			name: "Исключенный путь",
+			path: "/healthz",
+		},
+		{
+			name: "Выборка успешных запросов",
+			path: "/sampled",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			router := chi.NewRouter()
+			router.Use(middleware.RequestID)
+			router.Use(mwLogger.New(log, cfg))
+			ok := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }
+			router.Get("/api/wallet/{address}/balance", ok)
+			router.Get("/healthz", ok)
+			router.Get("/sampled", ok)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			var entries []map[string]any
+			dec := json.NewDecoder(&buf)
+			for dec.More() {
+				var entry map[string]any
+				require.NoError(t, dec.Decode(&entry))
+				if entry["msg"] == "request completed" {
+					entries = append(entries, entry)
+				}
+			}
+
+			if tc.expectedEntry == nil {
+				require.Empty(t, entries)
+				return
+			}
+
+			require.Len(t, entries, 1)
+			for key, value := range tc.expectedEntry {
+				require.Equal(t, value, entries[0][key], key)
+			}
+			require.NotEmpty(t, entries[0]["request_id"])
+			require.NotEmpty(t, entries[0]["duration"])
+		})
+	}
+}
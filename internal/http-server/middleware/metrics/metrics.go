@@ -0,0 +1,64 @@
+// Package metrics предоставляет middleware для сбора метрик HTTP-запросов.
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"infotecsTest/internal/lib/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute используется для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "unmatched"
+
+// New создает middleware, считающее запросы и их длительность
+// в разрезе метода, шаблона маршрута chi и кода ответа.
+func New(reg *metrics.Registry) func(next http.Handler) http.Handler {
+	requests := reg.NewCounterVec(
+		"http_requests_total",
+		"Number of HTTP requests by route and status.",
+		"method", "route", "status",
+	)
+	duration := reg.NewHistogramVec(
+		"http_request_duration_seconds",
+		"Duration of HTTP requests by route and status.",
+		nil, "method", "route", "status",
+	)
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			t1 := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				labels := []string{r.Method, routePattern(r), strconv.Itoa(status)}
+
+				requests.With(labels...).Inc()
+				duration.With(labels...).Observe(time.Since(t1).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// routePattern возвращает шаблон маршрута, чтобы не порождать
+// отдельные серии для каждого адреса кошелька.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedRoute
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return unmatchedRoute
+}
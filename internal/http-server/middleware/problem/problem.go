@@ -0,0 +1,26 @@
+// Package problem предоставляет middleware настройки формата ошибок (RFC 7807).
+package problem
+
+import (
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/api/response"
+	"net/http"
+)
+
+// New создает middleware, передающее обработчикам настройки формата ошибок.
+// Формат RFC 7807 используется для всех ошибок, если он выбран в конфигурации,
+// иначе только по запросу клиента через заголовок Accept.
+func New(cfg config.Errors) func(next http.Handler) http.Handler {
+	opts := response.ProblemOptions{
+		Default:     cfg.Format == config.ErrorFormatProblem,
+		TypeBaseURI: cfg.TypeBaseURI,
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(response.WithProblemOptions(r.Context(), opts)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
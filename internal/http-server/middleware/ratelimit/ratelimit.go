@@ -0,0 +1,368 @@
+// Package ratelimit предоставляет middleware для ограничения частоты запросов.
+// Использует алгоритм token bucket с ключами по клиенту и по кошельку отправителя.
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/render"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/lib/api/response"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Измерения, по которым считаются лимиты
+const (
+	DimensionClient = "client" // Клиент (аутентифицированный пользователь или IP)
+	DimensionWallet = "wallet" // Кошелек отправителя
+)
+
+// maxBodyPeek ограничивает объем тела запроса, читаемого для поиска кошелька.
+const maxBodyPeek = 1 << 20
+
+// sweepInterval задает период очистки неиспользуемых корзин.
+const sweepInterval = time.Minute
+
+// Stat содержит счетчики лимитера для одного маршрута и измерения.
+type Stat struct {
+	Route     string `json:"route"`     // Шаблон маршрута
+	Dimension string `json:"dimension"` // Измерение: client/wallet
+	Allowed   uint64 `json:"allowed"`   // Количество пропущенных запросов
+	Limited   uint64 `json:"limited"`   // Количество отклоненных запросов
+	Keys      int    `json:"keys"`      // Количество отслеживаемых ключей
+}
+
+// AddressResolver определяет интерфейс приведения адреса кошелька к каноническому виду.
+type AddressResolver interface {
+	ResolveAddress(input string) (string, error)
+}
+
+// Limiter хранит корзины всех маршрутов.
+// Безопасен для конкурентного использования.
+type Limiter struct {
+	log      *slog.Logger
+	routes   map[string]*route
+	resolver AddressResolver
+	now      func() time.Time
+}
+
+// route объединяет лимиты одного маршрута.
+type route struct {
+	pattern string
+	client  *buckets
+	wallet  *buckets
+}
+
+// New создает Limiter по конфигурации.
+// При выключенном ограничении все middleware пропускают запросы без проверок.
+func New(log *slog.Logger, cfg config.RateLimit) *Limiter {
+	l := &Limiter{
+		log:    log.With(slog.String("component", "middleware/ratelimit")),
+		routes: make(map[string]*route),
+		now:    time.Now,
+	}
+	if !cfg.Enabled {
+		return l
+	}
+
+	for pattern, rl := range cfg.Routes {
+		l.routes[pattern] = &route{
+			pattern: pattern,
+			client:  newBuckets(DimensionClient, rl.Client),
+			wallet:  newBuckets(DimensionWallet, rl.Wallet),
+		}
+	}
+
+	return l
+}
+
+// SetAddressResolver задает приведение адреса отправителя к каноническому виду,
+// чтобы адрес в другом регистре, адрес прежнего формата и имя кошелька
+// расходовали одну корзину. Адрес, который не удалось привести, используется как есть.
+// Вызывается до регистрации маршрутов.
+func (l *Limiter) SetAddressResolver(resolver AddressResolver) {
+	l.resolver = resolver
+}
+
+// Route создает middleware для маршрута с указанным шаблоном.
+// Подключается после middleware аутентификации, чтобы лимит клиента считался по пользователю.
+// Если для маршрута не настроены лимиты, middleware ничего не делает.
+func (l *Limiter) Route(pattern string) func(next http.Handler) http.Handler {
+	rt, ok := l.routes[pattern]
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	log := l.log.With(slog.String("route", pattern))
+	log.Info("rate limit enabled")
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			now := l.now()
+
+			clientKey := l.clientKey(r)
+			res := rt.client.take(clientKey, now)
+			if res.allowed && rt.wallet != nil {
+				if key := l.walletKey(r); key != "" {
+					wres := rt.wallet.take(key, now)
+					if !wres.allowed {
+						// Отклоненный запрос не должен расходовать лимит клиента
+						rt.client.refund(clientKey)
+						res = wres
+					} else if wres.remaining < res.remaining {
+						res = wres
+					}
+				}
+			}
+
+			if res.limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.remaining))
+				w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(res.reset)))
+			}
+
+			if !res.allowed {
+				log.Warn("rate limit exceeded", slog.String("dimension", res.dimension))
+				retryAfter := seconds(res.retryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				response.Render(w, r, response.Fail(r, response.CodeTooManyRequests, http.StatusTooManyRequests,
+					map[string]any{"dimension": res.dimension, "retry_after": retryAfter}))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Stats возвращает счетчики по всем маршрутам, отсортированные по маршруту.
+func (l *Limiter) Stats() []Stat {
+	stats := make([]Stat, 0, 2*len(l.routes))
+	for _, rt := range l.routes {
+		if rt.client != nil {
+			stats = append(stats, rt.client.stat(rt.pattern))
+		}
+		if rt.wallet != nil {
+			stats = append(stats, rt.wallet.stat(rt.pattern))
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Route != stats[j].Route {
+			return stats[i].Route < stats[j].Route
+		}
+		return stats[i].Dimension < stats[j].Dimension
+	})
+	return stats
+}
+
+// StatsHandler создает HTTP-обработчик, отдающий счетчики лимитера в JSON.
+func (l *Limiter) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, response.Success(l.Stats()))
+	}
+}
+
+// clientKey определяет клиента по пользователю, аутентифицированному middleware auth,
+// а без аутентификации - по IP. Непроверенные заголовки запроса не учитываются:
+// иначе клиент получал бы новую корзину, меняя значение заголовка.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return "user:" + user.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// walletKey определяет кошелек отправителя запроса в каноническом виде, если задан AddressResolver.
+func (l *Limiter) walletKey(r *http.Request) string {
+	key := senderAddress(r)
+	if key == "" || l.resolver == nil {
+		return key
+	}
+	if canonical, err := l.resolver.ResolveAddress(key); err == nil {
+		return canonical
+	}
+	return key
+}
+
+// senderAddress извлекает адрес отправителя из JSON-тела запроса.
+// Читается не более maxBodyPeek байт; тело восстанавливается целиком,
+// чтобы обработчик мог прочитать его повторно. Исходное тело закрывается
+// вместе с восстановленным.
+func senderAddress(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	orig := r.Body
+	body, err := io.ReadAll(io.LimitReader(orig, maxBodyPeek))
+	r.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(body), orig), Closer: orig}
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		From string `json:"from"`
+	}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.From
+}
+
+// peekedBody возвращает прочитанное начало тела и его непрочитанный остаток.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// seconds округляет длительность вверх до целых секунд.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// result описывает решение лимитера для одного запроса.
+type result struct {
+	dimension  string
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration // Время до полного пополнения корзины
+	retryAfter time.Duration // Время до появления следующего токена
+}
+
+// bucket хранит состояние одной корзины.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets хранит корзины одного измерения маршрута.
+type buckets struct {
+	dimension string
+	limit     config.Limit
+	mu        sync.Mutex
+	items     map[string]*bucket
+	lastSweep time.Time
+	allowed   atomic.Uint64
+	limited   atomic.Uint64
+}
+
+// newBuckets возвращает nil для ненастроенного лимита.
+func newBuckets(dimension string, limit config.Limit) *buckets {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return nil
+	}
+	return &buckets{
+		dimension: dimension,
+		limit:     limit,
+		items:     make(map[string]*bucket),
+	}
+}
+
+// take пытается списать токен из корзины ключа.
+// Для nil-корзин всегда разрешает запрос.
+func (b *buckets) take(key string, now time.Time) result {
+	if b == nil {
+		return result{allowed: true, remaining: math.MaxInt}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	burst := float64(b.limit.Burst)
+	bk, ok := b.items[key]
+	if !ok {
+		bk = &bucket{tokens: burst, last: now}
+		b.items[key] = bk
+	}
+
+	elapsed := now.Sub(bk.last).Seconds()
+	if elapsed > 0 {
+		bk.tokens = math.Min(burst, bk.tokens+elapsed*b.limit.Rate)
+		bk.last = now
+	}
+
+	res := result{dimension: b.dimension, limit: b.limit.Burst}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		res.allowed = true
+		b.allowed.Add(1)
+	} else {
+		res.retryAfter = b.duration(1 - bk.tokens)
+		b.limited.Add(1)
+	}
+	res.remaining = int(bk.tokens)
+	res.reset = b.duration(burst - bk.tokens)
+
+	return res
+}
+
+// refund возвращает в корзину ключа токен, списанный take для запроса,
+// который был отклонен по другому измерению.
+// Для nil-корзин ничего не делает.
+func (b *buckets) refund(key string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bk, ok := b.items[key]; ok {
+		bk.tokens = math.Min(float64(b.limit.Burst), bk.tokens+1)
+	}
+	b.allowed.Add(^uint64(0))
+}
+
+// sweep удаляет корзины, которые успели полностью пополниться.
+// Вызывается под мьютексом.
+func (b *buckets) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < sweepInterval {
+		return
+	}
+	b.lastSweep = now
+
+	for key, bk := range b.items {
+		if now.Sub(bk.last) >= b.duration(float64(b.limit.Burst)-bk.tokens) {
+			delete(b.items, key)
+		}
+	}
+}
+
+// duration возвращает время накопления указанного количества токенов.
+func (b *buckets) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.limit.Rate * float64(time.Second))
+}
+
+// stat возвращает снимок счетчиков.
+func (b *buckets) stat(pattern string) Stat {
+	b.mu.Lock()
+	keys := len(b.items)
+	b.mu.Unlock()
+
+	return Stat{
+		Route:     pattern,
+		Dimension: b.dimension,
+		Allowed:   b.allowed.Load(),
+		Limited:   b.limited.Load(),
+		Keys:      keys,
+	}
+}
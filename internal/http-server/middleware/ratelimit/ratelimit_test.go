@@ -0,0 +1,205 @@
+package ratelimit_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/http-server/middleware/auth"
+	"infotecsTest/internal/http-server/middleware/ratelimit"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// resolverFunc приводит адрес кошелька к каноническому виду функцией.
+type resolverFunc func(string) (string, error)
+
+func (f resolverFunc) ResolveAddress(input string) (string, error) { return f(input) }
+
+func TestRateLimitMiddleware(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	type request struct {
+		remoteAddr   string
+		userID       string // Пользователь, аутентифицированный middleware auth
+		apiKey       string // Непроверенный заголовок с ключом
+		body         string
+		expectedCode int
+	}
+
+	cases := []struct {
+		name     string
+		limit    config.RouteLimit
+		requests []request
+	}{
+		{
+			name:  "Лимит по IP",
+			limit: config.RouteLimit{Client: config.Limit{Rate: 0.001, Burst: 2}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1001", expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1002", expectedCode: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.2:1000", expectedCode: http.StatusOK},
+			},
+		},
+		{
+			name:  "Лимит по пользователю",
+			limit: config.RouteLimit{Client: config.Limit{Rate: 0.001, Burst: 1}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", userID: "u1", expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.2:1000", userID: "u1", expectedCode: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.1:1000", userID: "u2", expectedCode: http.StatusOK},
+			},
+		},
+		{
+			name:  "Непроверенный ключ не создает новую корзину",
+			limit: config.RouteLimit{Client: config.Limit{Rate: 0.001, Burst: 1}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", apiKey: "random1", expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1001", apiKey: "random2", expectedCode: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "Лимит по кошельку",
+			limit: config.RouteLimit{Wallet: config.Limit{Rate: 0.001, Burst: 1}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"addr1"}`, expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.2:1000", body: `{"from":"addr1"}`, expectedCode: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"addr2"}`, expectedCode: http.StatusOK},
+			},
+		},
+		{
+			name:  "Лимит по кошельку с именем",
+			limit: config.RouteLimit{Wallet: config.Limit{Rate: 0.001, Burst: 1}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"@alice"}`, expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.2:1000", body: `{"from":"addr1"}`, expectedCode: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"@ghost"}`, expectedCode: http.StatusOK},
+			},
+		},
+		{
+			name: "Отклонение по кошельку не расходует лимит клиента",
+			limit: config.RouteLimit{
+				Client: config.Limit{Rate: 0.001, Burst: 2},
+				Wallet: config.Limit{Rate: 0.001, Burst: 1},
+			},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"addr1"}`, expectedCode: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"addr1"}`, expectedCode: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.1:1000", body: `{"from":"addr2"}`, expectedCode: http.StatusOK},
+			},
+		},
+	}
+
+	// Имя @alice принадлежит addr1, имя @ghost не присвоено
+	resolver := resolverFunc(func(input string) (string, error) {
+		switch input {
+		case "@alice":
+			return "addr1", nil
+		case "@ghost":
+			return "", storage.ErrHandleNotFound
+		}
+		return input, nil
+	})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := ratelimit.New(testLogger, config.RateLimit{
+				Enabled: true,
+				Routes:  map[string]config.RouteLimit{"/api/send": tc.limit},
+			})
+			limiter.SetAddressResolver(resolver)
+
+			// Обработчик проверяет, что тело запроса доступно после middleware
+			handler := limiter.Route("/api/send")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				_, _ = w.Write(body)
+			}))
+
+			for _, rq := range tc.requests {
+				req := httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(rq.body))
+				req.RemoteAddr = rq.remoteAddr
+				if rq.apiKey != "" {
+					req.Header.Set("X-API-Key", rq.apiKey)
+				}
+				if rq.userID != "" {
+					req = req.WithContext(auth.WithUser(req.Context(), models.User{ID: rq.userID}))
+				}
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				require.Equal(t, rq.expectedCode, rr.Code)
+				require.NotEmpty(t, rr.Header().Get("X-RateLimit-Limit"))
+
+				if rq.expectedCode == http.StatusOK {
+					require.Equal(t, rq.body, rr.Body.String())
+					continue
+				}
+
+				require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, response.StatusError, resp.Status)
+				require.Equal(t, http.StatusTooManyRequests, resp.Code)
+			}
+
+			var limited uint64
+			for _, st := range limiter.Stats() {
+				limited += st.Limited
+			}
+			require.Equal(t, uint64(1), limited)
+		})
+	}
+}
+
+// closeRecorder отмечает закрытие тела запроса.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRateLimitLargeBody(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	limiter := ratelimit.New(testLogger, config.RateLimit{
+		Enabled: true,
+		Routes: map[string]config.RouteLimit{
+			"/api/send": {Wallet: config.Limit{Rate: 0.001, Burst: 1}},
+		},
+	})
+
+	// Тело больше объема, который middleware читает для поиска кошелька
+	body := `{"from":"addr1","comment":"` + strings.Repeat("x", 2<<20) + `"}`
+	orig := &closeRecorder{Reader: strings.NewReader(body)}
+
+	handler := limiter.Route("/api/send")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Исходное тело остается открытым до завершения чтения обработчиком
+		require.False(t, orig.closed)
+		got, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, string(got))
+		require.NoError(t, r.Body.Close())
+		require.True(t, orig.closed)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/send", nil)
+	req.Body = orig
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
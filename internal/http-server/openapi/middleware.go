@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxBodySize ограничивает размер проверяемого тела запроса.
+const maxBodySize = 1 << 20
+
+// Options задает режимы проверки.
+type Options struct {
+	ValidateRequests  bool // Проверять запросы и отклонять некорректные
+	ValidateResponses bool // Проверять ответы (для разработки и тестов)
+
+	// OnResponseViolation вызывается при несоответствии ответа спецификации.
+	// Используется в тестах, чтобы расхождение обработчика со спецификацией приводило к ошибке.
+	OnResponseViolation func(r *http.Request, err error)
+}
+
+// Validator создает middleware проверки запросов и ответов по спецификации.
+// Запросы к путям, отсутствующим в спецификации, не проверяются.
+func Validator(log *slog.Logger, doc *Document, opts Options) func(next http.Handler) http.Handler {
+	log = log.With(slog.String("component", "middleware/openapi"))
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			op, params := doc.Operation(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.ValidateRequests {
+				if code, err := doc.validateRequest(r, op, params); err != nil {
+					log.Warn("request does not match specification",
+						slog.String("operation", op.OperationID), sl.Err(err))
+
+					var details map[string]any
+					var verr *ValidationError
+					if errors.As(err, &verr) {
+						details = map[string]any{"violations": verr.Violations}
+					}
+					response.Render(w, r, response.Fail(r, code, http.StatusBadRequest, details))
+					return
+				}
+			}
+
+			if !opts.ValidateResponses || op.streamed() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			next.ServeHTTP(ww, r)
+
+			if err := doc.validateResponse(op, ww.Status(), ww.Header().Get("Content-Type"), buf.Bytes()); err != nil {
+				log.Error("response does not match specification",
+					slog.String("operation", op.OperationID), sl.Err(err))
+				if opts.OnResponseViolation != nil {
+					opts.OnResponseViolation(r, err)
+				}
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// validateRequest проверяет параметры и тело запроса.
+// Возвращает код ошибки API для ответа клиенту.
+func (d *Document) validateRequest(r *http.Request, op *Operation, pathParams map[string]string) (string, error) {
+	var violations []string
+
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = pathParams[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		case "header":
+			_, present = r.Header[http.CanonicalHeaderKey(p.Name)]
+			raw = r.Header.Get(p.Name)
+		default:
+			continue
+		}
+
+		path := p.In + "." + p.Name
+		if !present {
+			if p.Required {
+				violations = append(violations, path+": is required")
+			}
+			continue
+		}
+
+		value, err := coerce(p.Schema, raw)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		var verr *ValidationError
+		if err = d.ValidateValue(p.Schema, value, path); errors.As(err, &verr) {
+			violations = append(violations, verr.Violations...)
+		}
+	}
+
+	if len(violations) > 0 {
+		return response.CodeSchemaViolation, &ValidationError{Violations: violations}
+	}
+
+	if op.RequestBody == nil {
+		return "", nil
+	}
+	// Тела в других форматах (например, CSV-файлы) не буферизуются и проверяются обработчиком
+	media, ok := op.RequestBody.Content[contentJSON]
+	if !ok {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return response.CodeInvalidJSON, err
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return response.CodeEmptyBody, errors.New("request body is required")
+		}
+		return "", nil
+	}
+
+	var value any
+	if err = json.Unmarshal(body, &value); err != nil {
+		return response.CodeInvalidJSON, err
+	}
+	if err = d.ValidateValue(media.Schema, value, "body"); err != nil {
+		return response.CodeSchemaViolation, err
+	}
+
+	return "", nil
+}
+
+// validateResponse проверяет код, тип содержимого и тело ответа.
+func (d *Document) validateResponse(op *Operation, status int, contentType string, body []byte) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return &ValidationError{Violations: []string{fmt.Sprintf("status: %d is not documented", status)}}
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return &ValidationError{Violations: []string{fmt.Sprintf("content-type: invalid %q", contentType)}}
+	}
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return &ValidationError{Violations: []string{fmt.Sprintf("content-type: %s is not documented", mediaType)}}
+	}
+	if media.Schema == nil || mediaType != contentJSON {
+		return nil
+	}
+
+	return d.ValidateJSON(media.Schema, body, "response")
+}
+
+// coerce преобразует строковое значение параметра к типу схемы.
+func coerce(s *Schema, raw string) (any, error) {
+	if s == nil {
+		return raw, nil
+	}
+	switch s.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", raw)
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", raw)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", raw)
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// CheckRoutes сверяет маршруты роутера со спецификацией.
+// Возвращает ошибку со списком маршрутов, отсутствующих в спецификации, и наоборот.
+func CheckRoutes(routes chi.Routes, doc *Document) error {
+	const op = "openapi.CheckRoutes"
+
+	registered := make(map[string]struct{})
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	documented := make(map[string]struct{})
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = struct{}{}
+		}
+	}
+
+	var problems []string
+	for route := range registered {
+		if _, ok := documented[route]; !ok {
+			problems = append(problems, "undocumented route "+route)
+		}
+	}
+	for route := range documented {
+		if _, ok := registered[route]; !ok {
+			problems = append(problems, "documented route is not registered "+route)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s: %s", op, strings.Join(problems, "; "))
+	}
+	return nil
+}
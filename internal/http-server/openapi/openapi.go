@@ -0,0 +1,459 @@
+// Package openapi описывает HTTP API сервиса в формате OpenAPI 3
+// и предоставляет middleware для проверки запросов и ответов по спецификации.
+// Схемы данных строятся по структурам пакета models, поэтому не расходятся с кодом.
+package openapi
+
+import (
+	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version - версия формата OpenAPI.
+const Version = "3.0.3"
+
+// Document - корневой объект спецификации OpenAPI.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info содержит общие сведения об API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem сопоставляет HTTP-метод (в нижнем регистре) с операцией.
+type PathItem map[string]*Operation
+
+// Operation описывает одну операцию API.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter описывает параметр пути, строки запроса или заголовка.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody описывает тело запроса.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response описывает ответ операции.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType связывает тип содержимого со схемой.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components содержит переиспользуемые схемы.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema - подмножество JSON Schema, используемое в спецификации.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Типы содержимого
+const (
+	contentJSON    = "application/json"
+	contentProblem = response.ContentTypeProblem
+	contentCSV     = "text/csv"
+	contentJSONL   = "application/x-ndjson"
+)
+
+// Spec строит спецификацию API сервиса.
+func Spec() *Document {
+	c := Components{Schemas: map[string]*Schema{}}
+
+	wallet := c.register("Wallet", reflect.TypeFor[models.Wallet]())
+	tx := c.register("Transaction", reflect.TypeFor[models.Transaction]())
+	payoutJob := c.register("PayoutJob", reflect.TypeFor[models.PayoutJob]())
+	walletMeta := c.register("WalletMetadata", reflect.TypeFor[models.WalletMetadata]())
+	handle := c.register("Handle", reflect.TypeFor[models.Handle]())
+	handleReq := c.register("HandleRequest", reflect.TypeFor[models.HandleRequest]())
+	user := c.register("User", reflect.TypeFor[models.User]())
+	userWallets := c.register("UserWallets", reflect.TypeFor[models.UserWallets]())
+	errResp := c.register("Error", reflect.TypeFor[response.Response]())
+	c.Schemas["Error"].Required = []string{"status", "code", "error", "error_code"}
+
+	errorResponses := func(codes ...string) map[string]Response {
+		res := make(map[string]Response, len(codes))
+		for _, code := range codes {
+			res[code] = Response{
+				Description: http.StatusText(statusCode(code)),
+				Content: map[string]MediaType{
+					contentJSON:    {Schema: errResp},
+					contentProblem: {Schema: &Schema{Type: "object"}},
+				},
+			}
+		}
+		return res
+	}
+
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "Payment System API", Version: "1.0.0"},
+		Paths: map[string]PathItem{
+			"/api/wallet/{address}/balance": {
+				"get": {
+					OperationID: "getBalance",
+					Summary:     "Получение баланса кошелька",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+						{Name: "If-None-Match", In: "header", Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(success(wallet), map[string]Response{
+						"304": {Description: http.StatusText(http.StatusNotModified)},
+					}, errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/wallet/{address}": {
+				"get": {
+					OperationID: "getWallet",
+					Summary:     "Сведения о кошельке: баланс, владелец, метки и метаданные",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(success(wallet), errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/wallet/{address}/metadata": {
+				"put": {
+					OperationID: "setWalletMetadata",
+					Summary:     "Замена владельца, меток и метаданных кошелька",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content:  map[string]MediaType{contentJSON: {Schema: walletMeta}},
+					},
+					Responses: merge(success(walletMeta), errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/wallets": {
+				"get": {
+					OperationID: "searchWallets",
+					Summary:     "Поиск кошельков по метке и владельцу",
+					Parameters: []Parameter{
+						{Name: "label", In: "query", Schema: &Schema{Type: "string"}},
+						{Name: "owner", In: "query", Schema: &Schema{Type: "string"}},
+						{Name: "limit", In: "query", Schema: &Schema{Type: "integer"}},
+					},
+					Responses: merge(success(&Schema{Type: "array", Items: wallet}), errorResponses("400", "401", "429", "500")),
+				},
+			},
+			"/api/wallet/{address}/statement": {
+				"get": {
+					OperationID: "getStatement",
+					Summary:     "Выписка по кошельку за период",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+						{Name: "from", In: "query", Schema: &Schema{Type: "string"}},
+						{Name: "to", In: "query", Schema: &Schema{Type: "string"}},
+						{Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []any{"csv", "jsonl"}}},
+					},
+					Responses: merge(map[string]Response{
+						"200": {
+							Description: "OK",
+							Content: map[string]MediaType{
+								contentCSV:   {Schema: &Schema{Type: "string"}},
+								contentJSONL: {Schema: &Schema{Type: "string"}},
+							},
+						},
+					}, errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/wallet/{address}/handle": {
+				"put": {
+					OperationID: "claimHandle",
+					Summary:     "Присвоение имени кошельку",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content:  map[string]MediaType{contentJSON: {Schema: handleReq}},
+					},
+					Responses: merge(success(handle), errorResponses("400", "401", "403", "409", "429", "500")),
+				},
+				"delete": {
+					OperationID: "releaseHandle",
+					Summary:     "Освобождение имени кошелька",
+					Parameters: []Parameter{
+						{Name: "address", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(success(handle), errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/handles/{handle}": {
+				"get": {
+					OperationID: "resolveHandle",
+					Summary:     "Поиск кошелька по имени",
+					Parameters: []Parameter{
+						{Name: "handle", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(success(handle), errorResponses("400", "401", "429", "500")),
+				},
+			},
+			"/api/me": {
+				"get": {
+					OperationID: "getMe",
+					Summary:     "Профиль текущего пользователя",
+					Responses:   merge(success(user), errorResponses("401", "429", "500")),
+				},
+			},
+			"/api/me/wallets": {
+				"get": {
+					OperationID: "getMyWallets",
+					Summary:     "Кошельки текущего пользователя с суммарным балансом",
+					Responses:   merge(success(userWallets), errorResponses("401", "429", "500")),
+				},
+			},
+			"/api/transactions": {
+				"get": {
+					OperationID: "getTransactions",
+					Summary:     "Получение последних n транзакций (при аутентификации - с участием кошельков пользователя)",
+					Parameters: []Parameter{
+						{Name: "count", In: "query", Required: true, Schema: &Schema{Type: "integer"}},
+					},
+					Responses: merge(success(&Schema{Type: "array", Nullable: true, Items: tx}), errorResponses("400", "401", "429", "500")),
+				},
+			},
+			"/api/send": {
+				"post": {
+					OperationID: "sendTransfer",
+					Summary:     "Создание новой транзакции",
+					Parameters: []Parameter{
+						{Name: "If-Match", In: "header", Schema: &Schema{Type: "string"}},
+					},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content:  map[string]MediaType{contentJSON: {Schema: tx}},
+					},
+					Responses: merge(success(&Schema{Type: "string"}), errorResponses("400", "401", "403", "412", "429", "500", "503")),
+				},
+			},
+			"/api/payouts": {
+				"post": {
+					OperationID: "createPayout",
+					Summary:     "Загрузка задания на массовую выплату из CSV-файла",
+					Parameters: []Parameter{
+						{Name: "from", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content:  map[string]MediaType{contentCSV: {Schema: &Schema{Type: "string"}}},
+					},
+					Responses: merge(accepted(payoutJob), errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/payouts/{id}": {
+				"get": {
+					OperationID: "getPayout",
+					Summary:     "Статус задания на выплату",
+					Parameters: []Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(success(payoutJob), errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/api/payouts/{id}/results": {
+				"get": {
+					OperationID: "getPayoutResults",
+					Summary:     "Результаты задания на выплату в формате CSV",
+					Parameters: []Parameter{
+						{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+					},
+					Responses: merge(map[string]Response{
+						"200": {
+							Description: "OK",
+							Content:     map[string]MediaType{contentCSV: {Schema: &Schema{Type: "string"}}},
+						},
+					}, errorResponses("400", "401", "403", "429", "500")),
+				},
+			},
+			"/healthz": {
+				"get": {
+					OperationID: "liveness",
+					Summary:     "Проба живости процесса",
+					Responses:   success(&Schema{Type: "object"}),
+				},
+			},
+			"/readyz": {
+				"get": {
+					OperationID: "readiness",
+					Summary:     "Проба готовности сервиса",
+					Responses:   merge(success(&Schema{Type: "object"}), errorResponses("503")),
+				},
+			},
+			"/api/openapi.json": {
+				"get": {
+					OperationID: "openapi",
+					Summary:     "Спецификация API",
+					Responses: map[string]Response{
+						"200": {
+							Description: "OK",
+							Content:     map[string]MediaType{contentJSON: {Schema: &Schema{Type: "object"}}},
+						},
+					},
+				},
+			},
+		},
+		Components: c,
+	}
+}
+
+// Handler создает HTTP-обработчик, отдающий спецификацию в JSON.
+func Handler(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, doc)
+	}
+}
+
+// Operation находит операцию по методу и фактическому пути запроса.
+// Возвращает также значения параметров пути.
+func (d *Document) Operation(method, path string) (*Operation, map[string]string) {
+	for _, pattern := range d.patterns() {
+		params, ok := matchPath(pattern, path)
+		if !ok {
+			continue
+		}
+		if op, ok := d.Paths[pattern][strings.ToLower(method)]; ok {
+			return op, params
+		}
+	}
+	return nil, nil
+}
+
+// streamed сообщает, что успешный ответ операции передается потоком не в JSON.
+// Такие ответы не буферизуются для проверки.
+func (op *Operation) streamed() bool {
+	ok, found := op.Responses["200"]
+	if !found || len(ok.Content) == 0 {
+		return false
+	}
+	_, isJSON := ok.Content[contentJSON]
+	return !isJSON
+}
+
+// patterns возвращает шаблоны путей: сначала без параметров, затем по алфавиту.
+func (d *Document) patterns() []string {
+	patterns := make([]string, 0, len(d.Paths))
+	for p := range d.Paths {
+		patterns = append(patterns, p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		pi, pj := strings.Count(patterns[i], "{"), strings.Count(patterns[j], "{")
+		if pi != pj {
+			return pi < pj
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
+
+// matchPath сопоставляет путь с шаблоном вида /api/wallet/{address}/balance.
+func matchPath(pattern, path string) (map[string]string, bool) {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	as := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ps) != len(as) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, seg := range ps {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if as[i] == "" {
+				return nil, false
+			}
+			params[seg[1:len(seg)-1]] = as[i]
+			continue
+		}
+		if seg != as[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// success описывает успешный ответ в стандартной обертке Response.
+func success(data *Schema) map[string]Response {
+	return successStatus(http.StatusOK, data)
+}
+
+// accepted описывает ответ о принятии запроса к фоновому выполнению.
+func accepted(data *Schema) map[string]Response {
+	return successStatus(http.StatusAccepted, data)
+}
+
+// successStatus описывает ответ с указанным кодом в стандартной обертке Response.
+func successStatus(status int, data *Schema) map[string]Response {
+	return map[string]Response{
+		strconv.Itoa(status): {
+			Description: http.StatusText(status),
+			Content: map[string]MediaType{
+				contentJSON: {Schema: &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"status": {Type: "string", Enum: []any{response.StatusOK}},
+						"code":   {Type: "integer"},
+						"data":   data,
+					},
+					Required: []string{"status"},
+				}},
+			},
+		},
+	}
+}
+
+// merge объединяет описания ответов.
+func merge(parts ...map[string]Response) map[string]Response {
+	res := make(map[string]Response)
+	for _, part := range parts {
+		for k, v := range part {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// statusCode преобразует строковый код ответа спецификации в число.
+func statusCode(code string) int {
+	n, _ := strconv.Atoi(code)
+	return n
+}
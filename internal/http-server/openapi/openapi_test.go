@@ -0,0 +1,435 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	handleHandlers "infotecsTest/internal/http-server/handlers/handle"
+	handleMocks "infotecsTest/internal/http-server/handlers/handle/mocks"
+	"infotecsTest/internal/http-server/handlers/health"
+	payoutHandlers "infotecsTest/internal/http-server/handlers/payout"
+	payoutMocks "infotecsTest/internal/http-server/handlers/payout/mocks"
+	"infotecsTest/internal/http-server/handlers/transaction"
+	txMocks "infotecsTest/internal/http-server/handlers/transaction/mocks"
+	userHandlers "infotecsTest/internal/http-server/handlers/user"
+	userMocks "infotecsTest/internal/http-server/handlers/user/mocks"
+	"infotecsTest/internal/http-server/handlers/wallet"
+	walletMocks "infotecsTest/internal/http-server/handlers/wallet/mocks"
+	"infotecsTest/internal/http-server/openapi"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRouter регистрирует обработчики так же, как main, с проверкой ответов.
+// Любое расхождение ответа со спецификацией проваливает тест.
+func newRouter(t *testing.T, spec *openapi.Document) (*chi.Mux, *walletMocks.BalanceReceiver, *txMocks.TransactionMaker, *txMocks.TransactionsReceiver) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	balance := walletMocks.NewBalanceReceiver(t)
+	maker := txMocks.NewTransactionMaker(t)
+	receiver := txMocks.NewTransactionsReceiver(t)
+	resolver := walletMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	authorizer := walletMocks.NewAuthorizer(t)
+	authorizer.On("AuthorizeWallet", mock.Anything, "wlt1foreign").Return(storage.ErrForbidden).Maybe()
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+	users := userMocks.NewRegistry(t)
+
+	router := chi.NewRouter()
+	router.Use(openapi.Validator(testLogger, spec, openapi.Options{
+		ValidateRequests:  true,
+		ValidateResponses: true,
+		OnResponseViolation: func(r *http.Request, err error) {
+			t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		},
+	}))
+	router.Get("/healthz", health.Live())
+	router.Get("/readyz", health.Ready(testLogger, health.NewRegistry()))
+	router.Get("/api/transactions", transaction.GetLast(testLogger, receiver))
+	router.Get("/api/wallet/{address}/balance", wallet.GetBalance(testLogger, balance, resolver, authorizer))
+	router.Get("/api/wallet/{address}", wallet.Details(testLogger, balance, walletMocks.NewMetadataReader(t), resolver, authorizer))
+	router.Put("/api/wallet/{address}/metadata", wallet.SetMetadata(testLogger, walletMocks.NewMetadataWriter(t), resolver, authorizer))
+	router.Get("/api/wallets", wallet.Search(testLogger, walletMocks.NewWalletFinder(t), balance))
+	router.Get("/api/wallet/{address}/statement", wallet.Statement(testLogger, walletMocks.NewStatementReader(t), resolver, authorizer))
+	router.Post("/api/send", transaction.Send(testLogger, maker, resolver, authorizer))
+	router.Put("/api/wallet/{address}/handle", handleHandlers.Claim(testLogger, handleMocks.NewRegistry(t), resolver, authorizer))
+	router.Delete("/api/wallet/{address}/handle", handleHandlers.Release(testLogger, handleMocks.NewRegistry(t), resolver, authorizer))
+	router.Get("/api/handles/{handle}", handleHandlers.Resolve(testLogger, handleMocks.NewRegistry(t)))
+	router.Post("/api/payouts", payoutHandlers.Create(testLogger, payoutMocks.NewJobCreator(t), resolver, authorizer))
+	router.Get("/api/payouts/{id}", payoutHandlers.Get(testLogger, payoutMocks.NewJobReader(t), authorizer))
+	router.Get("/api/payouts/{id}/results", payoutHandlers.Results(testLogger, payoutMocks.NewJobReader(t), authorizer))
+	router.Get("/api/me", userHandlers.Me(testLogger))
+	router.Get("/api/me/wallets", userHandlers.MyWallets(testLogger, users, balance))
+	router.Get("/api/openapi.json", openapi.Handler(spec))
+
+	return router, balance, maker, receiver
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	spec := openapi.Spec()
+	router, _, _, _ := newRouter(t, spec)
+
+	require.NoError(t, openapi.CheckRoutes(router, spec))
+
+	router.Get("/api/undocumented", func(http.ResponseWriter, *http.Request) {})
+	require.ErrorContains(t, openapi.CheckRoutes(router, spec), "undocumented route GET /api/undocumented")
+}
+
+func TestHandlersMatchSpec(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		path         string
+		header       http.Header
+		body         string
+		expectedCode int
+		expectedErr  string
+		mockSetup    func(*walletMocks.BalanceReceiver, *txMocks.TransactionMaker, *txMocks.TransactionsReceiver)
+	}{
+		{
+			name:         "Баланс кошелька",
+			method:       http.MethodGet,
+			path:         "/api/wallet/addr1/balance",
+			expectedCode: http.StatusOK,
+			mockSetup: func(b *walletMocks.BalanceReceiver, _ *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100}, nil).Once()
+			},
+		},
+		{
+			name:         "Кошелек не найден",
+			method:       http.MethodGet,
+			path:         "/api/wallet/addr1/balance",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  storage.CodeWalletNotFound,
+			mockSetup: func(b *walletMocks.BalanceReceiver, _ *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{}, storage.ErrWalletNotFound).Once()
+			},
+		},
+		{
+			name:         "Баланс не изменился",
+			method:       http.MethodGet,
+			path:         "/api/wallet/addr1/balance",
+			header:       http.Header{"If-None-Match": {`"3"`}},
+			expectedCode: http.StatusNotModified,
+			mockSetup: func(b *walletMocks.BalanceReceiver, _ *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				b.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 100, Version: 3}, nil).Once()
+			},
+		},
+		{
+			name:         "Список транзакций",
+			method:       http.MethodGet,
+			path:         "/api/transactions?count=2",
+			expectedCode: http.StatusOK,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, _ *txMocks.TransactionMaker, r *txMocks.TransactionsReceiver) {
+				r.On("GetNTransactions", 2).Return([]models.Transaction{
+					{From: "addr1", To: "addr2", Amount: 1, Time: "05:35:41 24-02-2025"},
+				}, nil).Once()
+			},
+		},
+		{
+			name:         "Пустой список транзакций",
+			method:       http.MethodGet,
+			path:         "/api/transactions?count=2",
+			expectedCode: http.StatusOK,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, _ *txMocks.TransactionMaker, r *txMocks.TransactionsReceiver) {
+				r.On("GetNTransactions", 2).Return(nil, nil).Once()
+			},
+		},
+		{
+			name:         "Count не число",
+			method:       http.MethodGet,
+			path:         "/api/transactions?count=abc",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeSchemaViolation,
+		},
+		{
+			name:         "Count отсутствует",
+			method:       http.MethodGet,
+			path:         "/api/transactions",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeSchemaViolation,
+		},
+		{
+			name:         "Успешный перевод",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			body:         `{"from":"addr1","to":"addr2","amount":10}`,
+			expectedCode: http.StatusOK,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, m *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				m.On("AddTransaction", "addr1", "addr2", 10.0).Return(nil).Once()
+			},
+		},
+		{
+			name:         "Недостаточно средств",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			body:         `{"from":"addr1","to":"addr2","amount":10}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  storage.CodeInsufficientFunds,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, m *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				m.On("AddTransaction", "addr1", "addr2", 10.0).
+					Return(&storage.InsufficientFundsError{Available: 5, Requested: 10}).Once()
+			},
+		},
+		{
+			name:         "Кошелек отправителя изменился",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			header:       http.Header{"If-Match": {`"3"`}},
+			body:         `{"from":"addr1","to":"addr2","amount":10}`,
+			expectedCode: http.StatusPreconditionFailed,
+			expectedErr:  storage.CodeVersionMismatch,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, m *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				m.On("AddTransactionIfVersion", "addr1", "addr2", 10.0, int64(3)).
+					Return(&storage.VersionMismatchError{Expected: 3, Current: 4}).Once()
+			},
+		},
+		{
+			name:         "Внутренняя ошибка",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			body:         `{"from":"addr1","to":"addr2","amount":10}`,
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  response.CodeInternal,
+			mockSetup: func(_ *walletMocks.BalanceReceiver, m *txMocks.TransactionMaker, _ *txMocks.TransactionsReceiver) {
+				m.On("AddTransaction", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("db")).Once()
+			},
+		},
+		{
+			name:         "Пустое тело",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeEmptyBody,
+		},
+		{
+			name:         "Некорректный JSON",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			body:         `{"from":`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeInvalidJSON,
+		},
+		{
+			name:         "Сумма строкой",
+			method:       http.MethodPost,
+			path:         "/api/send",
+			body:         `{"from":"addr1","to":"addr2","amount":"сто"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeSchemaViolation,
+		},
+		{
+			name:         "Неподдерживаемый формат выписки",
+			method:       http.MethodGet,
+			path:         "/api/wallet/addr1/statement?format=xlsx",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeSchemaViolation,
+		},
+		{
+			name:         "Задание на выплату без источника",
+			method:       http.MethodPost,
+			path:         "/api/payouts",
+			body:         "addr2,10\n",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  response.CodeSchemaViolation,
+		},
+		{
+			name:         "Кошелек другого пользователя",
+			method:       http.MethodGet,
+			path:         "/api/wallet/wlt1foreign/balance",
+			expectedCode: http.StatusForbidden,
+			expectedErr:  storage.CodeForbidden,
+		},
+		{
+			name:         "Профиль без аутентификации",
+			method:       http.MethodGet,
+			path:         "/api/me",
+			expectedCode: http.StatusUnauthorized,
+			expectedErr:  response.CodeUnauthorized,
+		},
+		{
+			name:         "Проба готовности",
+			method:       http.MethodGet,
+			path:         "/readyz",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Спецификация",
+			method:       http.MethodGet,
+			path:         "/api/openapi.json",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router, balance, maker, receiver := newRouter(t, openapi.Spec())
+			if tc.mockSetup != nil {
+				tc.mockSetup(balance, maker, receiver)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+
+			if tc.expectedErr != "" {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.expectedErr, resp.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestPayoutMatchesSpec(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	creator := payoutMocks.NewJobCreator(t)
+	resolver := payoutMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	authorizer := payoutMocks.NewAuthorizer(t)
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	router := chi.NewRouter()
+	router.Use(openapi.Validator(testLogger, openapi.Spec(), openapi.Options{
+		ValidateRequests:  true,
+		ValidateResponses: true,
+		OnResponseViolation: func(r *http.Request, err error) {
+			t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		},
+	}))
+	router.Post("/api/payouts", payoutHandlers.Create(testLogger, creator, resolver, authorizer))
+
+	// Файл передается обработчику целиком, без буферизации в middleware
+	file := "to,amount\n" + strings.Repeat("addr2,1\n", 200000)
+	creator.On("Create", mock.Anything, "addr1", mock.Anything).
+		Run(func(args mock.Arguments) {
+			body, err := io.ReadAll(args.Get(2).(io.Reader))
+			require.NoError(t, err)
+			require.Equal(t, file, string(body))
+		}).
+		Return(models.PayoutJob{ID: "job1", From: "addr1", Status: models.PayoutJobPending, Total: 1, Pending: 1}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/payouts?from=addr1", strings.NewReader(file))
+	req.Header.Set("Content-Type", "text/csv")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+}
+
+func TestHandlesMatchSpec(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	registry := handleMocks.NewRegistry(t)
+	resolver := handleMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	authorizer := handleMocks.NewAuthorizer(t)
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	router := chi.NewRouter()
+	router.Use(openapi.Validator(testLogger, openapi.Spec(), openapi.Options{
+		ValidateRequests:  true,
+		ValidateResponses: true,
+		OnResponseViolation: func(r *http.Request, err error) {
+			t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		},
+	}))
+	router.Put("/api/wallet/{address}/handle", handleHandlers.Claim(testLogger, registry, resolver, authorizer))
+	router.Delete("/api/wallet/{address}/handle", handleHandlers.Release(testLogger, registry, resolver, authorizer))
+	router.Get("/api/handles/{handle}", handleHandlers.Resolve(testLogger, registry))
+
+	alice := models.Handle{Handle: "alice", Address: "addr1"}
+	registry.On("ClaimHandle", mock.Anything, "addr1", "alice").Return(alice, nil).Once()
+	registry.On("ClaimHandle", mock.Anything, "addr2", "alice").Return(models.Handle{}, storage.ErrHandleTaken).Once()
+	registry.On("ResolveHandle", mock.Anything, "@alice").Return(alice, nil).Once()
+	registry.On("ReleaseHandle", mock.Anything, "addr1").Return(alice, nil).Once()
+
+	cases := []struct {
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{http.MethodPut, "/api/wallet/addr1/handle", `{"handle":"alice"}`, http.StatusOK},
+		{http.MethodPut, "/api/wallet/addr2/handle", `{"handle":"alice"}`, http.StatusConflict},
+		{http.MethodGet, "/api/handles/@alice", "", http.StatusOK},
+		{http.MethodDelete, "/api/wallet/addr1/handle", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, tc.expectedCode, rr.Code, tc.method+" "+tc.path)
+	}
+}
+
+func TestWalletMetadataMatchesSpec(t *testing.T) {
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	balance := walletMocks.NewBalanceReceiver(t)
+	reader := walletMocks.NewMetadataReader(t)
+	writer := walletMocks.NewMetadataWriter(t)
+	finder := walletMocks.NewWalletFinder(t)
+	resolver := walletMocks.NewAddressResolver(t)
+	resolver.On("ResolveAddress", mock.Anything).Return(func(a string) (string, error) { return a, nil }).Maybe()
+	authorizer := walletMocks.NewAuthorizer(t)
+	authorizer.On("AuthorizeWallet", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	router := chi.NewRouter()
+	router.Use(openapi.Validator(testLogger, openapi.Spec(), openapi.Options{
+		ValidateRequests:  true,
+		ValidateResponses: true,
+		OnResponseViolation: func(r *http.Request, err error) {
+			t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		},
+	}))
+	router.Get("/api/wallet/{address}", wallet.Details(testLogger, balance, reader, resolver, authorizer))
+	router.Put("/api/wallet/{address}/metadata", wallet.SetMetadata(testLogger, writer, resolver, authorizer))
+	router.Get("/api/wallets", wallet.Search(testLogger, finder, balance))
+
+	md := models.WalletMetadata{Owner: "cust-1", Labels: []string{"vip"}, Metadata: map[string]string{"region": "eu"}}
+	balance.On("GetWalletBalance", "addr1").Return(models.Wallet{Address: "addr1", Balance: 10, Version: 2}, nil)
+	reader.On("WalletMetadata", mock.Anything, "addr1").Return(md, nil).Once()
+	writer.On("SetWalletMetadata", mock.Anything, "addr1", md).Return(md, nil).Once()
+	finder.On("FindWallets", mock.Anything, models.WalletFilter{Label: "vip", Limit: 100}).
+		Return([]models.Wallet{{Address: "addr1", Owner: "cust-1", Labels: []string{"vip"}}}, nil).Once()
+
+	cases := []struct {
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{http.MethodGet, "/api/wallet/addr1", "", http.StatusOK},
+		{http.MethodPut, "/api/wallet/addr1/metadata", `{"owner":"cust-1","labels":["vip"],"metadata":{"region":"eu"}}`, http.StatusOK},
+		{http.MethodGet, "/api/wallets?label=vip", "", http.StatusOK},
+		{http.MethodGet, "/api/wallets", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, tc.expectedCode, rr.Code, tc.method+" "+tc.path)
+	}
+}
@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// register строит схему структуры и добавляет ее в компоненты.
+// Возвращает ссылку на зарегистрированную схему.
+func (c *Components) register(name string, t reflect.Type) *Schema {
+	c.Schemas[name] = schemaOf(t)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schemaOf строит схему по типу Go с учетом json-тегов.
+// Поля без omitempty считаются обязательными.
+func schemaOf(t reflect.Type) *Schema {
+	if t == reflect.TypeFor[time.Time]() {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem())
+		s.Nullable = true
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Nullable: t.Kind() == reflect.Slice, Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		// interface{} и прочие типы допускают любое значение
+		return &Schema{}
+	}
+}
+
+// structSchema строит схему объекта по экспортируемым полям структуры.
+func structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
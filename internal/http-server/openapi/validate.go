@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError содержит список нарушений схемы.
+type ValidationError struct {
+	Violations []string // Нарушения в формате "путь: описание"
+}
+
+// Error объединяет нарушения в одну строку.
+func (e *ValidationError) Error() string {
+	return "openapi: " + strings.Join(e.Violations, "; ")
+}
+
+// validator проверяет значения по схемам с разрешением ссылок на компоненты.
+type validator struct {
+	components Components
+	violations []string
+}
+
+// ValidateValue проверяет значение, полученное из encoding/json, по схеме.
+// Возвращает *ValidationError при нарушениях.
+func (d *Document) ValidateValue(schema *Schema, value any, path string) error {
+	v := &validator{components: d.Components}
+	v.validate(schema, value, path)
+	if len(v.violations) > 0 {
+		return &ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+// ValidateJSON разбирает JSON-документ и проверяет его по схеме.
+func (d *Document) ValidateJSON(schema *Schema, data []byte, path string) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &ValidationError{Violations: []string{fmt.Sprintf("%s: invalid JSON: %v", path, err)}}
+	}
+	return d.ValidateValue(schema, value, path)
+}
+
+// resolve возвращает схему, на которую указывает ссылка $ref.
+func (v *validator) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = v.components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) validate(s *Schema, value any, path string) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			v.fail(path, "must not be null")
+		}
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		v.fail(path, "must be one of %v", s.Enum)
+	}
+
+	switch s.Type {
+	case "":
+		return
+	case "string":
+		if _, ok := value.(string); !ok {
+			v.fail(path, "expected string, got %s", typeName(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(path, "expected boolean, got %s", typeName(value))
+		}
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok {
+			v.fail(path, "expected %s, got %s", s.Type, typeName(value))
+			return
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			v.fail(path, "expected integer, got %v", n)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			v.fail(path, "must be >= %v", *s.Minimum)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			v.fail(path, "expected array, got %s", typeName(value))
+			return
+		}
+		for i, item := range items {
+			v.validate(s.Items, item, path+"["+strconv.Itoa(i)+"]")
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.fail(path, "expected object, got %s", typeName(value))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				v.fail(path+"."+name, "is required")
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				v.validate(prop, obj[k], path+"."+k)
+			} else if s.AdditionalProperties != nil {
+				v.validate(s.AdditionalProperties, obj[k], path+"."+k)
+			}
+		}
+	}
+}
+
+// typeName возвращает имя JSON-типа значения.
+func typeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,504 @@
+// Package ledger реализует движок переводов в памяти.
+// Балансы кошельков хранятся в памяти под блокировками, распределенными по кошелькам
+// (lock striping), каждый перевод до подтверждения записывается в журнал упреждающей записи
+// с групповой фиксацией, а журнал периодически переносится в SQLite.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Store - хранилище, в которое переносится журнал движка.
+// Из него же загружаются кошельки и читаются история транзакций и выписки:
+// движок переносит журнал перед чтением, поэтому история не отстает от балансов в памяти.
+type Store interface {
+	Wallets(ctx context.Context) ([]models.Wallet, error)
+	GetWalletBalance(address string) (models.Wallet, error)
+	TotalBalance(ctx context.Context) (float64, error)
+	LedgerCheckpoint(ctx context.Context) (uint64, error)
+	ApplyTransfers(ctx context.Context, transfers []storage.Transfer) error
+	Backup(ctx context.Context, dest string) error
+
+	GetNTransactions(N int) ([]models.Transaction, error)
+	UserTransactions(ctx context.Context, userID string, N int) ([]models.Transaction, error)
+	RecentTransactions(ctx context.Context, userID string, N int, fn func(models.Transaction) error) error
+	OpeningBalance(ctx context.Context, address string, at time.Time) (float64, error)
+	Movements(ctx context.Context, address string, from, to time.Time, fn func(models.Movement) error) error
+}
+
+// account - состояние кошелька в памяти. Защищено блокировкой полосы кошелька.
+type account struct {
+	balance float64
+	version int64
+}
+
+// Engine выполняет переводы в памяти и реализует интерфейсы обработчиков
+// переводов, балансов и истории транзакций. Кошельки загружаются при создании,
+// а добавленные в хранилище позже - при первом обращении.
+type Engine struct {
+	log      *slog.Logger
+	store    Store
+	cfg      config.Ledger
+	observer storage.Observer
+
+	accountsMu sync.RWMutex // Защищает набор кошельков, но не их состояние
+	accounts   map[string]*account
+	stripes    []sync.Mutex
+	wal        *wal
+}
+
+// New переносит в хранилище переводы, оставшиеся в журнале после прошлого запуска,
+// загружает кошельки и открывает журнал.
+// Перенос журнала в хранилище по расписанию запускается отдельно через Run.
+func New(log *slog.Logger, store Store, cfg config.Ledger) (*Engine, error) {
+	const op = "ledger.New"
+
+	ctx := context.Background()
+	log = log.With(slog.String("component", "ledger"))
+
+	if err := os.MkdirAll(cfg.WALDir, 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	seq, err := Recover(ctx, log, store, cfg.WALDir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	wallets, err := store.Wallets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	accounts := make(map[string]*account, len(wallets))
+	for _, w := range wallets {
+		accounts[w.Address] = &account{balance: w.Balance, version: w.Version}
+	}
+
+	w, err := openWAL(cfg.WALDir, seq, cfg.MaxBatch)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("ledger started",
+		slog.Int("wallets", len(accounts)),
+		slog.Uint64("seq", seq),
+		slog.Int("stripes", cfg.Stripes),
+	)
+	return &Engine{
+		log:      log,
+		store:    store,
+		cfg:      cfg,
+		observer: storage.NopObserver{},
+		accounts: accounts,
+		stripes:  make([]sync.Mutex, cfg.Stripes),
+		wal:      w,
+	}, nil
+}
+
+// Recover переносит в хранилище переводы из журнала в каталоге dir,
+// не перенесенные до остановки процесса, и удаляет журнал.
+// Возвращает номер последней перенесенной записи.
+// Вызывается и при выключенном движке, чтобы переводы не были потеряны после его отключения.
+func Recover(ctx context.Context, log *slog.Logger, store Store, dir string) (uint64, error) {
+	const op = "ledger.Recover"
+
+	checkpoint, err := store.LedgerCheckpoint(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	paths, err := segments(dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(paths) == 0 {
+		return checkpoint, nil
+	}
+
+	read, err := readSegments(paths)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Записи, не перенесенные в хранилище, должны идти подряд сразу после сохраненной
+	var transfers []storage.Transfer
+	for _, t := range read {
+		if t.Seq <= checkpoint {
+			continue
+		}
+		if t.Seq != checkpoint+uint64(len(transfers))+1 {
+			return 0, fmt.Errorf("%s: %w: expected record %d after checkpoint %d, got %d",
+				op, ErrCorrupted, checkpoint+uint64(len(transfers))+1, checkpoint, t.Seq)
+		}
+		transfers = append(transfers, t)
+	}
+
+	if err = store.ApplyTransfers(ctx, transfers); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, path := range paths {
+		if err = os.Remove(path); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err = syncDir(dir); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(transfers) > 0 {
+		log.Info("ledger wal recovered",
+			slog.Int("transfers", len(transfers)),
+			slog.Uint64("seq", transfers[len(transfers)-1].Seq),
+		)
+		return transfers[len(transfers)-1].Seq, nil
+	}
+	return checkpoint, nil
+}
+
+// Discard удаляет журнал в каталоге dir без переноса в хранилище.
+// Используется после восстановления хранилища из резервной копии:
+// записи журнала относятся к замененному файлу. Возвращает число удаленных сегментов.
+func Discard(dir string) (int, error) {
+	const op = "ledger.Discard"
+
+	paths, err := segments(dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, path := range paths {
+		if err = os.Remove(path); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if len(paths) > 0 {
+		if err = syncDir(dir); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return len(paths), nil
+}
+
+// SetObserver устанавливает получателя сведений о переводах.
+func (e *Engine) SetObserver(o storage.Observer) {
+	e.observer = o
+}
+
+// GetWalletBalance возвращает баланс и версию кошелька из памяти.
+// Баланс может включать переводы, еще не подтвержденные записью в журнал.
+// Возвращает ErrWalletNotFound если кошелек не существует.
+func (e *Engine) GetWalletBalance(address string) (models.Wallet, error) {
+	const op = "ledger.GetWalletBalance"
+
+	if err := e.wal.failure(); err != nil {
+		return models.Wallet{}, fmt.Errorf("%s: %w", op, err)
+	}
+	acc, err := e.account(address)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+
+	mu := &e.stripes[e.stripe(address)]
+	mu.Lock()
+	defer mu.Unlock()
+
+	return models.Wallet{Address: address, Balance: acc.balance, Version: acc.version}, nil
+}
+
+// AddTransaction выполняет перевод между кошельками.
+// Перевод подтверждается после записи в журнал на диске.
+func (e *Engine) AddTransaction(from, to string, amount float64) error {
+	const op = "ledger.AddTransaction"
+	defer e.observe(op, time.Now())
+
+	err := e.transfer(from, to, amount, 0, "")
+	e.observer.ObserveTransfer(amount, err)
+	return err
+}
+
+// AddTransactionWithReference выполняет перевод и сохраняет вместе с ним ссылку на его источник.
+// По ссылке после сбоя можно установить, был ли перевод выполнен.
+func (e *Engine) AddTransactionWithReference(from, to string, amount float64, reference string) error {
+	const op = "ledger.AddTransactionWithReference"
+	defer e.observe(op, time.Now())
+
+	err := e.transfer(from, to, amount, 0, reference)
+	e.observer.ObserveTransfer(amount, err)
+	return err
+}
+
+// AddTransactionIfVersion выполняет перевод, только если версия кошелька
+// отправителя равна version. Иначе возвращает *storage.VersionMismatchError.
+func (e *Engine) AddTransactionIfVersion(from, to string, amount float64, version int64) error {
+	const op = "ledger.AddTransactionIfVersion"
+	defer e.observe(op, time.Now())
+
+	err := e.transfer(from, to, amount, version, "")
+	e.observer.ObserveTransfer(amount, err)
+	return err
+}
+
+// transfer проверяет и выполняет перевод под блокировками обоих кошельков.
+// Перевод ставится в очередь журнала под блокировками, поэтому переводы одного кошелька
+// попадают в журнал в том же порядке, в котором применяются в памяти. Блокировки
+// освобождаются до записи на диск, чтобы переводы других кошельков попали в ту же группу;
+// подтверждение возвращается только после фиксации группы. Перевод, зависящий от еще
+// не записанного, оказывается в журнале позже него и не может быть подтвержден раньше.
+// Нулевая version отключает проверку версии отправителя.
+func (e *Engine) transfer(from, to string, amount float64, version int64, reference string) error {
+	const op = "ledger.transfer"
+
+	if amount <= 0 {
+		return storage.ErrIncorrectAmount
+	}
+	if from == to {
+		return storage.ErrAddressesEqual
+	}
+	src, err := e.account(from)
+	if err != nil {
+		return err
+	}
+	dst, err := e.account(to)
+	if err != nil {
+		return err
+	}
+
+	unlock := e.lock(from, to)
+	if version != 0 && src.version != version {
+		unlock()
+		return &storage.VersionMismatchError{Expected: version, Current: src.version}
+	}
+	if src.balance < amount {
+		unlock()
+		return &storage.InsufficientFundsError{Available: src.balance, Requested: amount}
+	}
+
+	committed, err := e.wal.enqueue(storage.Transfer{From: from, To: to, Amount: amount, Time: time.Now(), Reference: reference})
+	if err != nil {
+		unlock()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	src.balance -= amount
+	src.version++
+	dst.balance += amount
+	dst.version++
+	unlock()
+
+	// После ошибки записи состояние в памяти расходится с журналом:
+	// движок перестает принимать переводы и отдавать балансы до перезапуска
+	if err = <-committed; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// account возвращает состояние кошелька в памяти.
+// Кошелек, добавленный в хранилище после запуска движка, загружается при первом обращении:
+// переводов через движок у него еще не было, поэтому состояние в хранилище актуально.
+// Возвращает ErrWalletNotFound, если кошелька нет и в хранилище.
+func (e *Engine) account(address string) (*account, error) {
+	e.accountsMu.RLock()
+	acc, ok := e.accounts[address]
+	e.accountsMu.RUnlock()
+	if ok {
+		return acc, nil
+	}
+
+	w, err := e.store.GetWalletBalance(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Кошелек мог загрузить параллельный запрос: его состояние уже могло измениться переводами
+	e.accountsMu.Lock()
+	defer e.accountsMu.Unlock()
+	if acc, ok = e.accounts[address]; ok {
+		return acc, nil
+	}
+	acc = &account{balance: w.Balance, version: w.Version}
+	e.accounts[address] = acc
+	e.log.Info("wallet loaded", slog.String("address", address))
+	return acc, nil
+}
+
+// lock захватывает блокировки полос двух кошельков в порядке возрастания номера,
+// чтобы встречные переводы не взаимоблокировались. Возвращает функцию освобождения.
+func (e *Engine) lock(a, b string) func() {
+	i, j := e.stripe(a), e.stripe(b)
+	if i > j {
+		i, j = j, i
+	}
+
+	e.stripes[i].Lock()
+	if i == j {
+		return e.stripes[i].Unlock
+	}
+	e.stripes[j].Lock()
+	return func() {
+		e.stripes[j].Unlock()
+		e.stripes[i].Unlock()
+	}
+}
+
+// stripe возвращает номер полосы блокировок кошелька.
+func (e *Engine) stripe(address string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(address))
+	return int(h.Sum32() % uint32(len(e.stripes)))
+}
+
+// TotalBalance возвращает суммарный баланс всех кошельков.
+// Переводы не меняют сумму, а журнал переносится в хранилище целыми группами,
+// поэтому сумма читается из хранилища без блокировки кошельков в памяти
+// и учитывает кошельки, еще не загруженные движком.
+func (e *Engine) TotalBalance(ctx context.Context) (float64, error) {
+	const op = "ledger.TotalBalance"
+
+	total, err := e.store.TotalBalance(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return total, nil
+}
+
+// GetNTransactions переносит журнал в хранилище и возвращает N последних транзакций.
+func (e *Engine) GetNTransactions(N int) ([]models.Transaction, error) {
+	const op = "ledger.GetNTransactions"
+
+	if err := e.Checkpoint(context.Background()); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return e.store.GetNTransactions(N)
+}
+
+// UserTransactions переносит журнал в хранилище и возвращает N последних транзакций
+// с участием кошельков пользователя.
+func (e *Engine) UserTransactions(ctx context.Context, userID string, N int) ([]models.Transaction, error) {
+	const op = "ledger.UserTransactions"
+
+	if err := e.Checkpoint(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return e.store.UserTransactions(ctx, userID, N)
+}
+
+// RecentTransactions переносит журнал в хранилище и передает в fn N последних транзакций:
+// все или, при непустом userID, с участием кошельков пользователя.
+func (e *Engine) RecentTransactions(ctx context.Context, userID string, N int, fn func(models.Transaction) error) error {
+	const op = "ledger.RecentTransactions"
+
+	if err := e.Checkpoint(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return e.store.RecentTransactions(ctx, userID, N, fn)
+}
+
+// OpeningBalance переносит журнал в хранилище и возвращает баланс кошелька на момент at.
+func (e *Engine) OpeningBalance(ctx context.Context, address string, at time.Time) (float64, error) {
+	const op = "ledger.OpeningBalance"
+
+	if err := e.Checkpoint(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return e.store.OpeningBalance(ctx, address, at)
+}
+
+// Movements переносит журнал в хранилище и передает в fn движения по кошельку за период.
+func (e *Engine) Movements(ctx context.Context, address string, from, to time.Time, fn func(models.Movement) error) error {
+	const op = "ledger.Movements"
+
+	if err := e.Checkpoint(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return e.store.Movements(ctx, address, from, to, fn)
+}
+
+// Checkpoint переносит подтвержденные переводы из журнала в хранилище
+// и удаляет перенесенные сегменты журнала.
+func (e *Engine) Checkpoint(ctx context.Context) error {
+	const op = "ledger.Checkpoint"
+	defer e.observe(op, time.Now())
+
+	if err := e.wal.checkpoint(func(transfers []storage.Transfer) error {
+		return e.store.ApplyTransfers(ctx, transfers)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Run переносит журнал в хранилище с интервалом checkpoint_interval до отмены ctx.
+// Последний перенос выполняется при закрытии движка.
+func (e *Engine) Run(ctx context.Context) {
+	const op = "ledger.Run"
+
+	log := e.log.With("op", op)
+
+	ticker := time.NewTicker(e.cfg.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Checkpoint(ctx); err != nil {
+				log.Error("failed to checkpoint ledger", sl.Err(err))
+			}
+		}
+	}
+}
+
+// Backup переносит журнал в хранилище и создает копию хранилища в dest.
+// Переводы, подтвержденные после переноса, в копию не попадают.
+func (e *Engine) Backup(ctx context.Context, dest string) error {
+	const op = "ledger.Backup"
+
+	if err := e.Checkpoint(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := e.store.Backup(ctx, dest); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Check сообщает об ошибке записи журнала, после которой движок не принимает переводы.
+// Подходит для регистрации в качестве проверки готовности.
+func (e *Engine) Check(_ context.Context) error {
+	const op = "ledger.Check"
+
+	if err := e.wal.failure(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Close прекращает прием переводов, дожидается записи принятых
+// и переносит журнал в хранилище. Хранилище не закрывается.
+func (e *Engine) Close() error {
+	const op = "ledger.Close"
+
+	err := e.wal.close()
+	if cpErr := e.Checkpoint(context.Background()); cpErr != nil {
+		err = errors.Join(err, cpErr)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// observe записывает длительность операции.
+func (e *Engine) observe(op string, start time.Time) {
+	e.observer.ObserveQuery(op, time.Since(start))
+}
@@ -0,0 +1,417 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/ledger"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// sqliteConfig - настройки SQLite из config/local.yaml.
+var sqliteConfig = config.SQLite{
+	JournalMode:  "wal",
+	Synchronous:  "normal",
+	BusyTimeout:  5 * time.Second,
+	TxLock:       "immediate",
+	MaxOpenConns: 8,
+	MaxIdleConns: 8,
+	BusyRetries:  3,
+	RetryBackoff: time.Millisecond,
+}
+
+// newStore создает хранилище с кошельками wallets во временном каталоге.
+func newStore(tb testing.TB, wallets []models.Wallet) (*sqlite.Storage, string) {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	s, err := sqlite.New(filepath.Join(dir, "storage.db"), sqliteConfig)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = s.Close() })
+
+	require.NoError(tb, s.Seed(context.Background(), wallets, nil))
+	return s, dir
+}
+
+// ledgerConfig возвращает настройки движка с журналом в dir.
+func ledgerConfig(dir string) config.Ledger {
+	return config.Ledger{
+		Enabled:            true,
+		WALDir:             filepath.Join(dir, "wal"),
+		Stripes:            64,
+		MaxBatch:           512,
+		CheckpointInterval: time.Second,
+	}
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}})
+
+	e, err := ledger.New(testLogger, s, ledgerConfig(dir))
+	require.NoError(t, err)
+
+	require.NoError(t, e.AddTransaction("a", "b", 10))
+	require.NoError(t, e.AddTransactionIfVersion("a", "b", 5, 2))
+
+	cases := []struct {
+		name        string
+		from, to    string
+		amount      float64
+		version     int64
+		expectedErr error
+	}{
+		{name: "Кошелек не найден", from: "a", to: "ghost", amount: 1, expectedErr: storage.ErrWalletNotFound},
+		{name: "Недостаточно средств", from: "a", to: "b", amount: 100, expectedErr: storage.ErrInsufficientFunds},
+		{name: "Некорректная сумма", from: "a", to: "b", amount: 0, expectedErr: storage.ErrIncorrectAmount},
+		{name: "Одинаковые адреса", from: "a", to: "a", amount: 1, expectedErr: storage.ErrAddressesEqual},
+		{name: "Кошелек изменился", from: "a", to: "b", amount: 1, version: 1, expectedErr: storage.ErrVersionMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := e.AddTransactionIfVersion(tc.from, tc.to, tc.amount, tc.version)
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+
+	a, err := e.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "a", Balance: 35, Version: 3}, a)
+
+	// До переноса журнала хранилище не изменяется
+	stored, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 50.0, stored.Balance)
+
+	require.NoError(t, e.Checkpoint(ctx))
+	stored, err = s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, a, stored)
+
+	txs, err := s.GetNTransactions(10)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+
+	require.NoError(t, e.Close())
+	require.ErrorIs(t, e.AddTransaction("a", "b", 1), ledger.ErrClosed)
+}
+
+func TestEngineLoadsNewWallets(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}})
+
+	e, err := ledger.New(testLogger, s, ledgerConfig(dir))
+	require.NoError(t, err)
+	defer func() { _ = e.Close() }()
+	before, err := e.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	// Кошелек добавлен в хранилище после запуска движка, например командой seed
+	require.NoError(t, s.Seed(ctx, []models.Wallet{{Address: "b", Balance: 20}}, nil))
+
+	require.NoError(t, e.AddTransaction("b", "a", 5))
+	b, err := e.GetWalletBalance("b")
+	require.NoError(t, err)
+	require.Equal(t, 15.0, b.Balance)
+
+	_, err = e.GetWalletBalance("ghost")
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+	require.ErrorIs(t, e.AddTransaction("a", "ghost", 1), storage.ErrWalletNotFound)
+
+	total, err := e.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before+20, total)
+}
+
+func TestEngineHistory(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}})
+	user, err := s.CreateUser(ctx, models.UserRequest{Name: "alice"})
+	require.NoError(t, err)
+	require.NoError(t, s.AssignWallet(ctx, user.ID, "b"))
+
+	e, err := ledger.New(testLogger, s, ledgerConfig(dir))
+	require.NoError(t, err)
+	defer func() { _ = e.Close() }()
+
+	// История читается без ожидания переноса журнала по расписанию
+	start := time.Now().Add(-time.Minute)
+	require.NoError(t, e.AddTransaction("a", "b", 10))
+	require.NoError(t, e.AddTransaction("a", "b", 5))
+
+	txs, err := e.GetNTransactions(10)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	txs, err = e.UserTransactions(ctx, user.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+
+	// Итог выписки совпадает с балансом в памяти
+	require.NoError(t, e.AddTransaction("b", "a", 1))
+	opening, err := e.OpeningBalance(ctx, "b", start)
+	require.NoError(t, err)
+	closing := opening
+	require.NoError(t, e.Movements(ctx, "b", start, time.Now().Add(time.Minute), func(m models.Movement) error {
+		if m.To == "b" {
+			closing += m.Amount
+		} else {
+			closing -= m.Amount
+		}
+		return nil
+	}))
+	b, err := e.GetWalletBalance("b")
+	require.NoError(t, err)
+	require.Equal(t, b.Balance, closing)
+}
+
+func TestEngineHotWallet(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "fee", Balance: 5}, {Address: "a", Balance: 50}})
+	require.NoError(t, s.SetHotWallets(ctx, []config.HotWallet{{Address: "fee", Shards: 4}}))
+	for range 3 {
+		require.NoError(t, s.AddTransaction("a", "fee", 2))
+	}
+
+	// Движок загружает баланс с подбалансами, перенос журнала объединяет их
+	e, err := ledger.New(testLogger, s, ledgerConfig(dir))
+	require.NoError(t, err)
+	defer func() { _ = e.Close() }()
+
+	fee, err := e.GetWalletBalance("fee")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "fee", Balance: 11, Version: 4}, fee)
+
+	require.NoError(t, e.AddTransaction("fee", "a", 8))
+	require.NoError(t, e.Checkpoint(ctx))
+
+	stored, err := s.GetWalletBalance("fee")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "fee", Balance: 3, Version: 5}, stored)
+}
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}})
+	cfg := ledgerConfig(dir)
+
+	// Процесс остановлен без переноса журнала, последняя запись не дописана
+	crashed, err := ledger.New(testLogger, s, cfg)
+	require.NoError(t, err)
+	require.NoError(t, crashed.AddTransaction("a", "b", 10))
+	require.NoError(t, crashed.Checkpoint(ctx))
+	require.NoError(t, crashed.AddTransaction("a", "b", 5))
+	require.NoError(t, crashed.AddTransaction("b", "a", 1))
+
+	paths, err := filepath.Glob(filepath.Join(cfg.WALDir, "*.wal"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	f, err := os.OpenFile(paths[len(paths)-1], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	e, err := ledger.New(testLogger, s, cfg)
+	require.NoError(t, err)
+	defer func() { _ = e.Close() }()
+
+	for address, expected := range map[string]models.Wallet{
+		"a": {Address: "a", Balance: 36, Version: 4},
+		"b": {Address: "b", Balance: 14, Version: 4},
+	} {
+		stored, err := s.GetWalletBalance(address)
+		require.NoError(t, err)
+		require.Equal(t, expected, stored)
+
+		w, err := e.GetWalletBalance(address)
+		require.NoError(t, err)
+		require.Equal(t, expected, w)
+	}
+
+	// Повторное восстановление не переносит переводы дважды
+	seq, err := ledger.Recover(ctx, testLogger, s, cfg.WALDir)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), seq)
+	txs, err := s.GetNTransactions(10)
+	require.NoError(t, err)
+	require.Len(t, txs, 3)
+}
+
+func TestRecoverReference(t *testing.T) {
+	ctx := context.Background()
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}, {Address: "c"}})
+	cfg := ledgerConfig(dir)
+
+	require.NoError(t, s.CreatePayoutJob(ctx,
+		models.PayoutJob{ID: "job1", From: "a", CreatedAt: time.Now()},
+		[]models.PayoutRow{{Line: 1, To: "b", Amount: 10}, {Line: 2, To: "c", Amount: 5}},
+	))
+
+	// Перевод по первой строке записан только в журнал, вторая строка прервана до перевода
+	crashed, err := ledger.New(testLogger, s, cfg)
+	require.NoError(t, err)
+	row, ok, err := s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, crashed.AddTransactionWithReference(row.From, row.To, row.Amount, row.TransferReference))
+	require.NoError(t, crashed.AddTransaction("a", "c", 1))
+	_, ok, err = s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Ссылка переносится из журнала вместе с переводом
+	e, err := ledger.New(testLogger, s, cfg)
+	require.NoError(t, err)
+	defer func() { _ = e.Close() }()
+
+	succeeded, unknown, err := s.RecoverPayoutRows(ctx, "outcome_unknown")
+	require.NoError(t, err)
+	require.Equal(t, 1, succeeded)
+	require.Equal(t, 1, unknown)
+
+	stored, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 39.0, stored.Balance)
+}
+
+func TestRecoverCorrupted(t *testing.T) {
+	s, dir := newStore(t, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}})
+	cfg := ledgerConfig(dir)
+
+	// Поврежден не последний сегмент: часть подтвержденных переводов потеряна
+	require.NoError(t, os.MkdirAll(cfg.WALDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.WALDir, fmt.Sprintf("%020d.wal", 1)), []byte{1, 2, 3}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.WALDir, fmt.Sprintf("%020d.wal", 2)), nil, 0o600))
+
+	_, err := ledger.New(testLogger, s, cfg)
+	require.ErrorIs(t, err, ledger.ErrCorrupted)
+}
+
+func TestEngineConcurrent(t *testing.T) {
+	const (
+		workers   = 16
+		transfers = 200
+		funds     = 3000 // Меньше числа переводов из "a": часть должна быть отклонена
+	)
+
+	ctx := context.Background()
+	wallets := []models.Wallet{{Address: "a", Balance: funds}}
+	for i := range workers {
+		wallets = append(wallets, models.Wallet{Address: fmt.Sprintf("w%d", i), Balance: transfers})
+	}
+	s, dir := newStore(t, wallets)
+	cfg := ledgerConfig(dir)
+	cfg.Stripes = 4 // Кошельки делят полосы: проверяется упорядоченный захват блокировок
+
+	e, err := ledger.New(testLogger, s, cfg)
+	require.NoError(t, err)
+	before, err := e.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	var succeeded, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			self, next := fmt.Sprintf("w%d", i), fmt.Sprintf("w%d", (i+1)%workers)
+			for range transfers {
+				err := e.AddTransaction("a", self, 1)
+				switch {
+				case err == nil:
+					succeeded.Add(1)
+				case errors.Is(err, storage.ErrInsufficientFunds):
+					rejected.Add(1)
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+				// Встречные переводы между соседними кошельками
+				if err = e.AddTransaction(self, next, 1); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(funds), succeeded.Load())
+	require.Equal(t, int64(workers*transfers-funds), rejected.Load())
+
+	total, err := e.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, total)
+
+	require.NoError(t, e.Close())
+	stored, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, total, stored)
+}
+
+// BenchmarkTransfer сравнивает пропускную способность переводов между случайными
+// кошельками при параллельных запросах: напрямую в SQLite и через движок в памяти.
+func BenchmarkTransfer(b *testing.B) {
+	const (
+		wallets     = 1000
+		parallelism = 64 // Одновременных запросов на процессор, как у нагруженного HTTP-сервера
+	)
+
+	seed := make([]models.Wallet, wallets)
+	for i := range seed {
+		seed[i] = models.Wallet{Address: fmt.Sprintf("w%04d", i), Balance: 1e12}
+	}
+
+	type maker interface {
+		AddTransaction(from, to string, amount float64) error
+	}
+	run := func(b *testing.B, m maker) {
+		var n atomic.Uint64
+		b.SetParallelism(parallelism)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				i := n.Add(1)
+				from, to := seed[i%wallets].Address, seed[(i*7+1)%wallets].Address
+				if from == to {
+					continue
+				}
+				if err := m.AddTransaction(from, to, 1); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+		b.StopTimer()
+	}
+
+	b.Run("sqlite", func(b *testing.B) {
+		s, _ := newStore(b, seed)
+		run(b, s)
+	})
+
+	b.Run("ledger", func(b *testing.B) {
+		s, dir := newStore(b, seed)
+		e, err := ledger.New(testLogger, s, ledgerConfig(dir))
+		require.NoError(b, err)
+		defer func() { _ = e.Close() }()
+
+		// Перенос журнала в хранилище по расписанию входит в измерение
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go e.Run(ctx)
+
+		run(b, e)
+	})
+}
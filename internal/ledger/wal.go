@@ -0,0 +1,419 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"infotecsTest/internal/storage"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Журнал хранится в сегментах <номер первой записи>.wal.
+// Запись: длина данных (4 байта), CRC-32C данных (4 байта), данные:
+// номер (8), время в нс (8), сумма (8), адрес отправителя и получателя (2 байта длины + байты)
+// и, для перевода со ссылкой, ссылка в том же виде. Записи без ссылки совпадают с прежним форматом.
+const (
+	segmentExt      = ".wal"
+	segmentFileMode = 0o600
+	headerSize      = 8
+	maxPayloadSize  = 24 + 3*(2+math.MaxUint16)
+)
+
+var (
+	// ErrClosed возвращается при переводе после остановки движка.
+	ErrClosed = errors.New("ledger is closed")
+
+	// ErrCorrupted возвращается, если запись журнала повреждена не в конце последнего сегмента.
+	ErrCorrupted = errors.New("ledger wal is corrupted")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// walRequest - перевод, ожидающий записи в журнал.
+type walRequest struct {
+	transfer storage.Transfer
+	result   chan error
+}
+
+// wal - журнал упреждающей записи с групповой фиксацией:
+// переводы, поступившие во время записи предыдущей группы, записываются
+// следующей группой с одним вызовом fsync.
+type wal struct {
+	dir      string
+	maxBatch int
+
+	closeMu sync.RWMutex // Защищает отправку в reqs от закрытия канала
+	closed  bool
+	reqs    chan *walRequest
+	done    chan struct{}
+
+	mu      sync.Mutex // Защищает поля ниже
+	file    *os.File
+	segment string             // Путь к текущему сегменту
+	seq     uint64             // Номер последней записанной записи
+	pending []storage.Transfer // Записанные, но не перенесенные в хранилище переводы
+	sealed  []string           // Закрытые сегменты, ожидающие переноса
+
+	err atomic.Pointer[error] // Ошибка записи; после нее журнал не принимает переводы
+
+	checkpointMu sync.Mutex // Переносы выполняются по очереди
+}
+
+// openWAL начинает новый сегмент после записи seq и запускает групповую фиксацию.
+func openWAL(dir string, seq uint64, maxBatch int) (*wal, error) {
+	w := &wal{
+		dir:      dir,
+		maxBatch: maxBatch,
+		reqs:     make(chan *walRequest, maxBatch),
+		done:     make(chan struct{}),
+		seq:      seq,
+	}
+	if err := w.startSegment(); err != nil {
+		return nil, err
+	}
+
+	go w.commitLoop()
+	return w, nil
+}
+
+// enqueue ставит перевод в очередь записи и возвращает канал с результатом фиксации.
+// Переводы записываются в порядке постановки в очередь.
+func (w *wal) enqueue(t storage.Transfer) (<-chan error, error) {
+	req := &walRequest{transfer: t, result: make(chan error, 1)}
+
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if err := w.failure(); err != nil {
+		return nil, err
+	}
+	w.reqs <- req
+	return req.result, nil
+}
+
+// commitLoop собирает ожидающие переводы в группы и фиксирует их.
+func (w *wal) commitLoop() {
+	defer close(w.done)
+
+	batch := make([]*walRequest, 0, w.maxBatch)
+	for req := range w.reqs {
+		batch = append(batch[:0], req)
+
+	collect:
+		for len(batch) < w.maxBatch {
+			select {
+			case next, ok := <-w.reqs:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+
+		err := w.commit(batch)
+		for _, r := range batch {
+			r.result <- err
+		}
+	}
+}
+
+// commit записывает группу переводов в текущий сегмент одним вызовом fsync.
+func (w *wal) commit(batch []*walRequest) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.failure(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i, r := range batch {
+		r.transfer.Seq = w.seq + uint64(i) + 1
+		encode(&buf, r.transfer)
+	}
+
+	if _, err := w.file.Write(buf.Bytes()); err != nil {
+		return w.fail(fmt.Errorf("write wal: %w", err))
+	}
+	if err := w.file.Sync(); err != nil {
+		return w.fail(fmt.Errorf("sync wal: %w", err))
+	}
+
+	w.seq += uint64(len(batch))
+	for _, r := range batch {
+		w.pending = append(w.pending, r.transfer)
+	}
+	return nil
+}
+
+// checkpoint передает записанные переводы в apply и удаляет перенесенные сегменты.
+// Текущий сегмент закрывается, новые переводы пишутся в следующий.
+// При ошибке apply переводы остаются в журнале до следующего переноса.
+func (w *wal) checkpoint(apply func([]storage.Transfer) error) error {
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.Lock()
+	transfers := w.pending
+	w.pending = nil
+	if len(transfers) > 0 {
+		w.sealed = append(w.sealed, w.segment)
+		if w.file != nil && w.failure() == nil {
+			if err := w.startSegment(); err != nil {
+				w.fail(err)
+			}
+		}
+	}
+	sealed := w.sealed
+	w.mu.Unlock()
+
+	if len(transfers) == 0 && len(sealed) == 0 {
+		return nil
+	}
+
+	if err := apply(transfers); err != nil {
+		w.mu.Lock()
+		w.pending = append(transfers, w.pending...)
+		w.mu.Unlock()
+		return err
+	}
+
+	var errs []error
+	for _, path := range sealed {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	w.mu.Lock()
+	w.sealed = w.sealed[len(sealed):]
+	w.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// close прекращает прием переводов, дожидается фиксации принятых и закрывает сегмент.
+func (w *wal) close() error {
+	w.closeMu.Lock()
+	if w.closed {
+		w.closeMu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.reqs)
+	w.closeMu.Unlock()
+
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// fail запоминает первую ошибку записи журнала и возвращает ее.
+func (w *wal) fail(err error) error {
+	w.err.CompareAndSwap(nil, &err)
+	return *w.err.Load()
+}
+
+// failure возвращает ошибку записи журнала, если она произошла.
+// Не ожидает завершения текущей записи.
+func (w *wal) failure() error {
+	if err := w.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// startSegment закрывает текущий сегмент и создает новый для записей после w.seq.
+// Вызывается под w.mu.
+func (w *wal) startSegment() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("close wal segment: %w", err)
+		}
+		w.file = nil
+	}
+
+	path := segmentPath(w.dir, w.seq+1)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, segmentFileMode)
+	if err != nil {
+		return fmt.Errorf("create wal segment: %w", err)
+	}
+	if err = syncDir(w.dir); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.segment = path
+	return nil
+}
+
+// segmentPath возвращает путь к сегменту, начинающемуся с записи seq.
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// segments возвращает пути к сегментам журнала в порядке записи.
+func segments(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readSegments читает записи всех сегментов журнала.
+// Неполная или поврежденная запись в конце последнего сегмента - след прерванной записи:
+// перевод по ней не был подтвержден, поэтому она и все после нее отбрасываются.
+func readSegments(paths []string) ([]storage.Transfer, error) {
+	var transfers []storage.Transfer
+	for i, path := range paths {
+		read, err := readSegment(path)
+		transfers = append(transfers, read...)
+		if err != nil {
+			if errors.Is(err, errTornRecord) && i == len(paths)-1 {
+				break
+			}
+			return nil, fmt.Errorf("%w: %s: %v", ErrCorrupted, filepath.Base(path), err)
+		}
+	}
+	return transfers, nil
+}
+
+// errTornRecord возвращается для неполной или поврежденной записи сегмента.
+var errTornRecord = errors.New("torn record")
+
+// readSegment читает записи одного сегмента до первой поврежденной.
+func readSegment(path string) ([]storage.Transfer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var transfers []storage.Transfer
+	r := bufio.NewReader(file)
+	header := make([]byte, headerSize)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return transfers, nil
+			}
+			return transfers, errTornRecord
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		if size > maxPayloadSize {
+			return transfers, errTornRecord
+		}
+		payload := make([]byte, size)
+		if _, err = io.ReadFull(r, payload); err != nil || crc32.Checksum(payload, crcTable) != sum {
+			return transfers, errTornRecord
+		}
+
+		t, ok := decode(payload)
+		if !ok {
+			return transfers, errTornRecord
+		}
+		transfers = append(transfers, t)
+	}
+}
+
+// encode добавляет запись перевода в buf.
+func encode(buf *bytes.Buffer, t storage.Transfer) {
+	payload := make([]byte, 0, 24+6+len(t.From)+len(t.To)+len(t.Reference))
+	payload = binary.LittleEndian.AppendUint64(payload, t.Seq)
+	payload = binary.LittleEndian.AppendUint64(payload, uint64(t.Time.UnixNano()))
+	payload = binary.LittleEndian.AppendUint64(payload, math.Float64bits(t.Amount))
+	payload = appendString(payload, t.From)
+	payload = appendString(payload, t.To)
+	if t.Reference != "" {
+		payload = appendString(payload, t.Reference)
+	}
+
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+	buf.Write(header[:])
+	buf.Write(payload)
+}
+
+// decode разбирает данные записи перевода.
+func decode(payload []byte) (storage.Transfer, bool) {
+	if len(payload) < 24 {
+		return storage.Transfer{}, false
+	}
+	t := storage.Transfer{
+		Seq:    binary.LittleEndian.Uint64(payload[0:8]),
+		Time:   time.Unix(0, int64(binary.LittleEndian.Uint64(payload[8:16]))),
+		Amount: math.Float64frombits(binary.LittleEndian.Uint64(payload[16:24])),
+	}
+
+	rest := payload[24:]
+	var ok bool
+	if t.From, rest, ok = readString(rest); !ok {
+		return storage.Transfer{}, false
+	}
+	if t.To, rest, ok = readString(rest); !ok {
+		return storage.Transfer{}, false
+	}
+	if len(rest) == 0 {
+		return t, true
+	}
+	if t.Reference, rest, ok = readString(rest); !ok || t.Reference == "" || len(rest) != 0 {
+		return storage.Transfer{}, false
+	}
+	return t, true
+}
+
+// appendString добавляет строку с двухбайтовой длиной.
+func appendString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readString читает строку с двухбайтовой длиной.
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.LittleEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+// syncDir фиксирует на диске создание и удаление файлов каталога.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("sync wal dir: %w", err)
+	}
+	return nil
+}
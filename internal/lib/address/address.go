@@ -0,0 +1,140 @@
+// Package address формирует и проверяет адреса кошельков и их имена (@alice).
+//
+// Адрес имеет вид wlt1<26 символов идентификатора><6 символов контрольной суммы>
+// в алфавите и с контрольной суммой Bech32 (BIP 173): 128-битный случайный идентификатор
+// записывается по 5 бит на символ. Контрольная сумма обнаруживает любую замену
+// до четырех символов и перестановку соседних символов. Адрес записывается
+// строчными или прописными буквами, смешение регистров не допускается.
+package address
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Prefix - префикс адреса кошелька.
+const Prefix = "wlt"
+
+const (
+	separator   = "1"
+	charset     = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	idBytes     = 16
+	dataLen     = (idBytes*8 + 4) / 5 // Символов идентификатора
+	checksumLen = 6
+
+	// Length - длина адреса кошелька.
+	Length = len(Prefix) + len(separator) + dataLen + checksumLen
+)
+
+// ErrMalformed возвращается для строки, не являющейся адресом кошелька.
+var ErrMalformed = errors.New("malformed wallet address")
+
+// New возвращает адрес со случайным идентификатором.
+func New() string {
+	id := make([]byte, idBytes)
+	_, _ = rand.Read(id)
+	return encode(id)
+}
+
+// Parse проверяет адрес и возвращает его запись строчными буквами.
+// Возвращает ErrMalformed при неверной длине, префиксе, символах или контрольной сумме.
+func Parse(s string) (string, error) {
+	if len(s) != Length {
+		return "", ErrMalformed
+	}
+	lower := strings.ToLower(s)
+	if s != lower && s != strings.ToUpper(s) {
+		return "", ErrMalformed
+	}
+	if !strings.HasPrefix(lower, Prefix+separator) {
+		return "", ErrMalformed
+	}
+
+	data := make([]byte, 0, dataLen+checksumLen)
+	for _, c := range lower[len(Prefix)+len(separator):] {
+		v := strings.IndexRune(charset, c)
+		if v < 0 {
+			return "", ErrMalformed
+		}
+		data = append(data, byte(v))
+	}
+	if polymod(append(expandPrefix(), data...)) != 1 {
+		return "", ErrMalformed
+	}
+	// Неиспользуемые младшие биты последнего символа идентификатора равны нулю
+	if padding := dataLen*5 - idBytes*8; data[dataLen-1]&(1<<padding-1) != 0 {
+		return "", ErrMalformed
+	}
+	return lower, nil
+}
+
+// IsLegacy сообщает, является ли строка адресом прежнего формата - UUID.
+func IsLegacy(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(s)
+	return err == nil
+}
+
+// encode записывает идентификатор с префиксом и контрольной суммой.
+func encode(id []byte) string {
+	data := make([]byte, 0, dataLen+checksumLen)
+	var acc uint32
+	var bits uint
+	for _, b := range id {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			data = append(data, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		data = append(data, byte(acc<<(5-bits))&31)
+	}
+
+	mod := polymod(append(append(expandPrefix(), data...), make([]byte, checksumLen)...)) ^ 1
+	for i := range checksumLen {
+		data = append(data, byte(mod>>(5*(checksumLen-1-i)))&31)
+	}
+
+	var sb strings.Builder
+	sb.Grow(Length)
+	sb.WriteString(Prefix + separator)
+	for _, v := range data {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String()
+}
+
+// expandPrefix подготавливает префикс для вычисления контрольной суммы.
+func expandPrefix() []byte {
+	out := make([]byte, 0, 2*len(Prefix)+1)
+	for i := range len(Prefix) {
+		out = append(out, Prefix[i]>>5)
+	}
+	out = append(out, 0)
+	for i := range len(Prefix) {
+		out = append(out, Prefix[i]&31)
+	}
+	return out
+}
+
+// polymod вычисляет контрольную сумму Bech32.
+func polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range generator {
+			if top>>i&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
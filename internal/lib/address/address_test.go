@@ -0,0 +1,101 @@
+package address_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/address"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := address.New()
+	require.Len(t, valid, address.Length)
+	require.True(t, strings.HasPrefix(valid, address.Prefix+"1"))
+
+	// Замена символа на соседний в алфавите
+	replace := func(s string, i int) string {
+		c := "q"
+		if s[i] == 'q' {
+			c = "p"
+		}
+		return s[:i] + c + s[i+1:]
+	}
+
+	cases := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "Адрес", input: valid, expected: valid},
+		{name: "Прописные буквы", input: strings.ToUpper(valid), expected: valid},
+		{name: "Смешанный регистр", input: strings.ToUpper(valid[:10]) + valid[10:], expectedErr: address.ErrMalformed},
+		{name: "Опечатка в идентификаторе", input: replace(valid, 10), expectedErr: address.ErrMalformed},
+		{name: "Опечатка в контрольной сумме", input: replace(valid, address.Length-1), expectedErr: address.ErrMalformed},
+		{name: "Перестановка символов", input: valid[:8] + valid[9:10] + valid[8:9] + valid[10:], expectedErr: address.ErrMalformed},
+		{name: "Другой префикс", input: "abc" + valid[3:], expectedErr: address.ErrMalformed},
+		{name: "Недопустимый символ", input: valid[:10] + "b" + valid[11:], expectedErr: address.ErrMalformed},
+		{name: "Короткий адрес", input: valid[:address.Length-1], expectedErr: address.ErrMalformed},
+		{name: "UUID", input: "1077f2e5-cfd1-4a02-86cc-96fc7337d18c", expectedErr: address.ErrMalformed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := address.Parse(tc.input)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestParseDetectsSubstitutions(t *testing.T) {
+	valid := address.New()
+	prefix := len(address.Prefix) + 1
+	for i := prefix; i < len(valid); i++ {
+		for _, c := range "qpzry9x8gf2tvdw0s3jn54khce6mua7l" {
+			if byte(c) == valid[i] {
+				continue
+			}
+			typo := valid[:i] + string(c) + valid[i+1:]
+			_, err := address.Parse(typo)
+			require.ErrorIs(t, err, address.ErrMalformed, typo)
+		}
+	}
+}
+
+func TestIsLegacy(t *testing.T) {
+	require.True(t, address.IsLegacy("1077f2e5-cfd1-4a02-86cc-96fc7337d18c"))
+	require.False(t, address.IsLegacy(address.New()))
+	require.False(t, address.IsLegacy("{1077f2e5-cfd1-4a02-86cc-96fc7337d18c}"))
+	require.False(t, address.IsLegacy("a"))
+}
+
+func TestParseHandle(t *testing.T) {
+	cases := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "Имя с @", input: "@alice", expected: "alice"},
+		{name: "Имя без @", input: "alice_2", expected: "alice_2"},
+		{name: "Прописные буквы", input: "@Alice", expected: "alice"},
+		{name: "Короткое имя", input: "@al", expectedErr: address.ErrMalformedHandle},
+		{name: "Длинное имя", input: "@" + strings.Repeat("a", 33), expectedErr: address.ErrMalformedHandle},
+		{name: "Начинается с цифры", input: "@1alice", expectedErr: address.ErrMalformedHandle},
+		{name: "Недопустимый символ", input: "@alice.b", expectedErr: address.ErrMalformedHandle},
+		{name: "Кириллица", input: "@алиса", expectedErr: address.ErrMalformedHandle},
+		{name: "Только @", input: "@", expectedErr: address.ErrMalformedHandle},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := address.ParseHandle(tc.input)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+
+	require.True(t, address.IsHandle("@alice"))
+	require.False(t, address.IsHandle(address.New()))
+}
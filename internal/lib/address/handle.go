@@ -0,0 +1,40 @@
+package address
+
+import (
+	"errors"
+	"strings"
+)
+
+// HandlePrefix отличает имя кошелька от адреса там, где принимается адрес: @alice.
+const HandlePrefix = "@"
+
+// Ограничения длины имени кошелька
+const (
+	handleMinLen = 3
+	handleMaxLen = 32
+)
+
+// ErrMalformedHandle возвращается для строки, не являющейся именем кошелька.
+var ErrMalformedHandle = errors.New("malformed wallet handle")
+
+// IsHandle сообщает, указано ли вместо адреса имя кошелька (строка начинается с @).
+func IsHandle(s string) bool {
+	return strings.HasPrefix(s, HandlePrefix)
+}
+
+// ParseHandle проверяет имя кошелька и возвращает его запись строчными буквами без @.
+// Имя из 3-32 латинских букв, цифр и подчеркиваний начинается с буквы
+// и не зависит от регистра. Префикс @ необязателен.
+func ParseHandle(s string) (string, error) {
+	h := strings.ToLower(strings.TrimPrefix(s, HandlePrefix))
+	if len(h) < handleMinLen || len(h) > handleMaxLen || h[0] < 'a' || h[0] > 'z' {
+		return "", ErrMalformedHandle
+	}
+	for i := range len(h) {
+		c := h[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return "", ErrMalformedHandle
+		}
+	}
+	return h, nil
+}
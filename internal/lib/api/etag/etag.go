@@ -0,0 +1,48 @@
+// Package etag формирует и разбирает ETag на основе версии кошелька.
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid возвращается, если значение If-Match не является версией кошелька.
+var ErrInvalid = errors.New("invalid entity tag")
+
+// Format возвращает сильный ETag для версии: "7".
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Match сообщает, совпадает ли версия со значением заголовка If-None-Match.
+// Сравнение слабое: префикс W/ игнорируется, "*" совпадает с любой версией.
+func Match(header string, version int64) bool {
+	tag := Format(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIfMatch извлекает ожидаемую версию из заголовка If-Match.
+// Для "*" возвращает 0: перевод выполняется без проверки версии.
+// Допускается только один сильный ETag с положительной версией.
+func ParseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return 0, nil
+	}
+
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, ErrInvalid
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalid
+	}
+	return version, nil
+}
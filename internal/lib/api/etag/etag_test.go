@@ -0,0 +1,52 @@
+package etag_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/api/etag"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{name: "Совпадение", header: `"7"`, expected: true},
+		{name: "Слабый ETag", header: `W/"7"`, expected: true},
+		{name: "Список значений", header: `"5", "7"`, expected: true},
+		{name: "Любая версия", header: "*", expected: true},
+		{name: "Другая версия", header: `"8"`, expected: false},
+		{name: "Пустой заголовок", header: "", expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, etag.Match(tc.header, 7))
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	cases := []struct {
+		name        string
+		header      string
+		expected    int64
+		expectedErr error
+	}{
+		{name: "Версия", header: `"7"`, expected: 7},
+		{name: "Любая версия", header: "*", expected: 0},
+		{name: "Без кавычек", header: "7", expectedErr: etag.ErrInvalid},
+		{name: "Слабый ETag", header: `W/"7"`, expectedErr: etag.ErrInvalid},
+		{name: "Список значений", header: `"7", "8"`, expectedErr: etag.ErrInvalid},
+		{name: "Нулевая версия", header: `"0"`, expectedErr: etag.ErrInvalid},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := etag.ParseIfMatch(tc.header)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeProblem - тип содержимого документов RFC 7807.
+const ContentTypeProblem = "application/problem+json"
+
+// typeBlank - тип проблемы по умолчанию согласно RFC 7807.
+const typeBlank = "about:blank"
+
+// Problem - документ об ошибке в формате RFC 7807.
+type Problem struct {
+	Type       string         // URI типа проблемы
+	Title      string         // Краткое описание типа проблемы
+	Status     int            // HTTP-статус код
+	Detail     string         // Описание конкретного случая
+	Instance   string         // Идентификатор случая (ID запроса)
+	Extensions map[string]any // Дополнительные поля документа
+}
+
+// MarshalJSON выводит поля расширения на верхнем уровне документа.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		doc[k] = v
+	}
+	doc["type"] = p.Type
+	doc["title"] = p.Title
+	doc["status"] = p.Status
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+// ProblemOptions задает формирование документов RFC 7807.
+type ProblemOptions struct {
+	Default     bool   // Использовать формат RFC 7807 без явного запроса клиента
+	TypeBaseURI string // Префикс URI типа проблемы; к нему добавляется код ошибки
+}
+
+type problemOptionsKey struct{}
+
+// WithProblemOptions сохраняет настройки формата ошибок в контексте запроса.
+func WithProblemOptions(ctx context.Context, opts ProblemOptions) context.Context {
+	return context.WithValue(ctx, problemOptionsKey{}, opts)
+}
+
+// problemOptions возвращает настройки формата ошибок из контекста.
+func problemOptions(r *http.Request) ProblemOptions {
+	opts, _ := r.Context().Value(problemOptionsKey{}).(ProblemOptions)
+	return opts
+}
+
+// Render отправляет ответ клиенту.
+// Ошибки выводятся в формате RFC 7807, если клиент запросил application/problem+json
+// или формат включен глобально, иначе используется стандартная структура Response.
+func Render(w http.ResponseWriter, r *http.Request, resp Response) {
+	opts := problemOptions(r)
+	if resp.Status != StatusError || !(opts.Default || acceptsProblem(r)) {
+		render.JSON(w, r, resp)
+		return
+	}
+
+	body, err := json.Marshal(NewProblem(r, resp, opts.TypeBaseURI))
+	if err != nil {
+		render.JSON(w, r, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(resp.Code)
+	_, _ = w.Write(body)
+}
+
+// NewProblem преобразует ответ с ошибкой в документ RFC 7807.
+// Подробности ошибки выводятся полями расширения, данные ответа (например, отчет
+// о проверках готовности) - полем расширения data, как в стандартной структуре Response.
+func NewProblem(r *http.Request, resp Response, typeBaseURI string) Problem {
+	problemType := typeBlank
+	if typeBaseURI != "" && typeBaseURI != typeBlank && resp.ErrorCode != "" {
+		problemType = strings.TrimSuffix(typeBaseURI, "/") + "/" + resp.ErrorCode
+	}
+
+	ext := make(map[string]any, len(resp.Details)+2)
+	for k, v := range resp.Details {
+		ext[k] = v
+	}
+	if resp.Data != nil {
+		ext["data"] = resp.Data
+	}
+	if resp.ErrorCode != "" {
+		ext["error_code"] = resp.ErrorCode
+	}
+
+	return Problem{
+		Type:       problemType,
+		Title:      http.StatusText(resp.Code),
+		Status:     resp.Code,
+		Detail:     resp.Error,
+		Instance:   middleware.GetReqID(r.Context()),
+		Extensions: ext,
+	}
+}
+
+// acceptsProblem проверяет, запросил ли клиент формат RFC 7807.
+func acceptsProblem(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ContentTypeProblem {
+			continue
+		}
+		return params["q"] != "0"
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/api/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderError(t *testing.T) {
+	cases := []struct {
+		name                string
+		accept              string
+		opts                *response.ProblemOptions
+		data                any // Данные ответа с ошибкой
+		expectedContentType string
+		expectedBody        map[string]any
+	}{
+		{
+			name:                "Формат по умолчанию",
+			accept:              "application/json",
+			expectedContentType: "application/json",
+			expectedBody: map[string]any{
+				"status":     response.StatusError,
+				"code":       float64(http.StatusBadRequest),
+				"error":      "Недостаточно средств",
+				"error_code": "insufficient_funds",
+				"details":    map[string]any{"available": 50.0},
+			},
+		},
+		{
+			name:                "Запрос problem+json через Accept",
+			accept:              "application/problem+json, application/json;q=0.5",
+			expectedContentType: response.ContentTypeProblem,
+			expectedBody: map[string]any{
+				"type":       "about:blank",
+				"title":      "Bad Request",
+				"status":     float64(http.StatusBadRequest),
+				"detail":     "Недостаточно средств",
+				"instance":   "req-1",
+				"error_code": "insufficient_funds",
+				"available":  50.0,
+			},
+		},
+		{
+			name:                "Глобально включенный формат",
+			accept:              "application/json",
+			opts:                &response.ProblemOptions{Default: true, TypeBaseURI: "https://example.com/problems/"},
+			expectedContentType: response.ContentTypeProblem,
+			expectedBody: map[string]any{
+				"type":       "https://example.com/problems/insufficient_funds",
+				"title":      "Bad Request",
+				"status":     float64(http.StatusBadRequest),
+				"detail":     "Недостаточно средств",
+				"instance":   "req-1",
+				"error_code": "insufficient_funds",
+				"available":  50.0,
+			},
+		},
+		{
+			name:                "Данные ответа в problem+json",
+			accept:              "application/problem+json",
+			data:                map[string]any{"checks": map[string]any{"db": "fail"}},
+			expectedContentType: response.ContentTypeProblem,
+			expectedBody: map[string]any{
+				"type":       "about:blank",
+				"title":      "Bad Request",
+				"status":     float64(http.StatusBadRequest),
+				"detail":     "Недостаточно средств",
+				"instance":   "req-1",
+				"error_code": "insufficient_funds",
+				"available":  50.0,
+				"data":       map[string]any{"checks": map[string]any{"db": "fail"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/send", nil)
+			req.Header.Set("Accept", tc.accept)
+			ctx := req.Context()
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-1")
+			if tc.opts != nil {
+				ctx = response.WithProblemOptions(ctx, *tc.opts)
+			}
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			resp := response.Fail(req, "insufficient_funds", http.StatusBadRequest, map[string]any{"available": 50.0})
+			resp.Data = tc.data
+			response.Render(rr, req, resp)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Header().Get("Content-Type"), tc.expectedContentType)
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.expectedBody, body)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package response
 
 import (
 	"github.com/go-chi/render"
+	"infotecsTest/internal/lib/i18n"
 	"net/http"
 )
 
@@ -12,13 +13,33 @@ const (
 	StatusError = "Error" // Ошибочный статус операции
 )
 
+// Коды ошибок уровня API.
+// Коды ошибок хранилища определены в пакете storage.
+const (
+	CodeInternal        = "internal_error"    // Внутренняя ошибка сервиса
+	CodeEmptyBody       = "empty_body"        // Пустое тело запроса
+	CodeInvalidJSON     = "invalid_json"      // Некорректный JSON
+	CodeInvalidCount    = "invalid_count"     // Некорректный параметр count
+	CodeTooManyRequests = "too_many_requests" // Превышен лимит запросов
+	CodeNotReady        = "not_ready"         // Сервис не готов
+	CodeSchemaViolation = "schema_violation"  // Запрос не соответствует спецификации API
+	CodeInvalidPeriod   = "invalid_period"    // Некорректный период выписки
+	CodeInvalidFormat   = "invalid_format"    // Неподдерживаемый формат выгрузки
+	CodeInvalidCSV      = "invalid_csv"       // Некорректный CSV-файл выплат
+	CodeInvalidETag     = "invalid_etag"      // Некорректный заголовок If-Match
+	CodeInvalidFilter   = "invalid_filter"    // Некорректные условия поиска кошельков
+	CodeUnauthorized    = "unauthorized"      // Отсутствует или неизвестен ключ пользователя
+)
+
 // Response - базовая структура для всех HTTP-ответов
 // Содержит статус выполнения, код ответа и данные/ошибку
 type Response struct {
-	Status string `json:"status"`          // Статус операции: OK/Error
-	Code   int    `json:"code,omitempty"`  // HTTP-статус код (только для ошибок)
-	Data   any    `json:"data,omitempty"`  // Тело успешного ответа
-	Error  string `json:"error,omitempty"` // Сообщение об ошибке
+	Status    string         `json:"status"`               // Статус операции: OK/Error
+	Code      int            `json:"code,omitempty"`       // HTTP-статус код (только для ошибок)
+	Data      any            `json:"data,omitempty"`       // Тело успешного ответа
+	Error     string         `json:"error,omitempty"`      // Сообщение об ошибке
+	ErrorCode string         `json:"error_code,omitempty"` // Машиночитаемый код ошибки
+	Details   map[string]any `json:"details,omitempty"`    // Структурированные сведения об ошибке
 }
 
 // Success создает успешный JSON-ответ
@@ -41,3 +62,15 @@ func Error(r *http.Request, msg string, code int) Response {
 		Error:  msg,
 	}
 }
+
+// Fail создает JSON-ответ с машиночитаемым кодом ошибки.
+// Сообщение выбирается из каталога на языке из заголовка Accept-Language.
+// Автоматически устанавливает HTTP-статус через render
+func Fail(r *http.Request, errCode string, status int, details map[string]any) Response {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+	resp := Error(r, i18n.Message(lang, errCode), status)
+	resp.ErrorCode = errCode
+	resp.Details = details
+	return resp
+}
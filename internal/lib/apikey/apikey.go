@@ -0,0 +1,30 @@
+// Package apikey формирует ключи API пользователей и их хеши.
+//
+// Ключ имеет вид psk_<48 шестнадцатеричных символов> и содержит 192 случайных бита.
+// В хранилище записывается только SHA-256 ключа: утечка базы не раскрывает ключи,
+// а перебор по хешу невозможен из-за длины ключа.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Prefix - префикс ключа API.
+const Prefix = "psk_"
+
+const keyBytes = 24
+
+// New возвращает новый случайный ключ.
+func New() string {
+	b := make([]byte, keyBytes)
+	_, _ = rand.Read(b)
+	return Prefix + hex.EncodeToString(b)
+}
+
+// Hash возвращает хеш ключа для хранения и поиска.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
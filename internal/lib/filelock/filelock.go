@@ -0,0 +1,45 @@
+// Package filelock защищает файлы от одновременного использования несколькими процессами.
+//
+// Блокировка удерживается на отдельном файле и снимается операционной системой
+// при завершении процесса, в том числе аварийном, поэтому не остается после сбоя.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked возвращается, если файл заблокирован другим процессом.
+var ErrLocked = errors.New("file is locked by another process")
+
+// Lock - блокировка, удерживаемая процессом до вызова Release.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire создает файл path при отсутствии и захватывает его исключительную блокировку.
+// Не ожидает освобождения: возвращает ErrLocked, если блокировку удерживает другой процесс.
+func Acquire(path string) (*Lock, error) {
+	const op = "filelock.Acquire"
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = lock(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release снимает блокировку. Файл блокировки не удаляется.
+func (l *Lock) Release() error {
+	const op = "filelock.Release"
+
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
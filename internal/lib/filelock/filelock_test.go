@@ -0,0 +1,26 @@
+//go:build unix
+
+package filelock_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/filelock"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db.lock")
+
+	lock, err := filelock.Acquire(path)
+	require.NoError(t, err)
+
+	// Блокировку, удерживаемую одним владельцем, нельзя захватить повторно
+	_, err = filelock.Acquire(path)
+	require.ErrorIs(t, err, filelock.ErrLocked)
+
+	require.NoError(t, lock.Release())
+	lock, err = filelock.Acquire(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
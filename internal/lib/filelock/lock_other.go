@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// lock ничего не делает: на системах без flock блокировка не поддерживается.
+func lock(*os.File) error {
+	return nil
+}
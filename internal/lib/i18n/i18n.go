@@ -0,0 +1,141 @@
+// Package i18n содержит каталоги сообщений об ошибках API
+// и выбор языка по заголовку Accept-Language.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые языки
+const (
+	LangRU = "ru" // Русский
+	LangEN = "en" // Английский
+)
+
+// DefaultLang используется, если клиент не указал поддерживаемый язык.
+const DefaultLang = LangRU
+
+// catalogs сопоставляет код ошибки с сообщением для каждого языка.
+var catalogs = map[string]map[string]string{
+	LangRU: {
+		"wallet_not_found":   "Кошелек не найден",
+		"insufficient_funds": "Недостаточно средств",
+		"incorrect_amount":   "Сумма перевода должна быть больше нуля",
+		"invalid_request":    "Count должен быть больше 0",
+		"addresses_equal":    "Адреса одинаковые",
+		"internal_error":     "Внутренняя ошибка",
+		"empty_body":         "Требуется JSON-объект",
+		"invalid_json":       "Некорректный JSON-объект",
+		"invalid_count":      "Некорректное значение count",
+		"too_many_requests":  "Слишком много запросов",
+		"not_ready":          "Сервис не готов",
+		"schema_violation":   "Запрос не соответствует спецификации API",
+		"invalid_period":     "Некорректный период: ожидаются даты from < to в формате RFC 3339 или ГГГГ-ММ-ДД",
+		"invalid_format":     "Неподдерживаемый формат выгрузки",
+		"invalid_csv":        "Некорректный CSV-файл: ожидаются строки to,amount,reference",
+		"payout_not_found":   "Задание на выплату не найдено",
+		"outcome_unknown":    "Исход перевода неизвестен, требуется сверка",
+		"version_mismatch":   "Кошелек изменился, запросите баланс повторно",
+		"invalid_etag":       "Некорректный заголовок If-Match: ожидается версия кошелька в кавычках",
+		"malformed_address":  "Некорректный адрес кошелька: проверьте адрес на опечатки",
+		"invalid_handle":     "Некорректное имя кошелька: ожидается от 3 до 32 латинских букв, цифр и _, начиная с буквы",
+		"handle_not_found":   "Имя кошелька не найдено",
+		"handle_taken":       "Имя кошелька уже занято",
+		"invalid_metadata":   "Некорректные метаданные кошелька",
+		"invalid_filter":     "Некорректные условия поиска: укажите label или owner и limit от 1 до 1000",
+		"unauthorized":       "Требуется действующий ключ пользователя",
+		"forbidden":          "Нет доступа к кошельку: кошелек принадлежит другому пользователю",
+		"user_not_found":     "Пользователь не найден",
+		"invalid_user":       "Некорректный профиль пользователя: имя обязательно",
+		"wallet_owned":       "Кошелек уже принадлежит другому пользователю",
+		"storage_busy":       "Хранилище занято, повторите запрос позже",
+	},
+	LangEN: {
+		"wallet_not_found":   "Wallet not found",
+		"insufficient_funds": "Insufficient funds",
+		"incorrect_amount":   "Transfer amount must be greater than zero",
+		"invalid_request":    "Count must be greater than 0",
+		"addresses_equal":    "Sender and recipient addresses are the same",
+		"internal_error":     "Internal error",
+		"empty_body":         "JSON object is required",
+		"invalid_json":       "Malformed JSON object",
+		"invalid_count":      "Invalid count value",
+		"too_many_requests":  "Too many requests",
+		"not_ready":          "Service is not ready",
+		"schema_violation":   "Request does not match the API specification",
+		"invalid_period":     "Invalid period: expected from < to in RFC 3339 or YYYY-MM-DD format",
+		"invalid_format":     "Unsupported export format",
+		"invalid_csv":        "Malformed CSV file: expected rows of to,amount,reference",
+		"payout_not_found":   "Payout job not found",
+		"outcome_unknown":    "Transfer outcome is unknown, reconciliation required",
+		"version_mismatch":   "Wallet has changed, fetch the balance again",
+		"invalid_etag":       "Malformed If-Match header: expected a quoted wallet version",
+		"malformed_address":  "Malformed wallet address: check the address for typos",
+		"invalid_handle":     "Invalid wallet handle: expected 3 to 32 latin letters, digits and _, starting with a letter",
+		"handle_not_found":   "Wallet handle not found",
+		"handle_taken":       "Wallet handle is already taken",
+		"invalid_metadata":   "Invalid wallet metadata",
+		"invalid_filter":     "Invalid search filter: specify label or owner and limit from 1 to 1000",
+		"unauthorized":       "A valid user key is required",
+		"forbidden":          "Access to the wallet denied: the wallet belongs to another user",
+		"user_not_found":     "User not found",
+		"invalid_user":       "Invalid user profile: name is required",
+		"wallet_owned":       "Wallet already belongs to another user",
+		"storage_busy":       "Storage is busy, retry the request later",
+	},
+}
+
+// Message возвращает сообщение для кода ошибки на указанном языке.
+// При отсутствии перевода используется язык по умолчанию, затем сам код.
+func Message(lang, code string) string {
+	if msg, ok := catalogs[lang][code]; ok {
+		return msg
+	}
+	if msg, ok := catalogs[DefaultLang][code]; ok {
+		return msg
+	}
+	return code
+}
+
+// Negotiate выбирает поддерживаемый язык по значению заголовка Accept-Language
+// с учетом весов q. Региональные варианты (en-US) сводятся к основному языку.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		candidates = append(candidates, candidate{lang: lang, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if _, ok := catalogs[c.lang]; ok {
+			return c.lang
+		}
+	}
+	return DefaultLang
+}
@@ -0,0 +1,28 @@
+package i18n_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/i18n"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Пустой заголовок", header: "", expected: i18n.LangRU},
+		{name: "Английский", header: "en", expected: i18n.LangEN},
+		{name: "Региональный вариант", header: "en-US,en;q=0.9", expected: i18n.LangEN},
+		{name: "Веса", header: "ru;q=0.5, en;q=0.8", expected: i18n.LangEN},
+		{name: "Неподдерживаемый язык", header: "de-DE, fr;q=0.9", expected: i18n.LangRU},
+		{name: "Нулевой вес", header: "en;q=0, ru", expected: i18n.LangRU},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, i18n.Negotiate(tc.header))
+		})
+	}
+}
@@ -0,0 +1,390 @@
+// Package metrics реализует минимальный набор метрик Prometheus
+// (счетчики, гистограммы, вычисляемые значения) и их вывод
+// в текстовом формате экспозиции без внешних зависимостей.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType - тип содержимого текстового формата экспозиции Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets - границы гистограмм по умолчанию (в секундах).
+var DefBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// labelSep разделяет значения меток в ключе дочерней метрики.
+const labelSep = "\xff"
+
+// collector описывает метрику, которую умеет выводить Registry.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry хранит зарегистрированные метрики.
+// Безопасен для конкурентного использования.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry создает пустой реестр метрик.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register добавляет метрику в реестр.
+// Паникует при повторной регистрации имени, так как это ошибка программиста.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collectors[c.name()]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write выводит все метрики в текстовом формате, отсортированные по имени.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, len(names))
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	_ = bw.Flush()
+}
+
+// Handler создает HTTP-обработчик, отдающий метрики реестра.
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.Write(w)
+	}
+}
+
+// desc содержит общие поля описания метрики.
+type desc struct {
+	metricName string
+	help       string
+	typ        string
+	labels     []string
+}
+
+func (d *desc) name() string { return d.metricName }
+
+// header выводит строки HELP и TYPE.
+func (d *desc) header(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, escapeHelp(d.help))
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, d.typ)
+}
+
+// vec хранит дочерние метрики по значениям меток.
+type vec[T any] struct {
+	desc
+	mu       sync.RWMutex
+	children map[string]*T
+	newChild func() *T
+}
+
+// with возвращает дочернюю метрику, создавая ее при необходимости.
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, labelSep)
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if child, ok = v.children[key]; !ok {
+		child = v.newChild()
+		v.children[key] = child
+	}
+	return child
+}
+
+// each обходит дочерние метрики в порядке сортировки значений меток.
+func (v *vec[T]) each(fn func(values []string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	for i, key := range keys {
+		children[i] = v.children[key]
+	}
+	v.mu.RUnlock()
+
+	for i, key := range keys {
+		var values []string
+		if len(v.labels) > 0 {
+			values = strings.Split(key, labelSep)
+		}
+		fn(values, children[i])
+	}
+}
+
+// Counter - монотонно возрастающий счетчик.
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc увеличивает счетчик на единицу.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add увеличивает счетчик на v. Отрицательные значения игнорируются.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	addFloat(&c.bits, v)
+}
+
+// Value возвращает текущее значение счетчика.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec - набор счетчиков с метками.
+type CounterVec struct {
+	vec[Counter]
+}
+
+// NewCounterVec регистрирует набор счетчиков с указанными метками.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: vec[Counter]{
+		desc:     desc{metricName: name, help: help, typ: "counter", labels: labels},
+		children: make(map[string]*Counter),
+		newChild: func() *Counter { return &Counter{} },
+	}}
+	r.register(c)
+	return c
+}
+
+// With возвращает счетчик для значений меток.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.with(values)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.header(w)
+	c.each(func(values []string, child *Counter) {
+		writeSample(w, c.metricName, c.labels, values, "", "", child.Value())
+	})
+}
+
+// Histogram распределяет наблюдения по корзинам.
+type Histogram struct {
+	upper  []float64
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Uint64
+}
+
+// Observe добавляет наблюдение.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+	if i < len(h.counts) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+	addFloat(&h.sum, v)
+}
+
+// HistogramVec - набор гистограмм с метками.
+type HistogramVec struct {
+	vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec регистрирует набор гистограмм с указанными границами корзин.
+// При пустом buckets используются DefBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{buckets: buckets}
+	h.vec = vec[Histogram]{
+		desc:     desc{metricName: name, help: help, typ: "histogram", labels: labels},
+		children: make(map[string]*Histogram),
+		newChild: func() *Histogram {
+			return &Histogram{upper: buckets, counts: make([]atomic.Uint64, len(buckets))}
+		},
+	}
+	r.register(h)
+	return h
+}
+
+// With возвращает гистограмму для значений меток.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.with(values)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.header(w)
+	h.each(func(values []string, child *Histogram) {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += child.counts[i].Load()
+			writeSample(w, h.metricName+"_bucket", h.labels, values, "le", formatFloat(upper), float64(cumulative))
+		}
+		count := child.count.Load()
+		writeSample(w, h.metricName+"_bucket", h.labels, values, "le", "+Inf", float64(count))
+		writeSample(w, h.metricName+"_sum", h.labels, values, "", "", math.Float64frombits(child.sum.Load()))
+		writeSample(w, h.metricName+"_count", h.labels, values, "", "", float64(count))
+	})
+}
+
+// Sample - одно значение метрики, вычисляемой при сборе.
+type Sample struct {
+	Labels []string // Значения меток в порядке их объявления
+	Value  float64  // Значение
+}
+
+// funcCollector вычисляет значения метрики при каждом сборе.
+type funcCollector struct {
+	desc
+	fn func() ([]Sample, error)
+}
+
+// NewGaugeFunc регистрирует метрику-значение без меток, вычисляемую функцией.
+// При ошибке функции метрика пропускается в выводе.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() (float64, error)) {
+	r.register(&funcCollector{
+		desc: desc{metricName: name, help: help, typ: "gauge"},
+		fn: func() ([]Sample, error) {
+			v, err := fn()
+			if err != nil {
+				return nil, err
+			}
+			return []Sample{{Value: v}}, nil
+		},
+	})
+}
+
+// NewCounterFunc регистрирует счетчик с метками, значения которого
+// собираются функцией из внешнего источника.
+func (r *Registry) NewCounterFunc(name, help string, fn func() ([]Sample, error), labels ...string) {
+	r.register(&funcCollector{
+		desc: desc{metricName: name, help: help, typ: "counter", labels: labels},
+		fn:   fn,
+	})
+}
+
+func (f *funcCollector) write(w io.Writer) {
+	samples, err := f.fn()
+	if err != nil {
+		return
+	}
+	f.header(w)
+	for _, s := range samples {
+		writeSample(w, f.metricName, f.labels, s.Labels, "", "", s.Value)
+	}
+}
+
+// writeSample выводит строку значения с метками и дополнительной меткой extra.
+func writeSample(w io.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	var sb strings.Builder
+	sb.WriteString(name)
+
+	if len(labels) > 0 || extraName != "" {
+		sb.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			sb.WriteString(label)
+			sb.WriteString(`="`)
+			sb.WriteString(escapeLabel(value))
+			sb.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(extraName)
+			sb.WriteString(`="`)
+			sb.WriteString(extraValue)
+			sb.WriteByte('"')
+		}
+		sb.WriteByte('}')
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteString(formatFloat(v))
+	sb.WriteByte('\n')
+
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// formatFloat форматирует число по правилам формата экспозиции.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
+
+func escapeHelp(s string) string { return helpReplacer.Replace(s) }
+
+// addFloat атомарно прибавляет v к числу, хранящемуся в виде битов.
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
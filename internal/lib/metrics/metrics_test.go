@@ -0,0 +1,48 @@
+package metrics_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistryExposition(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	requests := reg.NewCounterVec("requests_total", "Number of requests.", "route", "status")
+	requests.With("/api/send", "200").Inc()
+	requests.With("/api/send", "200").Add(2)
+	requests.With(`/a"b`, "500").Inc()
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.With("/api/send").Observe(0.05)
+	latency.With("/api/send").Observe(0.5)
+	latency.With("/api/send").Observe(5)
+
+	reg.NewGaugeFunc("supply", "Total supply.", func() (float64, error) { return 1000.5, nil })
+	reg.NewGaugeFunc("broken", "Always fails.", func() (float64, error) { return 0, errors.New("db closed") })
+
+	rr := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, metrics.ContentType, rr.Header().Get("Content-Type"))
+	require.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/api/send",le="0.1"} 1
+latency_seconds_bucket{route="/api/send",le="1"} 2
+latency_seconds_bucket{route="/api/send",le="+Inf"} 3
+latency_seconds_sum{route="/api/send"} 5.55
+latency_seconds_count{route="/api/send"} 3
+# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="500"} 1
+requests_total{route="/api/send",status="200"} 3
+# HELP supply Total supply.
+# TYPE supply gauge
+supply 1000.5
+`, rr.Body.String())
+}
@@ -0,0 +1,98 @@
+// Package worker управляет жизненным циклом фоновых горутин приложения.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Group запускает фоновые задачи с общим контекстом отмены
+// и позволяет дождаться их завершения при остановке приложения.
+type Group struct {
+	log    *slog.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped map[string]struct{} // Задачи, завершившиеся до остановки группы
+}
+
+// NewGroup создает пустую группу фоновых задач.
+func NewGroup(log *slog.Logger) *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		log:     log.With(slog.String("component", "worker")),
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(map[string]struct{}),
+	}
+}
+
+// Go запускает задачу в отдельной горутине.
+// Задача должна завершиться после отмены переданного контекста.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		log := g.log.With(slog.String("worker", name))
+		log.Info("worker started")
+		fn(g.ctx)
+
+		if g.ctx.Err() == nil {
+			log.Error("worker stopped unexpectedly")
+			g.mu.Lock()
+			g.stopped[name] = struct{}{}
+			g.mu.Unlock()
+			return
+		}
+		log.Info("worker stopped")
+	}()
+}
+
+// Check сообщает об ошибке, если какая-либо задача завершилась до остановки группы.
+// Подходит для регистрации в качестве проверки готовности.
+func (g *Group) Check(_ context.Context) error {
+	const op = "worker.Group.Check"
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.stopped) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(g.stopped))
+	for name := range g.stopped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("%s: workers stopped: %s", op, strings.Join(names, ", "))
+}
+
+// Shutdown отменяет контекст задач и ожидает их завершения.
+// Возвращает ошибку, если задачи не успели завершиться до отмены ctx.
+func (g *Group) Shutdown(ctx context.Context) error {
+	const op = "worker.Group.Shutdown"
+
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
@@ -0,0 +1,75 @@
+package worker_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/lib/worker"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestShutdown(t *testing.T) {
+	cases := []struct {
+		name        string
+		task        func(release <-chan struct{}) func(ctx context.Context)
+		expectedErr error
+	}{
+		{
+			name: "Задачи завершились после отмены",
+			task: func(<-chan struct{}) func(ctx context.Context) {
+				return func(ctx context.Context) { <-ctx.Done() }
+			},
+		},
+		{
+			name: "Задача не успела завершиться",
+			task: func(release <-chan struct{}) func(ctx context.Context) {
+				return func(context.Context) { <-release }
+			},
+			expectedErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			g := worker.NewGroup(testLogger)
+			g.Go("first", tc.task(release))
+			g.Go("second", func(ctx context.Context) { <-ctx.Done() })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := g.Shutdown(ctx)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.expectedErr)
+			}
+			// Остановленная группа не считается сбоем задач
+			require.NoError(t, g.Check(context.Background()))
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	g := worker.NewGroup(testLogger)
+	g.Go("backup", func(ctx context.Context) { <-ctx.Done() })
+	require.NoError(t, g.Check(context.Background()))
+
+	// Задачи, завершившиеся до остановки группы, перечисляются по алфавиту
+	g.Go("payout", func(context.Context) {})
+	g.Go("checkpoint", func(context.Context) {})
+	require.Eventually(t, func() bool {
+		err := g.Check(context.Background())
+		return err != nil && err.Error() == "worker.Group.Check: workers stopped: checkpoint, payout"
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, g.Shutdown(context.Background()))
+	require.Error(t, g.Check(context.Background()))
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Статусы строк задания на выплату
+const (
+	PayoutRowPending    = "pending"    // Ожидает выполнения
+	PayoutRowProcessing = "processing" // Выполняется
+	PayoutRowSucceeded  = "succeeded"  // Перевод выполнен
+	PayoutRowFailed     = "failed"     // Перевод отклонен, причина в коде ошибки
+	PayoutRowUnknown    = "unknown"    // Исход неизвестен (выполнение прервано), требуется сверка
+)
+
+// Статусы задания на выплату
+const (
+	PayoutJobPending   = "pending"   // Ни одна строка еще не выполнялась
+	PayoutJobRunning   = "running"   // Выполняется
+	PayoutJobCompleted = "completed" // Все строки обработаны
+)
+
+// PayoutJob - задание на массовую выплату с одного кошелька.
+// Строки выполняются независимо: частичный успех допустим.
+type PayoutJob struct {
+	ID         string     `json:"id"`                    // Идентификатор задания
+	From       string     `json:"from"`                  // Кошелек-источник
+	Status     string     `json:"status"`                // Статус задания
+	Total      int        `json:"total"`                 // Всего строк
+	Pending    int        `json:"pending"`               // Ожидают выполнения или выполняются
+	Succeeded  int        `json:"succeeded"`             // Выполнены успешно
+	Failed     int        `json:"failed"`                // Отклонены
+	Unknown    int        `json:"unknown"`               // С неизвестным исходом
+	CreatedAt  time.Time  `json:"created_at"`            // Время создания
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // Время обработки последней строки
+}
+
+// PayoutRow - строка задания на выплату.
+type PayoutRow struct {
+	JobID     string  `json:"-"`                    // Идентификатор задания
+	From      string  `json:"-"`                    // Кошелек-источник задания
+	Line      int     `json:"line"`                 // Номер строки в загруженном файле
+	To        string  `json:"to"`                   // Кошелек получателя
+	Amount    float64 `json:"amount"`               // Сумма перевода
+	Reference string  `json:"reference"`            // Назначение платежа
+	Status    string  `json:"status"`               // Статус строки
+	ErrorCode string  `json:"error_code,omitempty"` // Код ошибки для отклоненных строк
+
+	TransferReference string `json:"-"` // Ссылка, сохраняемая вместе с переводом по строке
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Movement описывает перевод, затрагивающий кошелек, в выписке.
+type Movement struct {
+	ID     int64     `json:"id"`     // Идентификатор транзакции
+	Time   time.Time `json:"time"`   // Время транзакции
+	From   string    `json:"from"`   // Адрес отправителя
+	To     string    `json:"to"`     // Адрес получателя
+	Amount float64   `json:"amount"` // Сумма перевода
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// User - пользователь платежной системы: профиль, ключи API и кошельки.
+// Кошелек принадлежит не более чем одному пользователю.
+type User struct {
+	ID        string    `json:"id"`              // Идентификатор пользователя
+	Name      string    `json:"name"`            // Имя для отображения
+	Email     string    `json:"email,omitempty"` // Адрес электронной почты
+	CreatedAt time.Time `json:"created_at"`      // Время регистрации
+}
+
+// UserRequest - профиль создаваемого пользователя.
+type UserRequest struct {
+	Name  string `json:"name"`            // Имя для отображения
+	Email string `json:"email,omitempty"` // Адрес электронной почты
+}
+
+// Credential - ключ API пользователя.
+// Ключ возвращается только при выпуске, в хранилище сохраняется его хеш.
+type Credential struct {
+	UserID    string    `json:"user_id"`    // Идентификатор пользователя
+	Key       string    `json:"key"`        // Ключ для заголовка аутентификации
+	CreatedAt time.Time `json:"created_at"` // Время выпуска
+}
+
+// UserWallets - кошельки пользователя с текущими балансами и их суммой.
+type UserWallets struct {
+	UserID       string   `json:"user_id"`       // Идентификатор пользователя
+	Wallets      []Wallet `json:"wallets"`       // Кошельки, упорядоченные по адресу
+	TotalBalance float64  `json:"total_balance"` // Суммарный баланс кошельков
+}
+
+// RevokedKeys - результат отзыва ключей API пользователя.
+type RevokedKeys struct {
+	UserID  string `json:"user_id"` // Идентификатор пользователя
+	Revoked int    `json:"revoked"` // Число отозванных ключей
+}
@@ -2,7 +2,38 @@
 package models
 
 // Wallet представляет данные кошелька пользователя.
+// Владелец, метки и метаданные возвращаются в сведениях о кошельке и задаются при его создании.
 type Wallet struct {
-	Address string  `json:"address"` // Уникальный адрес кошелька
-	Balance float64 `json:"balance"` // Баланс кошелька
+	Address  string            `json:"address"`            // Уникальный адрес кошелька
+	Balance  float64           `json:"balance"`            // Баланс кошелька
+	Version  int64             `json:"version"`            // Версия кошелька, увеличивается при каждом изменении баланса
+	Owner    string            `json:"owner,omitempty"`    // Идентификатор владельца во внешней системе
+	Labels   []string          `json:"labels,omitempty"`   // Метки для поиска кошельков
+	Metadata map[string]string `json:"metadata,omitempty"` // Произвольные данные ключ-значение
+}
+
+// WalletMetadata - изменяемые сведения о кошельке: владелец, метки и метаданные.
+type WalletMetadata struct {
+	Owner    string            `json:"owner,omitempty"`    // Идентификатор владельца во внешней системе
+	Labels   []string          `json:"labels,omitempty"`   // Метки, без учета регистра
+	Metadata map[string]string `json:"metadata,omitempty"` // Произвольные данные ключ-значение
+}
+
+// WalletFilter задает условия поиска кошельков. Пустое условие не ограничивает поиск.
+type WalletFilter struct {
+	Label  string // Метка кошелька
+	Owner  string // Идентификатор владельца
+	UserID string // Пользователь, владеющий кошельками
+	Limit  int    // Максимальное число кошельков
+}
+
+// Handle - имя кошелька, которое можно указывать вместо адреса: @alice.
+type Handle struct {
+	Handle  string `json:"handle"`  // Имя строчными буквами без @
+	Address string `json:"address"` // Адрес кошелька
+}
+
+// HandleRequest - запрос на присвоение имени кошельку.
+type HandleRequest struct {
+	Handle string `json:"handle"` // Имя с @ или без, регистр не учитывается
 }
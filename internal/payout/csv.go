@@ -0,0 +1,103 @@
+package payout
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseError описывает ошибку в загруженном файле выплат.
+type ParseError struct {
+	Line   int    // Номер строки файла (0, если ошибка не относится к строке)
+	Reason string // Описание ошибки
+}
+
+// Error возвращает описание ошибки с номером строки.
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return "payout file: " + e.Reason
+	}
+	return fmt.Sprintf("payout file: line %d: %s", e.Line, e.Reason)
+}
+
+// Details возвращает структурированные сведения об ошибке для клиента.
+func (e *ParseError) Details() map[string]any {
+	details := map[string]any{"reason": e.Reason}
+	if e.Line != 0 {
+		details["line"] = e.Line
+	}
+	return details
+}
+
+// Parse читает строки выплат в формате CSV: to,amount[,reference].
+// Первая строка с заголовком (to в первом столбце) пропускается.
+// Сумма проверяется только на формат: недопустимые суммы отклоняются при выполнении строки.
+func Parse(r io.Reader, maxRows int) ([]models.PayoutRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var rows []models.PayoutRow
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				return nil, &ParseError{Line: perr.Line, Reason: perr.Err.Error()}
+			}
+			return nil, &ParseError{Reason: err.Error()}
+		}
+
+		line, _ := cr.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "to") {
+			continue
+		}
+
+		row, err := parseRow(record)
+		if err != nil {
+			return nil, &ParseError{Line: line, Reason: err.Error()}
+		}
+		if len(rows) == maxRows {
+			return nil, &ParseError{Reason: fmt.Sprintf("more than %d rows", maxRows)}
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, &ParseError{Reason: "no rows"}
+	}
+	return rows, nil
+}
+
+// parseRow разбирает одну запись файла.
+func parseRow(record []string) (models.PayoutRow, error) {
+	if len(record) < 2 || len(record) > 3 {
+		return models.PayoutRow{}, fmt.Errorf("expected 2 or 3 columns, got %d", len(record))
+	}
+
+	row := models.PayoutRow{To: strings.TrimSpace(record[0])}
+	if row.To == "" {
+		return row, errors.New("recipient is empty")
+	}
+
+	raw := strings.TrimSpace(record[1])
+	amount, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return row, fmt.Errorf("invalid amount %q", raw)
+	}
+	row.Amount = amount
+
+	if len(record) == 3 {
+		row.Reference = strings.TrimSpace(record[2])
+	}
+	return row, nil
+}
@@ -0,0 +1,228 @@
+// Package payout выполняет задания на массовую выплату с одного кошелька по CSV-файлу.
+// Строки задания выполняются фоновым обработчиком по одной через AddTransaction;
+// в отличие от атомарного пакета переводов, частичный успех допустим.
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/api/response"
+	"infotecsTest/internal/lib/logger/sl"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// CodeOutcomeUnknown - код ошибки строки, выполнение которой прервала остановка процесса.
+// Перевод по такой строке мог быть выполнен, поэтому она не повторяется автоматически.
+const CodeOutcomeUnknown = "outcome_unknown"
+
+// Store определяет хранилище заданий на выплату.
+type Store interface {
+	ResolveAddress(input string) (string, error)
+	GetWalletBalance(address string) (models.Wallet, error)
+	CreatePayoutJob(ctx context.Context, job models.PayoutJob, rows []models.PayoutRow) error
+	PayoutJob(ctx context.Context, id string) (models.PayoutJob, error)
+	PayoutRows(ctx context.Context, id string, fn func(models.PayoutRow) error) error
+	NextPayoutRow(ctx context.Context) (models.PayoutRow, bool, error)
+	FinishPayoutRow(ctx context.Context, jobID string, line int, status, errCode string) error
+	RecoverPayoutRows(ctx context.Context, errCode string) (int, int, error)
+}
+
+// TransactionMaker выполняет перевод по строке задания.
+// Ссылка на строку сохраняется вместе с переводом, чтобы после сбоя строку можно было сверить.
+type TransactionMaker interface {
+	AddTransactionWithReference(from, to string, amount float64, reference string) error
+}
+
+// Service принимает задания на выплату и выполняет их в фоне.
+type Service struct {
+	log   *slog.Logger
+	store Store
+	maker TransactionMaker
+	cfg   config.Payouts
+	wake  chan struct{}
+}
+
+// New создает сервис выплат.
+// Обработчик строк запускается отдельно через Run.
+func New(log *slog.Logger, store Store, maker TransactionMaker, cfg config.Payouts) *Service {
+	return &Service{
+		log:   log.With(slog.String("component", "payout")),
+		store: store,
+		maker: maker,
+		cfg:   cfg,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Create проверяет кошелек-источник, разбирает файл и сохраняет задание.
+// Адреса источника и получателей сохраняются в каноническом виде.
+// Источник и получатели задаются адресом или именем кошелька (@alice).
+// Возвращает ErrMalformedAddress, ErrWalletNotFound или ошибку имени для некорректного или неизвестного источника
+// и *ParseError для некорректного файла, в том числе для адреса получателя с неверной контрольной суммой
+// и неизвестного имени получателя.
+func (s *Service) Create(ctx context.Context, from string, file io.Reader) (models.PayoutJob, error) {
+	const op = "payout.Create"
+
+	from, err := s.store.ResolveAddress(from)
+	if err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// Проверяется только существование кошелька: кошельки создаются в хранилище,
+	// поэтому проверка верна и при переводах через движок в памяти
+	if _, err = s.store.GetWalletBalance(from); err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	limited := &io.LimitedReader{R: file, N: s.cfg.MaxFileSize + 1}
+	rows, err := Parse(limited, s.cfg.MaxRows)
+	if limited.N == 0 {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op,
+			&ParseError{Reason: fmt.Sprintf("file exceeds %d bytes", s.cfg.MaxFileSize)})
+	}
+	if err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = s.resolveRecipients(rows); err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	job := models.PayoutJob{
+		ID:        uuid.NewString(),
+		From:      from,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = s.store.CreatePayoutJob(ctx, job, rows); err != nil {
+		return models.PayoutJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	s.notify()
+
+	job, err = s.store.PayoutJob(ctx, job.ID)
+	if err != nil {
+		return job, fmt.Errorf("%s: %w", op, err)
+	}
+	return job, nil
+}
+
+// resolveRecipients приводит адреса и имена получателей к каноническому адресу.
+// Неизвестный адрес не является ошибкой файла: строка отклоняется при выполнении.
+// Неизвестное имя, напротив, отклоняет файл: получателя по нему не определить.
+func (s *Service) resolveRecipients(rows []models.PayoutRow) error {
+	for i := range rows {
+		to, err := s.store.ResolveAddress(rows[i].To)
+		switch {
+		case errors.Is(err, storage.ErrMalformedAddress):
+			return &ParseError{Line: rows[i].Line, Reason: fmt.Sprintf("malformed recipient address %q", rows[i].To)}
+		case errors.Is(err, storage.ErrInvalidHandle), errors.Is(err, storage.ErrHandleNotFound):
+			return &ParseError{Line: rows[i].Line, Reason: fmt.Sprintf("unknown recipient handle %q", rows[i].To)}
+		}
+		if err != nil {
+			return err
+		}
+		rows[i].To = to
+	}
+	return nil
+}
+
+// Job возвращает задание со сводкой по статусам строк.
+func (s *Service) Job(ctx context.Context, id string) (models.PayoutJob, error) {
+	return s.store.PayoutJob(ctx, id)
+}
+
+// Results передает в fn строки задания с их текущими статусами.
+func (s *Service) Results(ctx context.Context, id string, fn func(models.PayoutRow) error) error {
+	return s.store.PayoutRows(ctx, id, fn)
+}
+
+// notify будит обработчик, не дожидаясь очередного интервала опроса.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run выполняет ожидающие строки заданий до отмены ctx.
+// При запуске строки, прерванные предыдущей остановкой, сверяются с сохраненными переводами:
+// строки с найденным переводом помечаются выполненными, остальные - как unknown.
+// Строки выполняются последовательно; после остановки выполнение продолжается со следующей строки.
+func (s *Service) Run(ctx context.Context) {
+	const op = "payout.Run"
+
+	log := s.log.With("op", op)
+
+	succeeded, unknown, err := s.store.RecoverPayoutRows(ctx, CodeOutcomeUnknown)
+	if err != nil {
+		log.Error("failed to recover interrupted payout rows", sl.Err(err))
+	}
+	if succeeded > 0 {
+		log.Info("interrupted payout rows reconciled as succeeded", slog.Int("rows", succeeded))
+	}
+	if unknown > 0 {
+		log.Warn("interrupted payout rows marked as unknown", slog.Int("rows", unknown))
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain выполняет строки, пока они есть и ctx не отменен.
+func (s *Service) drain(ctx context.Context) {
+	const op = "payout.drain"
+
+	log := s.log.With("op", op)
+
+	for ctx.Err() == nil {
+		row, ok, err := s.store.NextPayoutRow(ctx)
+		if err != nil {
+			log.Error("failed to fetch payout row", sl.Err(err))
+			return
+		}
+		if !ok {
+			return
+		}
+		s.execute(ctx, row)
+	}
+}
+
+// execute выполняет перевод по строке и записывает его исход.
+func (s *Service) execute(ctx context.Context, row models.PayoutRow) {
+	const op = "payout.execute"
+
+	log := s.log.With("op", op,
+		slog.String("job", row.JobID),
+		slog.Int("line", row.Line),
+		slog.String("reference", row.TransferReference),
+	)
+
+	status, code := models.PayoutRowSucceeded, ""
+	if err := s.maker.AddTransactionWithReference(row.From, row.To, row.Amount, row.TransferReference); err != nil {
+		status, code = models.PayoutRowFailed, storage.Code(err)
+		if code == "" {
+			code = response.CodeInternal
+		}
+		log.Warn("payout row failed", slog.String("error_code", code), sl.Err(err))
+	}
+
+	// Исход перевода уже известен и должен быть записан даже при остановке
+	if err := s.store.FinishPayoutRow(context.WithoutCancel(ctx), row.JobID, row.Line, status, code); err != nil {
+		log.Error("failed to save payout row status", slog.String("status", status), sl.Err(err))
+	}
+}
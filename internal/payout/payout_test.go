@@ -0,0 +1,257 @@
+package payout_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/payout"
+	"infotecsTest/internal/storage"
+	"infotecsTest/internal/storage/sqlite"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Адреса кошельков тестового хранилища
+var (
+	payer = address.New()
+	alice = address.New()
+	bob   = address.New()
+	ghost = address.New() // Отсутствует в хранилище
+)
+
+// withAddresses заменяет в файле выплат имена кошельков их адресами.
+var withAddresses = strings.NewReplacer("payer", payer, "alice", alice, "bob", bob, "ghost", ghost)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name         string
+		file         string
+		maxRows      int
+		expectedRows []models.PayoutRow
+		expectedErr  *payout.ParseError
+	}{
+		{
+			name:    "Файл с заголовком",
+			file:    "to,amount,reference\naddr2, 10 ,salary\naddr3,2.5,\"bonus, May\"\n",
+			maxRows: 10,
+			expectedRows: []models.PayoutRow{
+				{Line: 2, To: "addr2", Amount: 10, Reference: "salary"},
+				{Line: 3, To: "addr3", Amount: 2.5, Reference: "bonus, May"},
+			},
+		},
+		{
+			name:    "Файл без заголовка и назначения платежа",
+			file:    "addr2,10\n\naddr3,-1\n",
+			maxRows: 10,
+			expectedRows: []models.PayoutRow{
+				{Line: 1, To: "addr2", Amount: 10},
+				{Line: 3, To: "addr3", Amount: -1},
+			},
+		},
+		{
+			name:        "Некорректная сумма",
+			file:        "to,amount\naddr2,ten\n",
+			maxRows:     10,
+			expectedErr: &payout.ParseError{Line: 2, Reason: `invalid amount "ten"`},
+		},
+		{
+			name:        "Неверное число столбцов",
+			file:        "addr2\n",
+			maxRows:     10,
+			expectedErr: &payout.ParseError{Line: 1, Reason: "expected 2 or 3 columns, got 1"},
+		},
+		{
+			name:        "Пустой получатель",
+			file:        " ,10\n",
+			maxRows:     10,
+			expectedErr: &payout.ParseError{Line: 1, Reason: "recipient is empty"},
+		},
+		{
+			name:        "Превышено число строк",
+			file:        "addr2,1\naddr3,1\n",
+			maxRows:     1,
+			expectedErr: &payout.ParseError{Reason: "more than 1 rows"},
+		},
+		{
+			name:        "Только заголовок",
+			file:        "to,amount,reference\n",
+			maxRows:     10,
+			expectedErr: &payout.ParseError{Reason: "no rows"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, err := payout.Parse(strings.NewReader(tc.file), tc.maxRows)
+			if tc.expectedErr != nil {
+				var perr *payout.ParseError
+				require.True(t, errors.As(err, &perr))
+				require.Equal(t, tc.expectedErr, perr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedRows, rows)
+		})
+	}
+}
+
+// newService создает сервис выплат с хранилищем во временном каталоге.
+func newService(t *testing.T, cfg config.Payouts) (*payout.Service, *sqlite.Storage) {
+	t.Helper()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	require.NoError(t, s.Seed(context.Background(), []models.Wallet{
+		{Address: payer, Balance: 100},
+		{Address: alice, Balance: 0},
+		{Address: bob, Balance: 0},
+	}, nil))
+
+	return payout.New(testLogger, s, s, cfg), s
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	svc, s := newService(t, config.Payouts{MaxRows: 10, MaxFileSize: 1 << 10, PollInterval: time.Hour})
+
+	_, err := svc.Create(ctx, ghost, strings.NewReader(withAddresses.Replace("alice,1\n")))
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+
+	_, err = svc.Create(ctx, "payer", strings.NewReader(withAddresses.Replace("alice,1\n")))
+	require.ErrorIs(t, err, storage.ErrMalformedAddress)
+
+	_, err = svc.Create(ctx, payer, strings.NewReader(strings.Repeat("x,1\n", 300)))
+	var perr *payout.ParseError
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, "file exceeds 1024 bytes", perr.Reason)
+
+	_, err = svc.Create(ctx, payer, strings.NewReader(withAddresses.Replace("alice,1\nalise,1\n")))
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, &payout.ParseError{Line: 2, Reason: `malformed recipient address "alise"`}, perr)
+
+	_, err = svc.Create(ctx, payer, strings.NewReader("@zoe,1\n"))
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, &payout.ParseError{Line: 1, Reason: `unknown recipient handle "@zoe"`}, perr)
+
+	// Получатель по имени сохраняется адресом кошелька
+	_, err = s.ClaimHandle(ctx, alice, "dana")
+	require.NoError(t, err)
+
+	// Адрес принимается прописными буквами и сохраняется строчными
+	job, err := svc.Create(ctx, strings.ToUpper(payer), strings.NewReader(withAddresses.Replace(
+		"to,amount,reference\n@Dana,30,salary\nghost,5,typo\nbob,0,zero\nbob,80,too much\nbob,20,bonus\n")))
+	require.NoError(t, err)
+	require.Equal(t, models.PayoutJobPending, job.Status)
+	require.Equal(t, 5, job.Total)
+	require.Equal(t, 5, job.Pending)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		svc.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		job, err = svc.Job(ctx, job.ID)
+		require.NoError(t, err)
+		return job.Status == models.PayoutJobCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 2, job.Succeeded)
+	require.Equal(t, 3, job.Failed)
+	require.NotNil(t, job.FinishedAt)
+
+	var codes, recipients []string
+	require.NoError(t, svc.Results(ctx, job.ID, func(row models.PayoutRow) error {
+		codes = append(codes, row.Status+":"+row.ErrorCode)
+		recipients = append(recipients, row.To)
+		return nil
+	}))
+	require.Equal(t, []string{
+		"succeeded:",
+		"failed:" + storage.CodeWalletNotFound,
+		"failed:" + storage.CodeIncorrectAmount,
+		"failed:" + storage.CodeInsufficientFunds,
+		"succeeded:",
+	}, codes)
+
+	require.Equal(t, alice, recipients[0])
+	require.Equal(t, payer, job.From)
+	w, err := s.GetWalletBalance(payer)
+	require.NoError(t, err)
+	require.Equal(t, 50.0, w.Balance)
+}
+
+func TestServiceRecover(t *testing.T) {
+	ctx := context.Background()
+	svc, s := newService(t, config.Payouts{MaxRows: 10, MaxFileSize: 1 << 10, PollInterval: time.Hour})
+
+	job, err := svc.Create(ctx, payer, strings.NewReader(withAddresses.Replace("alice,1\nbob,1\nbob,2\n")))
+	require.NoError(t, err)
+
+	// Строка, перевод по которой выполнен, но статус не записан до остановки процесса
+	row, ok, err := s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, alice, row.To)
+	require.NoError(t, s.AddTransactionWithReference(row.From, row.To, row.Amount, row.TransferReference))
+
+	// Строка, выполнение которой прервала остановка процесса до перевода
+	row, ok, err = s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, bob, row.To)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		svc.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		job, err = svc.Job(ctx, job.ID)
+		require.NoError(t, err)
+		return job.Status == models.PayoutJobCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 1, job.Unknown)
+	require.Equal(t, 2, job.Succeeded)
+
+	var statuses []string
+	require.NoError(t, svc.Results(ctx, job.ID, func(row models.PayoutRow) error {
+		statuses = append(statuses, row.Status+":"+row.ErrorCode)
+		return nil
+	}))
+	require.Equal(t, []string{
+		models.PayoutRowSucceeded + ":",
+		models.PayoutRowUnknown + ":" + payout.CodeOutcomeUnknown,
+		models.PayoutRowSucceeded + ":",
+	}, statuses)
+
+	for address, expected := range map[string]float64{alice: 1, bob: 2} {
+		w, err := s.GetWalletBalance(address)
+		require.NoError(t, err)
+		require.Equal(t, expected, w.Balance)
+	}
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"infotecsTest/internal/lib/metrics"
+	"time"
+)
+
+// MetricsObserver публикует события хранилища в реестр метрик.
+type MetricsObserver struct {
+	queryDuration  *metrics.HistogramVec
+	transfers      *metrics.CounterVec
+	transferAmount *metrics.CounterVec
+}
+
+// NewMetricsObserver регистрирует метрики хранилища в реестре.
+func NewMetricsObserver(reg *metrics.Registry) *MetricsObserver {
+	return &MetricsObserver{
+		queryDuration: reg.NewHistogramVec(
+			"payment_storage_query_duration_seconds",
+			"Duration of storage operations.",
+			nil, "operation",
+		),
+		transfers: reg.NewCounterVec(
+			"payment_transfers_total",
+			"Number of transfers by outcome.",
+			"outcome",
+		),
+		transferAmount: reg.NewCounterVec(
+			"payment_transfer_amount_total",
+			"Total requested transfer amount by outcome.",
+			"outcome",
+		),
+	}
+}
+
+// ObserveQuery записывает длительность операции.
+func (o *MetricsObserver) ObserveQuery(op string, d time.Duration) {
+	o.queryDuration.With(op).Observe(d.Seconds())
+}
+
+// ObserveTransfer увеличивает счетчики переводов по исходу.
+func (o *MetricsObserver) ObserveTransfer(amount float64, err error) {
+	outcome := Outcome(err)
+	o.transfers.With(outcome).Inc()
+	o.transferAmount.With(outcome).Add(amount)
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// Кошельки с адресами прежнего формата (UUID) при запуске получают адрес с контрольной суммой.
+// Прежний адрес сохраняется в wallet_aliases и продолжает приниматься API:
+// он заменяется на новый до обращения к данным кошелька.
+
+// queryRower выполняет запрос, возвращающий одну строку: *sql.DB или *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// legacyAddressTables - столбцы с адресами кошельков, переименовываемыми при переходе на новый формат.
+var legacyAddressTables = []struct{ table, column string }{
+	{"wallets", "address"},
+	{"wallet_shards", "address"},
+	{"wallet_handles", "address"},
+	{"wallet_labels", "address"},
+	{"wallet_metadata", "address"},
+	{"transactions", "from_address"},
+	{"transactions", "to_address"},
+	{"payout_jobs", "from_address"},
+	{"payout_rows", "to_address"},
+}
+
+// upgradeAddresses присваивает кошелькам с адресом-UUID адрес с контрольной суммой.
+// Прежний адрес сохраняется как псевдоним, адреса в транзакциях и выплатах заменяются.
+// Каждый кошелек переименовывается в отдельной транзакции.
+func (s *Storage) upgradeAddresses() error {
+	const op = "storage.sqlite.upgradeAddresses"
+
+	rows, err := s.db.Query("SELECT address FROM wallets")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var legacy []string
+	for rows.Next() {
+		var a string
+		if err = rows.Scan(&a); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if address.IsLegacy(a) {
+			legacy = append(legacy, a)
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, old := range legacy {
+		if err = s.renameWallet(old, address.New()); err != nil {
+			return fmt.Errorf("%s: wallet %s: %w", op, old, err)
+		}
+	}
+	return nil
+}
+
+// renameWallet заменяет адрес кошелька во всех таблицах и сохраняет прежний как псевдоним.
+func (s *Storage) renameWallet(old, renamed string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec("INSERT INTO wallet_aliases(alias, address) VALUES (?, ?)", old, renamed); err != nil {
+		return err
+	}
+	for _, t := range legacyAddressTables {
+		if _, err = tx.Exec(
+			fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", t.table, t.column, t.column), renamed, old,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ResolveAddress возвращает адрес кошелька в каноническом виде.
+// Адрес с контрольной суммой возвращается строчными буквами без обращения к базе,
+// адрес прежнего формата (UUID) заменяется по таблице псевдонимов,
+// имя кошелька (@alice) - по таблице имен с ошибками ResolveHandle.
+// Адрес прежнего формата без псевдонима возвращается без изменений: кошелек,
+// добавленный с таким адресом после запуска, получит новый адрес при следующем запуске.
+// Возвращает ErrMalformedAddress для строки, не являющейся адресом.
+func (s *Storage) ResolveAddress(input string) (string, error) {
+	const op = "storage.sqlite.ResolveAddress"
+	defer s.observe(op, time.Now())
+
+	if canonical, err := address.Parse(input); err == nil {
+		return canonical, nil
+	}
+	if address.IsHandle(input) {
+		h, err := s.ResolveHandle(context.Background(), input)
+		if err != nil {
+			return "", err
+		}
+		return h.Address, nil
+	}
+	if !address.IsLegacy(input) {
+		return "", storage.ErrMalformedAddress
+	}
+
+	canonical, err := lookupAlias(context.Background(), s.db, input)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return canonical, nil
+}
+
+// lookupAlias возвращает адрес кошелька с псевдонимом alias или сам alias,
+// если такого псевдонима нет.
+func lookupAlias(ctx context.Context, q queryRower, alias string) (string, error) {
+	if !address.IsLegacy(alias) {
+		return alias, nil
+	}
+	var canonical string
+	err := q.QueryRowContext(ctx, "SELECT address FROM wallet_aliases WHERE alias = ?", alias).Scan(&canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		return alias, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backup копирует базу в новый файл dest через online backup API SQLite.
+// Копирование выполняется порциями; при изменении базы другим соединением
+// SQLite начинает его заново, поэтому снимок всегда согласован.
+func (s *Storage) Backup(ctx context.Context, dest string) error {
+	const op = "storage.sqlite.Backup"
+	defer s.observe(op, time.Now())
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	err = conn.Raw(func(raw any) error {
+		return s.driver.backup(ctx, raw, dest)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"infotecsTest/internal/storage"
+	"sync"
+	"time"
+)
+
+// transferRequest - перевод, ожидающий выполнения в составе группы.
+type transferRequest struct {
+	from, to  string
+	amount    float64
+	version   int64
+	reference string
+	result    chan error
+}
+
+// batcher собирает параллельные переводы в группы и выполняет каждую группу
+// в одной транзакции БД: переводы проверяются и применяются по очереди,
+// а фиксация с записью на диск выполняется один раз на группу.
+// Каждый перевод получает собственный результат проверки.
+type batcher struct {
+	s        *Storage
+	maxBatch int           // Максимум переводов в группе
+	delay    time.Duration // Ожидание следующих переводов после первого в группе
+
+	closeMu sync.RWMutex // Защищает отправку в reqs от закрытия канала
+	closed  bool
+	reqs    chan *transferRequest
+	done    chan struct{}
+}
+
+// newBatcher запускает сбор переводов в группы.
+func newBatcher(s *Storage, maxBatch int, delay time.Duration) *batcher {
+	b := &batcher{
+		s:        s,
+		maxBatch: maxBatch,
+		delay:    delay,
+		reqs:     make(chan *transferRequest, maxBatch),
+		done:     make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// submit ставит перевод в очередь и дожидается результата.
+// После закрытия хранилища перевод выполняется отдельно.
+func (b *batcher) submit(from, to string, amount float64, version int64, reference string) error {
+	req := &transferRequest{
+		from: from, to: to, amount: amount, version: version, reference: reference,
+		result: make(chan error, 1),
+	}
+
+	b.closeMu.RLock()
+	if b.closed {
+		b.closeMu.RUnlock()
+		return b.s.retryBusy(func() error { return b.s.addTransaction(from, to, amount, version, reference) })
+	}
+	b.reqs <- req
+	b.closeMu.RUnlock()
+
+	return <-req.result
+}
+
+// loop собирает группу, начиная с первого поступившего перевода: до maxBatch переводов
+// или пока не истечет delay, - и выполняет ее. Переводы, поступившие во время
+// выполнения группы, попадают в следующую.
+func (b *batcher) loop() {
+	defer close(b.done)
+
+	batch := make([]*transferRequest, 0, b.maxBatch)
+	for req := range b.reqs {
+		batch = append(batch[:0], req)
+
+		timer := time.NewTimer(b.delay)
+	collect:
+		for len(batch) < b.maxBatch {
+			select {
+			case next, ok := <-b.reqs:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.commit(batch)
+	}
+}
+
+// commit выполняет группу и передает каждому переводу его результат.
+// Если группа не зафиксирована из-за ошибки базы, не связанной с блокировкой,
+// переводы выполняются по отдельности, чтобы ошибка одного не отклонила остальные.
+func (b *batcher) commit(batch []*transferRequest) {
+	const op = "storage.sqlite.commitBatch"
+	defer b.s.observe(op, time.Now())
+
+	var results []error
+	err := b.s.retryBusy(func() error {
+		var err error
+		results, err = b.s.addTransactions(batch)
+		return err
+	})
+
+	for i, r := range batch {
+		switch {
+		case err == nil:
+			r.result <- results[i]
+		case errors.Is(err, storage.ErrBusy):
+			r.result <- err
+		default:
+			r.result <- b.s.retryBusy(func() error { return b.s.addTransaction(r.from, r.to, r.amount, r.version, r.reference) })
+		}
+	}
+}
+
+// close прекращает прием переводов и дожидается выполнения принятых.
+func (b *batcher) close() {
+	b.closeMu.Lock()
+	if b.closed {
+		b.closeMu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.reqs)
+	b.closeMu.Unlock()
+
+	<-b.done
+}
+
+// addTransactions выполняет группу переводов в одной транзакции БД.
+// Переводы применяются по очереди, как при последовательном выполнении:
+// отклоненный перевод не изменяет данных и не влияет на остальные.
+// Возвращает результат каждого перевода или ошибку выполнения группы.
+func (s *Storage) addTransactions(batch []*transferRequest) ([]error, error) {
+	const op = "storage.sqlite.addTransactions"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]error, len(batch))
+	for i, r := range batch {
+		err = s.transferTx(tx, r.from, r.to, r.amount, r.version, r.reference)
+		if err != nil && !rejected(err) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		results[i] = err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"infotecsTest/internal/config"
+	"sort"
+)
+
+// Имена драйверов SQLite в конфигурации
+const (
+	DriverMattn   = "mattn"   // github.com/mattn/go-sqlite3, требует CGO
+	DriverModernc = "modernc" // modernc.org/sqlite, чистый Go
+)
+
+// driver описывает драйвер SQLite, подключенный к сборке.
+// Все обращения к API конкретного драйвера выполняются через эти функции,
+// остальной код пакета от драйвера не зависит.
+type driver struct {
+	sqlName string                                                 // Имя драйвера в database/sql
+	dsn     func(storagePath string, cfg config.SQLite) string     // Строка подключения с параметрами соединения
+	errCode func(err error) (int, bool)                            // Расширенный код результата SQLite из ошибки драйвера
+	backup  func(ctx context.Context, conn any, dest string) error // Online backup из соединения драйвера в файл dest
+}
+
+// drivers - драйверы, подключенные файлами driver_*.go по тегам сборки.
+var drivers = map[string]driver{}
+
+// Drivers возвращает имена драйверов, доступных в сборке.
+func Drivers() []string {
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupDriver возвращает драйвер по имени из конфигурации.
+// Пустое имя выбирает mattn, если сборка выполнена с CGO, иначе modernc.
+func lookupDriver(name string) (driver, error) {
+	if name == "" {
+		name = DriverMattn
+		if _, ok := drivers[name]; !ok {
+			name = DriverModernc
+		}
+	}
+	d, ok := drivers[name]
+	if !ok {
+		return driver{}, fmt.Errorf("sqlite driver %q is not available in this build (available: %v)", name, Drivers())
+	}
+	return d, nil
+}
+
+// backupStepPages - число страниц, копируемых за один шаг резервного копирования.
+// Между шагами блокировка чтения снимается, и запросы к базе не ожидают окончания копирования.
+const backupStepPages = 1024
+
+// copyPages выполняет копирование по шагам до завершения или отмены ctx.
+// step копирует очередную порцию страниц и сообщает о завершении копирования,
+// finish освобождает ресурсы копирования.
+func copyPages(ctx context.Context, step func(pages int) (bool, error), finish func() error) error {
+	for {
+		done, err := step(backupStepPages)
+		if err != nil {
+			_ = finish()
+			return err
+		}
+		if done {
+			break
+		}
+		if err = ctx.Err(); err != nil {
+			_ = finish()
+			return err
+		}
+	}
+	return finish()
+}
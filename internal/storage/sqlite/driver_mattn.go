@@ -0,0 +1,72 @@
+//go:build cgo && !sqlite_purego
+
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"infotecsTest/internal/config"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	drivers[DriverMattn] = driver{
+		sqlName: "sqlite3",
+		dsn:     mattnDSN,
+		errCode: mattnErrCode,
+		backup:  mattnBackup,
+	}
+}
+
+// mattnDSN добавляет к пути базы параметры соединения в формате go-sqlite3.
+func mattnDSN(storagePath string, cfg config.SQLite) string {
+	params := url.Values{}
+	if cfg.JournalMode != "" {
+		params.Set("_journal_mode", strings.ToUpper(cfg.JournalMode))
+	}
+	if cfg.Synchronous != "" {
+		params.Set("_synchronous", strings.ToUpper(cfg.Synchronous))
+	}
+	if cfg.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	}
+	if cfg.TxLock != "" {
+		params.Set("_txlock", strings.ToLower(cfg.TxLock))
+	}
+	if len(params) == 0 {
+		return storagePath
+	}
+	return storagePath + "?" + params.Encode()
+}
+
+// mattnErrCode извлекает расширенный код результата из ошибки go-sqlite3.
+func mattnErrCode(err error) (int, bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return 0, false
+	}
+	return int(sqliteErr.ExtendedCode), true
+}
+
+// mattnBackup копирует базу соединения conn в новый файл dest.
+func mattnBackup(ctx context.Context, conn any, dest string) error {
+	src, ok := conn.(*sqlite3.SQLiteConn)
+	if !ok {
+		return errors.New("unexpected source driver connection")
+	}
+
+	destRaw, err := (&sqlite3.SQLiteDriver{}).Open(dest)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = destRaw.Close() }()
+
+	b, err := destRaw.(*sqlite3.SQLiteConn).Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+	return copyPages(ctx, b.Step, b.Finish)
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/config"
+	"modernc.org/sqlite"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	drivers[DriverModernc] = driver{
+		sqlName: "sqlite",
+		dsn:     moderncDSN,
+		errCode: moderncErrCode,
+		backup:  moderncBackup,
+	}
+}
+
+// moderncDefaultBusyTimeout - ожидание блокировки, если оно не задано.
+// Совпадает со значением go-sqlite3 по умолчанию: без него modernc.org/sqlite
+// возвращает SQLITE_BUSY сразу.
+const moderncDefaultBusyTimeout = 5 * time.Second
+
+// moderncDSN добавляет к пути базы параметры соединения в формате modernc.org/sqlite.
+// Время записывается в том же формате, что и go-sqlite3, поэтому файл базы
+// можно открывать любым из драйверов.
+func moderncDSN(storagePath string, cfg config.SQLite) string {
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = moderncDefaultBusyTimeout
+	}
+
+	params := url.Values{}
+	params.Set("_time_format", "sqlite")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	if cfg.JournalMode != "" {
+		params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", strings.ToUpper(cfg.JournalMode)))
+	}
+	if cfg.Synchronous != "" {
+		params.Add("_pragma", fmt.Sprintf("synchronous(%s)", strings.ToUpper(cfg.Synchronous)))
+	}
+	if cfg.TxLock != "" {
+		params.Set("_txlock", strings.ToLower(cfg.TxLock))
+	}
+	return storagePath + "?" + params.Encode()
+}
+
+// moderncErrCode извлекает код результата из ошибки modernc.org/sqlite.
+func moderncErrCode(err error) (int, bool) {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return 0, false
+	}
+	return sqliteErr.Code(), true
+}
+
+// moderncBackup копирует базу соединения conn в новый файл dest.
+func moderncBackup(ctx context.Context, conn any, dest string) error {
+	src, ok := conn.(interface {
+		NewBackup(dstURI string) (*sqlite.Backup, error)
+	})
+	if !ok {
+		return errors.New("unexpected source driver connection")
+	}
+
+	b, err := src.NewBackup(dest)
+	if err != nil {
+		return err
+	}
+	step := func(pages int) (bool, error) {
+		more, err := b.Step(int32(pages))
+		return !more, err
+	}
+	return copyPages(ctx, step, b.Finish)
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"fmt"
+	"infotecsTest/internal/storage"
+)
+
+// Коды результата SQLite, общие для всех драйверов
+const (
+	codeBusy             = 5    // SQLITE_BUSY
+	codeLocked           = 6    // SQLITE_LOCKED
+	codeConstraintUnique = 2067 // SQLITE_CONSTRAINT_UNIQUE
+)
+
+// translate дополняет ошибку драйвера соответствующей ошибкой пакета storage:
+// ErrBusy для блокировки базы другим соединением, ErrDuplicate для нарушения UNIQUE.
+// Остальные ошибки возвращаются без изменений.
+func (s *Storage) translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	code, ok := s.driver.errCode(err)
+	if !ok {
+		return err
+	}
+	switch {
+	case code&0xff == codeBusy, code&0xff == codeLocked:
+		return fmt.Errorf("%w: %w", storage.ErrBusy, err)
+	case code == codeConstraintUnique:
+		return fmt.Errorf("%w: %w", storage.ErrDuplicate, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,8 @@
+package sqlite
+
+import "database/sql"
+
+// DB возвращает подключение хранилища для подготовки данных в тестах.
+func (s *Storage) DB() *sql.DB {
+	return s.db
+}
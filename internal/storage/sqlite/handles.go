@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// Имя кошелька (@alice) хранится в wallet_handles строчными буквами без @,
+// поэтому уникально без учета регистра. У кошелька не больше одного имени;
+// освобожденное имя может занять другой кошелек.
+
+// ClaimHandle присваивает кошельку имя, заменяя прежнее.
+// Повторное присвоение текущего имени кошелька ничего не изменяет.
+// Возвращает ErrInvalidHandle для имени недопустимого формата, ErrWalletNotFound
+// для неизвестного кошелька и ErrHandleTaken, если имя занято другим кошельком.
+func (s *Storage) ClaimHandle(ctx context.Context, wallet, handle string) (models.Handle, error) {
+	const op = "storage.sqlite.ClaimHandle"
+	defer s.observe(op, time.Now())
+
+	h, err := address.ParseHandle(handle)
+	if err != nil {
+		return models.Handle{}, storage.ErrInvalidHandle
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	if err = tx.QueryRowContext(ctx, "SELECT 1 FROM wallets WHERE address = ?", wallet).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Handle{}, storage.ErrWalletNotFound
+		}
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	var owner string
+	err = tx.QueryRowContext(ctx, "SELECT address FROM wallet_handles WHERE handle = ?", h).Scan(&owner)
+	switch {
+	case err == nil && owner == wallet:
+		return models.Handle{Handle: h, Address: wallet}, nil
+	case err == nil:
+		return models.Handle{}, storage.ErrHandleTaken
+	case !errors.Is(err, sql.ErrNoRows):
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM wallet_handles WHERE address = ?", wallet); err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO wallet_handles(handle, address, claimed_at) VALUES (?, ?, ?)", h, wallet, time.Now().UTC(),
+	); err != nil {
+		if errors.Is(s.translate(err), storage.ErrDuplicate) {
+			return models.Handle{}, storage.ErrHandleTaken
+		}
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return models.Handle{Handle: h, Address: wallet}, nil
+}
+
+// ReleaseHandle освобождает имя кошелька и возвращает его.
+// Возвращает ErrHandleNotFound, если у кошелька нет имени.
+func (s *Storage) ReleaseHandle(ctx context.Context, wallet string) (models.Handle, error) {
+	const op = "storage.sqlite.ReleaseHandle"
+	defer s.observe(op, time.Now())
+
+	var h string
+	err := s.db.QueryRowContext(ctx,
+		"DELETE FROM wallet_handles WHERE address = ? RETURNING handle", wallet,
+	).Scan(&h)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Handle{}, storage.ErrHandleNotFound
+		}
+		return models.Handle{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return models.Handle{Handle: h, Address: wallet}, nil
+}
+
+// ResolveHandle возвращает кошелек с указанным именем. Префикс @ и регистр не учитываются.
+// Возвращает ErrInvalidHandle для имени недопустимого формата
+// и ErrHandleNotFound, если имя не присвоено.
+func (s *Storage) ResolveHandle(ctx context.Context, handle string) (models.Handle, error) {
+	const op = "storage.sqlite.ResolveHandle"
+	defer s.observe(op, time.Now())
+
+	h, err := address.ParseHandle(handle)
+	if err != nil {
+		return models.Handle{}, storage.ErrInvalidHandle
+	}
+
+	var wallet string
+	err = s.db.QueryRowContext(ctx, "SELECT address FROM wallet_handles WHERE handle = ?", h).Scan(&wallet)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Handle{}, storage.ErrHandleNotFound
+		}
+		return models.Handle{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return models.Handle{Handle: h, Address: wallet}, nil
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"infotecsTest/internal/storage"
+)
+
+// integrityCheck - проверка данных, каждая строка результата которой является нарушением.
+type integrityCheck struct {
+	name  string
+	query string
+}
+
+// integrityChecks - проверки согласованности данных кошельков, транзакций и владельцев кошельков.
+var integrityChecks = []integrityCheck{
+	{
+		name:  "negative_balance",
+		query: "SELECT 'wallet ' || address || ' has negative balance ' || balance FROM wallets WHERE balance < 0",
+	},
+	{
+		name:  "negative_shard_balance",
+		query: "SELECT 'wallet ' || address || ' has negative shard ' || shard || ' balance ' || balance FROM wallet_shards WHERE balance < 0",
+	},
+	{
+		name: "unknown_wallet",
+		query: `
+		SELECT 'transaction ' || id || ' references unknown wallet ' || address
+		FROM (
+			SELECT id, from_address AS address FROM transactions
+			UNION ALL
+			SELECT id, to_address FROM transactions
+		)
+		WHERE address NOT IN (SELECT address FROM wallets)`,
+	},
+	{
+		name:  "invalid_amount",
+		query: "SELECT 'transaction ' || id || ' has non-positive amount ' || amount FROM transactions WHERE amount <= 0",
+	},
+	{
+		name:  "self_transfer",
+		query: "SELECT 'transaction ' || id || ' transfers to the same wallet ' || from_address FROM transactions WHERE from_address = to_address",
+	},
+	{
+		name:  "unknown_user",
+		query: "SELECT 'wallet ' || address || ' belongs to unknown user ' || user_id FROM wallets WHERE user_id != '' AND user_id NOT IN (SELECT id FROM users)",
+	},
+}
+
+// maxIssuesPerCheck ограничивает число нарушений, возвращаемых одной проверкой.
+const maxIssuesPerCheck = 20
+
+// CheckIntegrity проверяет файл базы данных, версию схемы и согласованность данных.
+// Возвращает найденные нарушения; ошибка означает, что проверку не удалось выполнить.
+func (s *Storage) CheckIntegrity(ctx context.Context) ([]storage.Issue, error) {
+	const op = "storage.sqlite.CheckIntegrity"
+
+	var issues []storage.Issue
+
+	rows, err := s.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for rows.Next() {
+		var msg string
+		if err = rows.Scan(&msg); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if msg != "ok" {
+			issues = append(issues, storage.Issue{Check: "sqlite_integrity", Message: msg})
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = s.CheckSchema(ctx); err != nil {
+		// Без схемы проверки данных невозможны
+		return append(issues, storage.Issue{Check: "schema", Message: err.Error()}), nil
+	}
+
+	for _, check := range integrityChecks {
+		found, err := s.queryIssues(ctx, check)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, check.name, err)
+		}
+		issues = append(issues, found...)
+	}
+
+	return issues, nil
+}
+
+// queryIssues выполняет проверку и возвращает не более maxIssuesPerCheck нарушений.
+func (s *Storage) queryIssues(ctx context.Context, check integrityCheck) ([]storage.Issue, error) {
+	rows, err := s.db.QueryContext(ctx, check.query+fmt.Sprintf(" LIMIT %d", maxIssuesPerCheck))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var issues []storage.Issue
+	for rows.Next() {
+		var msg string
+		if err = rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		issues = append(issues, storage.Issue{Check: check.name, Message: msg})
+	}
+	return issues, rows.Err()
+}
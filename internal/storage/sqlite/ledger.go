@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Wallets возвращает все кошельки с балансами и версиями с учетом подбалансов.
+// Используется движком переводов для загрузки состояния при запуске.
+func (s *Storage) Wallets(ctx context.Context) ([]models.Wallet, error) {
+	const op = "storage.sqlite.Wallets"
+	defer s.observe(op, time.Now())
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT w.address, w.balance + COALESCE(SUM(s.balance), 0), w.version + COALESCE(SUM(s.version), 0)
+		FROM wallets w
+		LEFT JOIN wallet_shards s ON s.address = w.address
+		GROUP BY w.id
+		ORDER BY w.address`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var wallets []models.Wallet
+	for rows.Next() {
+		var w models.Wallet
+		if err = rows.Scan(&w.Address, &w.Balance, &w.Version); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		wallets = append(wallets, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// LedgerCheckpoint возвращает номер последней записи журнала движка переводов,
+// перенесенной в хранилище. Для базы без переносов возвращает 0.
+func (s *Storage) LedgerCheckpoint(ctx context.Context) (uint64, error) {
+	const op = "storage.sqlite.LedgerCheckpoint"
+
+	var seq uint64
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM ledger_checkpoint").Scan(&seq); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return seq, nil
+}
+
+// insertChunk - число переводов в одном INSERT при переносе журнала.
+// 5 параметров на строку укладываются в ограничение SQLite в 999 параметров.
+const insertChunk = 190
+
+// ApplyTransfers переносит переводы движка в хранилище одной транзакцией:
+// записывает транзакции, изменяет балансы и версии кошельков и сохраняет номер
+// последней перенесенной записи. Переводы с номером не больше сохраненного пропускаются,
+// поэтому повторный перенос после сбоя не изменяет балансы дважды.
+// Переводы уже проверены движком, повторная проверка средств не выполняется.
+func (s *Storage) ApplyTransfers(ctx context.Context, transfers []storage.Transfer) error {
+	const op = "storage.sqlite.ApplyTransfers"
+	defer s.observe(op, time.Now())
+
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var checkpoint uint64
+	if err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM ledger_checkpoint").Scan(&checkpoint); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	fresh := make([]storage.Transfer, 0, len(transfers))
+	for _, t := range transfers {
+		if t.Seq > checkpoint {
+			fresh = append(fresh, t)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	// Журнал, записанный до перехода на новый формат адресов, содержит прежние адреса
+	if err = resolveAliases(ctx, tx, fresh); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	if err = s.applyBalances(ctx, tx, fresh); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	for chunk := range slices.Chunk(fresh, insertChunk) {
+		if err = insertTransfers(ctx, tx, chunk); err != nil {
+			return fmt.Errorf("%s: %w", op, s.translate(err))
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO ledger_checkpoint(id, seq) VALUES (1, ?) ON CONFLICT(id) DO UPDATE SET seq = excluded.seq",
+		fresh[len(fresh)-1].Seq,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return nil
+}
+
+// applyBalances изменяет баланс и версию каждого затронутого кошелька одним UPDATE.
+// Суммы применяются к балансу по одной в порядке журнала, как в памяти движка,
+// поэтому результат совпадает до последнего бита.
+// Подбалансы горячих кошельков переносятся в основной баланс.
+// Выражения готовятся в транзакции, поэтому перенос работает и в хранилище,
+// открытом через Open служебной командой.
+func (s *Storage) applyBalances(ctx context.Context, tx *sql.Tx, transfers []storage.Transfer) error {
+	deltas := make(map[string][]float64)
+	for _, t := range transfers {
+		deltas[t.From] = append(deltas[t.From], -t.Amount)
+		deltas[t.To] = append(deltas[t.To], t.Amount)
+	}
+
+	selectWallet, err := tx.PrepareContext(ctx, selectWalletQuery)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = selectWallet.Close() }()
+	update, err := tx.PrepareContext(ctx, "UPDATE wallets SET balance = ?, version = version + ? WHERE address = ?")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = update.Close() }()
+	// Число подбалансов не берется из кэша горячих кошельков: в хранилище из Open он не загружен
+	resetShards, err := tx.PrepareContext(ctx, "UPDATE wallet_shards SET balance = 0 WHERE address = ? AND balance != 0")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resetShards.Close() }()
+
+	for _, address := range slices.Sorted(maps.Keys(deltas)) {
+		var balance float64
+		var version int64
+		if err = selectWallet.QueryRowContext(ctx, address).Scan(&balance, &version); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("wallet %s: %w", address, storage.ErrWalletNotFound)
+			}
+			return err
+		}
+		for _, d := range deltas[address] {
+			balance += d
+		}
+		if _, err = update.ExecContext(ctx, balance, len(deltas[address]), address); err != nil {
+			return err
+		}
+		if _, err = resetShards.ExecContext(ctx, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAliases заменяет в переводах адреса прежнего формата по таблице псевдонимов.
+func resolveAliases(ctx context.Context, tx *sql.Tx, transfers []storage.Transfer) error {
+	resolved := make(map[string]string)
+	resolve := func(a string) (string, error) {
+		if r, ok := resolved[a]; ok {
+			return r, nil
+		}
+		r, err := lookupAlias(ctx, tx, a)
+		resolved[a] = r
+		return r, err
+	}
+
+	var err error
+	for i := range transfers {
+		if transfers[i].From, err = resolve(transfers[i].From); err != nil {
+			return err
+		}
+		if transfers[i].To, err = resolve(transfers[i].To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertTransfers записывает переводы в таблицу транзакций одним INSERT.
+// Время записывается в UTC, как и остальными операциями: выборка за период сравнивает строки времени.
+func insertTransfers(ctx context.Context, tx *sql.Tx, transfers []storage.Transfer) error {
+	query := "INSERT INTO transactions(from_address, to_address, amount, timestamp, reference) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", len(transfers)), ", ")
+	args := make([]any, 0, 5*len(transfers))
+	for _, t := range transfers {
+		args = append(args, t.From, t.To, t.Amount, t.Time.UTC(), t.Reference)
+	}
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
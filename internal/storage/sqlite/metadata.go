@@ -0,0 +1,262 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Владелец кошелька хранится в wallets.owner, метки - в wallet_labels строчными буквами,
+// метаданные - в wallet_metadata. Сведения заменяются целиком.
+
+// Ограничения метаданных кошелька
+const (
+	maxOwnerLen    = 128
+	maxLabels      = 32
+	maxLabelLen    = 64
+	maxMetadata    = 32
+	maxMetaKeyLen  = 64
+	maxMetaValLen  = 1024
+	maxFoundWallet = 1000
+)
+
+// normalizeMetadata проверяет сведения о кошельке и приводит их к хранимому виду:
+// пробелы по краям удаляются, метки приводятся к нижнему регистру, сортируются и не повторяются.
+// Возвращает *storage.InvalidMetadataError для недопустимых значений.
+func normalizeMetadata(md models.WalletMetadata) (models.WalletMetadata, error) {
+	invalid := func(format string, args ...any) (models.WalletMetadata, error) {
+		return models.WalletMetadata{}, &storage.InvalidMetadataError{Reason: fmt.Sprintf(format, args...)}
+	}
+
+	res := models.WalletMetadata{Owner: strings.TrimSpace(md.Owner)}
+	if len(res.Owner) > maxOwnerLen {
+		return invalid("owner exceeds %d bytes", maxOwnerLen)
+	}
+
+	if len(md.Labels) > maxLabels {
+		return invalid("more than %d labels", maxLabels)
+	}
+	seen := make(map[string]bool, len(md.Labels))
+	for _, l := range md.Labels {
+		l = strings.ToLower(strings.TrimSpace(l))
+		switch {
+		case l == "":
+			return invalid("label is empty")
+		case len(l) > maxLabelLen:
+			return invalid("label %q exceeds %d bytes", l, maxLabelLen)
+		case seen[l]:
+			continue
+		}
+		seen[l] = true
+		res.Labels = append(res.Labels, l)
+	}
+	sort.Strings(res.Labels)
+
+	if len(md.Metadata) > maxMetadata {
+		return invalid("more than %d metadata keys", maxMetadata)
+	}
+	for k, v := range md.Metadata {
+		k = strings.TrimSpace(k)
+		switch {
+		case k == "":
+			return invalid("metadata key is empty")
+		case len(k) > maxMetaKeyLen:
+			return invalid("metadata key %q exceeds %d bytes", k, maxMetaKeyLen)
+		case len(v) > maxMetaValLen:
+			return invalid("metadata value of %q exceeds %d bytes", k, maxMetaValLen)
+		}
+		if res.Metadata == nil {
+			res.Metadata = make(map[string]string, len(md.Metadata))
+		}
+		res.Metadata[k] = v
+	}
+
+	return res, nil
+}
+
+// writeMetadata заменяет сведения о существующем кошельке в транзакции tx.
+func writeMetadata(ctx context.Context, tx *sql.Tx, address string, md models.WalletMetadata) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE wallets SET owner = ? WHERE address = ?", md.Owner, address); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM wallet_labels WHERE address = ?", address); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM wallet_metadata WHERE address = ?", address); err != nil {
+		return err
+	}
+	for _, l := range md.Labels {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO wallet_labels(address, label) VALUES (?, ?)", address, l,
+		); err != nil {
+			return err
+		}
+	}
+	for k, v := range md.Metadata {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO wallet_metadata(address, key, value) VALUES (?, ?, ?)", address, k, v,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWalletMetadata заменяет владельца, метки и метаданные кошелька и возвращает сохраненные сведения.
+// Возвращает ErrWalletNotFound для неизвестного кошелька
+// и *storage.InvalidMetadataError для недопустимых значений.
+func (s *Storage) SetWalletMetadata(ctx context.Context, address string, md models.WalletMetadata) (models.WalletMetadata, error) {
+	const op = "storage.sqlite.SetWalletMetadata"
+	defer s.observe(op, time.Now())
+
+	md, err := normalizeMetadata(md)
+	if err != nil {
+		return models.WalletMetadata{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	if err = tx.QueryRowContext(ctx, "SELECT 1 FROM wallets WHERE address = ?", address).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WalletMetadata{}, storage.ErrWalletNotFound
+		}
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	if err = writeMetadata(ctx, tx, address, md); err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	if err = tx.Commit(); err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return md, nil
+}
+
+// WalletMetadata возвращает владельца, метки и метаданные кошелька.
+// Возвращает ErrWalletNotFound если кошелек не существует.
+func (s *Storage) WalletMetadata(ctx context.Context, address string) (models.WalletMetadata, error) {
+	const op = "storage.sqlite.WalletMetadata"
+	defer s.observe(op, time.Now())
+
+	var md models.WalletMetadata
+	err := s.db.QueryRowContext(ctx, "SELECT owner FROM wallets WHERE address = ?", address).Scan(&md.Owner)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WalletMetadata{}, storage.ErrWalletNotFound
+		}
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	wallets := []models.Wallet{{Address: address}}
+	if err = s.loadMetadata(ctx, wallets); err != nil {
+		return models.WalletMetadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+	md.Labels, md.Metadata = wallets[0].Labels, wallets[0].Metadata
+	return md, nil
+}
+
+// FindWallets возвращает кошельки с указанными меткой и владельцем, упорядоченные по адресу,
+// вместе с их сведениями. Баланс и версия не заполняются: их источник - движок переводов.
+// Метка сравнивается без учета регистра; число кошельков ограничено filter.Limit.
+// Непустой filter.UserID оставляет только кошельки этого пользователя.
+func (s *Storage) FindWallets(ctx context.Context, filter models.WalletFilter) ([]models.Wallet, error) {
+	const op = "storage.sqlite.FindWallets"
+	defer s.observe(op, time.Now())
+
+	limit := filter.Limit
+	if limit <= 0 || limit > maxFoundWallet {
+		limit = maxFoundWallet
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT w.address, w.owner FROM wallets w
+	WHERE (?1 = '' OR w.owner = ?1)
+		AND (?2 = '' OR EXISTS (SELECT 1 FROM wallet_labels l WHERE l.address = w.address AND l.label = ?2))
+		AND (?4 = '' OR w.user_id = ?4)
+	ORDER BY w.address
+	LIMIT ?3`,
+		strings.TrimSpace(filter.Owner), strings.ToLower(strings.TrimSpace(filter.Label)), limit, filter.UserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	var wallets []models.Wallet
+	for rows.Next() {
+		var w models.Wallet
+		if err = rows.Scan(&w.Address, &w.Owner); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		wallets = append(wallets, w)
+	}
+	if err = rows.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = s.loadMetadata(ctx, wallets); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+// loadMetadata заполняет метки и метаданные кошельков двумя запросами.
+func (s *Storage) loadMetadata(ctx context.Context, wallets []models.Wallet) error {
+	if len(wallets) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(wallets))
+	args := make([]any, len(wallets))
+	for i, w := range wallets {
+		index[w.Address] = i
+		args[i] = w.Address
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(wallets)), ",")
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT address, label FROM wallet_labels WHERE address IN ("+in+") ORDER BY address, label", args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var address, label string
+		if err = rows.Scan(&address, &label); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		w := &wallets[index[address]]
+		w.Labels = append(w.Labels, label)
+	}
+	if err = rows.Close(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.QueryContext(ctx,
+		"SELECT address, key, value FROM wallet_metadata WHERE address IN ("+in+")", args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var address, key, value string
+		if err = rows.Scan(&address, &key, &value); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		w := &wallets[index[address]]
+		if w.Metadata == nil {
+			w.Metadata = make(map[string]string)
+		}
+		w.Metadata[key] = value
+	}
+	return rows.Close()
+}
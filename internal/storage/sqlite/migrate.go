@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// migrations - версии схемы в порядке применения.
+// Примененная миграция не изменяется, изменения схемы добавляются новой версией.
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create wallets and transactions",
+		//Лучше использовать INTEGER с преобразованием в коде
+		//REAL не подходит для операций с деньгами
+		SQL: `
+	CREATE TABLE IF NOT EXISTS wallets(
+		id INTEGER PRIMARY KEY,
+		address TEXT NOT NULL UNIQUE,
+		balance REAL DEFAULT 0.0 
+	);
+	CREATE INDEX IF NOT EXISTS idx_address ON wallets(address);
+
+	CREATE TABLE IF NOT EXISTS transactions(
+	    id INTEGER PRIMARY KEY,
+	    from_address TEXT NOT NULL,
+	    to_address TEXT NOT NULL,
+	    amount REAL NOT NULL,
+	    timestamp DATE DEFAULT CURRENT_DATE
+	);
+	CREATE INDEX IF NOT EXISTS idx_transactions ON transactions(from_address,to_address);
+	`,
+	},
+	{
+		Version: 2,
+		Name:    "create payout jobs",
+		SQL: `
+	CREATE TABLE payout_jobs(
+		id TEXT PRIMARY KEY,
+		from_address TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		finished_at DATETIME
+	);
+
+	CREATE TABLE payout_rows(
+		job_id TEXT NOT NULL REFERENCES payout_jobs(id),
+		line INTEGER NOT NULL,
+		to_address TEXT NOT NULL,
+		amount REAL NOT NULL,
+		reference TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL,
+		error_code TEXT NOT NULL DEFAULT '',
+		processed_at DATETIME,
+		PRIMARY KEY(job_id, line)
+	);
+	CREATE INDEX idx_payout_rows_status ON payout_rows(status);
+	`,
+	},
+	{
+		Version: 3,
+		Name:    "add wallet versions",
+		SQL: `
+	ALTER TABLE wallets ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	`,
+	},
+	{
+		Version: 4,
+		Name:    "create ledger checkpoint",
+		SQL: `
+	CREATE TABLE ledger_checkpoint(
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		seq INTEGER NOT NULL
+	);
+	`,
+	},
+	{
+		Version: 5,
+		Name:    "create wallet shards",
+		SQL: `
+	CREATE TABLE wallet_shards(
+		address TEXT NOT NULL REFERENCES wallets(address),
+		shard INTEGER NOT NULL,
+		balance REAL NOT NULL DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY(address, shard)
+	);
+	`,
+	},
+	{
+		Version: 6,
+		Name:    "create wallet aliases",
+		SQL: `
+	CREATE TABLE wallet_aliases(
+		alias TEXT PRIMARY KEY,
+		address TEXT NOT NULL REFERENCES wallets(address)
+	);
+	`,
+	},
+	{
+		Version: 7,
+		Name:    "create wallet handles",
+		SQL: `
+	CREATE TABLE wallet_handles(
+		handle TEXT PRIMARY KEY,
+		address TEXT NOT NULL UNIQUE REFERENCES wallets(address),
+		claimed_at DATETIME NOT NULL
+	);
+	`,
+	},
+	{
+		Version: 8,
+		Name:    "add wallet metadata",
+		SQL: `
+	ALTER TABLE wallets ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_wallets_owner ON wallets(owner);
+	CREATE TABLE wallet_labels(
+		address TEXT NOT NULL REFERENCES wallets(address),
+		label TEXT NOT NULL,
+		PRIMARY KEY(address, label)
+	);
+	CREATE INDEX idx_wallet_labels_label ON wallet_labels(label);
+	CREATE TABLE wallet_metadata(
+		address TEXT NOT NULL REFERENCES wallets(address),
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY(address, key)
+	);
+	`,
+	},
+	{
+		Version: 9,
+		Name:    "create users",
+		SQL: `
+	CREATE TABLE users(
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		email TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE user_credentials(
+		key_hash TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL REFERENCES users(id),
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_user_credentials_user ON user_credentials(user_id);
+	ALTER TABLE wallets ADD COLUMN user_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_wallets_user ON wallets(user_id);
+	`,
+	},
+	{
+		Version: 10,
+		Name:    "add transaction references",
+		SQL: `
+	ALTER TABLE transactions ADD COLUMN reference TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_transactions_reference ON transactions(reference) WHERE reference != '';
+	`,
+	},
+	{
+		Version: 11,
+		Name:    "index transactions by wallet and time",
+		// Время переводов приводится к UTC в формате драйвера: строки сравниваются
+		// в порядке времени, и выборка за период использует индекс без julianday
+		SQL: `
+	UPDATE transactions
+	SET timestamp = strftime('%Y-%m-%d %H:%M:%f+00:00', timestamp)
+	WHERE julianday(timestamp) IS NOT NULL;
+	CREATE INDEX idx_transactions_from_time ON transactions(from_address, timestamp);
+	CREATE INDEX idx_transactions_to_time ON transactions(to_address, timestamp);
+	`,
+	},
+}
+
+// Migrate применяет недостающие миграции схемы.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью о ней.
+// Возвращает примененные миграции.
+func (s *Storage) Migrate(ctx context.Context) ([]storage.Migration, error) {
+	const op = "storage.sqlite.Migrate"
+
+	if _, err := s.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);
+	`); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var applied []storage.Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err = s.apply(ctx, m); err != nil {
+			return applied, fmt.Errorf("%s: migration %d: %w", op, m.Version, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// apply выполняет одну миграцию.
+func (s *Storage) apply(ctx context.Context, m storage.Migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations(version, name, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Name, time.Now(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schemaVersion возвращает версию последней примененной миграции.
+// Для базы без таблицы schema_migrations возвращает 0.
+func (s *Storage) schemaVersion(ctx context.Context) (int, error) {
+	var exists int
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'",
+	).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// latestVersion возвращает версию последней известной миграции.
+func latestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
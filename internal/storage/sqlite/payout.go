@@ -0,0 +1,291 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// payoutReferenceSQL вычисляет для строки r ссылку, с которой сохраняется перевод по ней.
+// Должно совпадать с payoutReference.
+const payoutReferenceSQL = "'payout:' || r.job_id || ':' || r.line"
+
+// payoutReference возвращает ссылку, с которой сохраняется перевод по строке задания.
+// По ней после сбоя определяется, был ли перевод выполнен.
+func payoutReference(jobID string, line int) string {
+	return fmt.Sprintf("payout:%s:%d", jobID, line)
+}
+
+// CreatePayoutJob сохраняет задание на выплату вместе со строками в одной транзакции.
+// Строки сохраняются со статусом pending.
+func (s *Storage) CreatePayoutJob(ctx context.Context, job models.PayoutJob, rows []models.PayoutRow) error {
+	const op = "storage.sqlite.CreatePayoutJob"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx,
+		"INSERT INTO payout_jobs(id, from_address, created_at) VALUES (?, ?, ?)",
+		job.ID, job.From, job.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO payout_rows(job_id, line, to_address, amount, reference, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, job.ID, row.Line, row.To, row.Amount, row.Reference, models.PayoutRowPending); err != nil {
+			return fmt.Errorf("%s: line %d: %w", op, row.Line, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// PayoutJob возвращает задание на выплату со сводкой по статусам строк.
+// Возвращает ErrPayoutNotFound если задание не существует.
+func (s *Storage) PayoutJob(ctx context.Context, id string) (models.PayoutJob, error) {
+	const op = "storage.sqlite.PayoutJob"
+	defer s.observe(op, time.Now())
+
+	job := models.PayoutJob{ID: id}
+	var finished sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT j.from_address, j.created_at, j.finished_at,
+		       COUNT(r.line),
+		       COALESCE(SUM(r.status IN (?2, ?3)), 0),
+		       COALESCE(SUM(r.status = ?4), 0),
+		       COALESCE(SUM(r.status = ?5), 0),
+		       COALESCE(SUM(r.status = ?6), 0)
+		FROM payout_jobs j
+		LEFT JOIN payout_rows r ON r.job_id = j.id
+		WHERE j.id = ?1
+		GROUP BY j.id
+	`, id,
+		models.PayoutRowPending, models.PayoutRowProcessing,
+		models.PayoutRowSucceeded, models.PayoutRowFailed, models.PayoutRowUnknown,
+	).Scan(&job.From, &job.CreatedAt, &finished,
+		&job.Total, &job.Pending, &job.Succeeded, &job.Failed, &job.Unknown)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return job, storage.ErrPayoutNotFound
+		}
+		return job, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if finished.Valid {
+		job.FinishedAt = &finished.Time
+	}
+	switch {
+	case job.Pending == 0:
+		job.Status = models.PayoutJobCompleted
+	case job.Pending == job.Total:
+		job.Status = models.PayoutJobPending
+	default:
+		job.Status = models.PayoutJobRunning
+	}
+	return job, nil
+}
+
+// PayoutRows передает в fn строки задания в порядке следования в файле.
+// Возвращает ErrPayoutNotFound если задание не существует.
+// Ошибка fn прекращает чтение и возвращается без изменений.
+func (s *Storage) PayoutRows(ctx context.Context, id string, fn func(models.PayoutRow) error) error {
+	const op = "storage.sqlite.PayoutRows"
+	defer s.observe(op, time.Now())
+
+	var from string
+	if err := s.db.QueryRowContext(ctx, "SELECT from_address FROM payout_jobs WHERE id = ?", id).Scan(&from); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrPayoutNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT line, to_address, amount, reference, status, error_code
+		FROM payout_rows
+		WHERE job_id = ?
+		ORDER BY line
+	`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		row := models.PayoutRow{JobID: id, From: from}
+		if err = rows.Scan(&row.Line, &row.To, &row.Amount, &row.Reference, &row.Status, &row.ErrorCode); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// NextPayoutRow выбирает следующую ожидающую строку из самого раннего задания
+// и переводит ее в статус processing. Строка возвращается со ссылкой,
+// которую нужно сохранить вместе с переводом по ней.
+// Возвращает false, если ожидающих строк нет.
+func (s *Storage) NextPayoutRow(ctx context.Context) (models.PayoutRow, bool, error) {
+	const op = "storage.sqlite.NextPayoutRow"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PayoutRow{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var row models.PayoutRow
+	err = tx.QueryRowContext(ctx, `
+		SELECT r.job_id, j.from_address, r.line, r.to_address, r.amount, r.reference
+		FROM payout_rows r
+		JOIN payout_jobs j ON j.id = r.job_id
+		WHERE r.status = ?
+		ORDER BY j.created_at, r.job_id, r.line
+		LIMIT 1
+	`, models.PayoutRowPending).Scan(&row.JobID, &row.From, &row.Line, &row.To, &row.Amount, &row.Reference)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return row, false, nil
+		}
+		return row, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE payout_rows SET status = ? WHERE job_id = ? AND line = ?",
+		models.PayoutRowProcessing, row.JobID, row.Line,
+	); err != nil {
+		return row, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return row, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	row.Status = models.PayoutRowProcessing
+	row.TransferReference = payoutReference(row.JobID, row.Line)
+	return row, true, nil
+}
+
+// FinishPayoutRow записывает итоговый статус строки и код ошибки.
+// После обработки последней строки отмечает время завершения задания.
+func (s *Storage) FinishPayoutRow(ctx context.Context, jobID string, line int, status, errCode string) error {
+	const op = "storage.sqlite.FinishPayoutRow"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE payout_rows SET status = ?, error_code = ?, processed_at = ? WHERE job_id = ? AND line = ?",
+		status, errCode, now, jobID, line,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = finishPayoutJobs(ctx, tx, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RecoverPayoutRows сверяет строки, выполнение которых было прервано остановкой процесса,
+// с сохраненными переводами. Строка, перевод по которой найден по ссылке, отмечается выполненной.
+// Остальные строки переводятся в статус unknown с кодом errCode: перевод по ним мог быть
+// выполнен, но не сохранен (например, журнал движка отброшен при восстановлении),
+// поэтому повторять его автоматически нельзя.
+// Возвращает число выполненных строк и число строк с неизвестным исходом.
+func (s *Storage) RecoverPayoutRows(ctx context.Context, errCode string) (int, int, error) {
+	const op = "storage.sqlite.RecoverPayoutRows"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	// Условие reference != '' повторяет условие частичного индекса idx_transactions_reference:
+	// без него SQLite не использует индекс и просматривает все транзакции для каждой строки
+	succeeded, err := updatePayoutRows(ctx, tx, `
+		UPDATE payout_rows AS r SET status = ?, error_code = '', processed_at = ?
+		WHERE r.status = ?
+		  AND EXISTS (SELECT 1 FROM transactions t WHERE t.reference != '' AND t.reference = `+payoutReferenceSQL+`)
+	`, models.PayoutRowSucceeded, now, models.PayoutRowProcessing)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: reconcile: %w", op, err)
+	}
+	unknown, err := updatePayoutRows(ctx, tx,
+		"UPDATE payout_rows SET status = ?, error_code = ?, processed_at = ? WHERE status = ?",
+		models.PayoutRowUnknown, errCode, now, models.PayoutRowProcessing,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = finishPayoutJobs(ctx, tx, now); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return succeeded, unknown, nil
+}
+
+// updatePayoutRows выполняет изменение строк заданий и возвращает число измененных строк.
+func updatePayoutRows(ctx context.Context, tx *sql.Tx, query string, args ...any) (int, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// finishPayoutJobs отмечает время завершения заданий, у которых не осталось необработанных строк.
+func finishPayoutJobs(ctx context.Context, tx *sql.Tx, at time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payout_jobs SET finished_at = ?
+		WHERE finished_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM payout_rows r
+			WHERE r.job_id = payout_jobs.id AND r.status IN (?, ?)
+		  )
+	`, at, models.PayoutRowPending, models.PayoutRowProcessing)
+	return err
+}
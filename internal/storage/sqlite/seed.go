@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// Seed добавляет кошельки и историю транзакций из фикстуры.
+// Данные добавляются в одной транзакции: при любой ошибке база не изменяется.
+// Балансы кошельков берутся из фикстуры как есть, транзакции их не меняют.
+// Владелец, метки и метаданные кошелька сохраняются по правилам SetWalletMetadata.
+// Время транзакции задается в формате RFC 3339 и сохраняется в UTC; пустое время заменяется текущим.
+func (s *Storage) Seed(ctx context.Context, wallets []models.Wallet, txs []models.Transaction) error {
+	const op = "storage.sqlite.Seed"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, w := range wallets {
+		if w.Address == "" {
+			return fmt.Errorf("%s: wallet with empty address", op)
+		}
+		if _, err = tx.ExecContext(ctx,
+			"INSERT INTO wallets(address, balance) VALUES (?, ?)", w.Address, w.Balance,
+		); err != nil {
+			if errors.Is(s.translate(err), storage.ErrDuplicate) {
+				return fmt.Errorf("%s: wallet %s already exists", op, w.Address)
+			}
+			return fmt.Errorf("%s: insert wallet %s: %w", op, w.Address, err)
+		}
+		md, err := normalizeMetadata(models.WalletMetadata{Owner: w.Owner, Labels: w.Labels, Metadata: w.Metadata})
+		if err != nil {
+			return fmt.Errorf("%s: wallet %s: %w", op, w.Address, err)
+		}
+		if err = writeMetadata(ctx, tx, w.Address, md); err != nil {
+			return fmt.Errorf("%s: wallet %s metadata: %w", op, w.Address, err)
+		}
+	}
+
+	for i, t := range txs {
+		at := time.Now()
+		if t.Time != "" {
+			if at, err = time.Parse(time.RFC3339, t.Time); err != nil {
+				return fmt.Errorf("%s: transaction %d: invalid time: %w", op, i, err)
+			}
+		}
+		if _, err = tx.ExecContext(ctx,
+			"INSERT INTO transactions(from_address, to_address, amount, timestamp) VALUES (?, ?, ?, ?)",
+			t.From, t.To, t.Amount, at.UTC(),
+		); err != nil {
+			return fmt.Errorf("%s: insert transaction %d: %w", op, i, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/storage"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Баланс горячего кошелька разделен между строкой wallets (основной баланс)
+// и строками wallet_shards (подбалансы). Зачисления распределяются по подбалансам
+// по очереди, поэтому параллельные переводы на кошелек изменяют разные строки.
+// Списания выполняются с основного баланса. Баланс и версия кошелька - суммы
+// по основной строке и подбалансам: версия подбаланса увеличивается при зачислении на него.
+
+// selectWalletQuery выбирает баланс и версию кошелька с учетом подбалансов.
+const selectWalletQuery = `
+	SELECT w.balance + COALESCE(SUM(s.balance), 0), w.version + COALESCE(SUM(s.version), 0)
+	FROM wallets w
+	LEFT JOIN wallet_shards s ON s.address = w.address
+	WHERE w.address = ?
+	GROUP BY w.id`
+
+// loadHotWallets читает число подбалансов горячих кошельков.
+func (s *Storage) loadHotWallets() error {
+	rows, err := s.db.Query("SELECT address, COUNT(*) FROM wallet_shards GROUP BY address")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	hot := make(map[string]int)
+	for rows.Next() {
+		var address string
+		var shards int
+		if err = rows.Scan(&address, &shards); err != nil {
+			return err
+		}
+		hot[address] = shards
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	s.hotMu.Lock()
+	s.hot = hot
+	s.hotMu.Unlock()
+	return nil
+}
+
+// shards возвращает число подбалансов кошелька, 0 - для обычного кошелька.
+func (s *Storage) shards(address string) int {
+	s.hotMu.RLock()
+	defer s.hotMu.RUnlock()
+	return s.hot[address]
+}
+
+// creditShard зачисляет сумму на очередной подбаланс кошелька.
+func (s *Storage) creditShard(tx *sql.Tx, address string, shards int, amount float64) error {
+	i := s.shardCursor.Add(1) % uint64(shards)
+	res, err := tx.Exec(`
+		UPDATE wallet_shards
+		SET balance = balance + ?, version = version + 1
+		WHERE address = ? AND shard = ?
+		`, amount, address, i)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return errors.Join(err, fmt.Errorf("wallet %s: shard %d not found", address, i))
+	}
+	return nil
+}
+
+// consolidateIfShort переносит подбалансы в основной баланс кошелька,
+// если основного баланса не хватает для списания amount. Версия не изменяется.
+func consolidateIfShort(tx *sql.Tx, address string, amount float64) error {
+	res, err := tx.Exec(`
+		UPDATE wallets
+		SET balance = balance + (SELECT COALESCE(SUM(balance), 0) FROM wallet_shards WHERE address = ?)
+		WHERE address = ? AND balance < ?
+		`, address, address, amount)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err = tx.Exec("UPDATE wallet_shards SET balance = 0 WHERE address = ?", address)
+	return err
+}
+
+// SetHotWallets делит баланс перечисленных кошельков на заданное число подбалансов.
+// Кошельки, не вошедшие в список, и кошельки с измененным числом подбалансов
+// сначала объединяются: подбалансы и их версии переносятся в основную строку.
+// Баланс и версия кошельков не изменяются. Возвращает ErrWalletNotFound,
+// если кошелек из списка не существует. Должен вызываться до начала обработки запросов.
+func (s *Storage) SetHotWallets(ctx context.Context, wallets []config.HotWallet) error {
+	const op = "storage.sqlite.SetHotWallets"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// В конфигурации кошелек может быть указан адресом прежнего формата
+	want := make(map[string]int, len(wallets))
+	for _, w := range wallets {
+		address, err := lookupAlias(ctx, tx, w.Address)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		want[address] = w.Shards
+	}
+
+	current := make(map[string]int)
+	rows, err := tx.QueryContext(ctx, "SELECT address, COUNT(*) FROM wallet_shards GROUP BY address")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for rows.Next() {
+		var address string
+		var shards int
+		if err = rows.Scan(&address, &shards); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		current[address] = shards
+	}
+	if err = rows.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	changed := maps.Clone(current)
+	maps.Copy(changed, want)
+	for _, address := range slices.Sorted(maps.Keys(changed)) {
+		if current[address] == want[address] {
+			continue
+		}
+		if current[address] > 0 {
+			if err = unshard(ctx, tx, address); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if want[address] > 0 {
+			if err = shard(ctx, tx, address, want[address]); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	s.hotMu.Lock()
+	s.hot = want
+	s.hotMu.Unlock()
+	return nil
+}
+
+// unshard переносит подбалансы и их версии в основную строку кошелька и удаляет их.
+func unshard(ctx context.Context, tx *sql.Tx, address string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE wallets
+		SET balance = balance + (SELECT COALESCE(SUM(balance), 0) FROM wallet_shards WHERE address = ?),
+		    version = version + (SELECT COALESCE(SUM(version), 0) FROM wallet_shards WHERE address = ?)
+		WHERE address = ?
+		`, address, address, address); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM wallet_shards WHERE address = ?", address)
+	return err
+}
+
+// shard создает n пустых подбалансов кошелька.
+func shard(ctx context.Context, tx *sql.Tx, address string, n int) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, "SELECT 1 FROM wallets WHERE address = ?", address).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("wallet %s: %w", address, storage.ErrWalletNotFound)
+		}
+		return err
+	}
+	for i := range n {
+		if _, err = tx.ExecContext(ctx,
+			"INSERT INTO wallet_shards(address, shard) VALUES (?, ?)", address, i,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,16 +1,18 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/google/uuid"
-	"github.com/mattn/go-sqlite3"
-	_ "github.com/mattn/go-sqlite3"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/address"
 	"infotecsTest/internal/models"
 	"infotecsTest/internal/storage"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,89 +20,133 @@ import (
 // Содержит подключение к БД и подготовленные SQL-выражения.
 type Storage struct {
 	db                     *sql.DB
+	driver                 driver
 	stmtSelectWallet       *sql.Stmt
 	stmtInsertTransaction  *sql.Stmt
 	stmtSelectTransactions *sql.Stmt
+	observer               storage.Observer
+	hotMu                  sync.RWMutex
+	hot                    map[string]int // Число подбалансов горячих кошельков
+	shardCursor            atomic.Uint64  // Очередь зачислений на подбалансы
+	batcher                *batcher       // Групповая фиксация переводов, nil - отключена
+	busyRetries            int            // Повторы перевода при SQLITE_BUSY
+	retryBackoff           time.Duration  // Пауза перед первым повтором
 }
 
 // New инициализирует новое подключение к SQLite.
-// Создает таблицы и индексы при первом запуске.
-func New(storagePath string) (*Storage, error) {
+// Применяет миграции схемы, создает кошельки при первом запуске
+// и присваивает адреса с контрольной суммой кошелькам с адресами прежнего формата.
+func New(storagePath string, cfg config.SQLite) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	s, err := Open(storagePath, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	//Лучше использовать INTEGER с преобразованием в коде
-	//REAL не подходит для операций с деньгами
-
-	if _, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS wallets(
-		id INTEGER PRIMARY KEY,
-		address TEXT NOT NULL UNIQUE,
-		balance REAL DEFAULT 0.0 
-	);
-	CREATE INDEX IF NOT EXISTS idx_address ON wallets(address);
-
-	CREATE TABLE IF NOT EXISTS transactions(
-	    id INTEGER PRIMARY KEY,
-	    from_address TEXT NOT NULL,
-	    to_address TEXT NOT NULL,
-	    amount REAL NOT NULL,
-	    timestamp DATE DEFAULT CURRENT_DATE
-	);
-	CREATE INDEX IF NOT EXISTS idx_transactions ON transactions(from_address,to_address);
-	`); err != nil {
+	if _, err = s.Migrate(context.Background()); err != nil {
+		_ = s.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmtSelectWallet, err := db.Prepare("SELECT balance FROM wallets WHERE address = ?")
-	if err != nil {
+	if err = s.checkDB(); err != nil {
+		_ = s.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	stmtInsertTransaction, err := db.Prepare("INSERT INTO transactions(from_address, to_address, amount, timestamp) VALUES (?, ?, ?, ?)")
+
+	if err = s.upgradeAddresses(); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = s.prepare(); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cfg.GroupCommitSize > 1 {
+		s.batcher = newBatcher(s, cfg.GroupCommitSize, cfg.GroupCommitDelay)
+	}
+
+	return s, nil
+}
+
+// Open подключается к SQLite без изменения схемы и данных.
+// Используется служебными командами (миграции, наполнение, проверки);
+// для обработки запросов хранилище создается через New.
+func Open(storagePath string, cfg config.SQLite) (*Storage, error) {
+	const op = "storage.sqlite.Open"
+
+	drv, err := lookupDriver(cfg.Driver)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	stmtSelectTransactions, err := db.Prepare(`
-		SELECT from_address, to_address, amount, timestamp
-		FROM transactions
-		ORDER BY timestamp DESC 
-		LIMIT ?
-`)
+
+	db, err := sql.Open(drv.sqlName, drv.dsn(storagePath, cfg))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	if err = checkDB(db); err != nil {
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return &Storage{
-		db:                     db,
-		stmtSelectWallet:       stmtSelectWallet,
-		stmtInsertTransaction:  stmtInsertTransaction,
-		stmtSelectTransactions: stmtSelectTransactions,
+		db:           db,
+		driver:       drv,
+		observer:     storage.NopObserver{},
+		busyRetries:  cfg.BusyRetries,
+		retryBackoff: cfg.RetryBackoff,
 	}, nil
 }
 
+// prepare подготавливает SQL-выражения для обработки запросов.
+func (s *Storage) prepare() error {
+	const op = "storage.sqlite.prepare"
+
+	var err error
+	s.stmtSelectWallet, err = s.db.Prepare(selectWalletQuery)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = s.loadHotWallets(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.stmtInsertTransaction, err = s.db.Prepare("INSERT INTO transactions(from_address, to_address, amount, timestamp, reference) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.stmtSelectTransactions, err = s.db.Prepare(`
+		SELECT from_address, to_address, amount, timestamp
+		FROM transactions
+		ORDER BY timestamp DESC 
+		LIMIT ?
+`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 // checkD автоматически создает 10 кошельки при первом запуске.
-func checkDB(db *sql.DB) error {
+func (s *Storage) checkDB() error {
 	const op = "storage.sqlite.checkDB"
 
 	var count int
-	if err := db.QueryRow("SELECT count(*) FROM wallets").Scan(&count); err != nil {
+	if err := s.db.QueryRow("SELECT count(*) FROM wallets").Scan(&count); err != nil {
 		return fmt.Errorf("%s: check number of wallets: %w", op, err)
 	}
 
 	if count == 0 {
 		for count < 10 {
-			address := uuid.NewString()
 			balance := 100.0
-			if _, err := db.Exec("INSERT INTO wallets(address, balance) VALUES (?,?)", address, balance); err != nil {
-				if errors.Is(err, sqlite3.ErrConstraintUnique) {
+			if _, err := s.db.Exec("INSERT INTO wallets(address, balance) VALUES (?,?)", address.New(), balance); err != nil {
+				if errors.Is(s.translate(err), storage.ErrDuplicate) {
 					log.Println("collision")
 					continue
 				}
@@ -112,14 +158,27 @@ func checkDB(db *sql.DB) error {
 	return nil
 }
 
+// SetObserver устанавливает получателя событий хранилища для метрик.
+// Должен вызываться до начала обработки запросов.
+func (s *Storage) SetObserver(o storage.Observer) {
+	s.observer = o
+}
+
+// observe сообщает наблюдателю о длительности операции.
+// Используется в виде defer s.observe(op, time.Now()).
+func (s *Storage) observe(op string, start time.Time) {
+	s.observer.ObserveQuery(op, time.Since(start))
+}
+
 // GetWalletBalance возвращает баланс кошелька по адресу.
 // Возвращает ErrWalletNotFound если кошелек не существует.
 func (s *Storage) GetWalletBalance(address string) (models.Wallet, error) {
 	const op = "storage.sqlite.GetWalletBalance"
+	defer s.observe(op, time.Now())
 
 	var wallet models.Wallet
 
-	err := s.stmtSelectWallet.QueryRow(address).Scan(&wallet.Balance)
+	err := s.stmtSelectWallet.QueryRow(address).Scan(&wallet.Balance, &wallet.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return wallet, storage.ErrWalletNotFound
@@ -133,15 +192,63 @@ func (s *Storage) GetWalletBalance(address string) (models.Wallet, error) {
 
 // AddTransaction выполняет перевод между кошельками.
 // Проверяет: сумму перевода, разные адреса, достаточный баланс.
+// Перевод, не выполненный из-за блокировки базы другим соединением (SQLITE_BUSY),
+// повторяется до busy_retries раз с удваивающейся паузой.
 func (s *Storage) AddTransaction(from, to string, amount float64) error {
 	const op = "storage.sqlite.AddTransaction"
+	defer s.observe(op, time.Now())
 
-	if amount <= 0 {
-		return storage.ErrIncorrectAmount
+	return s.transfer(from, to, amount, 0, "")
+}
+
+// AddTransactionWithReference выполняет перевод и сохраняет ссылку на его источник в транзакции.
+// По ссылке после сбоя можно установить, был ли перевод выполнен.
+func (s *Storage) AddTransactionWithReference(from, to string, amount float64, reference string) error {
+	const op = "storage.sqlite.AddTransactionWithReference"
+	defer s.observe(op, time.Now())
+
+	return s.transfer(from, to, amount, 0, reference)
+}
+
+// AddTransactionIfVersion выполняет перевод, только если версия кошелька
+// отправителя равна version. Иначе возвращает *storage.VersionMismatchError.
+func (s *Storage) AddTransactionIfVersion(from, to string, amount float64, version int64) error {
+	const op = "storage.sqlite.AddTransactionIfVersion"
+	defer s.observe(op, time.Now())
+
+	return s.transfer(from, to, amount, version, "")
+}
+
+// transfer выполняет перевод с повторами при блокировке базы.
+// При включенной групповой фиксации перевод выполняется в составе группы.
+// Нулевая version отключает проверку версии отправителя, пустая reference - ссылку на источник.
+func (s *Storage) transfer(from, to string, amount float64, version int64, reference string) error {
+	var err error
+	if s.batcher != nil {
+		err = s.batcher.submit(from, to, amount, version, reference)
+	} else {
+		err = s.retryBusy(func() error { return s.addTransaction(from, to, amount, version, reference) })
 	}
-	if from == to {
-		return storage.ErrAddressesEqual
+	s.observer.ObserveTransfer(amount, err)
+	return err
+}
+
+// retryBusy выполняет fn и повторяет ее до busy_retries раз с удваивающейся паузой,
+// пока она завершается блокировкой базы другим соединением (SQLITE_BUSY).
+func (s *Storage) retryBusy(fn func() error) error {
+	err := s.translate(fn())
+	backoff := s.retryBackoff
+	for attempt := 0; attempt < s.busyRetries && errors.Is(err, storage.ErrBusy); attempt++ {
+		time.Sleep(backoff)
+		backoff *= 2
+		err = s.translate(fn())
 	}
+	return err
+}
+
+// addTransaction выполняет перевод в одной транзакции БД.
+func (s *Storage) addTransaction(from, to string, amount float64, version int64, reference string) error {
+	const op = "storage.sqlite.AddTransaction"
 
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -154,15 +261,40 @@ func (s *Storage) AddTransaction(from, to string, amount float64) error {
 		}
 	}(tx)
 
+	if err = s.transferTx(tx, from, to, amount, version, reference); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// transferTx проверяет перевод и применяет его в транзакции tx.
+// Все проверки выполняются до изменения данных: отклоненный перевод
+// (ошибка, для которой rejected возвращает true) не изменяет базу.
+func (s *Storage) transferTx(tx *sql.Tx, from, to string, amount float64, version int64, reference string) error {
+	const op = "storage.sqlite.AddTransaction"
+
+	if amount <= 0 {
+		return storage.ErrIncorrectAmount
+	}
+	if from == to {
+		return storage.ErrAddressesEqual
+	}
+
 	var fromBalance, toBalance float64
-	err = tx.Stmt(s.stmtSelectWallet).QueryRow(from).Scan(&fromBalance)
+	var fromVersion, toVersion int64
+	err := tx.Stmt(s.stmtSelectWallet).QueryRow(from).Scan(&fromBalance, &fromVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.ErrWalletNotFound
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	err = tx.Stmt(s.stmtSelectWallet).QueryRow(to).Scan(&toBalance)
+	err = tx.Stmt(s.stmtSelectWallet).QueryRow(to).Scan(&toBalance, &toVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.ErrWalletNotFound
@@ -170,8 +302,12 @@ func (s *Storage) AddTransaction(from, to string, amount float64) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if version != 0 && fromVersion != version {
+		return &storage.VersionMismatchError{Expected: version, Current: fromVersion}
+	}
+
 	if fromBalance < amount {
-		return storage.ErrInsufficientFunds
+		return &storage.InsufficientFundsError{Available: fromBalance, Requested: amount}
 	}
 
 	err = s.updateBalance(tx, from, -amount)
@@ -184,26 +320,45 @@ func (s *Storage) AddTransaction(from, to string, amount float64) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = tx.Stmt(s.stmtInsertTransaction).Exec(from, to, amount, time.Now())
+	_, err = tx.Stmt(s.stmtInsertTransaction).Exec(from, to, amount, time.Now().UTC(), reference)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
 	return nil
 }
 
-// updateBalance изменяет баланс кошелька атомарно в транзакции.
+// rejected сообщает, что перевод отклонен проверкой, а не ошибкой базы.
+func rejected(err error) bool {
+	return errors.Is(err, storage.ErrIncorrectAmount) ||
+		errors.Is(err, storage.ErrAddressesEqual) ||
+		errors.Is(err, storage.ErrWalletNotFound) ||
+		errors.Is(err, storage.ErrVersionMismatch) ||
+		errors.Is(err, storage.ErrInsufficientFunds)
+}
+
+// updateBalance изменяет баланс кошелька атомарно в транзакции и увеличивает его версию.
+// Зачисление на горячий кошелек выполняется на один из подбалансов,
+// перед списанием при нехватке основного баланса подбалансы переносятся в него.
 // Используется внутри метода AddTransaction
 func (s *Storage) updateBalance(tx *sql.Tx, address string, delta float64) error {
 	const op = "storage.sqlite.updateBalance"
 
+	if shards := s.shards(address); shards > 0 {
+		if delta > 0 {
+			if err := s.creditShard(tx, address, shards, delta); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			return nil
+		}
+		if err := consolidateIfShort(tx, address, -delta); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	_, err := tx.Exec(`
 		UPDATE wallets 
-		SET balance = balance + ? 
+		SET balance = balance + ?, version = version + 1
 		WHERE address = ?
 		`, delta, address)
 	if err != nil {
@@ -218,45 +373,146 @@ func (s *Storage) updateBalance(tx *sql.Tx, address string, delta float64) error
 // При N <= 0 возвращает ErrInvalidRequest.
 func (s *Storage) GetNTransactions(N int) ([]models.Transaction, error) {
 	const op = "storage.sqlite.GetNTransactions"
+	defer s.observe(op, time.Now())
 
-	if N <= 0 {
-		return nil, storage.ErrInvalidRequest
+	var txs []models.Transaction
+	err := s.recentTransactions(context.Background(), "", N, func(tx models.Transaction) error {
+		txs = append(txs, tx)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.ErrInvalidRequest) {
+		return txs, fmt.Errorf("%s: %w", op, err)
 	}
+	return txs, err
+}
 
-	var txs []models.Transaction
+// RecentTransactions передает в fn N последних транзакций от новых к старым:
+// при пустом userID - все транзакции, иначе - с участием кошельков пользователя.
+// Строки читаются по одной, поэтому N не влияет на потребление памяти.
+// Ошибка fn прекращает чтение и возвращается без изменений.
+// При N <= 0 возвращает ErrInvalidRequest.
+func (s *Storage) RecentTransactions(ctx context.Context, userID string, N int, fn func(models.Transaction) error) error {
+	const op = "storage.sqlite.RecentTransactions"
+	defer s.observe(op, time.Now())
+
+	return s.recentTransactions(ctx, userID, N, fn)
+}
+
+// recentTransactions выполняет RecentTransactions без учета в метриках:
+// длительность учитывается под именем вызвавшей операции.
+func (s *Storage) recentTransactions(ctx context.Context, userID string, N int, fn func(models.Transaction) error) error {
+	const op = "storage.sqlite.recentTransactions"
+
+	if N <= 0 {
+		return storage.ErrInvalidRequest
+	}
 
-	rows, err := s.stmtSelectTransactions.Query(N)
+	var rows *sql.Rows
+	var err error
+	if userID == "" {
+		rows, err = s.stmtSelectTransactions.QueryContext(ctx, N)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT from_address, to_address, amount, timestamp
+			FROM transactions
+			WHERE from_address IN (SELECT address FROM wallets WHERE user_id = ?1)
+			   OR to_address IN (SELECT address FROM wallets WHERE user_id = ?1)
+			ORDER BY timestamp DESC
+			LIMIT ?2
+		`, userID, N)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = rows.Close() }()
 
 	for rows.Next() {
 		var tx models.Transaction
 		var date time.Time
 		if err = rows.Scan(&tx.From, &tx.To, &tx.Amount, &date); err != nil {
-			return txs, fmt.Errorf("%s: %w", op, err)
+			return fmt.Errorf("%s: %w", op, err)
 		}
 		tx.Time = date.Format("15:04:05 02-01-2006")
-		txs = append(txs, tx)
+		if err = fn(tx); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
+}
+
+// TotalBalance возвращает суммарный баланс всех кошельков с учетом подбалансов.
+func (s *Storage) TotalBalance(ctx context.Context) (float64, error) {
+	const op = "storage.sqlite.TotalBalance"
+	defer s.observe(op, time.Now())
+
+	var total float64
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT (SELECT COALESCE(SUM(balance), 0) FROM wallets) + (SELECT COALESCE(SUM(balance), 0) FROM wallet_shards)
+	`).Scan(&total); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return total, nil
+}
 
-	return txs, nil
+// Ping проверяет доступность базы данных.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CheckSchema проверяет, что все миграции применены и таблицы схемы созданы.
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	const op = "storage.sqlite.CheckSchema"
+
+	version, err := s.schemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if version < latestVersion() {
+		return fmt.Errorf("%s: schema version %d, expected %d", op, version, latestVersion())
+	}
+
+	for _, table := range []string{"wallets", "transactions", "payout_jobs", "payout_rows", "ledger_checkpoint", "wallet_shards", "wallet_aliases", "wallet_handles", "wallet_labels", "wallet_metadata", "users", "user_credentials"} {
+		var name string
+		err := s.db.QueryRowContext(ctx,
+			"SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table,
+		).Scan(&name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s: table %s is missing", op, table)
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
 }
 
 // Close Закрывает все подготовленные выражения и соединение.
+// Перед этим дожидается выполнения принятых в группы переводов.
+// Хранилище, открытое через Open, не имеет подготовленных выражений.
 // Возвращает объединенные ошибки при их наличии.
 func (s *Storage) Close() error {
 	const op = "storage.sqlite.Close"
 
-	errs := make([]string, 0, 4)
-	if err := s.stmtSelectWallet.Close(); err != nil {
-		errs = append(errs, err.Error())
-	}
-	if err := s.stmtInsertTransaction.Close(); err != nil {
-		errs = append(errs, err.Error())
+	if s.batcher != nil {
+		s.batcher.close()
 	}
-	if err := s.stmtSelectTransactions.Close(); err != nil {
-		errs = append(errs, err.Error())
+
+	errs := make([]string, 0, 4)
+	for _, stmt := range []*sql.Stmt{s.stmtSelectWallet, s.stmtInsertTransaction, s.stmtSelectTransactions} {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
 	}
 	if err := s.db.Close(); err != nil {
 		errs = append(errs, err.Error())
@@ -0,0 +1,1081 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"infotecsTest/internal/config"
+	"infotecsTest/internal/lib/address"
+	"infotecsTest/internal/lib/apikey"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"infotecsTest/internal/storage/sqlite"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// openMigrated открывает новую базу во временном каталоге и применяет миграции.
+func openMigrated(t *testing.T) (*sqlite.Storage, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+	s, err := sqlite.Open(path, config.SQLite{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	_, err = s.Migrate(context.Background())
+	require.NoError(t, err)
+	return s, path
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.Open(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.Error(t, s.CheckSchema(ctx))
+
+	applied, err := s.Migrate(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+	require.NoError(t, s.CheckSchema(ctx))
+
+	applied, err = s.Migrate(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+}
+
+func TestMigrateLegacySchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// База, созданная до появления миграций
+	legacy, err := sqlite.Open(path, config.SQLite{})
+	require.NoError(t, err)
+	_, err = legacy.DB().Exec(`
+		CREATE TABLE wallets(id INTEGER PRIMARY KEY, address TEXT NOT NULL UNIQUE, balance REAL DEFAULT 0.0);
+		CREATE TABLE transactions(id INTEGER PRIMARY KEY, from_address TEXT NOT NULL, to_address TEXT NOT NULL,
+			amount REAL NOT NULL, timestamp DATE DEFAULT CURRENT_DATE);
+		INSERT INTO wallets(address, balance) VALUES ('a', 10);
+	`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	s, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.CheckSchema(ctx))
+	w, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 10.0, w.Balance)
+	require.Equal(t, int64(1), w.Version)
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	s, path := openMigrated(t)
+
+	err := s.Seed(ctx,
+		[]models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}},
+		[]models.Transaction{{From: "a", To: "b", Amount: 5, Time: "2024-05-01T10:00:00Z"}},
+	)
+	require.NoError(t, err)
+
+	// Повторное наполнение не изменяет базу
+	err = s.Seed(ctx, []models.Wallet{{Address: "c"}, {Address: "a"}}, nil)
+	require.ErrorContains(t, err, "wallet a already exists")
+
+	require.NoError(t, s.Close())
+	served, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = served.Close() }()
+
+	_, err = served.GetWalletBalance("c")
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+
+	txs, err := served.GetNTransactions(10)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, "10:00:00 01-05-2024", txs[0].Time)
+}
+
+func TestRecentTransactions(t *testing.T) {
+	ctx := context.Background()
+	s, path := openMigrated(t)
+	require.NoError(t, s.Seed(ctx,
+		[]models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}, {Address: "c"}},
+		[]models.Transaction{
+			{From: "a", To: "b", Amount: 1, Time: "2024-05-01T10:00:00Z"},
+			{From: "b", To: "c", Amount: 2, Time: "2024-05-01T11:00:00Z"},
+			{From: "a", To: "c", Amount: 3, Time: "2024-05-01T12:00:00Z"},
+		},
+	))
+	_, err := s.DB().Exec("UPDATE wallets SET user_id = 'u1' WHERE address = 'b'")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	served, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = served.Close() }()
+
+	collect := func(userID string, n int) ([]float64, error) {
+		var amounts []float64
+		err := served.RecentTransactions(ctx, userID, n, func(tx models.Transaction) error {
+			amounts = append(amounts, tx.Amount)
+			return nil
+		})
+		return amounts, err
+	}
+
+	amounts, err := collect("", 2)
+	require.NoError(t, err)
+	require.Equal(t, []float64{3, 2}, amounts)
+	amounts, err = collect("u1", 10)
+	require.NoError(t, err)
+	require.Equal(t, []float64{2, 1}, amounts)
+	_, err = collect("", 0)
+	require.ErrorIs(t, err, storage.ErrInvalidRequest)
+
+	// Ошибка получателя прекращает чтение и возвращается без изменений
+	errStop := errors.New("stop")
+	calls := 0
+	err = served.RecentTransactions(ctx, "", 10, func(models.Transaction) error {
+		calls++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Согласованные данные", func(t *testing.T) {
+		s, _ := openMigrated(t)
+		require.NoError(t, s.Seed(ctx,
+			[]models.Wallet{{Address: "a", Balance: 1}, {Address: "b", Balance: 2}},
+			[]models.Transaction{{From: "a", To: "b", Amount: 1}},
+		))
+
+		issues, err := s.CheckIntegrity(ctx)
+		require.NoError(t, err)
+		require.Empty(t, issues)
+	})
+
+	t.Run("Нарушения", func(t *testing.T) {
+		s, _ := openMigrated(t)
+		require.NoError(t, s.Seed(ctx,
+			[]models.Wallet{{Address: "a", Balance: -1}},
+			[]models.Transaction{{From: "a", To: "a", Amount: 0}, {From: "a", To: "ghost", Amount: 1}},
+		))
+
+		issues, err := s.CheckIntegrity(ctx)
+		require.NoError(t, err)
+
+		checks := make(map[string]int)
+		for _, issue := range issues {
+			checks[issue.Check]++
+		}
+		require.Equal(t, map[string]int{
+			"negative_balance": 1,
+			"invalid_amount":   1,
+			"self_transfer":    1,
+			"unknown_wallet":   1,
+		}, checks)
+	})
+
+	t.Run("Схема не создана", func(t *testing.T) {
+		s, err := sqlite.Open(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+		require.NoError(t, err)
+		defer func() { _ = s.Close() }()
+
+		issues, err := s.CheckIntegrity(ctx)
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		require.Equal(t, "schema", issues[0].Check)
+	})
+}
+
+func TestStatement(t *testing.T) {
+	ctx := context.Background()
+	s, path := openMigrated(t)
+
+	require.NoError(t, s.Seed(ctx,
+		[]models.Wallet{{Address: "a", Balance: 100}, {Address: "b", Balance: 100}},
+		[]models.Transaction{{From: "b", To: "a", Amount: 20, Time: "2024-04-30T23:59:59Z"}},
+	))
+	require.NoError(t, s.Close())
+
+	served, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = served.Close() }()
+
+	from := time.Now().Add(-time.Minute)
+	require.NoError(t, served.AddTransaction("a", "b", 30))
+	require.NoError(t, served.AddTransaction("b", "a", 5))
+	to := time.Now().Add(time.Minute)
+
+	opening, err := served.OpeningBalance(ctx, "a", from)
+	require.NoError(t, err)
+	require.Equal(t, 100.0, opening)
+
+	var got []models.Movement
+	err = served.Movements(ctx, "a", from, to, func(m models.Movement) error {
+		got = append(got, m)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "b", got[0].To)
+	require.Equal(t, 30.0, got[0].Amount)
+	require.Equal(t, "a", got[1].To)
+
+	// Перевод до начала периода учитывается во входящем остатке
+	opening, err = served.OpeningBalance(ctx, "a", time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 80.0, opening)
+
+	_, err = served.OpeningBalance(ctx, "ghost", from)
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+}
+
+func TestStatementIndexes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// Перевод, записанный до миграции со смещением часового пояса
+	s, err := sqlite.Open(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	_, err = s.DB().Exec(`
+		CREATE TABLE wallets(id INTEGER PRIMARY KEY, address TEXT NOT NULL UNIQUE, balance REAL DEFAULT 0.0);
+		CREATE TABLE transactions(id INTEGER PRIMARY KEY, from_address TEXT NOT NULL, to_address TEXT NOT NULL,
+			amount REAL NOT NULL, timestamp DATE DEFAULT CURRENT_DATE);
+		INSERT INTO wallets(address, balance) VALUES ('a', 100), ('b', 100);
+		INSERT INTO transactions(from_address, to_address, amount, timestamp)
+			VALUES ('b', 'a', 20, '2024-05-01 02:30:00+03:00');
+	`)
+	require.NoError(t, err)
+	_, err = s.Migrate(ctx)
+	require.NoError(t, err)
+
+	var stored string
+	require.NoError(t, s.DB().QueryRow("SELECT CAST(timestamp AS TEXT) FROM transactions").Scan(&stored))
+	require.Equal(t, "2024-04-30 23:30:00.000+00:00", stored)
+
+	// Перевод совершен до 1 мая по UTC
+	opening, err := s.OpeningBalance(ctx, "a", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 100.0, opening)
+	opening, err = s.OpeningBalance(ctx, "a", time.Date(2024, 5, 1, 2, 0, 0, 0, time.FixedZone("MSK", 3*60*60)))
+	require.NoError(t, err)
+	require.Equal(t, 80.0, opening)
+
+	rows, err := s.DB().Query(`EXPLAIN QUERY PLAN
+		SELECT id FROM transactions WHERE (from_address = ?1 OR to_address = ?1) AND timestamp >= ?2 AND timestamp < ?3`,
+		"a", time.Now(), time.Now())
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+		plan = append(plan, detail)
+	}
+	require.NoError(t, rows.Err())
+	joined := strings.Join(plan, "\n")
+	require.Contains(t, joined, "idx_transactions_from_time")
+	require.Contains(t, joined, "idx_transactions_to_time")
+}
+
+func TestPayoutJob(t *testing.T) {
+	ctx := context.Background()
+	s, _ := openMigrated(t)
+
+	_, err := s.PayoutJob(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrPayoutNotFound)
+	require.ErrorIs(t, s.PayoutRows(ctx, "missing", func(models.PayoutRow) error { return nil }), storage.ErrPayoutNotFound)
+
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.CreatePayoutJob(ctx,
+		models.PayoutJob{ID: "job1", From: "a", CreatedAt: created},
+		[]models.PayoutRow{{Line: 2, To: "b", Amount: 10, Reference: "salary"}, {Line: 3, To: "c", Amount: 5}},
+	))
+
+	job, err := s.PayoutJob(ctx, "job1")
+	require.NoError(t, err)
+	require.Equal(t, models.PayoutJobPending, job.Status)
+	require.Equal(t, "a", job.From)
+	require.True(t, created.Equal(job.CreatedAt))
+	require.Equal(t, 2, job.Total)
+	require.Equal(t, 2, job.Pending)
+
+	row, ok, err := s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, models.PayoutRow{JobID: "job1", From: "a", Line: 2, To: "b", Amount: 10, Reference: "salary",
+		Status: models.PayoutRowProcessing, TransferReference: "payout:job1:2"}, row)
+	require.NoError(t, s.FinishPayoutRow(ctx, "job1", 2, models.PayoutRowSucceeded, ""))
+
+	job, err = s.PayoutJob(ctx, "job1")
+	require.NoError(t, err)
+	require.Equal(t, models.PayoutJobRunning, job.Status)
+	require.Nil(t, job.FinishedAt)
+
+	_, _, err = s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	succeeded, unknown, err := s.RecoverPayoutRows(ctx, "outcome_unknown")
+	require.NoError(t, err)
+	require.Equal(t, 0, succeeded)
+	require.Equal(t, 1, unknown)
+
+	_, ok, err = s.NextPayoutRow(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	job, err = s.PayoutJob(ctx, "job1")
+	require.NoError(t, err)
+	require.Equal(t, models.PayoutJobCompleted, job.Status)
+	require.Equal(t, 1, job.Succeeded)
+	require.Equal(t, 1, job.Unknown)
+	require.NotNil(t, job.FinishedAt)
+}
+
+func TestWalletVersion(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	require.NoError(t, s.Seed(ctx, []models.Wallet{{Address: "a", Balance: 50}, {Address: "b"}}, nil))
+
+	a, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), a.Version)
+
+	// Версия увеличивается у обоих участников перевода
+	require.NoError(t, s.AddTransactionIfVersion("a", "b", 10, a.Version))
+	a, err = s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "a", Balance: 40, Version: 2}, a)
+	b, err := s.GetWalletBalance("b")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), b.Version)
+
+	// Перевод по устаревшей версии отклоняется без изменения баланса
+	err = s.AddTransactionIfVersion("a", "b", 10, 1)
+	var mismatch *storage.VersionMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, &storage.VersionMismatchError{Expected: 1, Current: 2}, mismatch)
+
+	a, err = s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, 40.0, a.Balance)
+
+	// Версия получателя не проверяется
+	require.NoError(t, s.AddTransactionIfVersion("b", "a", 5, b.Version))
+}
+
+// concurrentConfig - настройки SQLite для параллельных переводов, как в config/local.yaml.
+var concurrentConfig = config.SQLite{
+	JournalMode:  "wal",
+	Synchronous:  "normal",
+	BusyTimeout:  5 * time.Second,
+	TxLock:       "immediate",
+	MaxOpenConns: 8,
+	MaxIdleConns: 8,
+	BusyRetries:  3,
+	RetryBackoff: time.Millisecond,
+}
+
+func TestAddTransactionConcurrent(t *testing.T) {
+	const (
+		workers   = 16
+		transfers = 100
+		funds     = 1500 // Меньше числа переводов: часть должна быть отклонена
+	)
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), concurrentConfig)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	wallets := []models.Wallet{{Address: "a", Balance: funds}}
+	for i := range workers {
+		wallets = append(wallets, models.Wallet{Address: fmt.Sprintf("w%d", i)})
+	}
+	require.NoError(t, s.Seed(ctx, wallets, nil))
+	before, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	var succeeded, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range transfers {
+				err := s.AddTransaction("a", fmt.Sprintf("w%d", i), 1)
+				switch {
+				case err == nil:
+					succeeded.Add(1)
+				case errors.Is(err, storage.ErrInsufficientFunds):
+					rejected.Add(1)
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Каждый успешный перевод учтен ровно один раз, списаний сверх баланса нет
+	require.EqualValues(t, funds, succeeded.Load())
+	require.EqualValues(t, workers*transfers-funds, rejected.Load())
+
+	a, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Zero(t, a.Balance)
+
+	var received float64
+	for i := range workers {
+		w, err := s.GetWalletBalance(fmt.Sprintf("w%d", i))
+		require.NoError(t, err)
+		received += w.Balance
+	}
+	require.EqualValues(t, funds, received)
+
+	after, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	txs, err := s.GetNTransactions(workers * transfers)
+	require.NoError(t, err)
+	require.Len(t, txs, funds)
+}
+
+func TestGroupCommit(t *testing.T) {
+	const (
+		workers   = 16
+		transfers = 100
+		funds     = 1500 // Меньше числа переводов: часть должна быть отклонена
+	)
+
+	cfg := concurrentConfig
+	cfg.GroupCommitSize = 32
+	cfg.GroupCommitDelay = time.Millisecond
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), cfg)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	wallets := []models.Wallet{{Address: "a", Balance: funds}}
+	for i := range workers {
+		wallets = append(wallets, models.Wallet{Address: fmt.Sprintf("w%d", i)})
+	}
+	require.NoError(t, s.Seed(ctx, wallets, nil))
+	before, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	// Отклоненные переводы попадают в те же группы, что и успешные,
+	// и получают собственные ошибки, не влияя на остальные переводы группы
+	var succeeded, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			self := fmt.Sprintf("w%d", i)
+			for range transfers {
+				err := s.AddTransaction("a", self, 1)
+				switch {
+				case err == nil:
+					succeeded.Add(1)
+				case errors.Is(err, storage.ErrInsufficientFunds):
+					rejected.Add(1)
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+
+				if err = s.AddTransaction(self, "ghost", 1); !errors.Is(err, storage.ErrWalletNotFound) {
+					t.Errorf("expected wallet not found, got %v", err)
+				}
+				var mismatch *storage.VersionMismatchError
+				if err = s.AddTransactionIfVersion(self, "a", 1, 1e9); !errors.As(err, &mismatch) {
+					t.Errorf("expected version mismatch, got %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, funds, succeeded.Load())
+	require.EqualValues(t, workers*transfers-funds, rejected.Load())
+
+	// Версия увеличивается на каждый примененный перевод, как без группировки
+	a, err := s.GetWalletBalance("a")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "a", Balance: 0, Version: 1 + funds}, a)
+
+	after, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	txs, err := s.GetNTransactions(workers * transfers)
+	require.NoError(t, err)
+	require.Len(t, txs, funds)
+}
+
+func TestHotWallet(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.Seed(ctx, []models.Wallet{{Address: "fee", Balance: 10}, {Address: "a", Balance: 100}}, nil))
+	before, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, s.SetHotWallets(ctx, []config.HotWallet{{Address: "ghost", Shards: 4}}), storage.ErrWalletNotFound)
+	require.NoError(t, s.SetHotWallets(ctx, []config.HotWallet{{Address: "fee", Shards: 4}}))
+
+	// Зачисления распределяются по подбалансам по очереди, основной баланс не изменяется
+	for range 8 {
+		require.NoError(t, s.AddTransaction("a", "fee", 1))
+	}
+	var main float64
+	require.NoError(t, s.DB().QueryRow("SELECT balance FROM wallets WHERE address = 'fee'").Scan(&main))
+	require.Equal(t, 10.0, main)
+	rows, err := s.DB().Query("SELECT balance FROM wallet_shards WHERE address = 'fee' ORDER BY shard")
+	require.NoError(t, err)
+	var shards []float64
+	for rows.Next() {
+		var b float64
+		require.NoError(t, rows.Scan(&b))
+		shards = append(shards, b)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []float64{2, 2, 2, 2}, shards)
+
+	fee, err := s.GetWalletBalance("fee")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "fee", Balance: 18, Version: 9}, fee)
+
+	// Списание сверх основного баланса объединяет подбалансы
+	require.ErrorIs(t, s.AddTransaction("fee", "a", 19), storage.ErrInsufficientFunds)
+	require.NoError(t, s.AddTransactionIfVersion("fee", "a", 15, fee.Version))
+	fee, err = s.GetWalletBalance("fee")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "fee", Balance: 3, Version: 10}, fee)
+
+	opening, err := s.OpeningBalance(ctx, "fee", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 10.0, opening)
+
+	// Изменение числа подбалансов и снятие отметки сохраняют баланс и версию
+	require.NoError(t, s.AddTransaction("a", "fee", 1))
+	require.NoError(t, s.SetHotWallets(ctx, []config.HotWallet{{Address: "fee", Shards: 2}}))
+	require.NoError(t, s.AddTransaction("a", "fee", 1))
+	require.NoError(t, s.SetHotWallets(ctx, nil))
+	fee, err = s.GetWalletBalance("fee")
+	require.NoError(t, err)
+	require.Equal(t, models.Wallet{Address: "fee", Balance: 5, Version: 12}, fee)
+
+	var count int
+	require.NoError(t, s.DB().QueryRow("SELECT COUNT(*) FROM wallet_shards").Scan(&count))
+	require.Zero(t, count)
+
+	after, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	issues, err := s.CheckIntegrity(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func TestApplyTransfersOpen(t *testing.T) {
+	ctx := context.Background()
+
+	// Перенос журнала служебной командой: хранилище открыто без New и не наполняется кошельками
+	s, _ := openMigrated(t)
+	require.NoError(t, s.Seed(ctx, []models.Wallet{{Address: "a", Balance: 100}, {Address: "b"}}, nil))
+	_, err := s.DB().Exec("INSERT INTO wallet_shards(address, shard, balance, version) VALUES ('b', 0, 5, 1)")
+	require.NoError(t, err)
+
+	require.NoError(t, s.ApplyTransfers(ctx, []storage.Transfer{
+		{Seq: 1, From: "a", To: "b", Amount: 10, Time: time.Now()},
+		{Seq: 2, From: "b", To: "a", Amount: 3, Time: time.Now()},
+	}))
+
+	wallets, err := s.Wallets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []models.Wallet{{Address: "a", Balance: 93, Version: 3}, {Address: "b", Balance: 12, Version: 4}}, wallets)
+
+	var shard float64
+	require.NoError(t, s.DB().QueryRow("SELECT balance FROM wallet_shards WHERE address = 'b'").Scan(&shard))
+	require.Zero(t, shard)
+	checkpoint, err := s.LedgerCheckpoint(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), checkpoint)
+}
+
+func TestHotWalletConcurrent(t *testing.T) {
+	const (
+		workers   = 16
+		transfers = 50
+	)
+
+	cfg := concurrentConfig
+	cfg.GroupCommitSize = 16
+	cfg.GroupCommitDelay = time.Millisecond
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), cfg)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	wallets := []models.Wallet{{Address: "fee"}}
+	for i := range workers {
+		wallets = append(wallets, models.Wallet{Address: fmt.Sprintf("w%d", i), Balance: transfers})
+	}
+	require.NoError(t, s.Seed(ctx, wallets, nil))
+	require.NoError(t, s.SetHotWallets(ctx, []config.HotWallet{{Address: "fee", Shards: 8}}))
+	before, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+
+	// Зачисления на горячий кошелек идут вперемешку со списаниями, объединяющими подбалансы
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			self := fmt.Sprintf("w%d", i)
+			for j := range transfers {
+				if err := s.AddTransaction(self, "fee", 1); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if j%5 == 0 {
+					err := s.AddTransaction("fee", self, 1)
+					if err != nil && !errors.Is(err, storage.ErrInsufficientFunds) {
+						t.Errorf("unexpected error: %v", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	after, err := s.TotalBalance(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	fee, err := s.GetWalletBalance("fee")
+	require.NoError(t, err)
+	txs, err := s.GetNTransactions(2 * workers * transfers)
+	require.NoError(t, err)
+	require.EqualValues(t, 1+len(txs), fee.Version)
+
+	issues, err := s.CheckIntegrity(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func TestUpgradeAddresses(t *testing.T) {
+	const (
+		legacyA = "1077f2e5-cfd1-4a02-86cc-96fc7337d18c"
+		legacyB = "6c1f1f9e-3b5e-4d2a-9f4e-2f6f0f3f7c11"
+	)
+
+	ctx := context.Background()
+	s, path := openMigrated(t)
+	require.NoError(t, s.Seed(ctx,
+		[]models.Wallet{{Address: legacyA, Balance: 50}, {Address: legacyB}, {Address: "named", Balance: 1}},
+		[]models.Transaction{{From: legacyA, To: legacyB, Amount: 5, Time: "2024-05-01T10:00:00Z"}},
+	))
+	require.NoError(t, s.Close())
+
+	upgraded, err := sqlite.New(path, config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = upgraded.Close() }()
+
+	a, err := upgraded.ResolveAddress(legacyA)
+	require.NoError(t, err)
+	_, err = address.Parse(a)
+	require.NoError(t, err)
+	b, err := upgraded.ResolveAddress(legacyB)
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+
+	// Прежний адрес больше не хранится, новый принимается в любом регистре
+	_, err = upgraded.GetWalletBalance(legacyA)
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+	w, err := upgraded.GetWalletBalance(a)
+	require.NoError(t, err)
+	require.Equal(t, 50.0, w.Balance)
+	resolved, err := upgraded.ResolveAddress(strings.ToUpper(a))
+	require.NoError(t, err)
+	require.Equal(t, a, resolved)
+
+	txs, err := upgraded.GetNTransactions(10)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, a, txs[0].From)
+	require.Equal(t, b, txs[0].To)
+
+	// Переводы из журнала движка, записанные до перехода, применяются к новым адресам
+	require.NoError(t, upgraded.ApplyTransfers(ctx, []storage.Transfer{{Seq: 1, From: legacyA, To: legacyB, Amount: 10, Time: time.Now()}}))
+	w, err = upgraded.GetWalletBalance(b)
+	require.NoError(t, err)
+	require.Equal(t, 10.0, w.Balance)
+
+	// Горячий кошелек может быть указан в конфигурации прежним адресом
+	require.NoError(t, upgraded.SetHotWallets(ctx, []config.HotWallet{{Address: legacyB, Shards: 2}}))
+	var shards int
+	require.NoError(t, upgraded.DB().QueryRow("SELECT COUNT(*) FROM wallet_shards WHERE address = ?", b).Scan(&shards))
+	require.Equal(t, 2, shards)
+
+	typo := a[:len(a)-1] + "q"
+	if strings.HasSuffix(a, "q") {
+		typo = a[:len(a)-1] + "p"
+	}
+	cases := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "Неизвестный прежний адрес", input: "00000000-0000-0000-0000-000000000000", expected: "00000000-0000-0000-0000-000000000000"},
+		{name: "Опечатка", input: typo, expectedErr: storage.ErrMalformedAddress},
+		{name: "Произвольная строка", input: "named", expectedErr: storage.ErrMalformedAddress},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := upgraded.ResolveAddress(tc.input)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+
+	issues, err := upgraded.CheckIntegrity(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func TestHandles(t *testing.T) {
+	ctx := context.Background()
+	a, b := address.New(), address.New()
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	require.NoError(t, s.Seed(ctx, []models.Wallet{{Address: a}, {Address: b}}, nil))
+
+	h, err := s.ClaimHandle(ctx, a, "@Alice")
+	require.NoError(t, err)
+	require.Equal(t, models.Handle{Handle: "alice", Address: a}, h)
+
+	// Повторное присвоение своего имени ничего не меняет, чужое имя занято в любом регистре
+	_, err = s.ClaimHandle(ctx, a, "alice")
+	require.NoError(t, err)
+	_, err = s.ClaimHandle(ctx, b, "ALICE")
+	require.ErrorIs(t, err, storage.ErrHandleTaken)
+	_, err = s.ClaimHandle(ctx, address.New(), "carol")
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+	_, err = s.ClaimHandle(ctx, b, "1x")
+	require.ErrorIs(t, err, storage.ErrInvalidHandle)
+
+	resolved, err := s.ResolveAddress("@ALICE")
+	require.NoError(t, err)
+	require.Equal(t, a, resolved)
+	_, err = s.ResolveAddress("@bob")
+	require.ErrorIs(t, err, storage.ErrHandleNotFound)
+	_, err = s.ResolveAddress("@1")
+	require.ErrorIs(t, err, storage.ErrInvalidHandle)
+
+	// Новое имя освобождает прежнее
+	_, err = s.ClaimHandle(ctx, a, "alicia")
+	require.NoError(t, err)
+	h, err = s.ClaimHandle(ctx, b, "alice")
+	require.NoError(t, err)
+	require.Equal(t, b, h.Address)
+
+	h, err = s.ReleaseHandle(ctx, a)
+	require.NoError(t, err)
+	require.Equal(t, models.Handle{Handle: "alicia", Address: a}, h)
+	_, err = s.ReleaseHandle(ctx, a)
+	require.ErrorIs(t, err, storage.ErrHandleNotFound)
+	_, err = s.ResolveHandle(ctx, "alicia")
+	require.ErrorIs(t, err, storage.ErrHandleNotFound)
+
+	issues, err := s.CheckIntegrity(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
+
+func TestWalletMetadata(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	// Сведения задаются при создании кошелька
+	require.NoError(t, s.Seed(ctx, []models.Wallet{
+		{Address: "a", Owner: "cust-1", Labels: []string{"VIP", "retail", "vip"}, Metadata: map[string]string{"region": "eu"}},
+		{Address: "b", Owner: "cust-1", Labels: []string{"retail"}},
+		{Address: "c", Owner: "cust-2"},
+		{Address: "d"},
+	}, nil))
+	err = s.Seed(ctx, []models.Wallet{{Address: "e", Labels: []string{" "}}}, nil)
+	require.ErrorIs(t, err, storage.ErrInvalidMetadata)
+
+	md, err := s.WalletMetadata(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, models.WalletMetadata{Owner: "cust-1", Labels: []string{"retail", "vip"}, Metadata: map[string]string{"region": "eu"}}, md)
+	md, err = s.WalletMetadata(ctx, "d")
+	require.NoError(t, err)
+	require.Equal(t, models.WalletMetadata{}, md)
+	_, err = s.WalletMetadata(ctx, "ghost")
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+
+	// Сведения заменяются целиком
+	md, err = s.SetWalletMetadata(ctx, "c", models.WalletMetadata{Owner: " cust-3 ", Labels: []string{"Retail"}})
+	require.NoError(t, err)
+	require.Equal(t, models.WalletMetadata{Owner: "cust-3", Labels: []string{"retail"}}, md)
+	_, err = s.SetWalletMetadata(ctx, "ghost", models.WalletMetadata{})
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+	_, err = s.SetWalletMetadata(ctx, "c", models.WalletMetadata{Metadata: map[string]string{"": "x"}})
+	require.ErrorIs(t, err, storage.ErrInvalidMetadata)
+	require.Equal(t, map[string]any{"reason": "metadata key is empty"}, storage.Details(err))
+
+	addresses := func(wallets []models.Wallet) []string {
+		var res []string
+		for _, w := range wallets {
+			res = append(res, w.Address)
+		}
+		return res
+	}
+	cases := []struct {
+		name     string
+		filter   models.WalletFilter
+		expected []string
+	}{
+		{name: "По метке", filter: models.WalletFilter{Label: "RETAIL"}, expected: []string{"a", "b", "c"}},
+		{name: "По владельцу", filter: models.WalletFilter{Owner: "cust-1"}, expected: []string{"a", "b"}},
+		{name: "По метке и владельцу", filter: models.WalletFilter{Label: "vip", Owner: "cust-1"}, expected: []string{"a"}},
+		{name: "С ограничением числа", filter: models.WalletFilter{Label: "retail", Limit: 2}, expected: []string{"a", "b"}},
+		{name: "Ничего не найдено", filter: models.WalletFilter{Owner: "cust-2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			wallets, err := s.FindWallets(ctx, tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, addresses(wallets))
+		})
+	}
+
+	wallets, err := s.FindWallets(ctx, models.WalletFilter{Label: "vip"})
+	require.NoError(t, err)
+	require.Equal(t, []models.Wallet{{
+		Address: "a", Owner: "cust-1", Labels: []string{"retail", "vip"}, Metadata: map[string]string{"region": "eu"},
+	}}, wallets)
+}
+
+// BenchmarkAddTransaction сравнивает пропускную способность параллельных переводов
+// между случайными кошельками без группировки и с групповой фиксацией.
+func BenchmarkAddTransaction(b *testing.B) {
+	const (
+		wallets     = 1000
+		parallelism = 64 // Одновременных запросов на процессор, как у нагруженного HTTP-сервера
+	)
+
+	seed := make([]models.Wallet, wallets)
+	for i := range seed {
+		seed[i] = models.Wallet{Address: fmt.Sprintf("w%04d", i), Balance: 1e12}
+	}
+
+	for _, size := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("group_commit_size=%d", size), func(b *testing.B) {
+			cfg := concurrentConfig
+			cfg.GroupCommitSize = size
+			cfg.GroupCommitDelay = time.Millisecond
+			s, err := sqlite.New(filepath.Join(b.TempDir(), "storage.db"), cfg)
+			require.NoError(b, err)
+			defer func() { _ = s.Close() }()
+			require.NoError(b, s.Seed(context.Background(), seed, nil))
+
+			var n atomic.Uint64
+			b.SetParallelism(parallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := n.Add(1)
+					from, to := seed[i%wallets].Address, seed[(i*7+1)%wallets].Address
+					if from == to {
+						continue
+					}
+					if err := s.AddTransaction(from, to, 1); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+			b.StopTimer()
+		})
+	}
+}
+
+func TestDrivers(t *testing.T) {
+	ctx := context.Background()
+
+	for _, writer := range sqlite.Drivers() {
+		for _, reader := range sqlite.Drivers() {
+			t.Run(writer+" -> "+reader, func(t *testing.T) {
+				dir := t.TempDir()
+				s, err := sqlite.New(filepath.Join(dir, "storage.db"), config.SQLite{Driver: writer, JournalMode: "wal"})
+				require.NoError(t, err)
+				defer func() { _ = s.Close() }()
+
+				require.NoError(t, s.Seed(ctx,
+					[]models.Wallet{{Address: "a", Balance: 100}, {Address: "b"}},
+					[]models.Transaction{{From: "b", To: "a", Amount: 1, Time: "2024-04-30T23:59:59Z"}},
+				))
+				require.NoError(t, s.AddTransaction("a", "b", 30))
+
+				// Ошибки драйвера переводятся в ошибки пакета storage
+				err = s.Seed(ctx, []models.Wallet{{Address: "a"}}, nil)
+				require.ErrorContains(t, err, "wallet a already exists")
+
+				// Снимок, созданный одним драйвером, читается другим
+				snapshot := filepath.Join(dir, "snapshot.db")
+				require.NoError(t, s.Backup(ctx, snapshot))
+
+				restored, err := sqlite.New(snapshot, config.SQLite{Driver: reader})
+				require.NoError(t, err)
+				defer func() { _ = restored.Close() }()
+
+				w, err := restored.GetWalletBalance("b")
+				require.NoError(t, err)
+				require.Equal(t, 30.0, w.Balance)
+
+				opening, err := restored.OpeningBalance(ctx, "a", time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC))
+				require.NoError(t, err)
+				require.Equal(t, 99.0, opening)
+			})
+		}
+	}
+
+	_, err := sqlite.Open(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{Driver: "unknown"})
+	require.ErrorContains(t, err, `sqlite driver "unknown" is not available`)
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), config.SQLite{})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	require.NoError(t, s.Seed(ctx, []models.Wallet{
+		{Address: "a", Balance: 10, Labels: []string{"retail"}},
+		{Address: "b", Balance: 20, Labels: []string{"retail"}},
+		{Address: "c", Balance: 30, Labels: []string{"retail"}},
+	}, nil))
+
+	_, err = s.CreateUser(ctx, models.UserRequest{Name: " "})
+	require.ErrorIs(t, err, storage.ErrInvalidUser)
+	alice, err := s.CreateUser(ctx, models.UserRequest{Name: " Alice ", Email: "alice@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "Alice", alice.Name)
+	bob, err := s.CreateUser(ctx, models.UserRequest{Name: "Bob"})
+	require.NoError(t, err)
+
+	user, err := s.User(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Equal(t, alice, user)
+	_, err = s.User(ctx, "ghost")
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	// Ключ находит пользователя по хешу, отозванный ключ недействителен
+	cred, err := s.IssueKey(ctx, alice.ID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(cred.Key, apikey.Prefix))
+	user, err = s.UserByKey(ctx, apikey.Hash(cred.Key))
+	require.NoError(t, err)
+	require.Equal(t, alice, user)
+	_, err = s.UserByKey(ctx, cred.Key)
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+	_, err = s.IssueKey(ctx, "ghost")
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	n, err := s.RevokeKeys(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = s.UserByKey(ctx, apikey.Hash(cred.Key))
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	// Кошелек принадлежит не более чем одному пользователю
+	require.NoError(t, s.AssignWallet(ctx, alice.ID, "b"))
+	require.NoError(t, s.AssignWallet(ctx, alice.ID, "a"))
+	require.NoError(t, s.AssignWallet(ctx, alice.ID, "a"))
+	require.ErrorIs(t, s.AssignWallet(ctx, bob.ID, "a"), storage.ErrWalletOwned)
+	require.ErrorIs(t, s.AssignWallet(ctx, bob.ID, "ghost"), storage.ErrWalletNotFound)
+	require.ErrorIs(t, s.AssignWallet(ctx, "ghost", "c"), storage.ErrUserNotFound)
+
+	addresses, err := s.UserWallets(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, addresses)
+	addresses, err = s.UserWallets(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Empty(t, addresses)
+	_, err = s.UserWallets(ctx, "ghost")
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	owner, err := s.WalletUser(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, owner)
+	owner, err = s.WalletUser(ctx, "c")
+	require.NoError(t, err)
+	require.Empty(t, owner)
+	_, err = s.WalletUser(ctx, "ghost")
+	require.ErrorIs(t, err, storage.ErrWalletNotFound)
+
+	// Поиск ограничивается кошельками пользователя
+	wallets, err := s.FindWallets(ctx, models.WalletFilter{Label: "retail", UserID: alice.ID})
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+
+	require.ErrorIs(t, s.UnassignWallet(ctx, bob.ID, "a"), storage.ErrWalletNotFound)
+	require.ErrorIs(t, s.UnassignWallet(ctx, "ghost", "a"), storage.ErrUserNotFound)
+	require.NoError(t, s.UnassignWallet(ctx, alice.ID, "a"))
+	require.NoError(t, s.AssignWallet(ctx, bob.ID, "a"))
+
+	// История транзакций ограничивается кошельками пользователя
+	require.NoError(t, s.AddTransaction("c", "b", 1))
+	require.NoError(t, s.AddTransaction("c", "a", 2))
+	require.NoError(t, s.AddTransaction("a", "c", 3))
+	txs, err := s.UserTransactions(ctx, alice.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, "b", txs[0].To)
+	txs, err = s.UserTransactions(ctx, bob.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	txs, err = s.UserTransactions(ctx, bob.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	txs, err = s.UserTransactions(ctx, "ghost", 10)
+	require.NoError(t, err)
+	require.Empty(t, txs)
+	_, err = s.UserTransactions(ctx, alice.ID, 0)
+	require.ErrorIs(t, err, storage.ErrInvalidRequest)
+
+	issues, err := s.CheckIntegrity(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"time"
+)
+
+// OpeningBalance возвращает баланс кошелька на момент at.
+// Вычисляется из текущего баланса за вычетом переводов, совершенных начиная с at.
+// Возвращает ErrWalletNotFound если кошелек не существует.
+func (s *Storage) OpeningBalance(ctx context.Context, address string, at time.Time) (float64, error) {
+	const op = "storage.sqlite.OpeningBalance"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var balance float64
+	var version int64
+	if err = tx.QueryRowContext(ctx, selectWalletQuery, address).Scan(&balance, &version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrWalletNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var net float64
+	if err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN to_address = ?1 THEN amount ELSE -amount END), 0)
+		FROM transactions
+		WHERE (from_address = ?1 OR to_address = ?1)
+		  AND timestamp >= ?2
+	`, address, at.UTC()).Scan(&net); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return balance - net, nil
+}
+
+// Movements передает в fn переводы кошелька за период [from, to) в хронологическом порядке.
+// Строки читаются по одной, поэтому объем периода не влияет на потребление памяти.
+// Ошибка fn прекращает чтение и возвращается без изменений.
+func (s *Storage) Movements(ctx context.Context, address string, from, to time.Time, fn func(models.Movement) error) error {
+	const op = "storage.sqlite.Movements"
+	defer s.observe(op, time.Now())
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, from_address, to_address, amount, timestamp
+		FROM transactions
+		WHERE (from_address = ?1 OR to_address = ?1)
+		  AND timestamp >= ?2
+		  AND timestamp < ?3
+		ORDER BY timestamp, id
+	`, address, from.UTC(), to.UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var m models.Movement
+		if err = rows.Scan(&m.ID, &m.From, &m.To, &m.Amount, &m.Time); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
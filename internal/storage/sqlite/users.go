@@ -0,0 +1,280 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"infotecsTest/internal/lib/apikey"
+	"infotecsTest/internal/models"
+	"infotecsTest/internal/storage"
+	"strings"
+	"time"
+)
+
+// Пользователи хранятся в users, хеши их ключей API - в user_credentials.
+// Кошелек принадлежит пользователю, указанному в wallets.user_id; пустое значение -
+// кошелек без владельца, операции с ним доступны только при выключенной аутентификации.
+
+// Ограничения профиля пользователя
+const (
+	maxUserNameLen  = 128
+	maxUserEmailLen = 254
+)
+
+// CreateUser регистрирует пользователя и возвращает его профиль.
+// Возвращает ErrInvalidUser для пустого или слишком длинного имени и слишком длинного адреса почты.
+func (s *Storage) CreateUser(ctx context.Context, req models.UserRequest) (models.User, error) {
+	const op = "storage.sqlite.CreateUser"
+	defer s.observe(op, time.Now())
+
+	user := models.User{
+		ID:        uuid.NewString(),
+		Name:      strings.TrimSpace(req.Name),
+		Email:     strings.TrimSpace(req.Email),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if user.Name == "" || len(user.Name) > maxUserNameLen || len(user.Email) > maxUserEmailLen {
+		return models.User{}, storage.ErrInvalidUser
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO users(id, name, email, created_at) VALUES (?, ?, ?, ?)",
+		user.ID, user.Name, user.Email, user.CreatedAt,
+	); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return user, nil
+}
+
+// User возвращает профиль пользователя.
+// Возвращает ErrUserNotFound для неизвестного пользователя.
+func (s *Storage) User(ctx context.Context, id string) (models.User, error) {
+	const op = "storage.sqlite.User"
+	defer s.observe(op, time.Now())
+
+	user, err := scanUser(s.db.QueryRowContext(ctx,
+		"SELECT id, name, email, created_at FROM users WHERE id = ?", id,
+	))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, err
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// UserByKey возвращает пользователя по хешу ключа API.
+// Возвращает ErrUserNotFound, если ключ не выпускался или отозван.
+func (s *Storage) UserByKey(ctx context.Context, keyHash string) (models.User, error) {
+	const op = "storage.sqlite.UserByKey"
+	defer s.observe(op, time.Now())
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, `
+	SELECT u.id, u.name, u.email, u.created_at FROM user_credentials c
+	JOIN users u ON u.id = c.user_id
+	WHERE c.key_hash = ?`, keyHash,
+	))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, err
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// IssueKey выпускает пользователю новый ключ API. Прежние ключи остаются действительными.
+// Ключ возвращается только здесь: в хранилище сохраняется его хеш.
+// Возвращает ErrUserNotFound для неизвестного пользователя.
+func (s *Storage) IssueKey(ctx context.Context, userID string) (models.Credential, error) {
+	const op = "storage.sqlite.IssueKey"
+	defer s.observe(op, time.Now())
+
+	if _, err := s.User(ctx, userID); err != nil {
+		return models.Credential{}, err
+	}
+
+	cred := models.Credential{UserID: userID, Key: apikey.New(), CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO user_credentials(key_hash, user_id, created_at) VALUES (?, ?, ?)",
+		apikey.Hash(cred.Key), cred.UserID, cred.CreatedAt,
+	); err != nil {
+		return models.Credential{}, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return cred, nil
+}
+
+// RevokeKeys отзывает все ключи API пользователя и возвращает их число.
+// Возвращает ErrUserNotFound для неизвестного пользователя.
+func (s *Storage) RevokeKeys(ctx context.Context, userID string) (int, error) {
+	const op = "storage.sqlite.RevokeKeys"
+	defer s.observe(op, time.Now())
+
+	if _, err := s.User(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM user_credentials WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return int(n), nil
+}
+
+// AssignWallet передает кошелек во владение пользователю.
+// Повторное назначение кошелька тому же пользователю ничего не изменяет.
+// Возвращает ErrUserNotFound, ErrWalletNotFound для неизвестных пользователя и кошелька
+// и ErrWalletOwned, если кошелек принадлежит другому пользователю.
+func (s *Storage) AssignWallet(ctx context.Context, userID, address string) error {
+	const op = "storage.sqlite.AssignWallet"
+	defer s.observe(op, time.Now())
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	if err = tx.QueryRowContext(ctx, "SELECT 1 FROM users WHERE id = ?", userID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+
+	var owner string
+	if err = tx.QueryRowContext(ctx, "SELECT user_id FROM wallets WHERE address = ?", address).Scan(&owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrWalletNotFound
+		}
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	switch owner {
+	case userID:
+		return nil
+	case "":
+	default:
+		return storage.ErrWalletOwned
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE wallets SET user_id = ? WHERE address = ?", userID, address); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	return nil
+}
+
+// UnassignWallet освобождает кошелек пользователя: кошелек остается без владельца.
+// Возвращает ErrUserNotFound для неизвестного пользователя
+// и ErrWalletNotFound, если кошелек ему не принадлежит.
+func (s *Storage) UnassignWallet(ctx context.Context, userID, address string) error {
+	const op = "storage.sqlite.UnassignWallet"
+	defer s.observe(op, time.Now())
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE wallets SET user_id = '' WHERE address = ? AND user_id = ? AND user_id != ''", address, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, s.translate(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	if _, err = s.User(ctx, userID); err != nil {
+		return err
+	}
+	return storage.ErrWalletNotFound
+}
+
+// UserWallets возвращает адреса кошельков пользователя, упорядоченные по адресу.
+// Возвращает ErrUserNotFound для неизвестного пользователя.
+func (s *Storage) UserWallets(ctx context.Context, userID string) ([]string, error) {
+	const op = "storage.sqlite.UserWallets"
+	defer s.observe(op, time.Now())
+
+	if _, err := s.User(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT address FROM wallets WHERE user_id = ? ORDER BY address", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err = rows.Scan(&address); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		addresses = append(addresses, address)
+	}
+	if err = rows.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return addresses, nil
+}
+
+// UserTransactions возвращает N последних транзакций, в которых отправитель или получатель -
+// кошелек пользователя. Транзакции сортируются от новых к старым.
+// При N <= 0 возвращает ErrInvalidRequest.
+func (s *Storage) UserTransactions(ctx context.Context, userID string, N int) ([]models.Transaction, error) {
+	const op = "storage.sqlite.UserTransactions"
+	defer s.observe(op, time.Now())
+
+	var txs []models.Transaction
+	err := s.recentTransactions(ctx, userID, N, func(tx models.Transaction) error {
+		txs = append(txs, tx)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.ErrInvalidRequest) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return txs, err
+}
+
+// WalletUser возвращает идентификатор пользователя, владеющего кошельком,
+// или пустую строку для кошелька без владельца.
+// Возвращает ErrWalletNotFound для неизвестного кошелька.
+func (s *Storage) WalletUser(ctx context.Context, address string) (string, error) {
+	const op = "storage.sqlite.WalletUser"
+	defer s.observe(op, time.Now())
+
+	var userID string
+	if err := s.db.QueryRowContext(ctx, "SELECT user_id FROM wallets WHERE address = ?", address).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrWalletNotFound
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return userID, nil
+}
+
+// scanUser читает профиль пользователя из строки результата.
+// Возвращает ErrUserNotFound, если строки нет.
+func scanUser(row *sql.Row) (models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, storage.ErrUserNotFound
+		}
+		return models.User{}, err
+	}
+	user.CreatedAt = user.CreatedAt.UTC()
+	return user, nil
+}
@@ -1,7 +1,11 @@
-// Package storage определяет ошибки уровня хранилища данных.
+// Package storage определяет ошибки уровня хранилища данных
+// и общие для реализаций хранилища типы.
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrWalletNotFound возвращается при отсутствии кошелька в БД.
@@ -18,4 +22,249 @@ var (
 
 	// ErrAddressesEqual возникает при совпадении адресов отправителя и получателя.
 	ErrAddressesEqual = errors.New("Адреса одинаковые")
+
+	// ErrPayoutNotFound возвращается при отсутствии задания на выплату.
+	ErrPayoutNotFound = errors.New("Задание на выплату не найдено")
+
+	// ErrVersionMismatch возвращается, если версия кошелька отличается от ожидаемой клиентом.
+	ErrVersionMismatch = errors.New("Кошелек изменился")
+
+	// ErrBusy возвращается, если база заблокирована другим соединением.
+	// Операция не выполнена и может быть повторена.
+	ErrBusy = errors.New("Хранилище занято")
+
+	// ErrDuplicate возвращается при нарушении ограничения уникальности.
+	ErrDuplicate = errors.New("Запись уже существует")
+
+	// ErrMalformedAddress возвращается для строки, не являющейся адресом кошелька:
+	// неверный формат или контрольная сумма.
+	ErrMalformedAddress = errors.New("Некорректный адрес кошелька")
+
+	// ErrInvalidHandle возвращается для имени кошелька недопустимого формата.
+	ErrInvalidHandle = errors.New("Некорректное имя кошелька")
+
+	// ErrHandleNotFound возвращается, если имя не присвоено ни одному кошельку.
+	ErrHandleNotFound = errors.New("Имя кошелька не найдено")
+
+	// ErrHandleTaken возвращается при попытке присвоить имя, занятое другим кошельком.
+	ErrHandleTaken = errors.New("Имя кошелька уже занято")
+
+	// ErrInvalidMetadata возвращается для недопустимых владельца, меток или метаданных кошелька.
+	ErrInvalidMetadata = errors.New("Некорректные метаданные кошелька")
+
+	// ErrUserNotFound возвращается при отсутствии пользователя или ключа API.
+	ErrUserNotFound = errors.New("Пользователь не найден")
+
+	// ErrInvalidUser возвращается для недопустимого профиля пользователя.
+	ErrInvalidUser = errors.New("Некорректный профиль пользователя")
+
+	// ErrWalletOwned возвращается при назначении кошелька, принадлежащего другому пользователю.
+	ErrWalletOwned = errors.New("Кошелек принадлежит другому пользователю")
+
+	// ErrForbidden возвращается, если пользователь не владеет кошельком, с которым выполняется операция.
+	ErrForbidden = errors.New("Нет доступа к кошельку")
+)
+
+// Машиночитаемые коды ошибок хранилища.
+// Коды стабильны и используются клиентами вместо текста сообщений.
+const (
+	CodeWalletNotFound    = "wallet_not_found"
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeIncorrectAmount   = "incorrect_amount"
+	CodeInvalidRequest    = "invalid_request"
+	CodeAddressesEqual    = "addresses_equal"
+	CodePayoutNotFound    = "payout_not_found"
+	CodeVersionMismatch   = "version_mismatch"
+	CodeMalformedAddress  = "malformed_address"
+	CodeInvalidHandle     = "invalid_handle"
+	CodeHandleNotFound    = "handle_not_found"
+	CodeHandleTaken       = "handle_taken"
+	CodeInvalidMetadata   = "invalid_metadata"
+	CodeUserNotFound      = "user_not_found"
+	CodeInvalidUser       = "invalid_user"
+	CodeWalletOwned       = "wallet_owned"
+	CodeForbidden         = "forbidden"
+	CodeBusy              = "storage_busy"
+)
+
+// Code возвращает код ошибки хранилища.
+// Для неизвестных ошибок возвращает пустую строку.
+func Code(err error) string {
+	switch {
+	case errors.Is(err, ErrWalletNotFound):
+		return CodeWalletNotFound
+	case errors.Is(err, ErrInsufficientFunds):
+		return CodeInsufficientFunds
+	case errors.Is(err, ErrIncorrectAmount):
+		return CodeIncorrectAmount
+	case errors.Is(err, ErrInvalidRequest):
+		return CodeInvalidRequest
+	case errors.Is(err, ErrAddressesEqual):
+		return CodeAddressesEqual
+	case errors.Is(err, ErrPayoutNotFound):
+		return CodePayoutNotFound
+	case errors.Is(err, ErrVersionMismatch):
+		return CodeVersionMismatch
+	case errors.Is(err, ErrMalformedAddress):
+		return CodeMalformedAddress
+	case errors.Is(err, ErrInvalidHandle):
+		return CodeInvalidHandle
+	case errors.Is(err, ErrHandleNotFound):
+		return CodeHandleNotFound
+	case errors.Is(err, ErrHandleTaken):
+		return CodeHandleTaken
+	case errors.Is(err, ErrInvalidMetadata):
+		return CodeInvalidMetadata
+	case errors.Is(err, ErrUserNotFound):
+		return CodeUserNotFound
+	case errors.Is(err, ErrInvalidUser):
+		return CodeInvalidUser
+	case errors.Is(err, ErrWalletOwned):
+		return CodeWalletOwned
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, ErrBusy):
+		return CodeBusy
+	default:
+		return ""
+	}
+}
+
+// InsufficientFundsError уточняет ErrInsufficientFunds сведениями о балансе.
+type InsufficientFundsError struct {
+	Available float64 // Доступный баланс отправителя
+	Requested float64 // Запрошенная сумма
+}
+
+// Error возвращает текст ErrInsufficientFunds.
+func (e *InsufficientFundsError) Error() string {
+	return ErrInsufficientFunds.Error()
+}
+
+// Is позволяет сравнивать ошибку с ErrInsufficientFunds через errors.Is.
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// Details возвращает структурированные сведения об ошибке для клиента.
+func (e *InsufficientFundsError) Details() map[string]any {
+	return map[string]any{
+		"available": e.Available,
+		"requested": e.Requested,
+	}
+}
+
+// VersionMismatchError уточняет ErrVersionMismatch текущей версией кошелька.
+type VersionMismatchError struct {
+	Expected int64 // Версия, ожидаемая клиентом
+	Current  int64 // Текущая версия кошелька
+}
+
+// Error возвращает текст ErrVersionMismatch.
+func (e *VersionMismatchError) Error() string {
+	return ErrVersionMismatch.Error()
+}
+
+// Is позволяет сравнивать ошибку с ErrVersionMismatch через errors.Is.
+func (e *VersionMismatchError) Is(target error) bool {
+	return target == ErrVersionMismatch
+}
+
+// Details возвращает структурированные сведения об ошибке для клиента.
+func (e *VersionMismatchError) Details() map[string]any {
+	return map[string]any{
+		"expected_version": e.Expected,
+		"current_version":  e.Current,
+	}
+}
+
+// InvalidMetadataError уточняет ErrInvalidMetadata причиной отказа.
+type InvalidMetadataError struct {
+	Reason string // Описание нарушенного ограничения
+}
+
+// Error возвращает текст ErrInvalidMetadata.
+func (e *InvalidMetadataError) Error() string {
+	return ErrInvalidMetadata.Error()
+}
+
+// Is позволяет сравнивать ошибку с ErrInvalidMetadata через errors.Is.
+func (e *InvalidMetadataError) Is(target error) bool {
+	return target == ErrInvalidMetadata
+}
+
+// Details возвращает структурированные сведения об ошибке для клиента.
+func (e *InvalidMetadataError) Details() map[string]any {
+	return map[string]any{"reason": e.Reason}
+}
+
+// Details извлекает структурированные сведения из ошибки хранилища.
+// Возвращает nil, если ошибка их не содержит.
+func Details(err error) map[string]any {
+	var d interface{ Details() map[string]any }
+	if errors.As(err, &d) {
+		return d.Details()
+	}
+	return nil
+}
+
+// Исходы операции перевода для мониторинга.
+// Помимо этих значений исходом может быть любой код ошибки хранилища.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
 )
+
+// Outcome сопоставляет ошибку хранилища с исходом операции.
+// Неизвестные ошибки считаются внутренними.
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if code := Code(err); code != "" {
+		return code
+	}
+	return OutcomeError
+}
+
+// Observer получает сведения о выполненных операциях хранилища.
+// Используется для сбора метрик и не должен блокировать вызывающую сторону.
+type Observer interface {
+	// ObserveQuery сообщает о длительности операции хранилища.
+	ObserveQuery(op string, d time.Duration)
+	// ObserveTransfer сообщает о результате перевода.
+	ObserveTransfer(amount float64, err error)
+}
+
+// NopObserver игнорирует все события.
+type NopObserver struct{}
+
+// ObserveQuery ничего не делает.
+func (NopObserver) ObserveQuery(string, time.Duration) {}
+
+// ObserveTransfer ничего не делает.
+func (NopObserver) ObserveTransfer(float64, error) {}
+
+// Transfer описывает перевод, выполненный движком переводов и переносимый в хранилище.
+// Seq - номер записи в журнале движка, по нему перенос выполняется ровно один раз.
+type Transfer struct {
+	Seq       uint64    // Номер записи журнала
+	From      string    // Адрес отправителя
+	To        string    // Адрес получателя
+	Amount    float64   // Сумма перевода
+	Time      time.Time // Время выполнения перевода
+	Reference string    // Ссылка на источник перевода, например строку задания на выплату
+}
+
+// Migration описывает одну версию схемы хранилища.
+type Migration struct {
+	Version int    // Номер версии схемы
+	Name    string // Краткое описание изменений
+	SQL     string // Выражения, применяющие миграцию
+}
+
+// Issue - нарушение целостности данных, найденное проверкой хранилища.
+type Issue struct {
+	Check   string // Название проверки
+	Message string // Описание нарушения
+}